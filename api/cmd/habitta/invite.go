@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+)
+
+// runInvite handles `habitta invite resend`
+func runInvite(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: habitta invite resend -id <invite-id>")
+	}
+
+	switch args[0] {
+	case "resend":
+		return runInviteResend(a, args[1:])
+	default:
+		return fmt.Errorf("unknown invite subcommand: %s", args[0])
+	}
+}
+
+// runInviteResend renews a pending or expired invite with a new link and queues its email again
+func runInviteResend(a *app, args []string) error {
+	fs := flag.NewFlagSet("invite resend", flag.ContinueOnError)
+	id := fs.Uint("id", 0, "invite ID (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return errors.New("-id is required")
+	}
+
+	invite, err := a.inviteService.ResendInvite(a.ctx, *id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Invite %d queued for %s (expires %s)\n", invite.ID, invite.Email, invite.ExpiresAt.Format("2006-01-02 15:04"))
+	return nil
+}
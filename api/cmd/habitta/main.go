@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"gorm.io/gorm"
+)
+
+// app holds the dependencies shared by all subcommands, wired like cmd/server
+type app struct {
+	cfg *config.Config
+	db  *gorm.DB
+	ctx context.Context // Carries db, so repositories use it instead of a request transaction
+
+	userService       services.UserService
+	tenantService     services.TenantService
+	tenantMgmtService services.TenantManagementService
+	inviteService     services.InviteService
+	unitService       services.UnitService
+
+	// The CLI doesn't connect to storage, so folders are written through the repository
+	folderRepo repositories.FolderRepository
+}
+
+// command is a top-level CLI subcommand
+type command struct {
+	name        string
+	description string
+	run         func(a *app, args []string) error
+}
+
+var commands = []command{
+	{name: "migrate", description: "Manage database migrations (up | down [steps] | status)", run: runMigrate},
+	{name: "user", description: "Manage users (create-admin | reset-password)", run: runUser},
+	{name: "tenant", description: "Manage tenants (list | deactivate)", run: runTenant},
+	{name: "invite", description: "Manage invites (resend)", run: runInvite},
+	{name: "seed", description: "Load sample data (demo)", run: runSeed},
+}
+
+func main() {
+	if len(os.Args) < 2 || isHelp(os.Args[1]) {
+		usage()
+		return
+	}
+
+	cmd, ok := findCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	// Same settings as the server
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// The CLI works across tenants, like the server's background workers
+	db, err := database.InitWorkerDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer database.Close(db)
+
+	if err := cmd.run(newApp(cfg, db), os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		database.Close(db)
+		os.Exit(1)
+	}
+}
+
+// newApp wires repositories and services
+func newApp(cfg *config.Config, db *gorm.DB) *app {
+	tenantRepo := repositories.NewTenantRepository(db)
+	userRepo := repositories.NewUserRepository(db)
+	userTenantRepo := repositories.NewUserTenantRepository(db)
+	inviteRepo := repositories.NewInviteRepository(db)
+	unitRepo := repositories.NewUnitRepository(db)
+	folderRepo := repositories.NewFolderRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	customRoleRepo := repositories.NewCustomRoleRepository(db)
+	unitMemberRepo := repositories.NewUnitMemberRepository(db)
+
+	// The CLI only queues emails; the API server's outbox worker delivers them
+	emailOutbox := services.NewEmailOutboxService(repositories.NewEmailOutboxRepository(db), services.NewEmailService(cfg))
+	roleService := services.NewRoleService(customRoleRepo, userTenantRepo, inviteRepo)
+
+	return &app{
+		cfg:               cfg,
+		db:                db,
+		ctx:               database.WithDB(context.Background(), db),
+		userService:       services.NewUserService(userRepo, tenantRepo, userTenantRepo, unitRepo, unitMemberRepo, sessionRepo, roleService),
+		tenantService:     services.NewTenantService(tenantRepo, sessionRepo),
+		tenantMgmtService: services.NewTenantManagementService(tenantRepo, userTenantRepo, userRepo, db),
+		inviteService:     services.NewInviteService(inviteRepo, userRepo, userTenantRepo, unitRepo, roleService, db, emailOutbox, cfg.Email.AppBaseURL),
+		unitService:       services.NewUnitService(unitRepo, tenantRepo),
+		folderRepo:        folderRepo,
+	}
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// isHelp checks if the argument asks for usage information
+func isHelp(arg string) bool {
+	return arg == "help" || arg == "-h" || arg == "--help"
+}
+
+// usage prints the available subcommands
+func usage() {
+	fmt.Println("Usage: habitta <command> [arguments]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	for _, cmd := range commands {
+		fmt.Printf("  %-10s %s\n", cmd.name, cmd.description)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+)
+
+// Demo data loaded by `habitta seed demo`
+const (
+	demoTenantName   = "Condomínio Demo"
+	demoTenantCNPJ   = "11.111.111/0001-11"
+	demoPassword     = "demo123"
+	demoSindicoEmail = "sindico@demo.habitta.local"
+	demoMoradorEmail = "morador@demo.habitta.local"
+)
+
+// runSeed handles `habitta seed demo`
+func runSeed(a *app, args []string) error {
+	if len(args) == 0 || args[0] != "demo" {
+		return errors.New("usage: habitta seed demo")
+	}
+
+	return seedDemo(a)
+}
+
+// seedDemo creates a demo condominium with a síndico, a morador, units and a folder
+func seedDemo(a *app) error {
+	if _, err := a.tenantService.GetByCNPJ(demoTenantCNPJ); err == nil {
+		return errors.New("demo data already exists")
+	}
+
+	sindico, err := a.userService.CreateUser(services.CreateUserRequest{
+		Email:         demoSindicoEmail,
+		Password:      demoPassword,
+		Name:          "Síndico Demo",
+		EmailVerified: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create síndico: %w", err)
+	}
+
+	morador, err := a.userService.CreateUser(services.CreateUserRequest{
+		Email:         demoMoradorEmail,
+		Password:      demoPassword,
+		Name:          "Morador Demo",
+		EmailVerified: true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create morador: %w", err)
+	}
+
+	tenant, err := a.tenantMgmtService.CreateTenantByUser(a.ctx, sindico.ID, services.CreateTenantRequest{
+		Name: demoTenantName,
+		CNPJ: demoTenantCNPJ,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create tenant: %w", err)
+	}
+
+	err = database.RunScoped(a.ctx, a.db, database.Scope{TenantID: tenant.ID}, func(ctx context.Context) error {
+		var units []models.Unit
+		for _, number := range []string{"101", "102", "201", "202"} {
+			unit := models.Unit{TenantID: tenant.ID, Number: number, Block: "A"}
+			if err := a.unitService.Create(ctx, &unit); err != nil {
+				return fmt.Errorf("failed to create unit %s: %w", number, err)
+			}
+			units = append(units, unit)
+		}
+
+		if err := a.userService.AddToTenant(ctx, tenant.ID, morador.ID, models.RoleMorador); err != nil {
+			return fmt.Errorf("failed to add morador: %w", err)
+		}
+		if err := a.userService.UpdateMembership(ctx, tenant.ID, models.RoleAdmin, morador.ID, true, &units[0].ID); err != nil {
+			return fmt.Errorf("failed to assign unit: %w", err)
+		}
+
+		folder := models.Folder{TenantID: tenant.ID, Name: "Atas", Description: "Atas de assembleia"}
+		if err := a.folderRepo.Create(ctx, &folder); err != nil {
+			return fmt.Errorf("failed to create folder: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Demo tenant %q created (id %d)\n", tenant.Name, tenant.ID)
+	fmt.Printf("  síndico: %s / %s\n", demoSindicoEmail, demoPassword)
+	fmt.Printf("  morador: %s / %s\n", demoMoradorEmail, demoPassword)
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// runTenant handles `habitta tenant list|deactivate`
+func runTenant(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: habitta tenant list|deactivate [flags]")
+	}
+
+	switch args[0] {
+	case "list":
+		return runTenantList(a)
+	case "deactivate":
+		return runTenantDeactivate(a, args[1:])
+	default:
+		return fmt.Errorf("unknown tenant subcommand: %s", args[0])
+	}
+}
+
+// runTenantList prints all tenants
+func runTenantList(a *app) error {
+	tenants, err := a.tenantService.GetAll()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCNPJ\tACTIVE")
+	for _, t := range tenants {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%t\n", t.ID, t.Name, t.CNPJ, t.Active)
+	}
+	return w.Flush()
+}
+
+// runTenantDeactivate deactivates a tenant and signs out its members
+func runTenantDeactivate(a *app, args []string) error {
+	fs := flag.NewFlagSet("tenant deactivate", flag.ContinueOnError)
+	id := fs.Uint("id", 0, "tenant ID (required)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *id == 0 {
+		return errors.New("-id is required")
+	}
+
+	if err := a.tenantService.Deactivate(*id); err != nil {
+		return err
+	}
+
+	fmt.Printf("Tenant %d deactivated; its sessions were revoked\n", *id)
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+)
+
+// Platform tenant that holds global admins created without -tenant-id
+const (
+	platformTenantName = "Habitta"
+	platformTenantCNPJ = "00.000.000/0000-00"
+)
+
+// runUser handles `habitta user create-admin|reset-password`
+func runUser(a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: habitta user create-admin|reset-password [flags]")
+	}
+
+	switch args[0] {
+	case "create-admin":
+		return runUserCreateAdmin(a, args[1:])
+	case "reset-password":
+		return runUserResetPassword(a, args[1:])
+	default:
+		return fmt.Errorf("unknown user subcommand: %s", args[0])
+	}
+}
+
+// runUserCreateAdmin creates (or promotes) a user as admin of a tenant
+func runUserCreateAdmin(a *app, args []string) error {
+	fs := flag.NewFlagSet("user create-admin", flag.ContinueOnError)
+	email := fs.String("email", "", "admin email (required)")
+	name := fs.String("name", "", "admin name (required for new users)")
+	password := fs.String("password", "", "admin password (generated if empty)")
+	tenantID := fs.Uint("tenant-id", 0, "tenant to administer (defaults to the platform tenant)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	user, err := a.userService.GetByEmail(*email)
+	if err != nil {
+		if *name == "" {
+			return errors.New("-name is required when creating a new user")
+		}
+
+		generated := *password == ""
+		if generated {
+			if *password, err = generatePassword(); err != nil {
+				return err
+			}
+		}
+
+		user, err = a.userService.CreateUser(services.CreateUserRequest{
+			Email:         *email,
+			Password:      *password,
+			Name:          *name,
+			EmailVerified: true,
+		})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created user %s (id %d)\n", user.Email, user.ID)
+		if generated {
+			fmt.Printf("Generated password: %s\n", *password)
+		}
+	} else {
+		fmt.Printf("Using existing user %s (id %d)\n", user.Email, user.ID)
+	}
+
+	tenant, err := resolveAdminTenant(a, *tenantID)
+	if err != nil {
+		return err
+	}
+
+	err = database.RunScoped(a.ctx, a.db, database.Scope{TenantID: tenant.ID}, func(ctx context.Context) error {
+		return a.userService.AddToTenant(ctx, tenant.ID, user.ID, models.RoleAdmin)
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("User %s is now admin of tenant %q (id %d)\n", user.Email, tenant.Name, tenant.ID)
+	return nil
+}
+
+// resolveAdminTenant returns the given tenant, or the platform tenant when id is zero
+func resolveAdminTenant(a *app, id uint) (*models.Tenant, error) {
+	if id != 0 {
+		return a.tenantService.GetByID(id)
+	}
+
+	if tenant, err := a.tenantService.GetByCNPJ(platformTenantCNPJ); err == nil {
+		return tenant, nil
+	}
+
+	tenant := &models.Tenant{
+		Name: platformTenantName,
+		CNPJ: platformTenantCNPJ,
+	}
+	if err := a.tenantService.Create(tenant); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Created platform tenant %q (id %d)\n", tenant.Name, tenant.ID)
+	return tenant, nil
+}
+
+// runUserResetPassword sets a new password and revokes the user's sessions
+func runUserResetPassword(a *app, args []string) error {
+	fs := flag.NewFlagSet("user reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "user email (required)")
+	password := fs.String("password", "", "new password (generated if empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	user, err := a.userService.GetByEmail(*email)
+	if err != nil {
+		return err
+	}
+
+	generated := *password == ""
+	if generated {
+		if *password, err = generatePassword(); err != nil {
+			return err
+		}
+	}
+
+	if err := a.userService.ResetPassword(user.ID, *password); err != nil {
+		return err
+	}
+
+	fmt.Printf("Password reset for %s; all sessions revoked\n", user.Email)
+	if generated {
+		fmt.Printf("Generated password: %s\n", *password)
+	}
+	return nil
+}
+
+// generatePassword returns a random password accepted by utils.IsPasswordValid
+func generatePassword() (string, error) {
+	return utils.GenerateSecureToken(12)
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,7 +16,9 @@ import (
 	"github.com/arturbaldoramos/Habitta/internal/handlers"
 	"github.com/arturbaldoramos/Habitta/internal/middleware"
 	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/ratelimit"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/internal/scheduler"
 	"github.com/arturbaldoramos/Habitta/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -37,20 +40,37 @@ func main() {
 	}
 	log.Println("Database connection established")
 
-	// Run migrations - ORDER MATTERS! (dependencies first)
-	log.Println("Running database migrations...")
-	if err := database.AutoMigrate(db,
-		&models.Tenant{},
-		&models.User{},
-		&models.UserTenant{}, // Pivot table for many-to-many
-		&models.Invite{},     // Invite system
-		&models.Unit{},
-		&models.Folder{},
-		&models.Document{},
-	); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
-	}
-	log.Println("Database migrations completed")
+	// Apply pending migrations as the schema owner (can also be run separately with `habitta migrate up`)
+	if cfg.Database.AutoMigrate {
+		log.Println("Running database migrations...")
+		migrateDB, err := database.InitMigrateDB(cfg)
+		if err != nil {
+			log.Fatalf("Failed to connect to run migrations: %v", err)
+		}
+		applied, err := database.MigrateUp(migrateDB)
+		database.Close(migrateDB)
+		if err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		log.Printf("Database migrations completed (%d applied)", applied)
+	}
+
+	// Background workers and scheduled jobs handle every tenant, on a connection that bypasses row-level security
+	workerDB, err := database.InitWorkerDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize worker database connection: %v", err)
+	}
+
+	// Tenant isolation relies on the API's role being subject to row-level security
+	for _, check := range []error{database.CheckAppRole(db), database.CheckWorkerRole(workerDB)} {
+		if check == nil {
+			continue
+		}
+		if cfg.Server.Env == "production" {
+			log.Fatalf("Unsafe database roles: %v", check)
+		}
+		log.Printf("WARNING: %v", check)
+	}
 
 	// Initialize repositories
 	tenantRepo := repositories.NewTenantRepository(db)
@@ -60,27 +80,68 @@ func main() {
 	unitRepo := repositories.NewUnitRepository(db)
 	folderRepo := repositories.NewFolderRepository(db)
 	documentRepo := repositories.NewDocumentRepository(db)
+	sessionRepo := repositories.NewSessionRepository(db)
+	passwordResetRepo := repositories.NewPasswordResetRepository(db)
+	emailVerificationRepo := repositories.NewEmailVerificationRepository(db)
+	recoveryCodeRepo := repositories.NewRecoveryCodeRepository(db)
+	customRoleRepo := repositories.NewCustomRoleRepository(db)
+	unitMemberRepo := repositories.NewUnitMemberRepository(db)
+	uploadSessionRepo := repositories.NewUploadSessionRepository(db)
+	shareLinkRepo := repositories.NewShareLinkRepository(db)
+	emailOutboxRepo := repositories.NewEmailOutboxRepository(db)
+	jobRunRepo := repositories.NewJobRunRepository(db)
 	log.Println("Repositories initialized")
 
 	// Initialize services
-	emailService := services.NewEmailService(cfg)
-	authService := services.NewAuthService(userRepo, userTenantRepo, tenantRepo, cfg)
-	tenantMgmtService := services.NewTenantManagementService(tenantRepo, userTenantRepo, db)
-	inviteService := services.NewInviteService(inviteRepo, userRepo, userTenantRepo, db, emailService, cfg.Email.AppBaseURL)
-	tenantService := services.NewTenantService(tenantRepo)
-	userService := services.NewUserService(userRepo, tenantRepo, userTenantRepo)
+	// Emails are queued in the outbox and delivered by its worker through the EMAIL_DRIVER backend
+	emailOutbox := services.NewEmailOutboxService(emailOutboxRepo, services.NewEmailService(cfg))
+	twoFactorService := services.NewTwoFactorService(userRepo, userTenantRepo, tenantRepo, recoveryCodeRepo)
+	authService := services.NewAuthService(userRepo, userTenantRepo, tenantRepo, sessionRepo, passwordResetRepo, emailVerificationRepo, db, emailOutbox, twoFactorService, cfg)
+	tenantMgmtService := services.NewTenantManagementService(tenantRepo, userTenantRepo, userRepo, db)
+	roleService := services.NewRoleService(customRoleRepo, userTenantRepo, inviteRepo)
+	inviteService := services.NewInviteService(inviteRepo, userRepo, userTenantRepo, unitRepo, roleService, db, emailOutbox, cfg.Email.AppBaseURL)
+	tenantService := services.NewTenantService(tenantRepo, sessionRepo)
+	userService := services.NewUserService(userRepo, tenantRepo, userTenantRepo, unitRepo, unitMemberRepo, sessionRepo, roleService)
 	unitService := services.NewUnitService(unitRepo, tenantRepo)
+	unitMemberService := services.NewUnitMemberService(unitRepo, unitMemberRepo, userTenantRepo)
 
-	// Initialize storage service (S3/MinIO)
+	// Initialize storage service (S3/MinIO or local disk, per STORAGE_DRIVER)
 	storageSvc, err := services.NewStorageService(cfg.Storage)
 	if err != nil {
 		log.Fatalf("Failed to initialize storage service: %v", err)
 	}
+	log.Printf("Storage driver: %s", cfg.Storage.Driver)
 
-	folderService := services.NewFolderService(folderRepo)
-	documentService := services.NewDocumentService(documentRepo, folderRepo, storageSvc)
+	malwareScanner, err := services.NewMalwareScanner(cfg.Scanner)
+	if err != nil {
+		log.Fatalf("Failed to initialize malware scanner: %v", err)
+	}
+	log.Printf("Malware scanner: %s", cfg.Scanner.Driver)
+
+	scanService := services.NewDocumentScanService(documentRepo, storageSvc, malwareScanner, db)
+	quotaService := services.NewStorageQuotaService(tenantRepo, userTenantRepo, documentRepo, emailOutbox, cfg.Storage.DefaultQuotaMB, cfg.Email.AppBaseURL)
+	folderService := services.NewFolderService(folderRepo, documentRepo, storageSvc, quotaService)
+	documentService := services.NewDocumentService(documentRepo, folderRepo, unitMemberRepo, tenantRepo, storageSvc, quotaService, scanService, shareLinkRepo, db, cfg.Storage.PublicBaseURL)
+	archiveService := services.NewDocumentArchiveService(documentRepo, folderRepo, storageSvc, db)
+	uploadSessionService := services.NewUploadSessionService(uploadSessionRepo, documentRepo, folderRepo, documentService, storageSvc, quotaService, db, cfg.Storage)
+	retentionService := services.NewRetentionService(workerDB, jobRunRepo)
 	log.Println("Services initialized")
 
+	// Scheduled jobs (cron expressions in UTC); each slot runs on a single replica
+	jobScheduler := scheduler.New(workerDB, jobRunRepo)
+	for _, job := range []struct {
+		name, schedule string
+		run            scheduler.JobFunc
+	}{
+		{"expire-invites", "*/10 * * * *", inviteService.ExpireInvites},
+		{"invite-reminders", "5 * * * *", inviteService.SendExpiryReminders},
+		{"purge-deleted", "30 3 * * *", retentionService.PurgeDeleted},
+	} {
+		if err := jobScheduler.Register(job.name, job.schedule, job.run); err != nil {
+			log.Fatalf("Failed to register job %s: %v", job.name, err)
+		}
+	}
+
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	tenantMgmtHandler := handlers.NewTenantManagementHandler(tenantMgmtService)
@@ -88,11 +149,27 @@ func main() {
 	userTenantsHandler := handlers.NewUserTenantsHandler(userTenantRepo)
 	tenantHandler := handlers.NewTenantHandler(tenantService)
 	userHandler := handlers.NewUserHandler(userService)
-	unitHandler := handlers.NewUnitHandler(unitService)
-	accountHandler := handlers.NewAccountHandler(userService)
-	documentHandler := handlers.NewDocumentHandler(folderService, documentService)
+	unitHandler := handlers.NewUnitHandler(unitService, unitMemberService)
+	accountHandler := handlers.NewAccountHandler(userService, twoFactorService)
+	documentHandler := handlers.NewDocumentHandler(folderService, documentService, quotaService, archiveService)
+	uploadHandler := handlers.NewUploadHandler(uploadSessionService)
+	roleHandler := handlers.NewRoleHandler(roleService)
+	emailOutboxHandler := handlers.NewEmailOutboxHandler(emailOutbox)
+	jobHandler := handlers.NewJobHandler(jobScheduler)
+	var storageHandler *handlers.StorageHandler
+	if localStore, ok := storageSvc.(services.LocalFileStore); ok {
+		storageHandler = handlers.NewStorageHandler(localStore)
+	}
 	log.Println("Handlers initialized")
 
+	// Initialize rate limiting
+	rateLimitStore, err := ratelimit.NewStore(cfg.Security.RateLimitStore)
+	if err != nil {
+		log.Fatalf("Failed to initialize rate limit store: %v", err)
+	}
+	ipLimit := ratelimit.PerMinute(cfg.Security.RateLimitIPPerMinute, cfg.Security.RateLimitIPBurst)
+	accountLimit := ratelimit.PerMinute(cfg.Security.RateLimitAccountPerMinute, cfg.Security.RateLimitAccountBurst)
+
 	// Setup Gin router
 	if cfg.Server.Env == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -115,14 +192,29 @@ func main() {
 	// API routes
 	api := router.Group("/api")
 	{
-		// Public routes (no authentication required)
-		authHandler.RegisterRoutes(api)
-		api.GET("/invites/:token", inviteHandler.GetInviteByToken)
-		api.POST("/invites/:token/accept", inviteHandler.AcceptInvite)
+		// Public routes (no authentication required), throttled per IP and per account email
+		public := api.Group("")
+		public.Use(middleware.RateLimitMiddleware(rateLimitStore, "ip", ipLimit, middleware.ClientIPKey))
+		public.Use(middleware.RateLimitMiddleware(rateLimitStore, "account", accountLimit, middleware.JSONFieldKey("email")))
+		{
+			authHandler.RegisterRoutes(public)
+			public.GET("/invites/:token", inviteHandler.GetInviteByToken)
+			public.POST("/invites/:token/accept", inviteHandler.AcceptInvite)
+
+			// Document share links, also throttled per link against password guessing
+			documentHandler.RegisterPublicRoutes(public.Group("",
+				middleware.RateLimitMiddleware(rateLimitStore, "share", accountLimit, middleware.ParamKey("token"))))
+		}
+
+		// Local storage downloads (the HMAC signature in the URL is the credential)
+		if storageHandler != nil {
+			storageHandler.RegisterRoutes(api)
+		}
 
 		// Protected routes WITHOUT tenant context (orphan users can access)
 		protectedNoTenant := api.Group("")
-		protectedNoTenant.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		protectedNoTenant.Use(middleware.AuthMiddleware(cfg.JWT.Secret, authService))
+		protectedNoTenant.Use(middleware.ScopedDBMiddleware(db))
 		{
 			// Tenant management - create condominium (user becomes síndico)
 			protectedNoTenant.POST("/tenants/create", tenantMgmtHandler.CreateTenant)
@@ -133,6 +225,12 @@ func main() {
 			// Auth - switch active tenant
 			protectedNoTenant.POST("/auth/switch-tenant/:tenant_id", authHandler.SwitchTenant)
 
+			// Auth - revoke current session
+			protectedNoTenant.POST("/auth/logout", authHandler.Logout)
+
+			// Auth - resend email verification link
+			protectedNoTenant.POST("/auth/resend-verification", authHandler.ResendVerification)
+
 			// Invites - my pending invites
 			protectedNoTenant.GET("/invites/me", inviteHandler.GetMyPendingInvites)
 
@@ -140,10 +238,21 @@ func main() {
 			accountHandler.RegisterRoutes(protectedNoTenant)
 		}
 
+		// Uploads through the API and ZIP downloads last as long as the client takes to send or read them,
+		// so they get short scoped transactions instead of one for the whole request
+		streamedRoutes := []string{
+			"POST /api/documents/upload",
+			"POST /api/documents/:id/versions",
+			"POST /api/documents/archive",
+		}
+
 		// Protected routes WITH tenant context (requires active_tenant_id)
+		// Routes check the active role's permissions with RequirePermission
 		protectedWithTenant := api.Group("")
-		protectedWithTenant.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
+		protectedWithTenant.Use(middleware.AuthMiddleware(cfg.JWT.Secret, authService))
 		protectedWithTenant.Use(middleware.TenantMiddleware())
+		protectedWithTenant.Use(middleware.ScopedDBMiddleware(db, streamedRoutes...))
+		protectedWithTenant.Use(middleware.LoadPermissions(db, roleService))
 		{
 			// User routes (tenant-isolated)
 			userHandler.RegisterRoutes(protectedWithTenant)
@@ -151,26 +260,42 @@ func main() {
 			// Unit routes (tenant-isolated)
 			unitHandler.RegisterRoutes(protectedWithTenant)
 
-			// Invite routes (tenant-isolated, síndico/admin only)
-			protectedWithTenant.POST("/invites", inviteHandler.CreateInvite)
+			// Invite routes (tenant-isolated; the inviter may also cancel their own invites)
+			protectedWithTenant.POST("/invites", middleware.RequirePermission(models.PermInvitesCreate), inviteHandler.CreateInvite)
+			protectedWithTenant.POST("/invites/bulk", middleware.RequirePermission(models.PermInvitesCreate), inviteHandler.CreateInvitesBulk)
+			// Gin needs the wildcard to share its name with POST /invites/:token/accept; it holds the invite ID
+			protectedWithTenant.POST("/invites/:token/resend", inviteHandler.ResendInvite)
 			protectedWithTenant.DELETE("/invites/:id", inviteHandler.CancelInvite)
-			protectedWithTenant.GET("/tenants/invites", inviteHandler.GetTenantInvites)
-
-			// Document and folder routes (síndico/admin only)
-			docRoutes := protectedWithTenant.Group("")
-			docRoutes.Use(middleware.RequireRole("sindico", "admin"))
-			{
-				documentHandler.RegisterRoutes(docRoutes)
-			}
+			protectedWithTenant.GET("/tenants/invites", middleware.RequirePermission(models.PermInvitesRead), inviteHandler.GetTenantInvites)
+
+			// Tenant security settings
+			protectedWithTenant.PATCH("/tenants/current/settings", middleware.RequirePermission(models.PermSettingsManage), tenantMgmtHandler.UpdateSettings)
+
+			// Role and permission routes
+			roleHandler.RegisterRoutes(protectedWithTenant)
+
+			// Document and folder routes
+			documentHandler.RegisterRoutes(protectedWithTenant)
+
+			// Direct-to-storage upload sessions
+			uploadHandler.RegisterRoutes(protectedWithTenant)
 		}
 
-		// Admin routes (global admin, no tenant context)
+		// Admin routes (platform admin via the tenants.manage permission)
 		admin := api.Group("")
-		admin.Use(middleware.AuthMiddleware(cfg.JWT.Secret))
-		admin.Use(middleware.RequireRole("admin"))
+		admin.Use(middleware.AuthMiddleware(cfg.JWT.Secret, authService))
+		admin.Use(middleware.ScopedDBMiddleware(db))
+		admin.Use(middleware.LoadPermissions(db, roleService))
+		admin.Use(middleware.RequirePermission(models.PermTenantsManage))
 		{
 			// Tenant routes (admin only)
 			tenantHandler.RegisterRoutes(admin)
+
+			// Email outbox inspection and retries (admin only)
+			emailOutboxHandler.RegisterRoutes(admin)
+
+			// Scheduled jobs and their run history (admin only)
+			jobHandler.RegisterRoutes(admin)
 		}
 	}
 
@@ -183,6 +308,26 @@ func main() {
 		Handler: router,
 	}
 
+	// Background workers: abort upload sessions left incomplete, scan uploaded files, deliver queued emails and run scheduled jobs
+	// Workers handle every tenant, so their repositories take the connection from workerCtx
+	// They are tracked so shutdown can wait for them before closing the database
+	workerCtx, stopWorkers := context.WithCancel(database.WithDB(context.Background(), workerDB))
+	var workers sync.WaitGroup
+	workers.Add(3)
+	go func() {
+		defer workers.Done()
+		uploadSessionService.RunSweeper(workerCtx, 10*time.Minute)
+	}()
+	go func() {
+		defer workers.Done()
+		scanService.Run(workerCtx, time.Minute)
+	}()
+	go func() {
+		defer workers.Done()
+		emailOutbox.Run(workerCtx, 15*time.Second)
+	}()
+	jobScheduler.Start(workerCtx)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Starting server on %s", addr)
@@ -198,6 +343,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down server...")
+	stopWorkers()
 
 	// Graceful shutdown with 5 second timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -207,7 +353,14 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
-	// Close database connection
+	// Let running jobs and workers finish their current step before the database goes away
+	jobScheduler.Stop()
+	workers.Wait()
+
+	// Close database connections
+	if err := database.Close(workerDB); err != nil {
+		log.Printf("Error closing worker database connection: %v", err)
+	}
 	if err := database.Close(db); err != nil {
 		log.Printf("Error closing database connection: %v", err)
 	}
@@ -15,23 +15,73 @@ type Config struct {
 	CORS     CORSConfig
 	Email    EmailConfig
 	Storage  StorageConfig
+	Scanner  ScannerConfig
+	Security SecurityConfig
 }
 
-// StorageConfig holds S3/MinIO storage configuration
+// SecurityConfig holds rate limiting and account lockout configuration
+type SecurityConfig struct {
+	RateLimitStore            string // Token bucket store backend (memory)
+	RateLimitIPPerMinute      int
+	RateLimitIPBurst          int
+	RateLimitAccountPerMinute int
+	RateLimitAccountBurst     int
+	LockoutThreshold          int // Failed logins before the account is locked
+	LockoutBaseMinutes        int // First lock duration; doubles on each consecutive lockout
+	LockoutMaxMinutes         int
+}
+
+// StorageConfig holds file storage configuration
 type StorageConfig struct {
+	Driver string // s3 or local
+
+	// S3/MinIO
 	Endpoint     string
 	Bucket       string
 	Region       string
 	AccessKey    string
 	SecretKey    string
 	UsePathStyle bool
+
+	// Local filesystem; downloads go through signed API URLs
+	LocalRoot     string
+	PublicBaseURL string // Base URL of the API, used to build download links
+	SigningKey    string // HMAC key for download links (defaults to JWT_SECRET)
+
+	// Direct uploads through upload sessions
+	MaxUploadMB          int // Largest file accepted by an upload session
+	MultipartThresholdMB int // Files above this size are uploaded in parts
+	MultipartPartMB      int // Part size for multipart uploads (S3 requires at least 5 MB)
+	UploadSessionMinutes int // How long an upload session and its URLs stay valid
+
+	// Quota for tenants without one of their own; 0 means unlimited
+	DefaultQuotaMB int
+}
+
+// ScannerConfig holds malware scanner configuration
+type ScannerConfig struct {
+	Driver         string // none (every file passes) or clamav
+	ClamAVAddress  string // clamd socket, e.g. tcp://localhost:3310 or unix:///run/clamav/clamd.ctl
+	TimeoutSeconds int    // Per-file scan timeout
 }
 
 // EmailConfig holds email service configuration
 type EmailConfig struct {
+	Driver       string // console, resend or smtp; defaults to console in development and resend otherwise
 	ResendAPIKey string
 	FromAddress  string
 	AppBaseURL   string
+	SMTP         SMTPConfig
+}
+
+// SMTPConfig holds the SMTP server used by the smtp email driver
+type SMTPConfig struct {
+	Host           string
+	Port           int
+	Username       string // Empty skips AUTH
+	Password       string
+	Security       string // starttls, tls (implicit TLS, usually port 465) or none (local mail sinks only)
+	TimeoutSeconds int
 }
 
 // ServerConfig holds server configuration
@@ -44,16 +94,28 @@ type ServerConfig struct {
 type DatabaseConfig struct {
 	Host     string
 	Port     string
-	User     string
+	User     string // The API's role (habitta_app), subject to row-level security
 	Password string
 	Name     string
 	SSLMode  string
+
+	// MigrateUser applies migrations: the owner of the schema, and a superuser (see migration 000017)
+	MigrateUser     string
+	MigratePassword string
+
+	// WorkerUser runs background workers and the CLI, which work across tenants: a role with BYPASSRLS (habitta_worker)
+	WorkerUser     string
+	WorkerPassword string
+
+	// AutoMigrate applies pending migrations when the server starts (safe with several replicas: an advisory lock serializes them)
+	AutoMigrate bool
 }
 
-// JWTConfig holds JWT configuration
+// JWTConfig holds JWT and session configuration
 type JWTConfig struct {
-	Secret          string
-	ExpirationHours int
+	Secret             string
+	AccessTokenMinutes int
+	RefreshTokenDays   int
 }
 
 // CORSConfig holds CORS configuration
@@ -82,16 +144,40 @@ func LoadConfig() (*Config, error) {
 	viper.SetDefault("DATABASE_HOST", "localhost")
 	viper.SetDefault("DATABASE_PORT", "5432")
 	viper.SetDefault("DATABASE_SSL_MODE", "disable")
-	viper.SetDefault("JWT_EXPIRATION_HOURS", 24)
+	viper.SetDefault("DATABASE_AUTO_MIGRATE", true)
+	viper.SetDefault("JWT_ACCESS_TOKEN_MINUTES", 15)
+	viper.SetDefault("JWT_REFRESH_TOKEN_DAYS", 30)
 	viper.SetDefault("ALLOWED_ORIGINS", "http://localhost:4200")
 	viper.SetDefault("EMAIL_FROM", "noreply@habitta.com")
 	viper.SetDefault("APP_BASE_URL", "http://localhost:4200")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("SMTP_SECURITY", "starttls")
+	viper.SetDefault("SMTP_TIMEOUT_SECONDS", 30)
+	viper.SetDefault("STORAGE_DRIVER", "s3")
+	viper.SetDefault("STORAGE_LOCAL_ROOT", "./storage")
+	viper.SetDefault("API_BASE_URL", "http://localhost:8080")
 	viper.SetDefault("S3_ENDPOINT", "http://localhost:9000")
 	viper.SetDefault("S3_BUCKET", "habitta-local")
 	viper.SetDefault("S3_REGION", "us-east-1")
 	viper.SetDefault("S3_ACCESS_KEY", "minioadmin")
 	viper.SetDefault("S3_SECRET_KEY", "minioadmin")
 	viper.SetDefault("S3_USE_PATH_STYLE", true)
+	viper.SetDefault("STORAGE_MAX_UPLOAD_MB", 2048)
+	viper.SetDefault("STORAGE_MULTIPART_THRESHOLD_MB", 64)
+	viper.SetDefault("STORAGE_MULTIPART_PART_MB", 16)
+	viper.SetDefault("STORAGE_UPLOAD_SESSION_MINUTES", 120)
+	viper.SetDefault("STORAGE_DEFAULT_QUOTA_MB", 5120)
+	viper.SetDefault("SCANNER_DRIVER", "none")
+	viper.SetDefault("CLAMAV_ADDRESS", "tcp://localhost:3310")
+	viper.SetDefault("SCANNER_TIMEOUT_SECONDS", 120)
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("RATE_LIMIT_IP_PER_MINUTE", 30)
+	viper.SetDefault("RATE_LIMIT_IP_BURST", 60)
+	viper.SetDefault("RATE_LIMIT_ACCOUNT_PER_MINUTE", 5)
+	viper.SetDefault("RATE_LIMIT_ACCOUNT_BURST", 10)
+	viper.SetDefault("LOCKOUT_THRESHOLD", 5)
+	viper.SetDefault("LOCKOUT_BASE_MINUTES", 15)
+	viper.SetDefault("LOCKOUT_MAX_MINUTES", 1440)
 
 	config := &Config{
 		Server: ServerConfig{
@@ -105,29 +191,92 @@ func LoadConfig() (*Config, error) {
 			Password: viper.GetString("DATABASE_PASSWORD"),
 			Name:     viper.GetString("DATABASE_NAME"),
 			SSLMode:  viper.GetString("DATABASE_SSL_MODE"),
+
+			MigrateUser:     viper.GetString("DATABASE_MIGRATE_USER"),
+			MigratePassword: viper.GetString("DATABASE_MIGRATE_PASSWORD"),
+			WorkerUser:      viper.GetString("DATABASE_WORKER_USER"),
+			WorkerPassword:  viper.GetString("DATABASE_WORKER_PASSWORD"),
+
+			AutoMigrate: viper.GetBool("DATABASE_AUTO_MIGRATE"),
 		},
 		JWT: JWTConfig{
-			Secret:          viper.GetString("JWT_SECRET"),
-			ExpirationHours: viper.GetInt("JWT_EXPIRATION_HOURS"),
+			Secret:             viper.GetString("JWT_SECRET"),
+			AccessTokenMinutes: viper.GetInt("JWT_ACCESS_TOKEN_MINUTES"),
+			RefreshTokenDays:   viper.GetInt("JWT_REFRESH_TOKEN_DAYS"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: viper.GetString("ALLOWED_ORIGINS"),
 		},
 		Email: EmailConfig{
+			Driver:       viper.GetString("EMAIL_DRIVER"),
 			ResendAPIKey: viper.GetString("RESEND_API_KEY"),
 			FromAddress:  viper.GetString("EMAIL_FROM"),
 			AppBaseURL:   viper.GetString("APP_BASE_URL"),
+			SMTP: SMTPConfig{
+				Host:           viper.GetString("SMTP_HOST"),
+				Port:           viper.GetInt("SMTP_PORT"),
+				Username:       viper.GetString("SMTP_USERNAME"),
+				Password:       viper.GetString("SMTP_PASSWORD"),
+				Security:       viper.GetString("SMTP_SECURITY"),
+				TimeoutSeconds: viper.GetInt("SMTP_TIMEOUT_SECONDS"),
+			},
 		},
 		Storage: StorageConfig{
+			Driver:       viper.GetString("STORAGE_DRIVER"),
 			Endpoint:     viper.GetString("S3_ENDPOINT"),
 			Bucket:       viper.GetString("S3_BUCKET"),
 			Region:       viper.GetString("S3_REGION"),
 			AccessKey:    viper.GetString("S3_ACCESS_KEY"),
 			SecretKey:    viper.GetString("S3_SECRET_KEY"),
 			UsePathStyle: viper.GetBool("S3_USE_PATH_STYLE"),
+
+			LocalRoot:     viper.GetString("STORAGE_LOCAL_ROOT"),
+			PublicBaseURL: viper.GetString("API_BASE_URL"),
+			SigningKey:    viper.GetString("STORAGE_SIGNING_KEY"),
+
+			MaxUploadMB:          viper.GetInt("STORAGE_MAX_UPLOAD_MB"),
+			MultipartThresholdMB: viper.GetInt("STORAGE_MULTIPART_THRESHOLD_MB"),
+			MultipartPartMB:      viper.GetInt("STORAGE_MULTIPART_PART_MB"),
+			UploadSessionMinutes: viper.GetInt("STORAGE_UPLOAD_SESSION_MINUTES"),
+
+			DefaultQuotaMB: viper.GetInt("STORAGE_DEFAULT_QUOTA_MB"),
+		},
+		Scanner: ScannerConfig{
+			Driver:         viper.GetString("SCANNER_DRIVER"),
+			ClamAVAddress:  viper.GetString("CLAMAV_ADDRESS"),
+			TimeoutSeconds: viper.GetInt("SCANNER_TIMEOUT_SECONDS"),
+		},
+		Security: SecurityConfig{
+			RateLimitStore:            viper.GetString("RATE_LIMIT_STORE"),
+			RateLimitIPPerMinute:      viper.GetInt("RATE_LIMIT_IP_PER_MINUTE"),
+			RateLimitIPBurst:          viper.GetInt("RATE_LIMIT_IP_BURST"),
+			RateLimitAccountPerMinute: viper.GetInt("RATE_LIMIT_ACCOUNT_PER_MINUTE"),
+			RateLimitAccountBurst:     viper.GetInt("RATE_LIMIT_ACCOUNT_BURST"),
+			LockoutThreshold:          viper.GetInt("LOCKOUT_THRESHOLD"),
+			LockoutBaseMinutes:        viper.GetInt("LOCKOUT_BASE_MINUTES"),
+			LockoutMaxMinutes:         viper.GetInt("LOCKOUT_MAX_MINUTES"),
 		},
 	}
 
+	// A single role keeps working for local setups; the startup checks warn that it isn't isolated
+	if config.Database.MigrateUser == "" {
+		config.Database.MigrateUser = config.Database.User
+		config.Database.MigratePassword = config.Database.Password
+	}
+	if config.Database.WorkerUser == "" {
+		config.Database.WorkerUser = config.Database.User
+		config.Database.WorkerPassword = config.Database.Password
+	}
+	if config.Storage.SigningKey == "" {
+		config.Storage.SigningKey = config.JWT.Secret
+	}
+	if config.Email.Driver == "" {
+		config.Email.Driver = "resend"
+		if config.Server.Env == "development" {
+			config.Email.Driver = "console"
+		}
+	}
+
 	// Validate required fields
 	if err := config.Validate(); err != nil {
 		return nil, err
@@ -150,20 +299,63 @@ func (c *Config) Validate() error {
 	if c.JWT.Secret == "" {
 		return fmt.Errorf("JWT_SECRET is required")
 	}
-	if c.Server.Env != "development" && c.Email.ResendAPIKey == "" {
-		return fmt.Errorf("RESEND_API_KEY is required in non-development environments")
+	if c.Storage.Driver != "s3" && c.Storage.Driver != "local" {
+		return fmt.Errorf("STORAGE_DRIVER must be s3 or local")
+	}
+	if c.Storage.MultipartPartMB < 5 {
+		return fmt.Errorf("STORAGE_MULTIPART_PART_MB must be at least 5")
+	}
+	if c.Storage.MaxUploadMB > c.Storage.MultipartPartMB*10000 {
+		return fmt.Errorf("STORAGE_MAX_UPLOAD_MB needs more than 10000 parts of STORAGE_MULTIPART_PART_MB")
+	}
+	if c.Storage.DefaultQuotaMB < 0 {
+		return fmt.Errorf("STORAGE_DEFAULT_QUOTA_MB must not be negative")
+	}
+	if c.Scanner.Driver != "none" && c.Scanner.Driver != "clamav" {
+		return fmt.Errorf("SCANNER_DRIVER must be none or clamav")
+	}
+	switch c.Email.Driver {
+	case "console":
+	case "resend":
+		if c.Email.ResendAPIKey == "" {
+			return fmt.Errorf("RESEND_API_KEY is required with EMAIL_DRIVER=resend")
+		}
+	case "smtp":
+		if c.Email.SMTP.Host == "" {
+			return fmt.Errorf("SMTP_HOST is required with EMAIL_DRIVER=smtp")
+		}
+		if s := c.Email.SMTP.Security; s != "starttls" && s != "tls" && s != "none" {
+			return fmt.Errorf("SMTP_SECURITY must be starttls, tls or none")
+		}
+	default:
+		return fmt.Errorf("EMAIL_DRIVER must be console, resend or smtp")
 	}
 	return nil
 }
 
-// GetDSN returns the PostgreSQL connection string
+// GetDSN returns the PostgreSQL connection string of the API's role
 func (c *Config) GetDSN() string {
+	return c.dsn(c.Database.User, c.Database.Password)
+}
+
+// GetMigrateDSN returns the PostgreSQL connection string of the role that applies migrations
+func (c *Config) GetMigrateDSN() string {
+	return c.dsn(c.Database.MigrateUser, c.Database.MigratePassword)
+}
+
+// GetWorkerDSN returns the PostgreSQL connection string of the role used by background workers and the CLI
+func (c *Config) GetWorkerDSN() string {
+	return c.dsn(c.Database.WorkerUser, c.Database.WorkerPassword)
+}
+
+// dsn builds a PostgreSQL connection string for the given role
+func (c *Config) dsn(user, password string) string {
 	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Host,
 		c.Database.Port,
-		c.Database.User,
-		c.Database.Password,
+		user,
+		password,
 		c.Database.Name,
 		c.Database.SSLMode,
 	)
@@ -13,10 +13,31 @@ import (
 
 var DB *gorm.DB
 
-// InitDB initializes the database connection
+// InitDB initializes the API's database connection, which is subject to row-level security
 func InitDB(cfg *config.Config) (*gorm.DB, error) {
-	dsn := cfg.GetDSN()
+	db, err := connect(cfg, cfg.GetDSN())
+	if err != nil {
+		return nil, err
+	}
+
+	// Set global DB variable
+	DB = db
 
+	return db, nil
+}
+
+// InitWorkerDB initializes the connection of background workers and the CLI, which bypasses row-level security
+func InitWorkerDB(cfg *config.Config) (*gorm.DB, error) {
+	return connect(cfg, cfg.GetWorkerDSN())
+}
+
+// InitMigrateDB initializes the connection that applies migrations, as the owner of the schema
+func InitMigrateDB(cfg *config.Config) (*gorm.DB, error) {
+	return connect(cfg, cfg.GetMigrateDSN())
+}
+
+// connect opens a connection pool with the given DSN
+func connect(cfg *config.Config, dsn string) (*gorm.DB, error) {
 	// Configure GORM logger based on environment
 	logLevel := logger.Silent
 	if cfg.Server.Env == "development" {
@@ -54,9 +75,6 @@ func InitDB(cfg *config.Config) (*gorm.DB, error) {
 
 	log.Println("Database connection established successfully")
 
-	// Set global DB variable
-	DB = db
-
 	return db, nil
 }
 
@@ -74,15 +92,3 @@ func Close(db *gorm.DB) error {
 	log.Println("Database connection closed")
 	return nil
 }
-
-// AutoMigrate runs automatic migrations for all models
-func AutoMigrate(db *gorm.DB, models ...interface{}) error {
-	log.Println("Running database migrations...")
-
-	if err := db.AutoMigrate(models...); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	log.Println("Database migrations completed successfully")
-	return nil
-}
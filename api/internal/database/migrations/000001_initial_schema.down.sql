@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS two_factor_recovery_codes;
+DROP TABLE IF EXISTS email_verification_tokens;
+DROP TABLE IF EXISTS password_reset_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS documents;
+DROP TABLE IF EXISTS folders;
+DROP TABLE IF EXISTS invites;
+DROP TABLE IF EXISTS user_tenants;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS units;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,202 @@
+-- Baseline schema (matches the tables previously created by GORM AutoMigrate).
+-- IF NOT EXISTS lets existing databases adopt the migration history without changes.
+
+CREATE TABLE IF NOT EXISTS tenants (
+    id                 BIGSERIAL PRIMARY KEY,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ,
+    deleted_at         TIMESTAMPTZ,
+    name               VARCHAR(255) NOT NULL,
+    cnpj               VARCHAR(18)  NOT NULL,
+    email              VARCHAR(255),
+    phone              VARCHAR(20),
+    active             BOOLEAN DEFAULT true,
+    require_two_factor BOOLEAN DEFAULT false
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_cnpj ON tenants (cnpj);
+CREATE INDEX IF NOT EXISTS idx_tenants_deleted_at ON tenants (deleted_at);
+
+CREATE TABLE IF NOT EXISTS units (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    tenant_id   BIGINT      NOT NULL,
+    number      VARCHAR(50) NOT NULL,
+    block       VARCHAR(50),
+    floor       BIGINT,
+    area        NUMERIC(10,2),
+    owner_name  VARCHAR(255),
+    owner_email VARCHAR(255),
+    owner_phone VARCHAR(20),
+    occupied    BOOLEAN DEFAULT true,
+    active      BOOLEAN DEFAULT true,
+    CONSTRAINT fk_tenants_units FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_units_tenant_id ON units (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenant_unit ON units (number);
+CREATE INDEX IF NOT EXISTS idx_units_deleted_at ON units (deleted_at);
+
+CREATE TABLE IF NOT EXISTS users (
+    id                    BIGSERIAL PRIMARY KEY,
+    created_at            TIMESTAMPTZ,
+    updated_at            TIMESTAMPTZ,
+    deleted_at            TIMESTAMPTZ,
+    email                 VARCHAR(255) NOT NULL,
+    password              VARCHAR(255) NOT NULL,
+    name                  VARCHAR(255) NOT NULL,
+    active                BOOLEAN DEFAULT true,
+    email_verified_at     TIMESTAMPTZ,
+    two_factor_enabled    BOOLEAN DEFAULT false,
+    two_factor_secret     VARCHAR(64),
+    failed_login_attempts BIGINT DEFAULT 0,
+    lockout_count         BIGINT DEFAULT 0,
+    locked_until          TIMESTAMPTZ,
+    phone                 VARCHAR(20),
+    cpf                   VARCHAR(14),
+    unit_id               BIGINT,
+    CONSTRAINT fk_units_users FOREIGN KEY (unit_id) REFERENCES units (id) ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_cpf ON users (cpf);
+CREATE INDEX IF NOT EXISTS idx_users_unit_id ON users (unit_id);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS user_tenants (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id    BIGINT      NOT NULL,
+    tenant_id  BIGINT      NOT NULL,
+    role       VARCHAR(50) NOT NULL DEFAULT 'morador',
+    is_active  BOOLEAN DEFAULT true,
+    joined_at  TIMESTAMPTZ NOT NULL,
+    CONSTRAINT fk_user_tenants_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_user_tenants_tenant FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_user_tenants_user_id ON user_tenants (user_id);
+CREATE INDEX IF NOT EXISTS idx_user_tenants_tenant_id ON user_tenants (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_user_tenants_deleted_at ON user_tenants (deleted_at);
+
+CREATE TABLE IF NOT EXISTS invites (
+    id                  BIGSERIAL PRIMARY KEY,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ,
+    tenant_id           BIGINT       NOT NULL,
+    email               VARCHAR(255) NOT NULL,
+    role                VARCHAR(50)  NOT NULL DEFAULT 'morador',
+    token               VARCHAR(255) NOT NULL,
+    status              VARCHAR(50)  NOT NULL DEFAULT 'pending',
+    invited_by_user_id  BIGINT       NOT NULL,
+    accepted_by_user_id BIGINT,
+    expires_at          TIMESTAMPTZ  NOT NULL,
+    accepted_at         TIMESTAMPTZ,
+    CONSTRAINT fk_invites_tenant FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_invites_invited_by FOREIGN KEY (invited_by_user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_invites_accepted_by FOREIGN KEY (accepted_by_user_id) REFERENCES users (id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS idx_invites_tenant_id ON invites (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_invites_email ON invites (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invites_token ON invites (token);
+CREATE INDEX IF NOT EXISTS idx_invites_deleted_at ON invites (deleted_at);
+
+CREATE TABLE IF NOT EXISTS folders (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    tenant_id   BIGINT       NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    description VARCHAR(500),
+    CONSTRAINT fk_folders_tenant FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_folders_tenant_id ON folders (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_folders_deleted_at ON folders (deleted_at);
+
+CREATE TABLE IF NOT EXISTS documents (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ,
+    tenant_id      BIGINT       NOT NULL,
+    folder_id      BIGINT,
+    name           VARCHAR(255) NOT NULL,
+    original_name  VARCHAR(255) NOT NULL,
+    content_type   VARCHAR(100),
+    size           BIGINT,
+    s3_key         VARCHAR(500) NOT NULL,
+    uploaded_by_id BIGINT       NOT NULL,
+    CONSTRAINT fk_documents_tenant FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_documents_folder FOREIGN KEY (folder_id) REFERENCES folders (id) ON DELETE SET NULL,
+    CONSTRAINT fk_documents_uploaded_by FOREIGN KEY (uploaded_by_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_documents_tenant_id ON documents (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_documents_folder_id ON documents (folder_id);
+CREATE INDEX IF NOT EXISTS idx_documents_deleted_at ON documents (deleted_at);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id                 BIGSERIAL PRIMARY KEY,
+    created_at         TIMESTAMPTZ,
+    updated_at         TIMESTAMPTZ,
+    deleted_at         TIMESTAMPTZ,
+    user_id            BIGINT      NOT NULL,
+    tenant_id          BIGINT,
+    refresh_token_hash VARCHAR(64) NOT NULL,
+    expires_at         TIMESTAMPTZ NOT NULL,
+    last_used_at       TIMESTAMPTZ NOT NULL,
+    revoked_at         TIMESTAMPTZ,
+    user_agent         VARCHAR(255),
+    ip_address         VARCHAR(45),
+    CONSTRAINT fk_sessions_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE,
+    CONSTRAINT fk_sessions_tenant FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_tenant_id ON sessions (tenant_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_refresh_token_hash ON sessions (refresh_token_hash);
+CREATE INDEX IF NOT EXISTS idx_sessions_deleted_at ON sessions (deleted_at);
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id    BIGINT      NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    CONSTRAINT fk_password_reset_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_password_reset_tokens_token_hash ON password_reset_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_deleted_at ON password_reset_tokens (deleted_at);
+
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id    BIGINT      NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    CONSTRAINT fk_email_verification_tokens_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens (user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_verification_tokens_token_hash ON email_verification_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_deleted_at ON email_verification_tokens (deleted_at);
+
+CREATE TABLE IF NOT EXISTS two_factor_recovery_codes (
+    id         BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    user_id    BIGINT      NOT NULL,
+    code_hash  VARCHAR(64) NOT NULL,
+    used_at    TIMESTAMPTZ,
+    CONSTRAINT fk_two_factor_recovery_codes_user FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_user_id ON two_factor_recovery_codes (user_id);
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_code_hash ON two_factor_recovery_codes (code_hash);
+CREATE INDEX IF NOT EXISTS idx_two_factor_recovery_codes_deleted_at ON two_factor_recovery_codes (deleted_at);
@@ -0,0 +1,19 @@
+DROP POLICY IF EXISTS tenant_isolation ON user_tenants;
+ALTER TABLE user_tenants NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_tenants DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON invites;
+ALTER TABLE invites NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE invites DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON documents;
+ALTER TABLE documents NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE documents DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON folders;
+ALTER TABLE folders NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE folders DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY IF EXISTS tenant_isolation ON units;
+ALTER TABLE units NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE units DISABLE ROW LEVEL SECURITY;
@@ -0,0 +1,27 @@
+-- Tenant-defined roles. Memberships and invites reference them by name, like the built-in roles.
+
+CREATE TABLE IF NOT EXISTS custom_roles (
+    id           BIGSERIAL PRIMARY KEY,
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ,
+    deleted_at   TIMESTAMPTZ,
+    tenant_id    BIGINT       NOT NULL,
+    name         VARCHAR(50)  NOT NULL,
+    display_name VARCHAR(100) NOT NULL,
+    description  VARCHAR(500),
+    permissions  JSONB        NOT NULL DEFAULT '[]',
+    CONSTRAINT fk_tenants_custom_roles FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_custom_roles_tenant_id ON custom_roles (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_custom_roles_deleted_at ON custom_roles (deleted_at);
+-- Names can be reused once the previous role is (soft) deleted
+CREATE UNIQUE INDEX IF NOT EXISTS idx_custom_roles_tenant_name ON custom_roles (tenant_id, name) WHERE deleted_at IS NULL;
+
+ALTER TABLE custom_roles ENABLE ROW LEVEL SECURITY;
+ALTER TABLE custom_roles FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON custom_roles;
+CREATE POLICY tenant_isolation ON custom_roles
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
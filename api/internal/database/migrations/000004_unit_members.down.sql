@@ -0,0 +1,17 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS unit_id BIGINT;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS fk_units_users;
+ALTER TABLE users ADD CONSTRAINT fk_units_users FOREIGN KEY (unit_id) REFERENCES units (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_users_unit_id ON users (unit_id);
+
+-- users.unit_id holds a single unit: keep the oldest current membership of each user
+UPDATE users u
+SET unit_id = m.unit_id
+FROM (
+    SELECT DISTINCT ON (user_id) user_id, unit_id
+    FROM unit_members
+    WHERE deleted_at IS NULL AND (end_date IS NULL OR end_date > CURRENT_DATE)
+    ORDER BY user_id, start_date, id
+) m
+WHERE m.user_id = u.id;
+
+DROP TABLE IF EXISTS unit_members;
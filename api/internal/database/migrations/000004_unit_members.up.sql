@@ -0,0 +1,41 @@
+-- Per-tenant unit membership, replacing the global users.unit_id column.
+
+CREATE TABLE IF NOT EXISTS unit_members (
+    id                    BIGSERIAL PRIMARY KEY,
+    created_at            TIMESTAMPTZ,
+    updated_at            TIMESTAMPTZ,
+    deleted_at            TIMESTAMPTZ,
+    tenant_id             BIGINT      NOT NULL,
+    unit_id               BIGINT      NOT NULL,
+    user_id               BIGINT      NOT NULL,
+    relationship          VARCHAR(20) NOT NULL,
+    start_date            DATE        NOT NULL,
+    end_date              DATE,
+    responsible_for_bills BOOLEAN DEFAULT false,
+    CONSTRAINT fk_tenants_unit_members FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_units_unit_members FOREIGN KEY (unit_id) REFERENCES units (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_unit_members FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_unit_members_tenant_id ON unit_members (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_unit_members_unit_id ON unit_members (unit_id);
+CREATE INDEX IF NOT EXISTS idx_unit_members_user_id ON unit_members (user_id);
+CREATE INDEX IF NOT EXISTS idx_unit_members_deleted_at ON unit_members (deleted_at);
+
+-- Existing assignments become resident (inquilino) memberships starting when the user was created
+INSERT INTO unit_members (created_at, updated_at, tenant_id, unit_id, user_id, relationship, start_date)
+SELECT NOW(), NOW(), un.tenant_id, un.id, u.id, 'inquilino', COALESCE(u.created_at, NOW())::date
+FROM users u
+JOIN units un ON un.id = u.unit_id
+WHERE u.unit_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_unit_id;
+ALTER TABLE users DROP COLUMN IF EXISTS unit_id;
+
+ALTER TABLE unit_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE unit_members FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON unit_members;
+CREATE POLICY tenant_isolation ON unit_members
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
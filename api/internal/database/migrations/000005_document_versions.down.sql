@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_versions;
+ALTER TABLE documents DROP COLUMN IF EXISTS current_version;
@@ -0,0 +1,43 @@
+-- Version history for documents; the documents row keeps the metadata of the current version.
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS current_version INTEGER NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS document_versions (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ,
+    tenant_id      BIGINT       NOT NULL,
+    document_id    BIGINT       NOT NULL,
+    version_number INTEGER      NOT NULL,
+    original_name  VARCHAR(255) NOT NULL,
+    content_type   VARCHAR(100),
+    size           BIGINT,
+    s3_key         VARCHAR(500) NOT NULL,
+    uploaded_by_id BIGINT       NOT NULL,
+    CONSTRAINT fk_tenants_document_versions FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_documents_versions FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    CONSTRAINT fk_document_versions_uploaded_by FOREIGN KEY (uploaded_by_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_document_versions_tenant_id ON document_versions (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_document_versions_document_id ON document_versions (document_id);
+CREATE INDEX IF NOT EXISTS idx_document_versions_deleted_at ON document_versions (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_versions_number ON document_versions (document_id, version_number)
+    WHERE deleted_at IS NULL;
+
+-- Existing files become version 1 of their document
+INSERT INTO document_versions (created_at, updated_at, tenant_id, document_id, version_number,
+                               original_name, content_type, size, s3_key, uploaded_by_id)
+SELECT COALESCE(d.created_at, NOW()), NOW(), d.tenant_id, d.id, 1,
+       d.original_name, d.content_type, d.size, d.s3_key, d.uploaded_by_id
+FROM documents d
+WHERE d.deleted_at IS NULL;
+
+ALTER TABLE document_versions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE document_versions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON document_versions;
+CREATE POLICY tenant_isolation ON document_versions
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
@@ -0,0 +1,5 @@
+ALTER TABLE documents DROP COLUMN IF EXISTS audience;
+ALTER TABLE documents DROP COLUMN IF EXISTS visibility;
+
+ALTER TABLE folders DROP COLUMN IF EXISTS audience;
+ALTER TABLE folders DROP COLUMN IF EXISTS visibility;
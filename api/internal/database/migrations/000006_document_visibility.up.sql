@@ -0,0 +1,8 @@
+-- Resident visibility rules for folders and documents.
+-- Folders start private to management; documents follow their folder.
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'management';
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS audience JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS visibility VARCHAR(20) NOT NULL DEFAULT 'inherit';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS audience JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_folders_sibling_name;
+DROP INDEX IF EXISTS idx_folders_path;
+DROP INDEX IF EXISTS idx_folders_parent_id;
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS fk_folders_parent;
+ALTER TABLE folders DROP COLUMN IF EXISTS path;
+ALTER TABLE folders DROP COLUMN IF EXISTS parent_id;
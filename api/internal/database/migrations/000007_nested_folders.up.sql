@@ -0,0 +1,15 @@
+-- Folder tree: parent reference plus a materialized path of ancestor IDs ("/1/5/9/").
+-- Names only need to be unique among siblings.
+
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS parent_id BIGINT;
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS path VARCHAR(1000) NOT NULL DEFAULT '';
+ALTER TABLE folders DROP CONSTRAINT IF EXISTS fk_folders_parent;
+ALTER TABLE folders ADD CONSTRAINT fk_folders_parent FOREIGN KEY (parent_id) REFERENCES folders (id) ON DELETE CASCADE;
+
+-- Existing folders become root folders
+UPDATE folders SET path = '/' || id || '/' WHERE path = '';
+
+CREATE INDEX IF NOT EXISTS idx_folders_parent_id ON folders (parent_id);
+CREATE INDEX IF NOT EXISTS idx_folders_path ON folders (path varchar_pattern_ops);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_folders_sibling_name ON folders (tenant_id, COALESCE(parent_id, 0), name)
+    WHERE deleted_at IS NULL;
@@ -0,0 +1,38 @@
+-- Direct-to-storage uploads; pending sessions past expires_at are aborted by the sweeper.
+
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id                  BIGSERIAL PRIMARY KEY,
+    created_at          TIMESTAMPTZ,
+    updated_at          TIMESTAMPTZ,
+    deleted_at          TIMESTAMPTZ,
+    tenant_id           BIGINT       NOT NULL,
+    user_id             BIGINT       NOT NULL,
+    folder_id           BIGINT,
+    document_id         BIGINT,
+    file_name           VARCHAR(255) NOT NULL,
+    content_type        VARCHAR(100),
+    size                BIGINT       NOT NULL,
+    s3_key              VARCHAR(500) NOT NULL,
+    multipart_upload_id VARCHAR(255),
+    part_size           BIGINT,
+    part_count          INTEGER,
+    status              VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    expires_at          TIMESTAMPTZ  NOT NULL,
+    completed_at        TIMESTAMPTZ,
+    CONSTRAINT fk_tenants_upload_sessions FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_users_upload_sessions FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_tenant_id ON upload_sessions (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_user_id ON upload_sessions (user_id);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_status ON upload_sessions (status);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_expires_at ON upload_sessions (expires_at);
+CREATE INDEX IF NOT EXISTS idx_upload_sessions_deleted_at ON upload_sessions (deleted_at);
+
+ALTER TABLE upload_sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE upload_sessions FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON upload_sessions;
+CREATE POLICY tenant_isolation ON upload_sessions
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
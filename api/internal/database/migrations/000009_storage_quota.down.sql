@@ -0,0 +1,3 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS storage_warning_level;
+ALTER TABLE tenants DROP COLUMN IF EXISTS storage_used_bytes;
+ALTER TABLE tenants DROP COLUMN IF EXISTS storage_quota_bytes;
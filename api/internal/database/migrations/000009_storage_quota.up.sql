@@ -0,0 +1,17 @@
+-- Per-tenant storage quota and usage accounting.
+-- A NULL quota falls back to STORAGE_DEFAULT_QUOTA_MB; 0 means unlimited.
+
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS storage_quota_bytes BIGINT;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS storage_used_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS storage_warning_level SMALLINT NOT NULL DEFAULT 0;
+
+-- Every stored version counts towards the usage
+UPDATE tenants t
+SET storage_used_bytes = u.total
+FROM (
+    SELECT tenant_id, COALESCE(SUM(size), 0) AS total
+    FROM document_versions
+    WHERE deleted_at IS NULL
+    GROUP BY tenant_id
+) u
+WHERE u.tenant_id = t.id;
@@ -0,0 +1,8 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS allowed_file_types;
+
+ALTER TABLE documents DROP COLUMN IF EXISTS scan_status;
+
+DROP INDEX IF EXISTS idx_document_versions_scan_pending;
+ALTER TABLE document_versions DROP COLUMN IF EXISTS scanned_at;
+ALTER TABLE document_versions DROP COLUMN IF EXISTS scan_signature;
+ALTER TABLE document_versions DROP COLUMN IF EXISTS scan_status;
@@ -0,0 +1,17 @@
+-- Malware scan state of stored files and per-tenant allowed document types.
+-- Files uploaded before scanning existed are treated as clean; new ones start pending.
+
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) NOT NULL DEFAULT 'clean';
+ALTER TABLE document_versions ALTER COLUMN scan_status SET DEFAULT 'pending';
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS scan_signature VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS scanned_at TIMESTAMPTZ;
+
+-- The scan worker only looks for pending versions
+CREATE INDEX IF NOT EXISTS idx_document_versions_scan_pending ON document_versions (id)
+    WHERE scan_status = 'pending' AND deleted_at IS NULL;
+
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS scan_status VARCHAR(20) NOT NULL DEFAULT 'clean';
+ALTER TABLE documents ALTER COLUMN scan_status SET DEFAULT 'pending';
+
+-- NULL accepts every supported type
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS allowed_file_types JSONB;
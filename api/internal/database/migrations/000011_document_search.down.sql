@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS trg_folders_search_reindex ON folders;
+DROP FUNCTION IF EXISTS folders_search_reindex();
+
+DROP TRIGGER IF EXISTS trg_document_versions_search_reindex ON document_versions;
+DROP FUNCTION IF EXISTS document_versions_search_reindex();
+
+DROP TRIGGER IF EXISTS trg_documents_search_vector ON documents;
+DROP FUNCTION IF EXISTS documents_search_vector_update();
+
+DROP INDEX IF EXISTS idx_documents_search_vector;
+ALTER TABLE documents DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE document_versions DROP COLUMN IF EXISTS content_text;
@@ -0,0 +1,62 @@
+-- Full-text search over document names, folder names and the text extracted from the current version.
+-- search_vector is maintained by triggers, so the application never writes it.
+
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS content_text TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE INDEX IF NOT EXISTS idx_documents_search_vector ON documents USING GIN (search_vector);
+
+-- Names weigh most, then the folder, then the file contents.
+-- Separators are turned into spaces so "ata_reuniao.pdf" indexes as words instead of a single file token.
+CREATE OR REPLACE FUNCTION documents_search_vector_update() RETURNS TRIGGER AS $$
+DECLARE
+    folder_name TEXT;
+    content     TEXT;
+BEGIN
+    SELECT f.name INTO folder_name FROM folders f WHERE f.id = NEW.folder_id;
+    SELECT v.content_text INTO content FROM document_versions v
+    WHERE v.document_id = NEW.id AND v.version_number = NEW.current_version AND v.deleted_at IS NULL;
+
+    NEW.search_vector :=
+        setweight(to_tsvector('portuguese', translate(COALESCE(NEW.name, '') || ' ' || COALESCE(NEW.original_name, ''), '_.-', '   ')), 'A') ||
+        setweight(to_tsvector('portuguese', COALESCE(folder_name, '')), 'B') ||
+        setweight(to_tsvector('portuguese', COALESCE(content, '')), 'C');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_documents_search_vector ON documents;
+CREATE TRIGGER trg_documents_search_vector
+    BEFORE INSERT OR UPDATE OF name, original_name, folder_id, current_version ON documents
+    FOR EACH ROW EXECUTE FUNCTION documents_search_vector_update();
+
+-- A document's first version is inserted after the document itself, so reindex it once the version exists
+CREATE OR REPLACE FUNCTION document_versions_search_reindex() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE documents SET current_version = current_version
+    WHERE id = NEW.document_id AND current_version = NEW.version_number;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_document_versions_search_reindex ON document_versions;
+CREATE TRIGGER trg_document_versions_search_reindex
+    AFTER INSERT ON document_versions
+    FOR EACH ROW EXECUTE FUNCTION document_versions_search_reindex();
+
+-- Renaming a folder reindexes the documents directly inside it
+CREATE OR REPLACE FUNCTION folders_search_reindex() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE documents SET folder_id = folder_id WHERE folder_id = NEW.id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_folders_search_reindex ON folders;
+CREATE TRIGGER trg_folders_search_reindex
+    AFTER UPDATE OF name ON folders
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION folders_search_reindex();
+
+-- Index existing documents by name and folder; their files were uploaded before text extraction
+UPDATE documents SET current_version = current_version;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_share_link_accesses;
+DROP TABLE IF EXISTS document_share_links;
@@ -0,0 +1,58 @@
+-- Public links to single documents for people outside the platform, and a log of every attempt to open them.
+
+CREATE TABLE IF NOT EXISTS document_share_links (
+    id               BIGSERIAL PRIMARY KEY,
+    created_at       TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ,
+    deleted_at       TIMESTAMPTZ,
+    tenant_id        BIGINT       NOT NULL,
+    document_id      BIGINT       NOT NULL,
+    token_hash       VARCHAR(64)  NOT NULL,
+    password_hash    VARCHAR(255) NOT NULL DEFAULT '',
+    has_password     BOOLEAN      NOT NULL DEFAULT false,
+    expires_at       TIMESTAMPTZ  NOT NULL,
+    max_downloads    INTEGER,
+    download_count   INTEGER      NOT NULL DEFAULT 0,
+    created_by_id    BIGINT       NOT NULL,
+    revoked_at       TIMESTAMPTZ,
+    last_accessed_at TIMESTAMPTZ,
+    CONSTRAINT fk_tenants_document_share_links FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_documents_share_links FOREIGN KEY (document_id) REFERENCES documents (id) ON DELETE CASCADE,
+    CONSTRAINT fk_document_share_links_created_by FOREIGN KEY (created_by_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_document_share_links_tenant_id ON document_share_links (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_document_share_links_document_id ON document_share_links (document_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_share_links_token_hash ON document_share_links (token_hash);
+CREATE INDEX IF NOT EXISTS idx_document_share_links_deleted_at ON document_share_links (deleted_at);
+
+CREATE TABLE IF NOT EXISTS document_share_link_accesses (
+    id            BIGSERIAL PRIMARY KEY,
+    created_at    TIMESTAMPTZ,
+    tenant_id     BIGINT      NOT NULL,
+    share_link_id BIGINT      NOT NULL,
+    outcome       VARCHAR(30) NOT NULL,
+    ip_address    VARCHAR(45),
+    user_agent    VARCHAR(255),
+    CONSTRAINT fk_tenants_document_share_link_accesses FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE,
+    CONSTRAINT fk_document_share_links_accesses FOREIGN KEY (share_link_id) REFERENCES document_share_links (id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_document_share_link_accesses_tenant_id ON document_share_link_accesses (tenant_id);
+CREATE INDEX IF NOT EXISTS idx_document_share_link_accesses_share_link_id ON document_share_link_accesses (share_link_id);
+
+ALTER TABLE document_share_links ENABLE ROW LEVEL SECURITY;
+ALTER TABLE document_share_links FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON document_share_links;
+CREATE POLICY tenant_isolation ON document_share_links
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+ALTER TABLE document_share_link_accesses ENABLE ROW LEVEL SECURITY;
+ALTER TABLE document_share_link_accesses FORCE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON document_share_link_accesses;
+CREATE POLICY tenant_isolation ON document_share_link_accesses
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
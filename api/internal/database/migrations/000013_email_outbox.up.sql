@@ -0,0 +1,21 @@
+-- Transactional outbox of emails, delivered by a background worker with retries.
+
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    created_at      TIMESTAMPTZ,
+    updated_at      TIMESTAMPTZ,
+    idempotency_key VARCHAR(255) NOT NULL,
+    to_address      VARCHAR(255) NOT NULL,
+    subject         TEXT         NOT NULL,
+    html            TEXT         NOT NULL,
+    text            TEXT         NOT NULL DEFAULT '',
+    status          VARCHAR(20)  NOT NULL DEFAULT 'pending',
+    attempts        INTEGER      NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ  NOT NULL,
+    locked_until    TIMESTAMPTZ,
+    last_error      TEXT         NOT NULL DEFAULT '',
+    sent_at         TIMESTAMPTZ
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_email_outbox_idempotency_key ON email_outbox (idempotency_key);
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_email_outbox_status ON email_outbox (status, created_at);
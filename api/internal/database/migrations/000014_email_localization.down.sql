@@ -0,0 +1,3 @@
+ALTER TABLE tenants DROP COLUMN IF EXISTS logo_url;
+ALTER TABLE tenants DROP COLUMN IF EXISTS locale;
+ALTER TABLE users DROP COLUMN IF EXISTS locale;
@@ -0,0 +1,5 @@
+-- Email language of users and tenants, and the tenant logo used in email branding.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'pt-BR';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS locale VARCHAR(10) NOT NULL DEFAULT 'pt-BR';
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS logo_url VARCHAR(512) NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+-- Invites can name the unit the invitee is linked to when accepting (bulk import by unit number).
+
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS unit_id BIGINT;
+ALTER TABLE invites DROP CONSTRAINT IF EXISTS fk_invites_unit;
+ALTER TABLE invites ADD CONSTRAINT fk_invites_unit FOREIGN KEY (unit_id) REFERENCES units (id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_invites_unit_id ON invites (unit_id);
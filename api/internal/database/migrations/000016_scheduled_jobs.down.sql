@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_invites_pending_expires_at;
+ALTER TABLE invites DROP COLUMN IF EXISTS reminder_sent_at;
+
+DROP TABLE IF EXISTS job_runs;
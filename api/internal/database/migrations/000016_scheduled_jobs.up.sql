@@ -0,0 +1,17 @@
+-- Run history of the scheduled jobs, and the invite reminder they send.
+
+CREATE TABLE IF NOT EXISTS job_runs (
+    id           BIGSERIAL PRIMARY KEY,
+    job_name     VARCHAR(100) NOT NULL,
+    scheduled_at TIMESTAMPTZ  NOT NULL,
+    started_at   TIMESTAMPTZ  NOT NULL,
+    finished_at  TIMESTAMPTZ,
+    status       VARCHAR(20)  NOT NULL DEFAULT 'running',
+    processed    INTEGER      NOT NULL DEFAULT 0,
+    error        TEXT         NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_job_runs_job_slot ON job_runs (job_name, scheduled_at);
+CREATE INDEX IF NOT EXISTS idx_job_runs_started_at ON job_runs (started_at);
+
+ALTER TABLE invites ADD COLUMN IF NOT EXISTS reminder_sent_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_invites_pending_expires_at ON invites (expires_at) WHERE status = 'pending';
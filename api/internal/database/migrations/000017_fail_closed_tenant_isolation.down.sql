@@ -0,0 +1,90 @@
+-- Restores the policies of 000002, which don't restrict access without app.tenant_id.
+-- The roles are kept: they are shared by every database of the cluster and may still own connections.
+
+DROP POLICY IF EXISTS share_link_by_token ON document_share_links;
+DROP POLICY IF EXISTS invite_by_token ON invites;
+DROP POLICY IF EXISTS invitee_invites ON invites;
+DROP POLICY IF EXISTS own_memberships ON user_tenants;
+
+DROP POLICY IF EXISTS tenant_isolation ON units;
+CREATE POLICY tenant_isolation ON units
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON folders;
+CREATE POLICY tenant_isolation ON folders
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON documents;
+CREATE POLICY tenant_isolation ON documents
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON invites;
+CREATE POLICY tenant_isolation ON invites
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON user_tenants;
+CREATE POLICY tenant_isolation ON user_tenants
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON custom_roles;
+CREATE POLICY tenant_isolation ON custom_roles
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON unit_members;
+CREATE POLICY tenant_isolation ON unit_members
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_versions;
+CREATE POLICY tenant_isolation ON document_versions
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON upload_sessions;
+CREATE POLICY tenant_isolation ON upload_sessions
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_share_links;
+CREATE POLICY tenant_isolation ON document_share_links
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_share_link_accesses;
+CREATE POLICY tenant_isolation ON document_share_link_accesses
+    USING (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (NULLIF(current_setting('app.tenant_id', true), '') IS NULL
+        OR tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE, SELECT ON SEQUENCES FROM habitta_app, habitta_worker;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM habitta_app, habitta_worker;
+REVOKE ALL ON ALL SEQUENCES IN SCHEMA public FROM habitta_app, habitta_worker;
+REVOKE ALL ON ALL TABLES IN SCHEMA public FROM habitta_app, habitta_worker;
+REVOKE USAGE ON SCHEMA public FROM habitta_app, habitta_worker;
@@ -0,0 +1,103 @@
+-- Fail-closed tenant isolation: a transaction without app.tenant_id (see database.RunScoped) sees and writes no tenant-owned row.
+-- A few narrower read-only policies let a scope find rows before their tenant is known: the user's own memberships
+-- (app.user_id), invites sent to the user's email (app.user_email), one invite by token (app.invite_token) and one
+-- share link by token hash (app.share_token_hash). They are FOR SELECT, so those rows still can't be written.
+--
+-- Work across tenants (migrations, background workers, the CLI) runs as a role with BYPASSRLS instead.
+-- habitta_app is the API's role: not a superuser, not the owner of the tables and subject to row-level security.
+-- habitta_worker bypasses row-level security. Deployments usually create both as LOGIN roles with a password before
+-- migrating (see docker/postgres/init); otherwise they are created here without LOGIN.
+-- Changing BYPASSRLS needs a superuser, so this migration must run as one.
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'habitta_app') THEN
+        CREATE ROLE habitta_app NOLOGIN;
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'habitta_worker') THEN
+        CREATE ROLE habitta_worker NOLOGIN;
+    END IF;
+END
+$$;
+ALTER ROLE habitta_app NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS;
+ALTER ROLE habitta_worker NOSUPERUSER NOCREATEDB NOCREATEROLE BYPASSRLS;
+
+GRANT USAGE ON SCHEMA public TO habitta_app, habitta_worker;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO habitta_app, habitta_worker;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO habitta_app, habitta_worker;
+REVOKE ALL ON schema_migrations FROM habitta_app, habitta_worker;
+
+-- Tables and sequences created by later migrations get the same grants
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO habitta_app, habitta_worker;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO habitta_app, habitta_worker;
+
+DROP POLICY IF EXISTS tenant_isolation ON units;
+CREATE POLICY tenant_isolation ON units
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON folders;
+CREATE POLICY tenant_isolation ON folders
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON documents;
+CREATE POLICY tenant_isolation ON documents
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON invites;
+CREATE POLICY tenant_isolation ON invites
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON user_tenants;
+CREATE POLICY tenant_isolation ON user_tenants
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON custom_roles;
+CREATE POLICY tenant_isolation ON custom_roles
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON unit_members;
+CREATE POLICY tenant_isolation ON unit_members
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_versions;
+CREATE POLICY tenant_isolation ON document_versions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON upload_sessions;
+CREATE POLICY tenant_isolation ON upload_sessions
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_share_links;
+CREATE POLICY tenant_isolation ON document_share_links
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS tenant_isolation ON document_share_link_accesses;
+CREATE POLICY tenant_isolation ON document_share_link_accesses
+    USING (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint)
+    WITH CHECK (tenant_id = NULLIF(current_setting('app.tenant_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS own_memberships ON user_tenants;
+CREATE POLICY own_memberships ON user_tenants FOR SELECT
+    USING (user_id = NULLIF(current_setting('app.user_id', true), '')::bigint);
+
+DROP POLICY IF EXISTS invitee_invites ON invites;
+CREATE POLICY invitee_invites ON invites FOR SELECT
+    USING (lower(email) = lower(NULLIF(current_setting('app.user_email', true), '')));
+
+DROP POLICY IF EXISTS invite_by_token ON invites;
+CREATE POLICY invite_by_token ON invites FOR SELECT
+    USING (token = NULLIF(current_setting('app.invite_token', true), ''));
+
+DROP POLICY IF EXISTS share_link_by_token ON document_share_links;
+CREATE POLICY share_link_by_token ON document_share_links FOR SELECT
+    USING (token_hash = NULLIF(current_setting('app.share_token_hash', true), ''));
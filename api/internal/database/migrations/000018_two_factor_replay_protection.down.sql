@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_challenge_id;
+ALTER TABLE users DROP COLUMN IF EXISTS two_factor_last_step;
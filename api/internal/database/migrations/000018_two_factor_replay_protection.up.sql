@@ -0,0 +1,6 @@
+-- Two-factor codes and login challenges can only be used once.
+-- two_factor_last_step is the TOTP time step of the last accepted code; codes from that step or earlier are refused.
+-- two_factor_challenge_id is the jti of the login challenge awaiting a code; it is cleared when the challenge is used.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_last_step BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS two_factor_challenge_id VARCHAR(64);
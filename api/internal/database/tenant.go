@@ -0,0 +1,200 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// PostgreSQL settings read by the row-level security policies
+// (see migrations/000017_fail_closed_tenant_isolation.up.sql)
+const (
+	TenantSetting         = "app.tenant_id"
+	UserSetting           = "app.user_id"
+	UserEmailSetting      = "app.user_email"
+	InviteTokenSetting    = "app.invite_token"
+	ShareTokenHashSetting = "app.share_token_hash"
+)
+
+// Scope is what row-level security exposes to a transaction
+// The policies fail closed: with an empty scope no tenant-owned row is visible or writable
+type Scope struct {
+	TenantID       uint   // Rows of this tenant can be read and written
+	UserID         uint   // The user's own memberships can be read in every tenant
+	UserEmail      string // Invites sent to this email can be read in every tenant
+	InviteToken    string // The invite with this token can be read
+	ShareTokenHash string // The share link with this token hash can be read
+}
+
+// scopedConn is the database carried by a context
+type scopedConn struct {
+	db          *gorm.DB
+	afterCommit *[]func() // nil when db is not a transaction
+	scope       *Scope    // Set by WithScope: applied to each transaction started on db
+}
+
+type connKey struct{}
+
+// WithDB returns a copy of ctx carrying db, which repositories use instead of their own connection
+// Background workers use it to run on the connection that bypasses row-level security.
+// It also leaves the transaction ctx carries, for writes that must commit even if that transaction rolls back
+func WithDB(ctx context.Context, db *gorm.DB) context.Context {
+	return context.WithValue(ctx, connKey{}, &scopedConn{db: db})
+}
+
+// WithScope returns a copy of ctx carrying scope but no transaction, for requests that must not hold one open
+// (long uploads and downloads). Transaction and Scoped start short transactions on db with scope;
+// outside of them repositories get db with no scope, which sees no tenant-owned rows
+func WithScope(ctx context.Context, db *gorm.DB, scope Scope) context.Context {
+	return context.WithValue(ctx, connKey{}, &scopedConn{db: db, scope: &scope})
+}
+
+// Conn returns the database carried by ctx, or fallback if there is none
+// A fallback on the application connection has no scope, so it sees no tenant-owned rows
+func Conn(ctx context.Context, fallback *gorm.DB) *gorm.DB {
+	if conn, ok := ctx.Value(connKey{}).(*scopedConn); ok {
+		return conn.db
+	}
+	return fallback
+}
+
+// RunScoped runs fn in a transaction where row-level security exposes scope, passing it a context that carries the transaction
+// If ctx already carries a scoped transaction, fn runs in a savepoint of it and the scope is replaced for the rest of it.
+// Functions registered with AfterCommit run once the outermost transaction commits
+func RunScoped(ctx context.Context, db *gorm.DB, scope Scope, fn func(ctx context.Context) error) error {
+	return run(ctx, db, &scope, fn)
+}
+
+// Transaction runs fn in a transaction, or in a savepoint of the transaction carried by ctx, keeping its scope
+// On a context from WithScope the transaction gets that scope
+func Transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	return run(ctx, db, nil, fn)
+}
+
+// Scoped runs fn in the transaction carried by ctx, or in a transaction of its own when there is none
+// Reads that take part in both kinds of requests use it to skip the savepoint Transaction would add
+func Scoped(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if conn, ok := ctx.Value(connKey{}).(*scopedConn); ok && conn.afterCommit != nil {
+		return fn(ctx)
+	}
+	return run(ctx, db, nil, fn)
+}
+
+// run runs fn in a (nested) transaction, setting scope if given
+func run(ctx context.Context, db *gorm.DB, scope *Scope, fn func(ctx context.Context) error) error {
+	outer, nested := ctx.Value(connKey{}).(*scopedConn)
+	if scope == nil && nested && outer.afterCommit == nil {
+		scope = outer.scope
+	}
+	nested = nested && outer.afterCommit != nil
+
+	var afterCommit []func()
+	err := Conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+		if scope != nil {
+			if err := setScope(tx, *scope); err != nil {
+				return err
+			}
+		}
+		return fn(context.WithValue(ctx, connKey{}, &scopedConn{db: tx, afterCommit: &afterCommit}))
+	})
+	if err != nil {
+		return err
+	}
+
+	if nested {
+		// Only a savepoint was released; the hooks wait for the outer transaction
+		*outer.afterCommit = append(*outer.afterCommit, afterCommit...)
+		return nil
+	}
+	for _, hook := range afterCommit {
+		hook()
+	}
+	return nil
+}
+
+// AfterCommit runs fn once the scoped transaction carried by ctx commits, and never if it rolls back
+// Without a scoped transaction fn runs right away. Used for side effects outside the database, such as deleting stored files
+func AfterCommit(ctx context.Context, fn func()) {
+	if conn, ok := ctx.Value(connKey{}).(*scopedConn); ok && conn.afterCommit != nil {
+		*conn.afterCommit = append(*conn.afterCommit, fn)
+		return
+	}
+	fn()
+}
+
+// setScope sets the row-level security settings for the rest of the transaction
+// The settings are transaction-local, so they never leak to other requests sharing the connection pool
+func setScope(tx *gorm.DB, scope Scope) error {
+	err := tx.Exec(
+		"SELECT set_config(?, ?, true), set_config(?, ?, true), set_config(?, ?, true), set_config(?, ?, true), set_config(?, ?, true)",
+		TenantSetting, formatID(scope.TenantID),
+		UserSetting, formatID(scope.UserID),
+		UserEmailSetting, scope.UserEmail,
+		InviteTokenSetting, scope.InviteToken,
+		ShareTokenHashSetting, scope.ShareTokenHash,
+	).Error
+	if err != nil {
+		return fmt.Errorf("failed to set tenant context: %w", err)
+	}
+	return nil
+}
+
+// formatID formats an ID for a setting; zero is left empty, which matches no rows
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// CurrentTenantSQL is the tenant of the transaction's Scope in SQL; NULL without one
+const CurrentTenantSQL = "NULLIF(current_setting('" + TenantSetting + "', true), '')::bigint"
+
+// ScopedTenant is a GORM scope that filters queries by the tenant of the transaction's Scope
+// Row-level security already does; the explicit filter keeps rows a Scope exposes in other tenants
+// (the user's own memberships and invites) out of tenant listings, and also holds on connections that bypass row-level security
+func ScopedTenant(db *gorm.DB) *gorm.DB {
+	return db.Where("tenant_id = " + CurrentTenantSQL)
+}
+
+// CheckAppRole returns an error if the connection's role isn't subject to row-level security
+// Superusers and roles with BYPASSRLS ignore the policies, so the API must not connect as one
+func CheckAppRole(db *gorm.DB) error {
+	role, bypass, err := bypassesRLS(db)
+	if err != nil {
+		return err
+	}
+	if bypass {
+		return fmt.Errorf("database role %s bypasses row-level security; set DATABASE_USER to a role like habitta_app", role)
+	}
+	return nil
+}
+
+// CheckWorkerRole returns an error if the connection's role is subject to row-level security
+// Background workers handle every tenant, so without BYPASSRLS they would find no rows
+func CheckWorkerRole(db *gorm.DB) error {
+	role, bypass, err := bypassesRLS(db)
+	if err != nil {
+		return err
+	}
+	if !bypass {
+		return fmt.Errorf("database role %s is subject to row-level security; set DATABASE_WORKER_USER to a role like habitta_worker", role)
+	}
+	return nil
+}
+
+// bypassesRLS reports the connection's role and whether it is a superuser or has BYPASSRLS
+func bypassesRLS(db *gorm.DB) (string, bool, error) {
+	var role struct {
+		Rolname      string
+		Rolsuper     bool
+		Rolbypassrls bool
+	}
+	err := db.Raw("SELECT rolname, rolsuper, rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&role).Error
+	if err != nil {
+		return "", false, fmt.Errorf("failed to check database role: %w", err)
+	}
+	return role.Rolname, role.Rolsuper || role.Rolbypassrls, nil
+}
@@ -0,0 +1,364 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDSNEnv names the keyword/value DSN of a PostgreSQL superuser the row-level security tests run against,
+// e.g. "host=localhost port=5432 user=habitta password=habitta123 dbname=postgres sslmode=disable"
+// The tests create and drop their own database and login role; without it they are skipped
+const testDSNEnv = "HABITTA_TEST_DATABASE_DSN"
+
+// errRollback ends a test transaction without keeping its changes
+var errRollback = errors.New("rollback")
+
+// rlsTenant holds the rows seeded for one tenant
+type rlsTenant struct {
+	id          uint
+	userID      uint
+	email       string
+	inviteToken string
+	shareHash   string
+}
+
+// rlsEnv is a migrated throwaway database with two seeded tenants
+type rlsEnv struct {
+	admin *gorm.DB // Superuser: seeds rows and reads them back regardless of the policies
+	app   *gorm.DB // Login role in habitta_app, subject to row-level security
+	a, b  rlsTenant
+}
+
+func TestTenantIsolation(t *testing.T) {
+	env := newRLSEnv(t)
+
+	tables := rlsTables(t, env.admin)
+	seeded := []string{
+		"custom_roles", "document_share_link_accesses", "document_share_links", "document_versions", "documents",
+		"folders", "invites", "unit_members", "units", "upload_sessions", "user_tenants",
+	}
+	if strings.Join(tables, ",") != strings.Join(seeded, ",") {
+		t.Fatalf("tables with row-level security = %v, seeded = %v; seed the new tables in newRLSEnv", tables, seeded)
+	}
+
+	scopes := []struct {
+		name  string
+		scope *Scope
+	}{
+		{"tenant B", &Scope{TenantID: env.b.id, UserID: env.b.userID, UserEmail: env.b.email}},
+		{"empty scope", &Scope{}},
+		{"no scope", nil},
+	}
+
+	for _, table := range tables {
+		t.Run(table, func(t *testing.T) {
+			// The tenant's own scope sees its rows, so the checks below aren't vacuous
+			if n := env.count(t, &Scope{TenantID: env.a.id}, table, env.a.id); n == 0 {
+				t.Fatalf("tenant A sees none of its own rows")
+			}
+
+			row := env.rowJSON(t, table, env.a.id)
+			for _, sc := range scopes {
+				t.Run(sc.name, func(t *testing.T) {
+					if n := env.count(t, sc.scope, table, env.a.id); n != 0 {
+						t.Errorf("SELECT sees %d rows of tenant A", n)
+					}
+					if n := env.affected(t, sc.scope, "UPDATE "+table+" SET tenant_id = tenant_id WHERE tenant_id = ?", env.a.id); n != 0 {
+						t.Errorf("UPDATE changed %d rows of tenant A", n)
+					}
+					if n := env.affected(t, sc.scope, "DELETE FROM "+table+" WHERE tenant_id = ?", env.a.id); n != 0 {
+						t.Errorf("DELETE removed %d rows of tenant A", n)
+					}
+
+					err := env.exec(sc.scope, func(tx *gorm.DB) error {
+						return tx.Exec("INSERT INTO "+table+" SELECT * FROM json_populate_record(NULL::"+table+", ?::json)", row).Error
+					})
+					expectRLSViolation(t, "INSERT of a tenant A row", err)
+				})
+			}
+
+			// Rows can't be moved out of the active tenant either
+			err := env.exec(&Scope{TenantID: env.b.id}, func(tx *gorm.DB) error {
+				return tx.Exec("UPDATE "+table+" SET tenant_id = ? WHERE tenant_id = ?", env.a.id, env.b.id).Error
+			})
+			expectRLSViolation(t, "UPDATE moving a tenant B row to tenant A", err)
+		})
+	}
+}
+
+func TestTenantIsolationReadOnlyScopes(t *testing.T) {
+	env := newRLSEnv(t)
+
+	cases := []struct {
+		name  string
+		scope Scope
+		table string
+	}{
+		{"own memberships", Scope{UserID: env.a.userID}, "user_tenants"},
+		{"invites to own email", Scope{UserEmail: strings.ToUpper(env.a.email)}, "invites"},
+		{"invite by token", Scope{InviteToken: env.a.inviteToken}, "invites"},
+		{"share link by token hash", Scope{ShareTokenHash: env.a.shareHash}, "document_share_links"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if n := env.count(t, &tc.scope, tc.table, env.a.id); n != 1 {
+				t.Fatalf("SELECT sees %d rows of tenant A, want 1", n)
+			}
+			if n := env.count(t, &tc.scope, tc.table, env.b.id); n != 0 {
+				t.Errorf("SELECT sees %d rows of tenant B", n)
+			}
+			if n := env.affected(t, &tc.scope, "UPDATE "+tc.table+" SET tenant_id = tenant_id WHERE tenant_id = ?", env.a.id); n != 0 {
+				t.Errorf("UPDATE changed %d rows", n)
+			}
+			if n := env.affected(t, &tc.scope, "DELETE FROM "+tc.table+" WHERE tenant_id = ?", env.a.id); n != 0 {
+				t.Errorf("DELETE removed %d rows", n)
+			}
+		})
+	}
+}
+
+func TestCheckRoles(t *testing.T) {
+	env := newRLSEnv(t)
+
+	if err := CheckAppRole(env.app); err != nil {
+		t.Errorf("CheckAppRole(app role) = %v, want nil", err)
+	}
+	if err := CheckWorkerRole(env.app); err == nil {
+		t.Error("CheckWorkerRole(app role) = nil, want an error")
+	}
+	if err := CheckAppRole(env.admin); err == nil {
+		t.Error("CheckAppRole(superuser) = nil, want an error")
+	}
+	if err := CheckWorkerRole(env.admin); err != nil {
+		t.Errorf("CheckWorkerRole(superuser) = %v, want nil", err)
+	}
+}
+
+// newRLSEnv creates a database migrated to the latest version, a login role in habitta_app and two tenants with
+// a row in every table with row-level security; everything is dropped when the test ends
+func newRLSEnv(t *testing.T) *rlsEnv {
+	t.Helper()
+
+	baseDSN := os.Getenv(testDSNEnv)
+	if baseDSN == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	suffix := time.Now().UnixNano()
+	dbName := fmt.Sprintf("habitta_rls_test_%d", suffix)
+	roleName := fmt.Sprintf("habitta_rls_test_%d", suffix)
+	const rolePassword = "rls-test"
+
+	base := openTestDB(t, baseDSN)
+	if err := base.Exec("CREATE DATABASE " + dbName).Error; err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	t.Cleanup(func() {
+		base.Exec("DROP DATABASE IF EXISTS " + dbName + " WITH (FORCE)")
+		base.Exec("DROP ROLE IF EXISTS " + roleName)
+		Close(base)
+	})
+
+	// Later keywords override earlier ones in a keyword/value DSN
+	admin := openTestDB(t, baseDSN+" dbname="+dbName)
+	t.Cleanup(func() { Close(admin) })
+
+	if _, err := MigrateUp(admin); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	err := admin.Exec(fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s' IN ROLE habitta_app", roleName, rolePassword)).Error
+	if err != nil {
+		t.Fatalf("failed to create test role: %v", err)
+	}
+
+	env := &rlsEnv{admin: admin}
+	env.a = seedTenant(t, admin, "a")
+	env.b = seedTenant(t, admin, "b")
+
+	env.app = openTestDB(t, fmt.Sprintf("%s dbname=%s user=%s password=%s", baseDSN, dbName, roleName, rolePassword))
+	t.Cleanup(func() { Close(env.app) })
+
+	return env
+}
+
+// openTestDB connects without query logging
+func openTestDB(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	return db
+}
+
+// seedTenant creates a tenant with a member and one row in every table with row-level security
+func seedTenant(t *testing.T, db *gorm.DB, name string) rlsTenant {
+	t.Helper()
+
+	tenant := rlsTenant{
+		email:       "member-" + name + "@rls.test",
+		inviteToken: "invite-token-" + name,
+		shareHash:   "share-hash-" + name,
+	}
+	expires := time.Now().Add(24 * time.Hour)
+
+	var unitID, folderID, docID, linkID uint
+	steps := []struct {
+		dest  *uint
+		query string
+		args  []interface{}
+	}{
+		{&tenant.id, "INSERT INTO tenants (name, cnpj) VALUES (?, ?) RETURNING id", []interface{}{"Tenant " + name, name}},
+		{&tenant.userID, "INSERT INTO users (email, password, name) VALUES (?, 'x', ?) RETURNING id", []interface{}{tenant.email, "Member " + name}},
+		{nil, "INSERT INTO user_tenants (user_id, tenant_id, joined_at) VALUES (?, ?, NOW())", []interface{}{&tenant.userID, &tenant.id}},
+		{nil, "INSERT INTO custom_roles (tenant_id, name, display_name) VALUES (?, 'custom', 'Custom')", []interface{}{&tenant.id}},
+		{&unitID, "INSERT INTO units (tenant_id, number) VALUES (?, '101') RETURNING id", []interface{}{&tenant.id}},
+		{nil, "INSERT INTO unit_members (tenant_id, unit_id, user_id, relationship, start_date) VALUES (?, ?, ?, 'proprietario', CURRENT_DATE)", []interface{}{&tenant.id, &unitID, &tenant.userID}},
+		{nil, "INSERT INTO invites (tenant_id, email, token, invited_by_user_id, expires_at) VALUES (?, ?, ?, ?, ?)", []interface{}{&tenant.id, tenant.email, tenant.inviteToken, &tenant.userID, expires}},
+		{&folderID, "INSERT INTO folders (tenant_id, name) VALUES (?, 'Atas') RETURNING id", []interface{}{&tenant.id}},
+		{&docID, "INSERT INTO documents (tenant_id, folder_id, name, original_name, s3_key, uploaded_by_id) VALUES (?, ?, 'Ata', 'ata.pdf', ?, ?) RETURNING id", []interface{}{&tenant.id, &folderID, "docs/" + name, &tenant.userID}},
+		{nil, "INSERT INTO document_versions (tenant_id, document_id, version_number, original_name, s3_key, uploaded_by_id) VALUES (?, ?, 1, 'ata.pdf', ?, ?)", []interface{}{&tenant.id, &docID, "docs/" + name, &tenant.userID}},
+		{nil, "INSERT INTO upload_sessions (tenant_id, user_id, file_name, size, s3_key, expires_at) VALUES (?, ?, 'ata.pdf', 1, ?, ?)", []interface{}{&tenant.id, &tenant.userID, "uploads/" + name, expires}},
+		{&linkID, "INSERT INTO document_share_links (tenant_id, document_id, token_hash, expires_at, created_by_id) VALUES (?, ?, ?, ?, ?) RETURNING id", []interface{}{&tenant.id, &docID, tenant.shareHash, expires, &tenant.userID}},
+		{nil, "INSERT INTO document_share_link_accesses (tenant_id, share_link_id, outcome) VALUES (?, ?, 'granted')", []interface{}{&tenant.id, &linkID}},
+	}
+
+	for _, step := range steps {
+		// IDs returned by earlier steps are passed by pointer
+		args := make([]interface{}, len(step.args))
+		for i, arg := range step.args {
+			if id, ok := arg.(*uint); ok {
+				arg = *id
+			}
+			args[i] = arg
+		}
+
+		var err error
+		if step.dest != nil {
+			err = db.Raw(step.query, args...).Scan(step.dest).Error
+		} else {
+			err = db.Exec(step.query, args...).Error
+		}
+		if err != nil {
+			t.Fatalf("failed to seed tenant %s: %s: %v", name, step.query, err)
+		}
+	}
+
+	return tenant
+}
+
+// rlsTables lists the tables with row-level security enabled
+func rlsTables(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+
+	var tables []string
+	err := db.Raw(`SELECT c.relname FROM pg_class c
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = 'public' AND c.relkind = 'r' AND c.relrowsecurity`).Scan(&tables).Error
+	if err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// exec runs fn as the app role in a transaction with scope (or none if nil), always rolling it back
+func (e *rlsEnv) exec(scope *Scope, fn func(tx *gorm.DB) error) error {
+	run := func(ctx context.Context) error {
+		if err := fn(Conn(ctx, e.app)); err != nil {
+			return err
+		}
+		return errRollback
+	}
+
+	var err error
+	if scope == nil {
+		err = Transaction(context.Background(), e.app, run)
+	} else {
+		err = RunScoped(context.Background(), e.app, *scope, run)
+	}
+	if errors.Is(err, errRollback) {
+		return nil
+	}
+	return err
+}
+
+// count returns how many rows of the tenant the app role sees in table
+func (e *rlsEnv) count(t *testing.T, scope *Scope, table string, tenantID uint) int64 {
+	t.Helper()
+
+	var n int64
+	err := e.exec(scope, func(tx *gorm.DB) error {
+		return tx.Raw("SELECT COUNT(*) FROM "+table+" WHERE tenant_id = ?", tenantID).Scan(&n).Error
+	})
+	if err != nil {
+		t.Fatalf("SELECT on %s failed: %v", table, err)
+	}
+	return n
+}
+
+// affected runs a statement as the app role and returns how many rows it changed
+func (e *rlsEnv) affected(t *testing.T, scope *Scope, query string, args ...interface{}) int64 {
+	t.Helper()
+
+	var n int64
+	err := e.exec(scope, func(tx *gorm.DB) error {
+		result := tx.Exec(query, args...)
+		n = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		t.Fatalf("%s failed: %v", query, err)
+	}
+	return n
+}
+
+// rowJSON returns a row of the tenant in table as JSON, with a new ID so only the policy can reject inserting it
+func (e *rlsEnv) rowJSON(t *testing.T, table string, tenantID uint) string {
+	t.Helper()
+
+	var raw string
+	err := e.admin.Raw("SELECT row_to_json(r)::text FROM "+table+" r WHERE tenant_id = ? LIMIT 1", tenantID).Scan(&raw).Error
+	if err != nil || raw == "" {
+		t.Fatalf("failed to read a row of %s: %v", table, err)
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &row); err != nil {
+		t.Fatalf("failed to decode row of %s: %v", table, err)
+	}
+	row["id"] = 1_000_000 + int64(row["id"].(float64))
+
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		t.Fatalf("failed to encode row of %s: %v", table, err)
+	}
+	return string(encoded)
+}
+
+// expectRLSViolation checks that a statement was rejected by a row-level security policy
+func expectRLSViolation(t *testing.T, what string, err error) {
+	t.Helper()
+
+	if err == nil {
+		t.Errorf("%s succeeded, want a row-level security violation", what)
+		return
+	}
+	if !strings.Contains(err.Error(), "row-level security") {
+		t.Errorf("%s failed with %v, want a row-level security violation", what, err)
+	}
+}
@@ -4,19 +4,22 @@ import (
 	"net/http"
 
 	"github.com/arturbaldoramos/Habitta/internal/middleware"
+	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/services"
 	"github.com/gin-gonic/gin"
 )
 
 // AccountHandler handles account routes for the authenticated user
 type AccountHandler struct {
-	userService services.UserService
+	userService      services.UserService
+	twoFactorService services.TwoFactorService
 }
 
 // NewAccountHandler creates a new account handler
-func NewAccountHandler(userService services.UserService) *AccountHandler {
+func NewAccountHandler(userService services.UserService, twoFactorService services.TwoFactorService) *AccountHandler {
 	return &AccountHandler{
-		userService: userService,
+		userService:      userService,
+		twoFactorService: twoFactorService,
 	}
 }
 
@@ -59,8 +62,9 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 	}
 
 	var req struct {
-		Name  string `json:"name"`
-		Phone string `json:"phone"`
+		Name   string `json:"name"`
+		Phone  string `json:"phone"`
+		Locale string `json:"locale"` // Optional; keeps the current language when empty
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -89,8 +93,19 @@ func (h *AccountHandler) UpdateAccount(c *gin.Context) {
 		return
 	}
 
+	if req.Locale != "" && !models.IsSupportedLocale(req.Locale) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": models.ErrUnsupportedLocale.Error(),
+		})
+		return
+	}
+
 	user.Name = req.Name
 	user.Phone = req.Phone
+	if req.Locale != "" {
+		user.Locale = req.Locale
+	}
 
 	if err := h.userService.Update(user); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -146,6 +161,140 @@ func (h *AccountHandler) UpdatePassword(c *gin.Context) {
 	})
 }
 
+// SetupTwoFactor starts 2FA enrollment and returns the secret and provisioning URI
+// POST /api/account/2fa/setup
+func (h *AccountHandler) SetupTwoFactor(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	setup, err := h.twoFactorService.Setup(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": setup,
+	})
+}
+
+// ConfirmTwoFactor enables 2FA and returns the recovery codes (shown only once)
+// POST /api/account/2fa/confirm
+func (h *AccountHandler) ConfirmTwoFactor(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	var req services.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.twoFactorService.Confirm(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
+// DisableTwoFactor turns off 2FA for the authenticated user
+// POST /api/account/2fa/disable
+func (h *AccountHandler) DisableTwoFactor(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	var req services.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.twoFactorService.Disable(c.Request.Context(), userID, req.Password, req.Code); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "two-factor authentication disabled",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+// POST /api/account/2fa/recovery-codes
+func (h *AccountHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	var req services.TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	codes, err := h.twoFactorService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"recovery_codes": codes,
+		},
+	})
+}
+
 // RegisterRoutes registers account routes
 func (h *AccountHandler) RegisterRoutes(router *gin.RouterGroup) {
 	account := router.Group("/account")
@@ -153,5 +302,11 @@ func (h *AccountHandler) RegisterRoutes(router *gin.RouterGroup) {
 		account.GET("", h.GetAccount)
 		account.PATCH("", h.UpdateAccount)
 		account.PATCH("/password", h.UpdatePassword)
+
+		// Two-factor authentication
+		account.POST("/2fa/setup", h.SetupTwoFactor)
+		account.POST("/2fa/confirm", h.ConfirmTwoFactor)
+		account.POST("/2fa/disable", h.DisableTwoFactor)
+		account.POST("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
 	}
 }
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/arturbaldoramos/Habitta/internal/middleware"
+	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -33,12 +35,9 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.Login(req)
+	response, err := h.authService.Login(c.Request.Context(), req, sessionMetadata(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Unauthorized",
-			"message": err.Error(),
-		})
+		respondLoginError(c, err)
 		return
 	}
 
@@ -69,15 +68,35 @@ func (h *AuthHandler) LoginWithTenant(c *gin.Context) {
 		return
 	}
 
-	response, err := h.authService.LoginWithTenant(req.Email, req.Password, uint(tenantID))
+	response, err := h.authService.LoginWithTenant(c.Request.Context(), req.Email, req.Password, uint(tenantID), sessionMetadata(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Unauthorized",
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": response,
+	})
+}
+
+// VerifyTwoFactor completes a login by checking the TOTP or recovery code for a challenge token
+// POST /api/auth/2fa/verify
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req services.VerifyTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
 			"message": err.Error(),
 		})
 		return
 	}
 
+	response, err := h.authService.VerifyTwoFactor(c.Request.Context(), req, sessionMetadata(c))
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": response,
 	})
@@ -131,7 +150,16 @@ func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 		return
 	}
 
-	token, err := h.authService.SwitchTenant(userID, uint(tenantID))
+	sessionID, exists := middleware.GetSessionID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "session not found in context",
+		})
+		return
+	}
+
+	tokens, err := h.authService.SwitchTenant(c.Request.Context(), userID, sessionID, uint(tenantID))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
@@ -141,9 +169,58 @@ func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": gin.H{
-			"token": token,
-		},
+		"data": tokens,
+	})
+}
+
+// Refresh exchanges a refresh token for a new token pair
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req services.RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tokens, err := h.authService.Refresh(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tokens,
+	})
+}
+
+// Logout revokes the current session
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, exists := middleware.GetSessionID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "session not found in context",
+		})
+		return
+	}
+
+	if err := h.authService.Logout(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "logged out successfully",
 	})
 }
 
@@ -153,6 +230,136 @@ func (h *AuthHandler) RegisterRoutes(router *gin.RouterGroup) {
 	{
 		auth.POST("/login", h.Login)
 		auth.POST("/login/tenant/:tenant_id", h.LoginWithTenant)
+		auth.POST("/2fa/verify", h.VerifyTwoFactor)
 		auth.POST("/register", h.Register)
+		auth.POST("/refresh", h.Refresh)
+		auth.POST("/forgot-password", h.ForgotPassword)
+		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/verify-email", h.VerifyEmail)
+	}
+}
+
+// ForgotPassword sends a password reset link if the email is registered
+// POST /api/auth/forgot-password
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req services.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ForgotPassword(req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "failed to process password reset request",
+		})
+		return
+	}
+
+	// Same response whether or not the email exists
+	c.JSON(http.StatusOK, gin.H{
+		"message": "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using a reset token
+// POST /api/auth/reset-password
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req services.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "password reset successfully",
+	})
+}
+
+// VerifyEmail confirms ownership of the user's email address
+// POST /api/auth/verify-email
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req services.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.authService.VerifyEmail(req.Token); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "email verified successfully",
+	})
+}
+
+// ResendVerification sends a new verification email to the authenticated user
+// POST /api/auth/resend-verification
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user not found in context",
+		})
+		return
+	}
+
+	if err := h.authService.ResendVerification(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "verification email sent",
+	})
+}
+
+// respondLoginError maps a login failure to 423 when the account is locked, 401 otherwise
+func respondLoginError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrAccountLocked) {
+		c.JSON(http.StatusLocked, gin.H{
+			"error":   "Locked",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusUnauthorized, gin.H{
+		"error":   "Unauthorized",
+		"message": err.Error(),
+	})
+}
+
+// sessionMetadata extracts client information to record on a new session
+func sessionMetadata(c *gin.Context) services.SessionMetadata {
+	return services.SessionMetadata{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
 	}
 }
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+	"log"
+	"mime"
 	"net/http"
 	"strconv"
 
@@ -14,16 +17,22 @@ import (
 type DocumentHandler struct {
 	folderService   services.FolderService
 	documentService services.DocumentService
+	quotaService    services.StorageQuotaService
+	archiveService  services.DocumentArchiveService
 }
 
 // NewDocumentHandler creates a new document handler
 func NewDocumentHandler(
 	folderService services.FolderService,
 	documentService services.DocumentService,
+	quotaService services.StorageQuotaService,
+	archiveService services.DocumentArchiveService,
 ) *DocumentHandler {
 	return &DocumentHandler{
 		folderService:   folderService,
 		documentService: documentService,
+		quotaService:    quotaService,
+		archiveService:  archiveService,
 	}
 }
 
@@ -31,20 +40,47 @@ func NewDocumentHandler(
 func (h *DocumentHandler) RegisterRoutes(router *gin.RouterGroup) {
 	folders := router.Group("/folders")
 	{
-		folders.POST("", h.CreateFolder)
-		folders.GET("", h.GetFolders)
-		folders.PUT("/:id", h.UpdateFolder)
-		folders.DELETE("/:id", h.DeleteFolder)
+		folders.POST("", middleware.RequirePermission(models.PermFoldersWrite), h.CreateFolder)
+		folders.GET("", middleware.RequirePermission(models.PermDocumentsRead), h.GetFolders)
+		folders.GET("/:id", middleware.RequirePermission(models.PermDocumentsRead), h.GetFolder)
+		folders.PUT("/:id", middleware.RequirePermission(models.PermFoldersWrite), h.UpdateFolder)
+		folders.PATCH("/:id/move", middleware.RequirePermission(models.PermFoldersWrite), h.MoveFolder)
+		folders.DELETE("/:id", middleware.RequirePermission(models.PermFoldersWrite), h.DeleteFolder)
 	}
 
 	documents := router.Group("/documents")
 	{
-		documents.POST("/upload", h.UploadDocument)
-		documents.GET("", h.GetDocuments)
-		documents.GET("/:id", h.GetDocument)
-		documents.GET("/:id/download", h.GetDownloadURL)
-		documents.DELETE("/:id", h.DeleteDocument)
-		documents.PATCH("/:id/move", h.MoveDocument)
+		documents.POST("/upload", middleware.RequirePermission(models.PermDocumentsUpload), h.UploadDocument)
+		documents.GET("", middleware.RequirePermission(models.PermDocumentsRead), h.GetDocuments)
+		documents.GET("/usage", middleware.RequirePermission(models.PermDocumentsRead), h.GetUsage)
+		documents.POST("/archive", middleware.RequirePermission(models.PermDocumentsRead), h.DownloadArchive)
+		documents.GET("/search", middleware.RequireAnyPermission(models.PermDocumentsRead, models.PermDocumentsReadShared), h.SearchDocuments)
+		documents.GET("/:id", middleware.RequirePermission(models.PermDocumentsRead), h.GetDocument)
+		documents.GET("/:id/download", middleware.RequirePermission(models.PermDocumentsRead), h.GetDownloadURL)
+		documents.DELETE("/:id", middleware.RequirePermission(models.PermDocumentsDelete), h.DeleteDocument)
+		documents.PATCH("/:id/move", middleware.RequirePermission(models.PermDocumentsUpload), h.MoveDocument)
+		documents.GET("/:id/versions", middleware.RequirePermission(models.PermDocumentsRead), h.ListVersions)
+		documents.POST("/:id/versions", middleware.RequirePermission(models.PermDocumentsUpload), h.UploadVersion)
+		documents.GET("/:id/versions/:version/download", middleware.RequirePermission(models.PermDocumentsRead), h.GetVersionDownloadURL)
+		documents.POST("/:id/versions/:version/restore", middleware.RequirePermission(models.PermDocumentsUpload), h.RestoreVersion)
+		documents.PATCH("/:id/visibility", middleware.RequirePermission(models.PermDocumentsUpload), h.UpdateVisibility)
+		documents.POST("/:id/share-links", middleware.RequirePermission(models.PermDocumentsShare), h.CreateShareLink)
+	}
+
+	// Public links to documents, for people outside the platform
+	shareLinks := router.Group("/share-links", middleware.RequirePermission(models.PermDocumentsShare))
+	{
+		shareLinks.GET("", h.GetShareLinks)
+		shareLinks.GET("/:id/accesses", h.GetShareLinkAccesses)
+		shareLinks.DELETE("/:id", h.RevokeShareLink)
+	}
+
+	// Read-only views for residents, filtered by the folder and document visibility rules
+	resident := router.Group("/resident", middleware.RequirePermission(models.PermDocumentsReadShared))
+	{
+		resident.GET("/folders", h.GetSharedFolders)
+		resident.GET("/documents", h.GetSharedDocuments)
+		resident.GET("/documents/:id/download", h.GetSharedDownloadURL)
 	}
 }
 
@@ -71,7 +107,7 @@ func (h *DocumentHandler) CreateFolder(c *gin.Context) {
 
 	folder.TenantID = tenantID
 
-	if err := h.folderService.Create(&folder); err != nil {
+	if err := h.folderService.Create(c.Request.Context(), &folder); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -84,8 +120,8 @@ func (h *DocumentHandler) CreateFolder(c *gin.Context) {
 	})
 }
 
-// GetFolders handles listing all folders
-// GET /api/folders
+// GetFolders handles listing folders
+// GET /api/folders (all folders) or /api/folders?parent_id=1 (subfolders; 0 for the root)
 func (h *DocumentHandler) GetFolders(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantID(c)
 	if !ok {
@@ -96,7 +132,26 @@ func (h *DocumentHandler) GetFolders(c *gin.Context) {
 		return
 	}
 
-	folders, err := h.folderService.GetAll(tenantID)
+	var folders []models.Folder
+	var err error
+	if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+		id, parseErr := strconv.ParseUint(parentIDStr, 10, 32)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": "invalid parent_id",
+			})
+			return
+		}
+		var parentID *uint
+		if id != 0 {
+			pid := uint(id)
+			parentID = &pid
+		}
+		folders, err = h.folderService.GetChildren(c.Request.Context(), tenantID, parentID)
+	} else {
+		folders, err = h.folderService.GetAll(c.Request.Context(), tenantID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -110,6 +165,41 @@ func (h *DocumentHandler) GetFolders(c *gin.Context) {
 	})
 }
 
+// GetFolder handles fetching a folder with its breadcrumbs
+// GET /api/folders/:id
+func (h *DocumentHandler) GetFolder(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid folder ID",
+		})
+		return
+	}
+
+	folder, err := h.folderService.GetByID(c.Request.Context(), tenantID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not Found",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": folder,
+	})
+}
+
 // UpdateFolder handles folder update
 // PUT /api/folders/:id
 func (h *DocumentHandler) UpdateFolder(c *gin.Context) {
@@ -143,7 +233,53 @@ func (h *DocumentHandler) UpdateFolder(c *gin.Context) {
 	folder.ID = uint(id)
 	folder.TenantID = tenantID
 
-	if err := h.folderService.Update(&folder); err != nil {
+	if err := h.folderService.Update(c.Request.Context(), &folder); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": folder,
+	})
+}
+
+// MoveFolder handles moving a folder under another parent
+// PATCH /api/folders/:id/move
+func (h *DocumentHandler) MoveFolder(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid folder ID",
+		})
+		return
+	}
+
+	var body struct {
+		ParentID *uint `json:"parent_id"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	folder, err := h.folderService.Move(c.Request.Context(), tenantID, uint(id), body.ParentID)
+	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -157,7 +293,7 @@ func (h *DocumentHandler) UpdateFolder(c *gin.Context) {
 }
 
 // DeleteFolder handles folder deletion
-// DELETE /api/folders/:id
+// DELETE /api/folders/:id?mode=recursive|move_to_parent
 func (h *DocumentHandler) DeleteFolder(c *gin.Context) {
 	tenantID, ok := middleware.GetTenantID(c)
 	if !ok {
@@ -177,7 +313,8 @@ func (h *DocumentHandler) DeleteFolder(c *gin.Context) {
 		return
 	}
 
-	if err := h.folderService.Delete(tenantID, uint(id)); err != nil {
+	mode := services.FolderDeleteMode(c.Query("mode"))
+	if err := h.folderService.Delete(c.Request.Context(), tenantID, uint(id), mode); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -236,12 +373,9 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		folderID = &fid
 	}
 
-	doc, err := h.documentService.Upload(tenantID, userID, folderID, file, header)
+	doc, err := h.documentService.Upload(c.Request.Context(), tenantID, userID, folderID, file, header)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Bad Request",
-			"message": err.Error(),
-		})
+		respondUploadError(c, err)
 		return
 	}
 
@@ -276,7 +410,7 @@ func (h *DocumentHandler) GetDocuments(c *gin.Context) {
 		folderID = &fid
 	}
 
-	docs, err := h.documentService.GetAll(tenantID, folderID)
+	docs, err := h.documentService.GetAll(c.Request.Context(), tenantID, folderID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -311,7 +445,7 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 		return
 	}
 
-	doc, err := h.documentService.GetByID(tenantID, uint(id))
+	doc, err := h.documentService.GetByID(c.Request.Context(), tenantID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Not Found",
@@ -346,12 +480,9 @@ func (h *DocumentHandler) GetDownloadURL(c *gin.Context) {
 		return
 	}
 
-	url, err := h.documentService.GetDownloadURL(tenantID, uint(id))
+	url, err := h.documentService.GetDownloadURL(c.Request.Context(), tenantID, uint(id))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Bad Request",
-			"message": err.Error(),
-		})
+		respondScanError(c, err, http.StatusBadRequest)
 		return
 	}
 
@@ -383,7 +514,7 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 		return
 	}
 
-	if err := h.documentService.Delete(tenantID, uint(id)); err != nil {
+	if err := h.documentService.Delete(c.Request.Context(), tenantID, uint(id)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -428,7 +559,7 @@ func (h *DocumentHandler) MoveDocument(c *gin.Context) {
 		return
 	}
 
-	if err := h.documentService.MoveToFolder(tenantID, uint(id), body.FolderID); err != nil {
+	if err := h.documentService.MoveToFolder(c.Request.Context(), tenantID, uint(id), body.FolderID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -440,3 +571,738 @@ func (h *DocumentHandler) MoveDocument(c *gin.Context) {
 		"message": "document moved successfully",
 	})
 }
+
+// UploadVersion handles uploading a new version of a document
+// POST /api/documents/:id/versions
+func (h *DocumentHandler) UploadVersion(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "file is required",
+		})
+		return
+	}
+	defer file.Close()
+
+	version, err := h.documentService.UploadVersion(c.Request.Context(), tenantID, userID, uint(id), file, header)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": version,
+	})
+}
+
+// ListVersions handles listing the version history of a document
+// GET /api/documents/:id/versions
+func (h *DocumentHandler) ListVersions(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	versions, err := h.documentService.ListVersions(c.Request.Context(), tenantID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not Found",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": versions,
+	})
+}
+
+// GetVersionDownloadURL handles generating a download URL for a document version
+// GET /api/documents/:id/versions/:version/download
+func (h *DocumentHandler) GetVersionDownloadURL(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid version number",
+		})
+		return
+	}
+
+	url, err := h.documentService.GetVersionDownloadURL(c.Request.Context(), tenantID, uint(id), versionNumber)
+	if err != nil {
+		respondScanError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"url": url,
+		},
+	})
+}
+
+// RestoreVersion handles restoring an older version as the current one
+// POST /api/documents/:id/versions/:version/restore
+func (h *DocumentHandler) RestoreVersion(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(c.Param("version"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid version number",
+		})
+		return
+	}
+
+	doc, err := h.documentService.RestoreVersion(c.Request.Context(), tenantID, uint(id), versionNumber)
+	if err != nil {
+		respondScanError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": doc,
+	})
+}
+
+// UpdateVisibility handles changing who can see a document
+// PATCH /api/documents/:id/visibility
+func (h *DocumentHandler) UpdateVisibility(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	var body struct {
+		Visibility models.Visibility `json:"visibility" binding:"required"`
+		Audience   models.Audience   `json:"audience"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	doc, err := h.documentService.SetVisibility(c.Request.Context(), tenantID, uint(id), body.Visibility, body.Audience)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": doc,
+	})
+}
+
+// DownloadArchive handles streaming a ZIP of a folder tree or a selection of documents
+// POST /api/documents/archive
+func (h *DocumentHandler) DownloadArchive(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	var req services.ArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	archive, err := h.archiveService.Prepare(c.Request.Context(), tenantID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archive.FileName}))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure midway can only cut the download short
+	if err := h.archiveService.Write(c.Request.Context(), c.Writer, archive); err != nil {
+		log.Printf("Failed to stream archive for tenant %d: %v", tenantID, err)
+	}
+}
+
+// SearchDocuments handles full-text search over document names, folders and contents
+// Roles that can read every document search them all; residents only search what is shared with them
+// GET /api/documents/search?q=ata+assembleia&page=1&per_page=20
+func (h *DocumentHandler) SearchDocuments(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if perPage < 1 || perPage > 50 {
+		perPage = 20
+	}
+
+	var results []models.DocumentSearchResult
+	var err error
+	if perms, _ := middleware.GetPermissions(c); perms.Has(models.PermDocumentsRead) {
+		results, err = h.documentService.Search(c.Request.Context(), tenantID, c.Query("q"), page, perPage)
+	} else {
+		results, err = h.documentService.SearchShared(c.Request.Context(), tenantID, userID, c.Query("q"), page, perPage)
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidSearchQuery) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   http.StatusText(status),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     results,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// RegisterPublicRoutes registers the routes that open share links, which need no authentication
+func (h *DocumentHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("/share/:token", h.OpenShareLink)
+	router.POST("/share/:token", h.OpenShareLink)
+}
+
+// CreateShareLink handles creating a public link to a document
+// POST /api/documents/:id/share-links
+func (h *DocumentHandler) CreateShareLink(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	var req services.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	link, err := h.documentService.CreateShareLink(c.Request.Context(), tenantID, userID, uint(id), req)
+	if err != nil {
+		respondScanError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": link,
+	})
+}
+
+// GetShareLinks handles listing the tenant's share links
+// GET /api/share-links?document_id=1&include_inactive=true
+func (h *DocumentHandler) GetShareLinks(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	var docID *uint
+	if docIDStr := c.Query("document_id"); docIDStr != "" {
+		id, err := strconv.ParseUint(docIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": "invalid document_id",
+			})
+			return
+		}
+		did := uint(id)
+		docID = &did
+	}
+
+	links, err := h.documentService.ListShareLinks(c.Request.Context(), tenantID, docID, c.Query("include_inactive") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": links,
+	})
+}
+
+// GetShareLinkAccesses handles listing every attempt to open a share link
+// GET /api/share-links/:id/accesses
+func (h *DocumentHandler) GetShareLinkAccesses(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid share link ID",
+		})
+		return
+	}
+
+	accesses, err := h.documentService.ListShareLinkAccesses(c.Request.Context(), tenantID, uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrShareLinkNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":   http.StatusText(status),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": accesses,
+	})
+}
+
+// RevokeShareLink handles disabling a share link
+// DELETE /api/share-links/:id
+func (h *DocumentHandler) RevokeShareLink(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid share link ID",
+		})
+		return
+	}
+
+	if err := h.documentService.RevokeShareLink(c.Request.Context(), tenantID, uint(id)); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, models.ErrShareLinkNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error":   http.StatusText(status),
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Share link revoked successfully",
+	})
+}
+
+// OpenShareLink handles opening a share link (public endpoint) by redirecting to a short-lived download URL
+// Protected links take the password as a form or JSON field, so a plain HTML form can submit it
+// GET /api/share/:token
+// POST /api/share/:token
+func (h *DocumentHandler) OpenShareLink(c *gin.Context) {
+	var req struct {
+		Password string `json:"password" form:"password"`
+	}
+	if c.Request.Method == http.MethodPost {
+		if err := c.ShouldBind(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	url, err := h.documentService.OpenShareLink(c.Request.Context(), c.Param("token"), req.Password, services.ShareLinkClient{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	})
+	if err != nil {
+		respondShareLinkError(c, err)
+		return
+	}
+
+	status := http.StatusFound
+	if c.Request.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	c.Redirect(status, url)
+}
+
+// GetSharedFolders handles listing the folders shared with the current resident
+// GET /api/resident/folders
+func (h *DocumentHandler) GetSharedFolders(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	folders, err := h.documentService.ListSharedFolders(c.Request.Context(), tenantID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": folders,
+	})
+}
+
+// GetSharedDocuments handles listing the documents shared with the current resident
+// GET /api/resident/documents?folder_id=1
+func (h *DocumentHandler) GetSharedDocuments(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	var folderID *uint
+	if folderIDStr := c.Query("folder_id"); folderIDStr != "" {
+		id, err := strconv.ParseUint(folderIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": "invalid folder_id",
+			})
+			return
+		}
+		fid := uint(id)
+		folderID = &fid
+	}
+
+	docs, err := h.documentService.ListShared(c.Request.Context(), tenantID, userID, folderID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": docs,
+	})
+}
+
+// GetSharedDownloadURL handles generating a download URL for a document shared with the current resident
+// GET /api/resident/documents/:id/download
+func (h *DocumentHandler) GetSharedDownloadURL(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid document ID",
+		})
+		return
+	}
+
+	url, err := h.documentService.GetSharedDownloadURL(c.Request.Context(), tenantID, userID, uint(id))
+	if err != nil {
+		respondScanError(c, err, http.StatusNotFound)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"url": url,
+		},
+	})
+}
+
+// GetUsage handles the storage usage report
+// GET /api/documents/usage
+func (h *DocumentHandler) GetUsage(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	usage, err := h.quotaService.GetUsage(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": usage,
+	})
+}
+
+// respondUploadError maps an upload failure to 413 when the storage quota is exceeded,
+// 415 when the file type is refused and 400 otherwise
+func respondUploadError(c *gin.Context, err error) {
+	if errors.Is(err, models.ErrStorageQuotaExceeded) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "Request Entity Too Large",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if errors.Is(err, models.ErrFileTypeNotAllowed) || errors.Is(err, models.ErrFileContentMismatch) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{
+			"error":   "Unsupported Media Type",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":   "Bad Request",
+		"message": err.Error(),
+	})
+}
+
+// respondShareLinkError maps the errors of opening a share link to their status codes
+func respondShareLinkError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrShareLinkNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrShareLinkUnavailable):
+		status = http.StatusGone
+	case errors.Is(err, models.ErrSharePasswordRequired), errors.Is(err, models.ErrSharePasswordInvalid):
+		status = http.StatusUnauthorized
+	case errors.Is(err, models.ErrScanPending), errors.Is(err, models.ErrScanBlocked):
+		status = http.StatusConflict
+	}
+
+	c.JSON(status, gin.H{
+		"error":   http.StatusText(status),
+		"message": err.Error(),
+	})
+}
+
+// respondScanError maps a file that is pending or failed the malware scan to 409, other errors to status
+func respondScanError(c *gin.Context, err error, status int) {
+	if errors.Is(err, models.ErrScanPending) || errors.Is(err, models.ErrScanBlocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Conflict",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"error":   http.StatusText(status),
+		"message": err.Error(),
+	})
+}
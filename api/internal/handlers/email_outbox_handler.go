@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// EmailOutboxHandler handles the admin routes of the email outbox
+type EmailOutboxHandler struct {
+	emailOutbox services.EmailOutboxService
+}
+
+// NewEmailOutboxHandler creates a new email outbox handler
+func NewEmailOutboxHandler(emailOutbox services.EmailOutboxService) *EmailOutboxHandler {
+	return &EmailOutboxHandler{
+		emailOutbox: emailOutbox,
+	}
+}
+
+// List handles listing queued emails, newest first
+// GET /api/email-outbox?status=failed&page=1&per_page=20
+func (h *EmailOutboxHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	emails, total, err := h.emailOutbox.List(models.OutboxStatus(c.Query("status")), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     emails,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// GetByID handles getting a queued email
+// GET /api/email-outbox/:id
+func (h *EmailOutboxHandler) GetByID(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid email ID",
+		})
+		return
+	}
+
+	email, err := h.emailOutbox.GetByID(uint(id))
+	if err != nil {
+		respondOutboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": email,
+	})
+}
+
+// Retry handles putting a failed email back in the queue
+// POST /api/email-outbox/:id/retry
+func (h *EmailOutboxHandler) Retry(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid email ID",
+		})
+		return
+	}
+
+	email, err := h.emailOutbox.Retry(uint(id))
+	if err != nil {
+		respondOutboxError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": email,
+	})
+}
+
+// respondOutboxError maps outbox errors to HTTP statuses
+func respondOutboxError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, models.ErrOutboxEmailNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, models.ErrOutboxEmailNotRetryable):
+		status = http.StatusConflict
+	}
+	c.JSON(status, gin.H{
+		"error":   http.StatusText(status),
+		"message": err.Error(),
+	})
+}
+
+// RegisterRoutes registers email outbox routes
+func (h *EmailOutboxHandler) RegisterRoutes(router *gin.RouterGroup) {
+	outbox := router.Group("/email-outbox")
+	{
+		outbox.GET("", h.List)
+		outbox.GET("/:id", h.GetByID)
+		outbox.POST("/:id/retry", h.Retry)
+	}
+}
@@ -66,7 +66,7 @@ func (h *InviteHandler) CreateInvite(c *gin.Context) {
 		return
 	}
 
-	invite, err := h.inviteService.CreateInvite(tenantID, userID, req)
+	invite, err := h.inviteService.CreateInvite(c.Request.Context(), tenantID, userID, req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
@@ -80,12 +80,87 @@ func (h *InviteHandler) CreateInvite(c *gin.Context) {
 	})
 }
 
+// maxBulkInviteBody caps the size of a bulk import, JSON or CSV
+const maxBulkInviteBody = 1 << 20
+
+// CreateInvitesBulk handles importing many invites at once, from JSON or from a CSV with email, role and unit_number
+// Invalid rows are reported one by one; the others are created together
+// POST /api/invites/bulk
+func (h *InviteHandler) CreateInvitesBulk(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user not found in context",
+		})
+		return
+	}
+
+	tenantID, exists := middleware.GetTenantID(c)
+	if !exists {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "active tenant required",
+		})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBulkInviteBody)
+
+	var rows []services.BulkInviteRow
+	var err error
+	switch c.ContentType() {
+	case "multipart/form-data":
+		file, _, formErr := c.Request.FormFile("file")
+		if formErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": "file is required",
+			})
+			return
+		}
+		defer file.Close()
+		rows, err = services.ParseBulkInviteCSV(file)
+	case "text/csv", "application/csv":
+		rows, err = services.ParseBulkInviteCSV(c.Request.Body)
+	default:
+		var req services.BulkInviteRequest
+		err = c.ShouldBindJSON(&req)
+		rows = req.Invites
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.inviteService.CreateInvitesBulk(c.Request.Context(), tenantID, userID, rows)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Nothing imported: every row was rejected
+	status := http.StatusCreated
+	if len(result.Created) == 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, gin.H{
+		"data": result,
+	})
+}
+
 // GetInviteByToken handles retrieving an invite by token (public endpoint)
 // GET /api/invites/:token
 func (h *InviteHandler) GetInviteByToken(c *gin.Context) {
 	token := c.Param("token")
 
-	invite, err := h.inviteService.GetInviteByToken(token)
+	invite, err := h.inviteService.GetInviteByToken(c.Request.Context(), token)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Not Found",
@@ -113,7 +188,7 @@ func (h *InviteHandler) AcceptInvite(c *gin.Context) {
 		return
 	}
 
-	user, err := h.inviteService.AcceptInvite(token, req)
+	user, err := h.inviteService.AcceptInvite(c.Request.Context(), token, req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
@@ -148,7 +223,7 @@ func (h *InviteHandler) GetMyPendingInvites(c *gin.Context) {
 		return
 	}
 
-	invites, err := h.inviteService.GetPendingInvitesByEmail(emailStr)
+	invites, err := h.inviteService.GetPendingInvitesByEmail(c.Request.Context(), emailStr)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -193,7 +268,7 @@ func (h *InviteHandler) CancelInvite(c *gin.Context) {
 		return
 	}
 
-	if err := h.inviteService.CancelInvite(uint(inviteID), userID, tenantID); err != nil {
+	if err := h.inviteService.CancelInvite(c.Request.Context(), uint(inviteID), userID, tenantID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -206,6 +281,51 @@ func (h *InviteHandler) CancelInvite(c *gin.Context) {
 	})
 }
 
+// ResendInvite handles renewing an invite with a new link and sending it again (requires active tenant)
+// POST /api/invites/:id/resend (registered as :token, see main.go)
+func (h *InviteHandler) ResendInvite(c *gin.Context) {
+	inviteIDStr := c.Param("token")
+	inviteID, err := strconv.ParseUint(inviteIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid invite ID format",
+		})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user not found in context",
+		})
+		return
+	}
+
+	tenantID, exists := middleware.GetTenantID(c)
+	if !exists {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "active tenant required",
+		})
+		return
+	}
+
+	invite, err := h.inviteService.ResendTenantInvite(c.Request.Context(), uint(inviteID), userID, tenantID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": invite,
+	})
+}
+
 // GetTenantInvites handles retrieving all invites for the active tenant
 // GET /api/tenants/invites
 func (h *InviteHandler) GetTenantInvites(c *gin.Context) {
@@ -218,7 +338,7 @@ func (h *InviteHandler) GetTenantInvites(c *gin.Context) {
 		return
 	}
 
-	invites, err := h.inviteService.GetTenantInvites(tenantID)
+	invites, err := h.inviteService.GetTenantInvites(c.Request.Context(), tenantID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/arturbaldoramos/Habitta/internal/scheduler"
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler handles the admin routes of the scheduled jobs
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(scheduler *scheduler.Scheduler) *JobHandler {
+	return &JobHandler{
+		scheduler: scheduler,
+	}
+}
+
+// List handles listing the scheduled jobs with their next and last runs
+// GET /api/jobs
+func (h *JobHandler) List(c *gin.Context) {
+	jobs, err := h.scheduler.Jobs()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": jobs,
+	})
+}
+
+// Runs handles listing the run history, newest first
+// GET /api/jobs/runs?job=expire-invites&page=1&per_page=20
+func (h *JobHandler) Runs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	if perPage < 1 || perPage > 100 {
+		perPage = 20
+	}
+
+	runs, total, err := h.scheduler.Runs(c.Query("job"), page, perPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":     runs,
+		"total":    total,
+		"page":     page,
+		"per_page": perPage,
+	})
+}
+
+// RegisterRoutes registers scheduled job routes
+func (h *JobHandler) RegisterRoutes(router *gin.RouterGroup) {
+	jobs := router.Group("/jobs")
+	{
+		jobs.GET("", h.List)
+		jobs.GET("/runs", h.Runs)
+	}
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/arturbaldoramos/Habitta/internal/middleware"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MyPermissionsResponse represents the active role and its permissions
+type MyPermissionsResponse struct {
+	Role        string             `json:"role"`
+	Permissions models.Permissions `json:"permissions"`
+}
+
+// RoleHandler handles role and permission routes
+type RoleHandler struct {
+	roleService services.RoleService
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleService services.RoleService) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+	}
+}
+
+// ListPermissions handles listing the permission registry
+// GET /api/roles/permissions
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": h.roleService.ListPermissions(),
+	})
+}
+
+// GetMyPermissions handles retrieving the active role's permissions
+// GET /api/roles/me
+func (h *RoleHandler) GetMyPermissions(c *gin.Context) {
+	role, _ := middleware.GetActiveRole(c)
+	perms, ok := middleware.GetPermissions(c)
+	if !ok {
+		perms = models.Permissions{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": MyPermissionsResponse{
+			Role:        role,
+			Permissions: perms,
+		},
+	})
+}
+
+// List handles listing the roles available in the active tenant
+// GET /api/roles
+func (h *RoleHandler) List(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	roles, err := h.roleService.ListRoles(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": roles,
+	})
+}
+
+// Create handles custom role creation
+// POST /api/roles
+func (h *RoleHandler) Create(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	var req services.CreateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	actorPerms, _ := middleware.GetPermissions(c)
+
+	role, err := h.roleService.CreateRole(c.Request.Context(), tenantID, actorPerms, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": role,
+	})
+}
+
+// Update handles custom role updates
+// PATCH /api/roles/:id
+func (h *RoleHandler) Update(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid role ID",
+		})
+		return
+	}
+
+	var req services.UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	actorRole, _ := middleware.GetActiveRole(c)
+	actorPerms, _ := middleware.GetPermissions(c)
+
+	role, err := h.roleService.UpdateRole(c.Request.Context(), tenantID, uint(id), models.UserRole(actorRole), actorPerms, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": role,
+	})
+}
+
+// Delete handles custom role deletion
+// DELETE /api/roles/:id
+func (h *RoleHandler) Delete(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid role ID",
+		})
+		return
+	}
+
+	if err := h.roleService.DeleteRole(c.Request.Context(), tenantID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "role deleted successfully",
+	})
+}
+
+// RegisterRoutes registers role routes
+func (h *RoleHandler) RegisterRoutes(router *gin.RouterGroup) {
+	roles := router.Group("/roles")
+	{
+		roles.GET("", h.List)
+		roles.GET("/permissions", h.ListPermissions)
+		roles.GET("/me", h.GetMyPermissions)
+		roles.POST("", middleware.RequirePermission(models.PermRolesManage), h.Create)
+		roles.PATCH("/:id", middleware.RequirePermission(models.PermRolesManage), h.Update)
+		roles.DELETE("/:id", middleware.RequirePermission(models.PermRolesManage), h.Delete)
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// StorageHandler serves and receives files of the local storage backend through signed URLs
+type StorageHandler struct {
+	store services.LocalFileStore
+}
+
+// NewStorageHandler creates a new storage handler
+func NewStorageHandler(store services.LocalFileStore) *StorageHandler {
+	return &StorageHandler{
+		store: store,
+	}
+}
+
+// Download handles signed file downloads (stands in for S3 presigned URLs)
+// GET /api/storage/files/*key?expires=...&signature=...
+func (h *StorageHandler) Download(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid expires parameter",
+		})
+		return
+	}
+
+	if err := h.store.VerifySignature(key, expires, c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	file, err := h.store.Open(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Not Found",
+				"message": "file not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "failed to open file",
+		})
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": "failed to read file",
+		})
+		return
+	}
+
+	// Content type is inferred from the file name, which keys keep from the original upload
+	name := path.Base(key)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Header("Cache-Control", "private, no-store")
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), file)
+}
+
+// Upload handles signed direct uploads of a whole file or of one multipart part (stands in for S3 presigned PUTs)
+// PUT /api/storage/files/*key?expires=...&signature=...[&upload_id=...&part_number=...]
+func (h *StorageHandler) Upload(c *gin.Context) {
+	key := strings.TrimPrefix(c.Param("key"), "/")
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid expires parameter",
+		})
+		return
+	}
+
+	uploadID := c.Query("upload_id")
+	var partNumber int32
+	if uploadID != "" {
+		n, err := strconv.ParseInt(c.Query("part_number"), 10, 32)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": "invalid part_number parameter",
+			})
+			return
+		}
+		partNumber = int32(n)
+	}
+
+	if err := h.store.VerifyUploadSignature(key, uploadID, partNumber, expires, c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	etag, err := h.store.WriteUpload(key, uploadID, partNumber, c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Clients read the ETag header like they do for S3 part uploads
+	c.Header("ETag", fmt.Sprintf("%q", etag))
+	c.Header("Access-Control-Expose-Headers", "ETag")
+	c.Status(http.StatusOK)
+}
+
+// RegisterRoutes registers storage routes
+func (h *StorageHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/storage/files/*key", h.Download)
+	router.PUT("/storage/files/*key", h.Upload)
+}
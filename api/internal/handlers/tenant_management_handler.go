@@ -41,7 +41,7 @@ func (h *TenantManagementHandler) CreateTenant(c *gin.Context) {
 		return
 	}
 
-	tenant, err := h.tenantMgmtService.CreateTenantByUser(userID, req)
+	tenant, err := h.tenantMgmtService.CreateTenantByUser(c.Request.Context(), userID, req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
@@ -54,3 +54,47 @@ func (h *TenantManagementHandler) CreateTenant(c *gin.Context) {
 		"data": tenant,
 	})
 }
+
+// UpdateSettings updates the security settings of the active tenant
+// PATCH /api/tenants/current/settings
+func (h *TenantManagementHandler) UpdateSettings(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "Unauthorized",
+			"message": "user not found in context",
+		})
+		return
+	}
+
+	tenantID, exists := middleware.GetTenantID(c)
+	if !exists {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	var req services.UpdateTenantSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tenant, err := h.tenantMgmtService.UpdateSettings(userID, tenantID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": tenant,
+	})
+}
@@ -10,11 +10,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// UnitResident represents a resident in the unit detail response
+// UnitResident represents a current member in the unit detail response
 type UnitResident struct {
-	ID    uint   `json:"id"`
-	Name  string `json:"name"`
-	Phone string `json:"phone"`
+	ID                  uint                    `json:"id"`
+	Name                string                  `json:"name"`
+	Phone               string                  `json:"phone"`
+	Relationship        models.UnitRelationship `json:"relationship"`
+	ResponsibleForBills bool                    `json:"responsible_for_bills"`
 }
 
 // UnitDetail represents the detailed response for a single unit
@@ -32,15 +34,17 @@ type UnitDetail struct {
 	Residents  []UnitResident `json:"residents"`
 }
 
-// UnitHandler handles unit routes
+// UnitHandler handles unit and unit membership routes
 type UnitHandler struct {
-	unitService services.UnitService
+	unitService       services.UnitService
+	unitMemberService services.UnitMemberService
 }
 
 // NewUnitHandler creates a new unit handler
-func NewUnitHandler(unitService services.UnitService) *UnitHandler {
+func NewUnitHandler(unitService services.UnitService, unitMemberService services.UnitMemberService) *UnitHandler {
 	return &UnitHandler{
-		unitService: unitService,
+		unitService:       unitService,
+		unitMemberService: unitMemberService,
 	}
 }
 
@@ -68,7 +72,7 @@ func (h *UnitHandler) Create(c *gin.Context) {
 	// Override tenant_id from context for security
 	unit.TenantID = tenantID
 
-	if err := h.unitService.Create(&unit); err != nil {
+	if err := h.unitService.Create(c.Request.Context(), &unit); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -102,7 +106,7 @@ func (h *UnitHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	unit, err := h.unitService.GetByID(tenantID, uint(id))
+	unit, err := h.unitService.GetByID(c.Request.Context(), tenantID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Not Found",
@@ -111,12 +115,17 @@ func (h *UnitHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	residents := make([]UnitResident, 0, len(unit.Users))
-	for _, u := range unit.Users {
+	residents := make([]UnitResident, 0, len(unit.Members))
+	for _, m := range unit.Members {
+		if m.User == nil {
+			continue
+		}
 		residents = append(residents, UnitResident{
-			ID:    u.ID,
-			Name:  u.Name,
-			Phone: u.Phone,
+			ID:                  m.User.ID,
+			Name:                m.User.Name,
+			Phone:               m.User.Phone,
+			Relationship:        m.Relationship,
+			ResponsibleForBills: m.ResponsibleForBills,
 		})
 	}
 
@@ -154,7 +163,7 @@ func (h *UnitHandler) GetAll(c *gin.Context) {
 	// Optional: filter by block
 	blockParam := c.Query("block")
 	if blockParam != "" {
-		units, err := h.unitService.GetByBlock(tenantID, blockParam)
+		units, err := h.unitService.GetByBlock(c.Request.Context(), tenantID, blockParam)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Internal Server Error",
@@ -169,7 +178,7 @@ func (h *UnitHandler) GetAll(c *gin.Context) {
 		return
 	}
 
-	units, err := h.unitService.GetAll(tenantID)
+	units, err := h.unitService.GetAll(c.Request.Context(), tenantID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -217,7 +226,7 @@ func (h *UnitHandler) Update(c *gin.Context) {
 	unit.ID = uint(id)
 	unit.TenantID = tenantID
 
-	if err := h.unitService.Update(&unit); err != nil {
+	if err := h.unitService.Update(c.Request.Context(), &unit); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -251,7 +260,7 @@ func (h *UnitHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := h.unitService.Delete(tenantID, uint(id)); err != nil {
+	if err := h.unitService.Delete(c.Request.Context(), tenantID, uint(id)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -264,14 +273,196 @@ func (h *UnitHandler) Delete(c *gin.Context) {
 	})
 }
 
+// ListMembers handles listing the members of a unit
+// GET /api/units/:id/members?include_past=true
+func (h *UnitHandler) ListMembers(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	unitID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid unit ID",
+		})
+		return
+	}
+
+	includePast := c.Query("include_past") == "true"
+
+	members, err := h.unitMemberService.ListMembers(c.Request.Context(), tenantID, uint(unitID), includePast)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not Found",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": members,
+	})
+}
+
+// AddMember handles linking a user to a unit
+// POST /api/units/:id/members
+func (h *UnitHandler) AddMember(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	unitID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid unit ID",
+		})
+		return
+	}
+
+	var req services.AddUnitMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	member, err := h.unitMemberService.AddMember(c.Request.Context(), tenantID, uint(unitID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": member,
+	})
+}
+
+// UpdateMember handles updating a unit membership
+// PATCH /api/units/:id/members/:member_id
+func (h *UnitHandler) UpdateMember(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	unitID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid unit ID",
+		})
+		return
+	}
+
+	memberID, err := strconv.ParseUint(c.Param("member_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid member ID",
+		})
+		return
+	}
+
+	var req services.UpdateUnitMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	member, err := h.unitMemberService.UpdateMember(c.Request.Context(), tenantID, uint(unitID), uint(memberID), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": member,
+	})
+}
+
+// RemoveMember handles deleting a unit membership
+// DELETE /api/units/:id/members/:member_id
+func (h *UnitHandler) RemoveMember(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	unitID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid unit ID",
+		})
+		return
+	}
+
+	memberID, err := strconv.ParseUint(c.Param("member_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid member ID",
+		})
+		return
+	}
+
+	if err := h.unitMemberService.RemoveMember(c.Request.Context(), tenantID, uint(unitID), uint(memberID)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "unit member removed successfully",
+	})
+}
+
 // RegisterRoutes registers unit routes
 func (h *UnitHandler) RegisterRoutes(router *gin.RouterGroup) {
 	units := router.Group("/units")
 	{
-		units.POST("", h.Create)
-		units.GET("", h.GetAll)
-		units.GET("/:id", h.GetByID)
-		units.PUT("/:id", h.Update)
-		units.DELETE("/:id", h.Delete)
+		units.POST("", middleware.RequirePermission(models.PermUnitsWrite), h.Create)
+		units.GET("", middleware.RequirePermission(models.PermUnitsRead), h.GetAll)
+		units.GET("/:id", middleware.RequirePermission(models.PermUnitsRead), h.GetByID)
+		units.PUT("/:id", middleware.RequirePermission(models.PermUnitsWrite), h.Update)
+		units.DELETE("/:id", middleware.RequirePermission(models.PermUnitsWrite), h.Delete)
+
+		units.GET("/:id/members", middleware.RequirePermission(models.PermUnitsRead), h.ListMembers)
+		units.POST("/:id/members", middleware.RequirePermission(models.PermUnitsWrite), h.AddMember)
+		units.PATCH("/:id/members/:member_id", middleware.RequirePermission(models.PermUnitsWrite), h.UpdateMember)
+		units.DELETE("/:id/members/:member_id", middleware.RequirePermission(models.PermUnitsWrite), h.RemoveMember)
 	}
 }
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/arturbaldoramos/Habitta/internal/middleware"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CompleteUploadRequest represents the request to complete an upload session
+// Parts are only required for multipart uploads
+type CompleteUploadRequest struct {
+	Parts []services.CompletedPart `json:"parts" binding:"dive"`
+}
+
+// UploadHandler handles direct-to-storage upload sessions
+type UploadHandler struct {
+	uploadSessionService services.UploadSessionService
+}
+
+// NewUploadHandler creates a new upload handler
+func NewUploadHandler(uploadSessionService services.UploadSessionService) *UploadHandler {
+	return &UploadHandler{
+		uploadSessionService: uploadSessionService,
+	}
+}
+
+// RegisterRoutes registers upload session routes
+func (h *UploadHandler) RegisterRoutes(router *gin.RouterGroup) {
+	uploads := router.Group("/uploads", middleware.RequirePermission(models.PermDocumentsUpload))
+	{
+		uploads.POST("", h.Start)
+		uploads.POST("/:id/complete", h.Complete)
+		uploads.DELETE("/:id", h.Abort)
+	}
+}
+
+// Start handles starting an upload session
+// POST /api/uploads
+func (h *UploadHandler) Start(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	var req services.StartUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	result, err := h.uploadSessionService.Start(c.Request.Context(), tenantID, userID, &req)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": result,
+	})
+}
+
+// Complete handles completing an upload session, which creates the document
+// POST /api/uploads/:id/complete
+func (h *UploadHandler) Complete(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid upload session ID",
+		})
+		return
+	}
+
+	var req CompleteUploadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Bad Request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	completion, err := h.uploadSessionService.Complete(c.Request.Context(), tenantID, userID, uint(id), req.Parts)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": completion,
+	})
+}
+
+// Abort handles cancelling an upload session
+// DELETE /api/uploads/:id
+func (h *UploadHandler) Abort(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "user_id not found in context",
+		})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid upload session ID",
+		})
+		return
+	}
+
+	if err := h.uploadSessionService.Abort(c.Request.Context(), tenantID, userID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "upload cancelled successfully",
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"strconv"
 
 	"github.com/arturbaldoramos/Habitta/internal/middleware"
+	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/services"
 	"github.com/gin-gonic/gin"
 )
@@ -55,7 +56,21 @@ func (h *UserHandler) List(c *gin.Context) {
 	}
 	search := c.Query("search")
 
-	userTenants, total, err := h.userService.ListByTenant(tenantID, page, perPage, search)
+	userTenants, total, err := h.userService.ListByTenant(c.Request.Context(), tenantID, page, perPage, search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Internal Server Error",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	userIDs := make([]uint, 0, len(userTenants))
+	for _, ut := range userTenants {
+		userIDs = append(userIDs, ut.UserID)
+	}
+
+	unitIDs, err := h.userService.GetUnitIDs(c.Request.Context(), tenantID, userIDs)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -70,15 +85,18 @@ func (h *UserHandler) List(c *gin.Context) {
 		if ut.User == nil {
 			continue
 		}
-		items = append(items, UserListItem{
+		item := UserListItem{
 			ID:       ut.User.ID,
 			Name:     ut.User.Name,
 			Email:    ut.User.Email,
 			Phone:    ut.User.Phone,
 			Role:     string(ut.Role),
 			IsActive: ut.IsActive,
-			UnitID:   ut.User.UnitID,
-		})
+		}
+		if unitID, ok := unitIDs[ut.User.ID]; ok {
+			item.UnitID = &unitID
+		}
+		items = append(items, item)
 	}
 
 	totalPages := int(math.Ceil(float64(total) / float64(perPage)))
@@ -113,7 +131,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	userInTenant, err := h.userService.GetByIDInTenant(tenantID, uint(id))
+	userInTenant, err := h.userService.GetByIDInTenant(c.Request.Context(), tenantID, uint(id))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error":   "Not Found",
@@ -167,7 +185,9 @@ func (h *UserHandler) UpdateMembership(c *gin.Context) {
 		isActive = *req.IsActive
 	}
 
-	if err := h.userService.UpdateMembership(tenantID, uint(id), isActive, req.UnitID); err != nil {
+	actorRole, _ := middleware.GetActiveRole(c)
+
+	if err := h.userService.UpdateMembership(c.Request.Context(), tenantID, models.UserRole(actorRole), uint(id), isActive, req.UnitID); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -180,6 +200,54 @@ func (h *UserHandler) UpdateMembership(c *gin.Context) {
 	})
 }
 
+// ChangeRole handles assigning a new role to a member of the current tenant
+// PATCH /api/users/:id/role
+func (h *UserHandler) ChangeRole(c *gin.Context) {
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "tenant_id not found in context",
+		})
+		return
+	}
+
+	actorRole, _ := middleware.GetActiveRole(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": "invalid user ID",
+		})
+		return
+	}
+
+	var req struct {
+		Role models.UserRole `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.userService.ChangeRole(c.Request.Context(), tenantID, models.UserRole(actorRole), uint(id), req.Role); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Bad Request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "role updated successfully",
+	})
+}
+
 // RemoveFromTenant removes a user's membership from the current tenant
 // DELETE /api/users/:id
 func (h *UserHandler) RemoveFromTenant(c *gin.Context) {
@@ -201,7 +269,9 @@ func (h *UserHandler) RemoveFromTenant(c *gin.Context) {
 		return
 	}
 
-	if err := h.userService.RemoveFromTenant(tenantID, uint(id)); err != nil {
+	actorRole, _ := middleware.GetActiveRole(c)
+
+	if err := h.userService.RemoveFromTenant(c.Request.Context(), tenantID, models.UserRole(actorRole), uint(id)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": err.Error(),
@@ -218,9 +288,10 @@ func (h *UserHandler) RemoveFromTenant(c *gin.Context) {
 func (h *UserHandler) RegisterRoutes(router *gin.RouterGroup) {
 	users := router.Group("/users")
 	{
-		users.GET("", h.List)
-		users.GET("/:id", h.GetByID)
-		users.PATCH("/:id/membership", h.UpdateMembership)
-		users.DELETE("/:id", h.RemoveFromTenant)
+		users.GET("", middleware.RequirePermission(models.PermUsersRead), h.List)
+		users.GET("/:id", middleware.RequirePermission(models.PermUsersRead), h.GetByID)
+		users.PATCH("/:id/membership", middleware.RequirePermission(models.PermUsersManage), h.UpdateMembership)
+		users.PATCH("/:id/role", middleware.RequirePermission(models.PermUsersManage), h.ChangeRole)
+		users.DELETE("/:id", middleware.RequirePermission(models.PermUsersManage), h.RemoveFromTenant)
 	}
 }
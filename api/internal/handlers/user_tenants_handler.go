@@ -32,7 +32,7 @@ func (h *UserTenantsHandler) GetMyTenants(c *gin.Context) {
 		return
 	}
 
-	userTenants, err := h.userTenantRepo.GetAllByUser(userID)
+	userTenants, err := h.userTenantRepo.GetAllByUser(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Internal Server Error",
@@ -7,8 +7,13 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates JWT token and sets user claims in context
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// SessionValidator checks whether the session behind an access token is still active
+type SessionValidator interface {
+	ValidateSession(sessionID uint) error
+}
+
+// AuthMiddleware validates JWT token and its session, and sets user claims in context
+func AuthMiddleware(jwtSecret string, sessions SessionValidator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
@@ -33,7 +38,18 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens whose session was revoked (logout, removal from tenant, etc.)
+		if err := sessions.ValidateSession(claims.SessionID); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"message": "Session has been revoked or expired",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set claims in context for later use
+		c.Set("session_id", claims.SessionID)
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
 
@@ -46,53 +62,3 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-// RequireRole checks if the authenticated user has the required role in the active tenant
-func RequireRole(roles ...string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Check if user has an active tenant
-		_, hasTenant := c.Get("active_tenant_id")
-		if !hasTenant {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Forbidden",
-				"message": "Active tenant required",
-			})
-			c.Abort()
-			return
-		}
-
-		userRole, exists := c.Get("active_role")
-		if !exists {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Forbidden",
-				"message": "User role not found in context",
-			})
-			c.Abort()
-			return
-		}
-
-		roleStr, ok := userRole.(string)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{
-				"error":   "Forbidden",
-				"message": "Invalid role format",
-			})
-			c.Abort()
-			return
-		}
-
-		// Check if user role matches any of the required roles
-		for _, role := range roles {
-			if roleStr == role {
-				c.Next()
-				return
-			}
-		}
-
-		c.JSON(http.StatusForbidden, gin.H{
-			"error":   "Forbidden",
-			"message": "Insufficient permissions",
-		})
-		c.Abort()
-	}
-}
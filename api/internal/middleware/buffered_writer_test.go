@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestBufferedWriterHoldsResponseUntilFlush(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	writer := newBufferedWriter(c.Writer)
+	c.Writer = writer
+	c.Header("Location", "/api/documents/1")
+	c.JSON(http.StatusCreated, gin.H{"data": "ok"})
+
+	if !writer.Written() || writer.Status() != http.StatusCreated {
+		t.Fatalf("want the handler to see a written %d response, got written=%v status=%d", http.StatusCreated, writer.Written(), writer.Status())
+	}
+	if recorder.Body.Len() != 0 || recorder.Header().Get("Location") != "" || recorder.Header().Get("Content-Type") != "" {
+		t.Fatal("response reached the client before flush")
+	}
+
+	writer.flush()
+
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("want status %d, got %d", http.StatusCreated, recorder.Code)
+	}
+	if recorder.Header().Get("Location") != "/api/documents/1" {
+		t.Errorf("want the buffered Location header, got %q", recorder.Header().Get("Location"))
+	}
+	if recorder.Body.String() != `{"data":"ok"}` {
+		t.Errorf("want the buffered body, got %q", recorder.Body.String())
+	}
+}
+
+func TestBufferedWriterDroppedResponse(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+
+	writer := newBufferedWriter(c.Writer)
+	c.Writer = writer
+	c.Header("Location", "/api/documents/1")
+	c.JSON(http.StatusCreated, gin.H{"data": "ok"})
+
+	// The commit failed: the middleware swaps the writer back and reports the error instead
+	c.Writer = writer.ResponseWriter
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal Server Error"})
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("want status %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+	if recorder.Header().Get("Location") != "" {
+		t.Errorf("dropped response leaked its headers: %q", recorder.Header().Get("Location"))
+	}
+	if recorder.Body.String() != `{"error":"Internal Server Error"}` {
+		t.Errorf("want only the error body, got %q", recorder.Body.String())
+	}
+}
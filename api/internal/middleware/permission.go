@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PermissionResolver resolves the permissions granted by a role in a tenant
+type PermissionResolver interface {
+	GetPermissions(ctx context.Context, tenantID uint, role string) (models.Permissions, error)
+}
+
+// LoadPermissions resolves the active role's permissions and sets them in context
+// This middleware requires AuthMiddleware and ScopedDBMiddleware to run first
+func LoadPermissions(db *gorm.DB, resolver PermissionResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, hasTenant := GetTenantID(c)
+		role, hasRole := GetActiveRole(c)
+		if !hasTenant || !hasRole {
+			// Orphan users have no permissions; RequirePermission rejects them
+			c.Next()
+			return
+		}
+
+		// Streamed routes carry no request transaction, so custom roles are read in one of their own
+		var perms models.Permissions
+		err := database.Scoped(c.Request.Context(), db, func(ctx context.Context) error {
+			var err error
+			perms, err = resolver.GetPermissions(ctx, tenantID, role)
+			return err
+		})
+		if err != nil {
+			log.Printf("ERROR: failed to resolve permissions for role %s in tenant %d: %v", role, tenantID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal Server Error",
+				"message": "Failed to resolve permissions",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Set("permissions", perms)
+		c.Next()
+	}
+}
+
+// RequirePermission checks if the active role grants all the given permissions
+// This middleware requires LoadPermissions to run first
+func RequirePermission(perms ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Check if user has an active tenant
+		if _, hasTenant := c.Get("active_tenant_id"); !hasTenant {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Active tenant required",
+			})
+			c.Abort()
+			return
+		}
+
+		granted, _ := GetPermissions(c)
+		for _, perm := range perms {
+			if !granted.Has(perm) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":   "Forbidden",
+					"message": "Insufficient permissions",
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAnyPermission checks if the active role grants at least one of the given permissions
+// Used by routes that serve both management and residents, with the handler narrowing the results
+func RequireAnyPermission(perms ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, hasTenant := c.Get("active_tenant_id"); !hasTenant {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": "Active tenant required",
+			})
+			c.Abort()
+			return
+		}
+
+		granted, _ := GetPermissions(c)
+		for _, perm := range perms {
+			if granted.Has(perm) {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Forbidden",
+			"message": "Insufficient permissions",
+		})
+		c.Abort()
+	}
+}
+
+// GetPermissions is a helper function to extract the active role's permissions from context
+func GetPermissions(c *gin.Context) (models.Permissions, bool) {
+	perms, exists := c.Get("permissions")
+	if !exists {
+		return nil, false
+	}
+
+	permsSet, ok := perms.(models.Permissions)
+	return permsSet, ok
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/arturbaldoramos/Habitta/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPeekBodyBytes limits how much of the request body is read to extract a rate limit key
+const maxPeekBodyBytes = 64 << 10
+
+// RateLimitKeyFunc extracts the bucket key from a request. An empty key skips the limit
+type RateLimitKeyFunc func(c *gin.Context) string
+
+// ClientIPKey keys buckets by client IP
+func ClientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ParamKey keys buckets by a path parameter (e.g. the token of a public link)
+func ParamKey(name string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		return c.Param(name)
+	}
+}
+
+// JSONFieldKey keys buckets by a field of the JSON body (e.g. the email being logged into)
+// Only the first maxPeekBodyBytes are read; they are put back in front of the rest so handlers still get the whole body
+func JSONFieldKey(field string) RateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil || !strings.HasPrefix(c.ContentType(), "application/json") {
+			return ""
+		}
+
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPeekBodyBytes))
+		c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+		if err != nil {
+			return ""
+		}
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return ""
+		}
+
+		value, _ := payload[field].(string)
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
+
+// RateLimitMiddleware throttles requests using a token bucket per key
+// name namespaces the buckets so several limiters can share one store
+func RateLimitMiddleware(store ratelimit.Store, name string, limit ratelimit.Limit, keyFunc RateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := keyFunc(c)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter, err := store.Take(name+":"+key, limit)
+		if err != nil {
+			// Fail open: an unavailable store must not take the API down
+			log.Printf("WARNING: rate limit store error: %v", err)
+			c.Next()
+			return
+		}
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Too Many Requests",
+				"message": "rate limit exceeded, try again later",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,11 +1,19 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// errRequestFailed rolls back the request transaction of a request that didn't succeed
+var errRequestFailed = errors.New("request failed")
+
 // TenantMiddleware validates that user has an active tenant in context
 // This middleware requires AuthMiddleware to run first
 func TenantMiddleware() gin.HandlerFunc {
@@ -37,6 +45,58 @@ func TenantMiddleware() gin.HandlerFunc {
 	}
 }
 
+// ScopedDBMiddleware runs the rest of the request in a transaction where row-level security
+// exposes the active tenant's rows and the user's own memberships and invites; repositories take it from the request context.
+// The transaction commits when the handler succeeds and rolls back on an error response or a panic.
+// The response is held back until the commit, so a client is never told about changes that were rolled back.
+// Streamed routes ("METHOD /path", e.g. "POST /api/documents/upload") get no request transaction: their services
+// run short scoped transactions around the database work instead of holding one open while bytes flow.
+// This middleware requires AuthMiddleware (and TenantMiddleware, where used) to run first
+func ScopedDBMiddleware(db *gorm.DB, streamed ...string) gin.HandlerFunc {
+	streamedRoutes := make(map[string]bool, len(streamed))
+	for _, route := range streamed {
+		streamedRoutes[route] = true
+	}
+
+	return func(c *gin.Context) {
+		scope := database.Scope{}
+		scope.TenantID, _ = GetTenantID(c)
+		scope.UserID, _ = GetUserID(c)
+		scope.UserEmail = c.GetString("email")
+
+		if streamedRoutes[c.Request.Method+" "+c.FullPath()] {
+			c.Request = c.Request.WithContext(database.WithScope(c.Request.Context(), db, scope))
+			c.Next()
+			return
+		}
+
+		writer := newBufferedWriter(c.Writer)
+		c.Writer = writer
+		// Restored before a panic reaches the recovery middleware, so its error response isn't buffered away
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		err := database.RunScoped(c.Request.Context(), db, scope, func(ctx context.Context) error {
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+			if writer.Status() >= http.StatusBadRequest || len(c.Errors) > 0 {
+				return errRequestFailed
+			}
+			return nil
+		})
+		c.Writer = writer.ResponseWriter
+
+		if err != nil && !errors.Is(err, errRequestFailed) {
+			log.Printf("ERROR: request transaction of %s %s failed: %v", c.Request.Method, c.Request.URL.Path, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   "Internal Server Error",
+				"message": "Failed to process request",
+			})
+			return
+		}
+		writer.flush()
+	}
+}
+
 // OptionalTenantMiddleware allows access with or without an active tenant
 // This is useful for endpoints that work differently based on tenant context
 func OptionalTenantMiddleware() gin.HandlerFunc {
@@ -78,3 +138,14 @@ func GetUserID(c *gin.Context) (uint, bool) {
 	userIDUint, ok := userID.(uint)
 	return userIDUint, ok
 }
+
+// GetSessionID is a helper function to extract session_id from context
+func GetSessionID(c *gin.Context) (uint, bool) {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return 0, false
+	}
+
+	sessionIDUint, ok := sessionID.(uint)
+	return sessionIDUint, ok
+}
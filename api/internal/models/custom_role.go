@@ -0,0 +1,18 @@
+package models
+
+// CustomRole is a tenant-defined role that maps to a set of permissions
+// Memberships and invites reference it by Name, just like the built-in roles
+type CustomRole struct {
+	BaseModel
+	TenantID    uint        `gorm:"not null;index" json:"tenant_id"`
+	Name        UserRole    `gorm:"type:varchar(50);not null" json:"name"`
+	DisplayName string      `gorm:"type:varchar(100);not null" json:"display_name"`
+	Description string      `gorm:"type:varchar(500)" json:"description"`
+	Permissions Permissions `gorm:"type:jsonb;not null;default:'[]'" json:"permissions"`
+	Tenant      *Tenant     `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name for CustomRole model
+func (CustomRole) TableName() string {
+	return "custom_roles"
+}
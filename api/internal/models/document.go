@@ -3,20 +3,55 @@ package models
 // Document represents a file uploaded to a condominium
 type Document struct {
 	BaseModel
-	TenantID     uint    `gorm:"not null;index" json:"tenant_id"`
-	FolderID     *uint   `gorm:"index" json:"folder_id"`
-	Name         string  `gorm:"type:varchar(255);not null" json:"name"`
-	OriginalName string  `gorm:"type:varchar(255);not null" json:"original_name"`
-	ContentType  string  `gorm:"type:varchar(100)" json:"content_type"`
-	Size         int64   `json:"size"`
-	S3Key        string  `gorm:"type:varchar(500);not null" json:"s3_key"`
-	UploadedByID uint    `gorm:"not null" json:"uploaded_by_id"`
-	Tenant       *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
-	Folder       *Folder `gorm:"foreignKey:FolderID;constraint:OnDelete:SET NULL" json:"folder,omitempty"`
-	UploadedBy   *User   `gorm:"foreignKey:UploadedByID" json:"uploaded_by,omitempty"`
+	TenantID       uint              `gorm:"not null;index" json:"tenant_id"`
+	FolderID       *uint             `gorm:"index" json:"folder_id"`
+	Name           string            `gorm:"type:varchar(255);not null" json:"name"`
+	OriginalName   string            `gorm:"type:varchar(255);not null" json:"original_name"`
+	ContentType    string            `gorm:"type:varchar(100)" json:"content_type"`
+	Size           int64             `json:"size"`
+	S3Key          string            `gorm:"type:varchar(500);not null" json:"s3_key"`
+	UploadedByID   uint              `gorm:"not null" json:"uploaded_by_id"`
+	CurrentVersion int               `gorm:"not null;default:1" json:"current_version"`
+	ScanStatus     ScanStatus        `gorm:"type:varchar(20);not null;default:pending" json:"scan_status"` // Of the current version
+	Visibility     Visibility        `gorm:"type:varchar(20);not null;default:inherit" json:"visibility"`
+	Audience       Audience          `gorm:"type:jsonb;not null;default:'{}'" json:"audience"`
+	Tenant         *Tenant           `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+	Folder         *Folder           `gorm:"foreignKey:FolderID;constraint:OnDelete:SET NULL" json:"folder,omitempty"`
+	UploadedBy     *User             `gorm:"foreignKey:UploadedByID" json:"uploaded_by,omitempty"`
+	Versions       []DocumentVersion `gorm:"foreignKey:DocumentID" json:"versions,omitempty"`
 }
 
 // TableName specifies the table name for Document model
 func (Document) TableName() string {
 	return "documents"
 }
+
+// ApplyVersion makes the given version the current file of the document
+func (d *Document) ApplyVersion(v *DocumentVersion) {
+	d.OriginalName = v.OriginalName
+	d.ContentType = v.ContentType
+	d.Size = v.Size
+	d.S3Key = v.S3Key
+	d.UploadedByID = v.UploadedByID
+	d.CurrentVersion = v.VersionNumber
+	d.ScanStatus = v.ScanStatus
+}
+
+// EffectiveVisibility resolves the rules that apply to the document
+// Documents set to inherit follow their folder; without a folder they stay private to management
+// The Folder relationship must be loaded
+func (d *Document) EffectiveVisibility() (Visibility, Audience) {
+	if d.Visibility != VisibilityInherit && d.Visibility != "" {
+		return d.Visibility, d.Audience
+	}
+	if d.Folder != nil {
+		return d.Folder.Visibility, d.Folder.Audience
+	}
+	return VisibilityManagement, Audience{}
+}
+
+// VisibleTo checks if the document is shared with the resident
+func (d *Document) VisibleTo(scope ResidentScope) bool {
+	v, a := d.EffectiveVisibility()
+	return scope.Allows(v, a)
+}
@@ -0,0 +1,22 @@
+package models
+
+import "errors"
+
+// ErrInvalidSearchQuery is returned for search queries that are too short or too long
+var ErrInvalidSearchQuery = errors.New("invalid search query")
+
+// Markers around the matched words of search highlights, as returned by the database
+// They can't appear in indexed text, so highlights can be HTML-escaped before turning them into tags
+const (
+	HighlightStart = "\x01"
+	HighlightStop  = "\x02"
+)
+
+// DocumentSearchResult is a document matching a full-text search
+// NameHighlight and Snippet carry the matched words between HighlightStart and HighlightStop
+type DocumentSearchResult struct {
+	Document      Document `json:"document"`
+	Rank          float64  `json:"rank"`
+	NameHighlight string   `json:"name_highlight"`
+	Snippet       string   `json:"snippet"` // Excerpt of the file's text; empty when none was extracted
+}
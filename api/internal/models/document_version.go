@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// DocumentVersion represents one stored revision of a document's file
+// The document row mirrors the metadata of its current version
+type DocumentVersion struct {
+	BaseModel
+	TenantID      uint   `gorm:"not null;index" json:"tenant_id"`
+	DocumentID    uint   `gorm:"not null;index" json:"document_id"`
+	VersionNumber int    `gorm:"not null" json:"version_number"`
+	OriginalName  string `gorm:"type:varchar(255);not null" json:"original_name"`
+	ContentType   string `gorm:"type:varchar(100)" json:"content_type"`
+	Size          int64  `json:"size"`
+	S3Key         string `gorm:"type:varchar(500);not null" json:"s3_key"`
+	UploadedByID  uint   `gorm:"not null" json:"uploaded_by_id"`
+
+	// Malware scan; the file can only be downloaded once it is clean
+	ScanStatus    ScanStatus `gorm:"type:varchar(20);not null;default:pending" json:"scan_status"`
+	ScanSignature string     `gorm:"type:varchar(255);not null;default:''" json:"scan_signature,omitempty"`
+	ScannedAt     *time.Time `json:"scanned_at,omitempty"`
+
+	// Text extracted from PDFs and plain-text files at upload, indexed for search
+	ContentText string `gorm:"type:text;not null;default:''" json:"-"`
+
+	// Relationships
+	Tenant     *Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Document   *Document `gorm:"foreignKey:DocumentID;constraint:OnDelete:CASCADE" json:"-"`
+	UploadedBy *User     `gorm:"foreignKey:UploadedByID" json:"uploaded_by,omitempty"`
+}
+
+// TableName specifies the table name for DocumentVersion model
+func (DocumentVersion) TableName() string {
+	return "document_versions"
+}
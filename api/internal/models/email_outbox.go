@@ -0,0 +1,47 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrOutboxEmailNotFound is returned when no queued email matches the ID
+	ErrOutboxEmailNotFound = errors.New("email not found")
+	// ErrOutboxEmailNotRetryable is returned when retrying an email that has not failed
+	ErrOutboxEmailNotRetryable = errors.New("only failed emails can be retried")
+)
+
+// OutboxStatus is the delivery state of a queued email
+type OutboxStatus string
+
+const (
+	OutboxPending OutboxStatus = "pending" // Waiting for its first or next delivery attempt
+	OutboxSent    OutboxStatus = "sent"
+	OutboxFailed  OutboxStatus = "failed" // Dead letter: gave up after the maximum attempts
+)
+
+// OutboxEmail is an email waiting to be delivered, or the record of one that was
+// It is written in the same transaction as the change that triggers it, so the email is never lost
+// nor sent for a change that was rolled back
+type OutboxEmail struct {
+	ID             uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+	IdempotencyKey string       `gorm:"type:varchar(255);not null;uniqueIndex" json:"idempotency_key"`
+	ToAddress      string       `gorm:"type:varchar(255);not null" json:"to"`
+	Subject        string       `gorm:"type:text;not null" json:"subject"`
+	HTML           string       `gorm:"type:text;not null" json:"html"`
+	Text           string       `gorm:"type:text;not null;default:''" json:"text"`
+	Status         OutboxStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Attempts       int          `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time    `gorm:"not null" json:"next_attempt_at"`
+	LockedUntil    *time.Time   `json:"-"` // Lease of the worker delivering it
+	LastError      string       `gorm:"type:text;not null;default:''" json:"last_error,omitempty"`
+	SentAt         *time.Time   `json:"sent_at,omitempty"`
+}
+
+// TableName specifies the table name for OutboxEmail model
+func (OutboxEmail) TableName() string {
+	return "email_outbox"
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// EmailVerificationToken represents a single-use token proving ownership of a user's email
+// Only the SHA-256 hash of the token is stored
+type EmailVerificationToken struct {
+	BaseModel
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for EmailVerificationToken model
+func (EmailVerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
+
+// IsValid checks if the token is unused and not expired
+func (t *EmailVerificationToken) IsValid() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
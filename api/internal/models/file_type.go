@@ -0,0 +1,132 @@
+package models
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrFileTypeNotAllowed is returned when a file's extension is not accepted by the tenant
+var ErrFileTypeNotAllowed = errors.New("file type not allowed")
+
+// ErrFileContentMismatch is returned when a file's bytes don't match its extension
+var ErrFileContentMismatch = errors.New("file content does not match its extension")
+
+// oleMagic starts legacy Office files (.doc, .xls, .ppt), which content sniffing doesn't recognize
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// FileType is a document type that can be uploaded, keyed by extension in FileTypes
+type FileType struct {
+	ContentType string   // MIME type stored for the document, regardless of what the client sent
+	Sniffed     []string // MIME types content sniffing may report for a genuine file
+	Magic       []byte   // Required leading bytes, for formats sniffing can't tell apart
+}
+
+// Matches checks the first bytes of a file, and the MIME type sniffed from them, against the type
+func (t FileType) Matches(head []byte, sniffed string) bool {
+	if t.Magic != nil {
+		return bytes.HasPrefix(head, t.Magic)
+	}
+	for _, candidate := range t.Sniffed {
+		if candidate == sniffed {
+			return true
+		}
+	}
+	return false
+}
+
+// FileTypes lists every document type the platform accepts
+// Markup types (HTML, SVG) are left out on purpose: they could run scripts when opened from storage
+var FileTypes = map[string]FileType{
+	"pdf":  {ContentType: "application/pdf", Sniffed: []string{"application/pdf"}},
+	"doc":  {ContentType: "application/msword", Magic: oleMagic},
+	"xls":  {ContentType: "application/vnd.ms-excel", Magic: oleMagic},
+	"ppt":  {ContentType: "application/vnd.ms-powerpoint", Magic: oleMagic},
+	"docx": {ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", Sniffed: []string{"application/zip"}},
+	"xlsx": {ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Sniffed: []string{"application/zip"}},
+	"pptx": {ContentType: "application/vnd.openxmlformats-officedocument.presentationml.presentation", Sniffed: []string{"application/zip"}},
+	"odt":  {ContentType: "application/vnd.oasis.opendocument.text", Sniffed: []string{"application/zip"}},
+	"ods":  {ContentType: "application/vnd.oasis.opendocument.spreadsheet", Sniffed: []string{"application/zip"}},
+	"txt":  {ContentType: "text/plain; charset=utf-8", Sniffed: []string{"text/plain"}},
+	"csv":  {ContentType: "text/csv; charset=utf-8", Sniffed: []string{"text/plain"}},
+	"jpg":  {ContentType: "image/jpeg", Sniffed: []string{"image/jpeg"}},
+	"jpeg": {ContentType: "image/jpeg", Sniffed: []string{"image/jpeg"}},
+	"png":  {ContentType: "image/png", Sniffed: []string{"image/png"}},
+	"gif":  {ContentType: "image/gif", Sniffed: []string{"image/gif"}},
+	"webp": {ContentType: "image/webp", Sniffed: []string{"image/webp"}},
+	"mp3":  {ContentType: "audio/mpeg", Sniffed: []string{"audio/mpeg"}},
+	"mp4":  {ContentType: "video/mp4", Sniffed: []string{"video/mp4"}},
+	"zip":  {ContentType: "application/zip", Sniffed: []string{"application/zip"}},
+}
+
+// FileTypeList is a set of file extensions, stored as a JSON array
+// A nil list means every type in FileTypes
+type FileTypeList []string
+
+// NormalizeFileTypes lower-cases, dedupes and validates a list of extensions
+// An empty list becomes nil, which allows every type
+func NormalizeFileTypes(exts []string) (FileTypeList, error) {
+	seen := make(map[string]bool)
+	var list FileTypeList
+	for _, ext := range exts {
+		ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+		if _, ok := FileTypes[ext]; !ok {
+			return nil, fmt.Errorf("unknown file type: %s", ext)
+		}
+		if !seen[ext] {
+			seen[ext] = true
+			list = append(list, ext)
+		}
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// Allows checks if the list accepts an extension
+func (l FileTypeList) Allows(ext string) bool {
+	if _, ok := FileTypes[ext]; !ok {
+		return false
+	}
+	if l == nil {
+		return true
+	}
+	for _, candidate := range l {
+		if candidate == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (l FileTypeList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (l *FileTypeList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for FileTypeList: %T", value)
+	}
+	return json.Unmarshal(data, l)
+}
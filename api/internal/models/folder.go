@@ -1,15 +1,62 @@
 package models
 
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrFolderCycle is returned when a folder would be moved into itself or one of its descendants
+var ErrFolderCycle = errors.New("cannot move a folder into itself or one of its subfolders")
+
 // Folder represents a document folder in a condominium
+// Folders form a tree; Path holds the IDs from the root down to the folder, e.g. "/1/5/9/"
 type Folder struct {
 	BaseModel
-	TenantID    uint    `gorm:"not null;index" json:"tenant_id"`
-	Name        string  `gorm:"type:varchar(100);not null" json:"name" binding:"required"`
-	Description string  `gorm:"type:varchar(500)" json:"description"`
-	Tenant      *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+	TenantID    uint       `gorm:"not null;index" json:"tenant_id"`
+	ParentID    *uint      `gorm:"index" json:"parent_id"`
+	Path        string     `gorm:"type:varchar(1000);not null;default:''" json:"path"`
+	Name        string     `gorm:"type:varchar(100);not null" json:"name" binding:"required"`
+	Description string     `gorm:"type:varchar(500)" json:"description"`
+	Visibility  Visibility `gorm:"type:varchar(20);not null;default:management" json:"visibility"`
+	Audience    Audience   `gorm:"type:jsonb;not null;default:'{}'" json:"audience"`
+	Tenant      *Tenant    `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+	Parent      *Folder    `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"-"`
+
+	// Breadcrumbs is filled when a single folder is requested
+	Breadcrumbs []FolderCrumb `gorm:"-" json:"breadcrumbs,omitempty"`
+}
+
+// FolderCrumb is one step of the path to a folder
+type FolderCrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
 }
 
 // TableName specifies the table name for Folder model
 func (Folder) TableName() string {
 	return "folders"
 }
+
+// VisibleTo checks if the folder is shared with the resident
+func (f *Folder) VisibleTo(scope ResidentScope) bool {
+	return scope.Allows(f.Visibility, f.Audience)
+}
+
+// PathIDs returns the folder IDs in the path, from the root down to the folder itself
+func (f *Folder) PathIDs() []uint {
+	var ids []uint
+	for _, part := range strings.Split(strings.Trim(f.Path, "/"), "/") {
+		id, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
+// Contains checks if other is the folder itself or one of its descendants
+func (f *Folder) Contains(other *Folder) bool {
+	return f.Path != "" && strings.HasPrefix(other.Path, f.Path)
+}
@@ -24,9 +24,12 @@ type Invite struct {
 	AcceptedByUserID *uint        `json:"accepted_by_user_id,omitempty"`
 	ExpiresAt        time.Time    `gorm:"not null" json:"expires_at"`
 	AcceptedAt       *time.Time   `json:"accepted_at,omitempty"`
+	UnitID           *uint        `json:"unit_id,omitempty"`          // Unit the invitee is linked to on acceptance
+	ReminderSentAt   *time.Time   `json:"reminder_sent_at,omitempty"` // When the "expires soon" reminder was queued
 
 	// Relationships
 	Tenant     *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+	Unit       *Unit   `gorm:"foreignKey:UnitID;constraint:OnDelete:SET NULL" json:"unit,omitempty"`
 	InvitedBy  *User   `gorm:"foreignKey:InvitedByUserID;constraint:OnDelete:CASCADE" json:"invited_by,omitempty"`
 	AcceptedBy *User   `gorm:"foreignKey:AcceptedByUserID;constraint:OnDelete:SET NULL" json:"accepted_by,omitempty"`
 }
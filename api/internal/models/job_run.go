@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// JobRunStatus is the outcome of a scheduled job run
+type JobRunStatus string
+
+const (
+	JobRunRunning   JobRunStatus = "running"
+	JobRunSucceeded JobRunStatus = "succeeded"
+	JobRunFailed    JobRunStatus = "failed"
+)
+
+// JobRun is the record of one run of a scheduled job
+// ScheduledAt is the schedule slot it ran for, so replicas that wake up for the same slot run it only once
+type JobRun struct {
+	ID          uint         `gorm:"primaryKey" json:"id"`
+	JobName     string       `gorm:"type:varchar(100);not null;index:idx_job_runs_job_slot" json:"job_name"`
+	ScheduledAt time.Time    `gorm:"not null;index:idx_job_runs_job_slot" json:"scheduled_at"`
+	StartedAt   time.Time    `gorm:"not null" json:"started_at"`
+	FinishedAt  *time.Time   `json:"finished_at,omitempty"`
+	Status      JobRunStatus `gorm:"type:varchar(20);not null;default:'running'" json:"status"`
+	Processed   int          `gorm:"not null;default:0" json:"processed"` // Rows or emails the run handled
+	Error       string       `gorm:"type:text;not null;default:''" json:"error,omitempty"`
+}
+
+// TableName specifies the table name for JobRun model
+func (JobRun) TableName() string {
+	return "job_runs"
+}
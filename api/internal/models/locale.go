@@ -0,0 +1,37 @@
+package models
+
+import "errors"
+
+// ErrUnsupportedLocale is returned when a user or tenant picks a language without email templates
+var ErrUnsupportedLocale = errors.New("unsupported locale (use pt-BR or en)")
+
+// Locales with email templates
+const (
+	LocalePtBR = "pt-BR"
+	LocaleEn   = "en"
+
+	DefaultLocale = LocalePtBR
+)
+
+// SupportedLocales lists the locales emails can be rendered in
+var SupportedLocales = []string{LocalePtBR, LocaleEn}
+
+// IsSupportedLocale checks if emails can be rendered in the locale
+func IsSupportedLocale(locale string) bool {
+	for _, l := range SupportedLocales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveLocale returns the first supported locale among the candidates, or DefaultLocale
+func ResolveLocale(candidates ...string) string {
+	for _, l := range candidates {
+		if IsSupportedLocale(l) {
+			return l
+		}
+	}
+	return DefaultLocale
+}
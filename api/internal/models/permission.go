@@ -0,0 +1,174 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Permission is a named capability checked by RequirePermission and the services
+type Permission string
+
+const (
+	PermDocumentsRead       Permission = "documents.read"
+	PermDocumentsReadShared Permission = "documents.read_shared"
+	PermDocumentsUpload     Permission = "documents.upload"
+	PermDocumentsDelete     Permission = "documents.delete"
+	PermDocumentsShare      Permission = "documents.share"
+	PermFoldersWrite        Permission = "folders.write"
+	PermUnitsRead           Permission = "units.read"
+	PermUnitsWrite          Permission = "units.write"
+	PermUsersRead           Permission = "users.read"
+	PermUsersManage         Permission = "users.manage"
+	PermInvitesRead         Permission = "invites.read"
+	PermInvitesCreate       Permission = "invites.create"
+	PermInvitesCancel       Permission = "invites.cancel"
+	PermRolesManage         Permission = "roles.manage"
+	PermSettingsManage      Permission = "settings.manage"
+	PermTenantsManage       Permission = "tenants.manage"
+)
+
+// PermissionDefinition describes a permission in the registry
+type PermissionDefinition struct {
+	Name        Permission `json:"name"`
+	Description string     `json:"description"`
+}
+
+// PermissionRegistry lists every permission the API checks
+var PermissionRegistry = []PermissionDefinition{
+	{PermDocumentsRead, "Ver pastas e documentos"},
+	{PermDocumentsReadShared, "Ver documentos compartilhados com os moradores"},
+	{PermDocumentsUpload, "Enviar e mover documentos"},
+	{PermDocumentsDelete, "Excluir documentos"},
+	{PermDocumentsShare, "Criar e revogar links públicos de documentos"},
+	{PermFoldersWrite, "Criar, renomear e excluir pastas"},
+	{PermUnitsRead, "Ver unidades"},
+	{PermUnitsWrite, "Cadastrar e editar unidades"},
+	{PermUsersRead, "Ver membros do condomínio"},
+	{PermUsersManage, "Ativar, desativar e remover membros"},
+	{PermInvitesRead, "Ver convites do condomínio"},
+	{PermInvitesCreate, "Convidar novos membros"},
+	{PermInvitesCancel, "Cancelar convites"},
+	{PermRolesManage, "Gerenciar papéis personalizados"},
+	{PermSettingsManage, "Alterar configurações do condomínio"},
+	{PermTenantsManage, "Administrar todos os condomínios (plataforma)"},
+}
+
+// IsValidPermission checks if a permission exists in the registry
+func IsValidPermission(p Permission) bool {
+	for _, def := range PermissionRegistry {
+		if def.Name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Built-in roles beyond admin, síndico and morador
+const (
+	RoleSubsindico        UserRole = "subsindico"
+	RoleConselheiroFiscal UserRole = "conselheiro_fiscal"
+	RolePorteiro          UserRole = "porteiro"
+	RoleAdministradora    UserRole = "administradora"
+)
+
+// BuiltinRolePermissions maps the roles available in every tenant to their permissions
+var BuiltinRolePermissions = map[UserRole]Permissions{
+	RoleAdmin: allPermissions(),
+	RoleSindico: {
+		PermDocumentsRead, PermDocumentsReadShared, PermDocumentsUpload, PermDocumentsDelete, PermDocumentsShare, PermFoldersWrite,
+		PermUnitsRead, PermUnitsWrite, PermUsersRead, PermUsersManage,
+		PermInvitesRead, PermInvitesCreate, PermInvitesCancel,
+		PermRolesManage, PermSettingsManage,
+	},
+	RoleSubsindico: {
+		PermDocumentsRead, PermDocumentsReadShared, PermDocumentsUpload, PermDocumentsDelete, PermFoldersWrite,
+		PermUnitsRead, PermUnitsWrite, PermUsersRead, PermUsersManage,
+		PermInvitesRead, PermInvitesCreate, PermInvitesCancel,
+	},
+	RoleAdministradora: {
+		PermDocumentsRead, PermDocumentsUpload, PermDocumentsDelete, PermFoldersWrite,
+		PermUnitsRead, PermUnitsWrite, PermUsersRead,
+		PermInvitesRead, PermInvitesCreate, PermInvitesCancel,
+	},
+	RoleConselheiroFiscal: {
+		PermDocumentsRead, PermDocumentsReadShared, PermUnitsRead, PermUsersRead, PermInvitesRead,
+	},
+	RolePorteiro: {
+		PermUnitsRead, PermUsersRead,
+	},
+	RoleMorador: {
+		PermDocumentsReadShared, PermUnitsRead, PermUsersRead,
+	},
+}
+
+// IsBuiltinRole checks if a role is one of the built-in roles
+func IsBuiltinRole(role UserRole) bool {
+	_, ok := BuiltinRolePermissions[role]
+	return ok
+}
+
+// allPermissions returns every registered permission
+func allPermissions() Permissions {
+	perms := make(Permissions, 0, len(PermissionRegistry))
+	for _, def := range PermissionRegistry {
+		perms = append(perms, def.Name)
+	}
+	return perms
+}
+
+// Permissions is a set of permissions, stored as a JSON array
+type Permissions []Permission
+
+// Has checks if the set contains a permission
+func (p Permissions) Has(perm Permission) bool {
+	for _, candidate := range p {
+		if candidate == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// Covers checks if the set contains every permission in other
+func (p Permissions) Covers(other Permissions) bool {
+	for _, perm := range other {
+		if !p.Has(perm) {
+			return false
+		}
+	}
+	return true
+}
+
+// Value implements driver.Valuer
+func (p Permissions) Value() (driver.Value, error) {
+	if p == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (p *Permissions) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*p = Permissions{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for Permissions: %T", value)
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return errors.New("invalid permissions JSON")
+	}
+	return nil
+}
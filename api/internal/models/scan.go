@@ -0,0 +1,31 @@
+package models
+
+import "errors"
+
+// ErrScanPending is returned when a file is downloaded before the malware scan finished
+var ErrScanPending = errors.New("file is pending a malware scan")
+
+// ErrScanBlocked is returned when a file failed the malware scan or could not be scanned
+var ErrScanBlocked = errors.New("file did not pass the malware scan")
+
+// ScanStatus is the malware scan state of a stored file
+type ScanStatus string
+
+const (
+	ScanPending  ScanStatus = "pending"  // Waiting for the scanner
+	ScanClean    ScanStatus = "clean"    // Scanned, nothing found
+	ScanInfected ScanStatus = "infected" // The scanner found a signature
+	ScanFailed   ScanStatus = "failed"   // The scanner refused the file (e.g. over its size limit)
+)
+
+// DownloadError returns why a file with this status can't be downloaded, or nil once it passed the scan
+func (s ScanStatus) DownloadError() error {
+	switch s {
+	case ScanClean:
+		return nil
+	case ScanPending:
+		return ErrScanPending
+	default:
+		return ErrScanBlocked
+	}
+}
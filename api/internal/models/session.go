@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Session represents a login session backed by a rotating refresh token
+// Access tokens carry the session ID so revoking the session invalidates them immediately
+type Session struct {
+	BaseModel
+	UserID           uint       `gorm:"not null;index" json:"user_id"`
+	TenantID         *uint      `gorm:"index" json:"tenant_id,omitempty"` // Active tenant for this session (nil for orphan users)
+	RefreshTokenHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	ExpiresAt        time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt       time.Time  `gorm:"not null" json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	UserAgent        string     `gorm:"type:varchar(255)" json:"user_agent"`
+	IPAddress        string     `gorm:"type:varchar(45)" json:"ip_address"`
+
+	// Relationships
+	User   *User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+}
+
+// TableName specifies the table name for Session model
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive checks if the session is neither revoked nor expired
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
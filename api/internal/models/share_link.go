@@ -0,0 +1,95 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrShareLinkNotFound is returned when no link matches the token
+	ErrShareLinkNotFound = errors.New("share link not found")
+	// ErrShareLinkUnavailable is returned for links that were revoked, expired, used up, or whose document is gone
+	ErrShareLinkUnavailable = errors.New("share link is no longer available")
+	// ErrSharePasswordRequired is returned when a protected link is opened without a password
+	ErrSharePasswordRequired = errors.New("this link requires a password")
+	// ErrSharePasswordInvalid is returned when the password of a protected link is wrong
+	ErrSharePasswordInvalid = errors.New("invalid password")
+)
+
+// DocumentShareLink is a public link that lets someone outside the platform download a document
+// Only the SHA-256 hash of the token is stored; the token is shown once, when the link is created
+type DocumentShareLink struct {
+	BaseModel
+	TenantID       uint       `gorm:"not null;index" json:"tenant_id"`
+	DocumentID     uint       `gorm:"not null;index" json:"document_id"`
+	TokenHash      string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	PasswordHash   string     `gorm:"type:varchar(255);not null;default:''" json:"-"`
+	HasPassword    bool       `gorm:"not null;default:false" json:"has_password"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	MaxDownloads   *int       `json:"max_downloads"` // nil means unlimited
+	DownloadCount  int        `gorm:"not null;default:0" json:"download_count"`
+	CreatedByID    uint       `gorm:"not null" json:"created_by_id"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+
+	// Token and URL are only filled in the response that creates the link
+	Token string `gorm:"-" json:"token,omitempty"`
+	URL   string `gorm:"-" json:"url,omitempty"`
+
+	// Relationships
+	Tenant    *Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Document  *Document `gorm:"foreignKey:DocumentID;constraint:OnDelete:CASCADE" json:"document,omitempty"`
+	CreatedBy *User     `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+}
+
+// TableName specifies the table name for DocumentShareLink model
+func (DocumentShareLink) TableName() string {
+	return "document_share_links"
+}
+
+// UnavailableReason tells why the link can't be used anymore, or returns an empty outcome if it still can
+func (l *DocumentShareLink) UnavailableReason() ShareAccessOutcome {
+	switch {
+	case l.RevokedAt != nil:
+		return ShareAccessRevoked
+	case !time.Now().Before(l.ExpiresAt):
+		return ShareAccessExpired
+	case l.MaxDownloads != nil && l.DownloadCount >= *l.MaxDownloads:
+		return ShareAccessExhausted
+	default:
+		return ""
+	}
+}
+
+// ShareAccessOutcome records what happened when a share link was opened
+type ShareAccessOutcome string
+
+const (
+	ShareAccessGranted          ShareAccessOutcome = "granted"
+	ShareAccessPasswordRequired ShareAccessOutcome = "password_required"
+	ShareAccessWrongPassword    ShareAccessOutcome = "wrong_password"
+	ShareAccessRevoked          ShareAccessOutcome = "revoked"
+	ShareAccessExpired          ShareAccessOutcome = "expired"
+	ShareAccessExhausted        ShareAccessOutcome = "exhausted"
+	ShareAccessUnavailable      ShareAccessOutcome = "unavailable" // Document deleted or not cleared by the malware scan
+)
+
+// DocumentShareLinkAccess is one attempt to open a share link
+type DocumentShareLinkAccess struct {
+	ID          uint               `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	TenantID    uint               `gorm:"not null;index" json:"tenant_id"`
+	ShareLinkID uint               `gorm:"not null;index" json:"share_link_id"`
+	Outcome     ShareAccessOutcome `gorm:"type:varchar(30);not null" json:"outcome"`
+	IPAddress   string             `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent   string             `gorm:"type:varchar(255)" json:"user_agent"`
+
+	// Relationships
+	Tenant    *Tenant            `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	ShareLink *DocumentShareLink `gorm:"foreignKey:ShareLinkID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for DocumentShareLinkAccess model
+func (DocumentShareLinkAccess) TableName() string {
+	return "document_share_link_accesses"
+}
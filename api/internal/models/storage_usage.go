@@ -0,0 +1,17 @@
+package models
+
+// FolderUsage is the storage taken by the documents directly inside a folder, counting every version
+type FolderUsage struct {
+	FolderID   *uint  `json:"folder_id"` // nil for documents at the root
+	FolderName string `json:"folder_name"`
+	Documents  int64  `json:"documents"`
+	Bytes      int64  `json:"bytes"`
+}
+
+// UploaderUsage is the storage taken by the files a user uploaded, counting every version
+type UploaderUsage struct {
+	UserID   uint   `json:"user_id"`
+	Name     string `json:"name"`
+	Versions int64  `json:"versions"`
+	Bytes    int64  `json:"bytes"`
+}
@@ -1,5 +1,10 @@
 package models
 
+import "errors"
+
+// ErrStorageQuotaExceeded is returned when a file would take a tenant past its storage quota
+var ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
 // Tenant represents a condominium (customer/tenant in the SaaS platform)
 type Tenant struct {
 	BaseModel
@@ -9,6 +14,22 @@ type Tenant struct {
 	Phone  string `gorm:"type:varchar(20)" json:"phone"`
 	Active bool   `gorm:"default:true" json:"active"`
 
+	// Email branding: the logo shown above the condominium name, and the language of emails to people without an account
+	LogoURL string `gorm:"type:varchar(512);not null;default:''" json:"logo_url"`
+	Locale  string `gorm:"type:varchar(10);not null;default:'pt-BR'" json:"locale"`
+
+	// Security settings
+	RequireTwoFactor bool `gorm:"default:false" json:"require_two_factor"` // Síndicos and admins must use 2FA
+
+	// Storage quota. A nil quota falls back to the platform default; 0 means unlimited
+	// StorageUsedBytes is only changed through the repository's atomic reserve/release
+	StorageQuotaBytes   *int64 `json:"storage_quota_bytes"`
+	StorageUsedBytes    int64  `gorm:"default:0" json:"storage_used_bytes"`
+	StorageWarningLevel int    `gorm:"default:0" json:"-"` // Highest usage warning sent to síndicos (0, 80 or 100)
+
+	// Document types accepted for upload, as extensions; nil accepts every type in FileTypes
+	AllowedFileTypes FileTypeList `gorm:"type:jsonb" json:"allowed_file_types"`
+
 	// Relationships - Many-to-Many with User
 	UserTenants []UserTenant `gorm:"foreignKey:TenantID" json:"user_tenants,omitempty"`
 	Users       []User       `gorm:"many2many:user_tenants" json:"users,omitempty"`
@@ -19,3 +40,11 @@ type Tenant struct {
 func (Tenant) TableName() string {
 	return "tenants"
 }
+
+// StorageQuota returns the tenant's quota in bytes, falling back to defaultQuota; 0 means unlimited
+func (t *Tenant) StorageQuota(defaultQuota int64) int64 {
+	if t.StorageQuotaBytes != nil {
+		return *t.StorageQuotaBytes
+	}
+	return defaultQuota
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TwoFactorRecoveryCode represents a single-use backup code for two-factor authentication
+// Only the SHA-256 hash of the code is stored
+type TwoFactorRecoveryCode struct {
+	BaseModel
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"type:varchar(64);not null;index" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for TwoFactorRecoveryCode model
+func (TwoFactorRecoveryCode) TableName() string {
+	return "two_factor_recovery_codes"
+}
@@ -19,8 +19,8 @@ type Unit struct {
 	Active   bool `gorm:"default:true" json:"active"`
 
 	// Relationships
-	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
-	Users  []User  `gorm:"foreignKey:UnitID;constraint:OnDelete:SET NULL" json:"users,omitempty"`
+	Tenant  *Tenant      `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"tenant,omitempty"`
+	Members []UnitMember `gorm:"foreignKey:UnitID" json:"members,omitempty"`
 }
 
 // TableName specifies the table name for Unit model
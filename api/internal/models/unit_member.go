@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// UnitRelationship represents how a user is linked to a unit
+type UnitRelationship string
+
+const (
+	UnitRelationshipOwner     UnitRelationship = "proprietario"
+	UnitRelationshipTenant    UnitRelationship = "inquilino"
+	UnitRelationshipDependent UnitRelationship = "dependente"
+)
+
+// IsValidUnitRelationship checks if a relationship type is supported
+func IsValidUnitRelationship(r UnitRelationship) bool {
+	return r == UnitRelationshipOwner || r == UnitRelationshipTenant || r == UnitRelationshipDependent
+}
+
+// UnitMember links a user to a unit of a tenant
+// A user can be linked to several units, in the same or in different condominiums
+type UnitMember struct {
+	BaseModel
+	TenantID            uint             `gorm:"not null;index" json:"tenant_id"`
+	UnitID              uint             `gorm:"not null;index" json:"unit_id"`
+	UserID              uint             `gorm:"not null;index" json:"user_id"`
+	Relationship        UnitRelationship `gorm:"type:varchar(20);not null" json:"relationship"`
+	StartDate           time.Time        `gorm:"type:date;not null" json:"start_date"`
+	EndDate             *time.Time       `gorm:"type:date" json:"end_date,omitempty"`
+	ResponsibleForBills bool             `gorm:"default:false" json:"responsible_for_bills"`
+
+	// Relationships
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Unit   *Unit   `gorm:"foreignKey:UnitID;constraint:OnDelete:CASCADE" json:"unit,omitempty"`
+	User   *User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for UnitMember model
+func (UnitMember) TableName() string {
+	return "unit_members"
+}
+
+// IsCurrent checks if the membership is in effect today
+func (m *UnitMember) IsCurrent() bool {
+	now := time.Now()
+	return !m.StartDate.After(now) && (m.EndDate == nil || m.EndDate.After(now))
+}
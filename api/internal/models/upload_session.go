@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// UploadSessionStatus represents the state of a direct upload
+type UploadSessionStatus string
+
+const (
+	UploadSessionPending   UploadSessionStatus = "pending"
+	UploadSessionCompleted UploadSessionStatus = "completed"
+	UploadSessionAborted   UploadSessionStatus = "aborted"
+)
+
+// UploadSession tracks a file the client uploads straight to storage
+// The document (or new version) is only created when the session is completed
+type UploadSession struct {
+	BaseModel
+	TenantID          uint                `gorm:"not null;index" json:"tenant_id"`
+	UserID            uint                `gorm:"not null;index" json:"user_id"`
+	FolderID          *uint               `json:"folder_id"`
+	DocumentID        *uint               `json:"document_id"` // Target document for a new version, or the created document
+	FileName          string              `gorm:"type:varchar(255);not null" json:"file_name"`
+	ContentType       string              `gorm:"type:varchar(100)" json:"content_type"`
+	Size              int64               `gorm:"not null" json:"size"`
+	S3Key             string              `gorm:"type:varchar(500);not null" json:"-"`
+	MultipartUploadID string              `gorm:"type:varchar(255)" json:"-"`
+	PartSize          int64               `json:"part_size,omitempty"`
+	PartCount         int                 `json:"part_count,omitempty"`
+	Status            UploadSessionStatus `gorm:"type:varchar(20);not null;default:pending;index" json:"status"`
+	ExpiresAt         time.Time           `gorm:"not null;index" json:"expires_at"`
+	CompletedAt       *time.Time          `json:"completed_at,omitempty"`
+
+	// Relationships
+	Tenant *Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	User   *User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName specifies the table name for UploadSession model
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// IsMultipart checks if the file is uploaded in parts
+func (s *UploadSession) IsMultipart() bool {
+	return s.MultipartUploadID != ""
+}
+
+// IsExpired checks if the session can no longer be completed
+func (s *UploadSession) IsExpired() bool {
+	return time.Now().After(s.ExpiresAt)
+}
@@ -1,10 +1,22 @@
 package models
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 // ErrUserNotInTenant is returned when a user doesn't belong to a tenant
 var ErrUserNotInTenant = errors.New("user does not belong to this tenant")
 
+// ErrEmailNotVerified is returned when an action requires a verified email address
+var ErrEmailNotVerified = errors.New("email address must be verified first")
+
+// ErrAccountLocked is returned when login is attempted while the account is locked out
+var ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
+
+// ErrTwoFactorRequired is returned when a tenant requires 2FA and the user has not enabled it
+var ErrTwoFactorRequired = errors.New("two-factor authentication is required by this tenant")
+
 // UserRole represents the role of a user in the system
 type UserRole string
 
@@ -23,15 +35,32 @@ type User struct {
 	Name     string `gorm:"type:varchar(255);not null" json:"name" binding:"required"`
 	Active   bool   `gorm:"default:true" json:"active"`
 
+	// EmailVerifiedAt is set once the user proves ownership of the email address
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// Two-factor authentication (TOTP). The secret is kept while enrollment is pending confirmation
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret  string `gorm:"type:varchar(64)" json:"-"`
+	// TOTP time step of the last accepted code; codes from that step or earlier are replays
+	TwoFactorLastStep int64 `gorm:"not null;default:0" json:"-"`
+	// ID of the login challenge awaiting a code; cleared when it is used, so each challenge works once
+	TwoFactorChallengeID *string `gorm:"type:varchar(64)" json:"-"`
+
+	// Brute-force protection. LockoutCount grows with each lockout so locks get progressively longer
+	FailedLoginAttempts int        `gorm:"default:0" json:"-"`
+	LockoutCount        int        `gorm:"default:0" json:"-"`
+	LockedUntil         *time.Time `json:"-"`
+
 	// Optional fields
-	Phone  string `gorm:"type:varchar(20)" json:"phone"`
-	CPF    string `gorm:"type:varchar(14);uniqueIndex" json:"cpf"`
-	UnitID *uint  `gorm:"index" json:"unit_id,omitempty"`
+	Phone string `gorm:"type:varchar(20)" json:"phone"`
+	CPF   string `gorm:"type:varchar(14);uniqueIndex" json:"cpf"`
 
-	// Relationships - Many-to-Many with Tenant
+	// Language of the emails sent to the user (see SupportedLocales)
+	Locale string `gorm:"type:varchar(10);not null;default:'pt-BR'" json:"locale"`
+
+	// Relationships - Many-to-Many with Tenant; units are linked per tenant through UnitMember
 	UserTenants []UserTenant `gorm:"foreignKey:UserID" json:"user_tenants,omitempty"`
 	Tenants     []Tenant     `gorm:"many2many:user_tenants" json:"tenants,omitempty"`
-	Unit        *Unit        `gorm:"foreignKey:UnitID;constraint:OnDelete:SET NULL" json:"unit,omitempty"`
 }
 
 // TableName specifies the table name for User model
@@ -39,6 +68,16 @@ func (User) TableName() string {
 	return "users"
 }
 
+// IsEmailVerified checks if the user has verified their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsLocked checks if the account is currently locked out
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
 // BelongsToTenant checks if user belongs to a specific tenant
 func (u *User) BelongsToTenant(tenantID uint) bool {
 	for _, ut := range u.UserTenants {
@@ -78,6 +117,11 @@ func (u *User) IsAdminInTenant(tenantID uint) bool {
 	return role == RoleAdmin
 }
 
+// IsManagerRole checks if a role manages a tenant (síndico or admin)
+func IsManagerRole(role UserRole) bool {
+	return role == RoleSindico || role == RoleAdmin
+}
+
 // IsSindicoInTenant checks if user has síndico role in a specific tenant
 func (u *User) IsSindicoInTenant(tenantID uint) bool {
 	role, err := u.GetRoleInTenant(tenantID)
@@ -21,3 +21,9 @@ type UserTenant struct {
 func (UserTenant) TableName() string {
 	return "user_tenants"
 }
+
+// HasAccess checks if the membership grants access: active membership in an active tenant
+// The tenant must be preloaded for its status to be taken into account
+func (ut *UserTenant) HasAccess() bool {
+	return ut.IsActive && (ut.Tenant == nil || ut.Tenant.Active)
+}
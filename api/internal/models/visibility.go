@@ -0,0 +1,108 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Visibility controls which residents can see a folder or document
+type Visibility string
+
+const (
+	// VisibilityInherit makes a document follow the rules of its folder
+	VisibilityInherit Visibility = "inherit"
+	// VisibilityManagement restricts access to users with documents.read
+	VisibilityManagement Visibility = "management"
+	// VisibilityResidents shares with every member of the condominium
+	VisibilityResidents Visibility = "residents"
+	// VisibilityUnits shares with the residents of the units and blocks in the audience
+	VisibilityUnits Visibility = "units"
+)
+
+// IsValidFolderVisibility checks if a visibility can be set on a folder
+func IsValidFolderVisibility(v Visibility) bool {
+	return v == VisibilityManagement || v == VisibilityResidents || v == VisibilityUnits
+}
+
+// IsValidDocumentVisibility checks if a visibility can be set on a document
+func IsValidDocumentVisibility(v Visibility) bool {
+	return v == VisibilityInherit || IsValidFolderVisibility(v)
+}
+
+// Audience lists the units and blocks a folder or document is shared with
+type Audience struct {
+	UnitIDs []uint   `json:"unit_ids"`
+	Blocks  []string `json:"blocks"`
+}
+
+// IsEmpty checks if the audience has no units and no blocks
+func (a Audience) IsEmpty() bool {
+	return len(a.UnitIDs) == 0 && len(a.Blocks) == 0
+}
+
+// Value implements driver.Valuer
+func (a Audience) Value() (driver.Value, error) {
+	if a.UnitIDs == nil {
+		a.UnitIDs = []uint{}
+	}
+	if a.Blocks == nil {
+		a.Blocks = []string{}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan implements sql.Scanner
+func (a *Audience) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = Audience{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported type for Audience: %T", value)
+	}
+	if err := json.Unmarshal(data, a); err != nil {
+		return errors.New("invalid audience JSON")
+	}
+	return nil
+}
+
+// ResidentScope describes the units a resident currently lives in
+type ResidentScope struct {
+	UnitIDs []uint
+	Blocks  []string
+}
+
+// Allows checks if content with the given visibility and audience is visible to the resident
+func (s ResidentScope) Allows(v Visibility, a Audience) bool {
+	switch v {
+	case VisibilityResidents:
+		return true
+	case VisibilityUnits:
+		for _, id := range a.UnitIDs {
+			for _, own := range s.UnitIDs {
+				if id == own {
+					return true
+				}
+			}
+		}
+		for _, block := range a.Blocks {
+			for _, own := range s.Blocks {
+				if block != "" && block == own {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept before being pruned
+const idleBucketTTL = 10 * time.Minute
+
+// bucket holds the state of a single token bucket
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// memoryStore keeps buckets in process memory (single instance deployments)
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+// NewMemoryStore creates an in-memory token bucket store
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets:   make(map[string]*bucket),
+		lastPrune: time.Now(),
+	}
+}
+
+// Take consumes one token from the bucket for key
+func (s *memoryStore) Take(key string, limit Limit) (bool, time.Duration, error) {
+	now := time.Now()
+	ratePerSecond := float64(limit.Rate) / limit.Per.Seconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), lastSeen: now}
+		s.buckets[key] = b
+	}
+
+	// Refill based on elapsed time
+	elapsed := now.Sub(b.lastSeen).Seconds()
+	b.tokens += elapsed * ratePerSecond
+	if b.tokens > float64(limit.Burst) {
+		b.tokens = float64(limit.Burst)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / ratePerSecond * float64(time.Second))
+		return false, wait, nil
+	}
+
+	b.tokens--
+	return true, 0, nil
+}
+
+// prune drops idle buckets so memory does not grow with every client seen
+func (s *memoryStore) prune(now time.Time) {
+	if now.Sub(s.lastPrune) < time.Minute {
+		return
+	}
+	for key, b := range s.buckets {
+		if now.Sub(b.lastSeen) > idleBucketTTL {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastPrune = now
+}
@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limit describes a token bucket: Burst tokens at most, refilled at Rate tokens per Per
+type Limit struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
+
+// PerMinute builds a limit refilling rate tokens per minute with the given burst
+func PerMinute(rate, burst int) Limit {
+	return Limit{Rate: rate, Per: time.Minute, Burst: burst}
+}
+
+// Store keeps token buckets keyed by an arbitrary string (IP, email, ...)
+// Implementations must be safe for concurrent use
+type Store interface {
+	// Take consumes one token from the bucket for key.
+	// When the bucket is empty it returns false and how long until a token is available
+	Take(key string, limit Limit) (bool, time.Duration, error)
+}
+
+// NewStore creates a store for the configured driver
+func NewStore(driver string) (Store, error) {
+	switch driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unsupported rate limit store: %s", driver)
+	}
+}
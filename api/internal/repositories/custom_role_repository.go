@@ -0,0 +1,85 @@
+package repositories
+
+import (
+	"context"
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// CustomRoleRepository defines the interface for custom role operations
+type CustomRoleRepository interface {
+	Create(ctx context.Context, role *models.CustomRole) error
+	GetByID(ctx context.Context, roleID uint) (*models.CustomRole, error)
+	GetByName(ctx context.Context, name models.UserRole) (*models.CustomRole, error)
+	GetAllByTenant(ctx context.Context) ([]models.CustomRole, error)
+	Update(ctx context.Context, role *models.CustomRole) error
+	Delete(ctx context.Context, roleID uint) error
+}
+
+// customRoleRepository implements CustomRoleRepository
+type customRoleRepository struct {
+	db *gorm.DB
+}
+
+// NewCustomRoleRepository creates a new custom role repository
+func NewCustomRoleRepository(db *gorm.DB) CustomRoleRepository {
+	return &customRoleRepository{db: db}
+}
+
+// Create creates a new custom role
+func (r *customRoleRepository) Create(ctx context.Context, role *models.CustomRole) error {
+	return database.Conn(ctx, r.db).Create(role).Error
+}
+
+// GetByID retrieves a custom role by ID with tenant isolation
+func (r *customRoleRepository) GetByID(ctx context.Context, roleID uint) (*models.CustomRole, error) {
+	var role models.CustomRole
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", roleID).
+		First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetByName retrieves a custom role by name with tenant isolation
+func (r *customRoleRepository) GetByName(ctx context.Context, name models.UserRole) (*models.CustomRole, error) {
+	var role models.CustomRole
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("name = ?", name).
+		First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetAllByTenant retrieves all custom roles of a tenant
+func (r *customRoleRepository) GetAllByTenant(ctx context.Context) ([]models.CustomRole, error) {
+	var roles []models.CustomRole
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Order("display_name ASC").
+		Find(&roles).Error
+	return roles, err
+}
+
+// Update updates a custom role
+func (r *customRoleRepository) Update(ctx context.Context, role *models.CustomRole) error {
+	return database.Conn(ctx, r.db).Model(&models.CustomRole{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", role.ID).
+		Updates(map[string]interface{}{
+			"display_name": role.DisplayName,
+			"description":  role.Description,
+			"permissions":  role.Permissions,
+		}).Error
+}
+
+// Delete soft deletes a custom role
+func (r *customRoleRepository) Delete(ctx context.Context, roleID uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", roleID).
+		Delete(&models.CustomRole{}).Error
+}
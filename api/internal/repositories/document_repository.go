@@ -1,20 +1,42 @@
 package repositories
 
 import (
+	"context"
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DocumentRepository defines the interface for document operations
 type DocumentRepository interface {
-	Create(doc *models.Document) error
-	GetByID(tenantID, docID uint) (*models.Document, error)
-	GetAll(tenantID uint, folderID *uint) ([]models.Document, error)
-	GetByFolder(tenantID, folderID uint) ([]models.Document, error)
-	Update(doc *models.Document) error
-	Delete(tenantID, docID uint) error
+	Create(ctx context.Context, doc *models.Document) error
+	GetByID(ctx context.Context, docID uint) (*models.Document, error)
+	GetAll(ctx context.Context, folderID *uint) ([]models.Document, error)
+	GetByFolder(ctx context.Context, folderID uint) ([]models.Document, error)
+	GetByFolders(ctx context.Context, folderIDs []uint) ([]models.Document, error)
+	GetByIDs(ctx context.Context, docIDs []uint) ([]models.Document, error)
+	Update(ctx context.Context, doc *models.Document) error
+	Delete(ctx context.Context, docID uint) error
+	AddVersion(ctx context.Context, doc *models.Document, version *models.DocumentVersion) error
+	GetVersions(ctx context.Context, docID uint) ([]models.DocumentVersion, error)
+	GetVersionsByFolders(ctx context.Context, folderIDs []uint) ([]models.DocumentVersion, error)
+	GetVersion(ctx context.Context, docID uint, versionNumber int) (*models.DocumentVersion, error)
+	IsKeyInUse(ctx context.Context, key string) (bool, error)
+	GetPendingScans(ctx context.Context, limit int) ([]models.DocumentVersion, error)
+	SetScanResult(ctx context.Context, version *models.DocumentVersion) error
+	UsageByFolder(ctx context.Context) ([]models.FolderUsage, error)
+	UsageByUploader(ctx context.Context) ([]models.UploaderUsage, error)
+	Search(ctx context.Context, query string, limit, offset int) ([]models.DocumentSearchResult, error)
 }
 
+// ts_headline options for search results; the markers are swapped for HTML tags by the service
+const (
+	nameHeadlineOptions    = "StartSel=" + models.HighlightStart + ", StopSel=" + models.HighlightStop + ", HighlightAll=true"
+	snippetHeadlineOptions = "StartSel=" + models.HighlightStart + ", StopSel=" + models.HighlightStop +
+		`, MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … "`
+)
+
 // documentRepository implements DocumentRepository
 type documentRepository struct {
 	db *gorm.DB
@@ -26,14 +48,15 @@ func NewDocumentRepository(db *gorm.DB) DocumentRepository {
 }
 
 // Create creates a new document
-func (r *documentRepository) Create(doc *models.Document) error {
-	return r.db.Create(doc).Error
+func (r *documentRepository) Create(ctx context.Context, doc *models.Document) error {
+	return database.Conn(ctx, r.db).Create(doc).Error
 }
 
 // GetByID retrieves a document by ID with tenant isolation
-func (r *documentRepository) GetByID(tenantID, docID uint) (*models.Document, error) {
+func (r *documentRepository) GetByID(ctx context.Context, docID uint) (*models.Document, error) {
 	var doc models.Document
-	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, docID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", docID).
 		Preload("Folder").
 		Preload("UploadedBy").
 		First(&doc).Error
@@ -44,9 +67,9 @@ func (r *documentRepository) GetByID(tenantID, docID uint) (*models.Document, er
 }
 
 // GetAll retrieves all documents for a tenant, optionally filtered by folder
-func (r *documentRepository) GetAll(tenantID uint, folderID *uint) ([]models.Document, error) {
+func (r *documentRepository) GetAll(ctx context.Context, folderID *uint) ([]models.Document, error) {
 	var docs []models.Document
-	query := r.db.Where("tenant_id = ?", tenantID)
+	query := database.Conn(ctx, r.db).Scopes(database.ScopedTenant)
 	if folderID != nil {
 		query = query.Where("folder_id = ?", *folderID)
 	}
@@ -59,9 +82,10 @@ func (r *documentRepository) GetAll(tenantID uint, folderID *uint) ([]models.Doc
 }
 
 // GetByFolder retrieves all documents in a folder
-func (r *documentRepository) GetByFolder(tenantID, folderID uint) ([]models.Document, error) {
+func (r *documentRepository) GetByFolder(ctx context.Context, folderID uint) ([]models.Document, error) {
 	var docs []models.Document
-	err := r.db.Where("tenant_id = ? AND folder_id = ?", tenantID, folderID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("folder_id = ?", folderID).
 		Preload("Folder").
 		Preload("UploadedBy").
 		Order("created_at DESC").
@@ -69,17 +93,279 @@ func (r *documentRepository) GetByFolder(tenantID, folderID uint) ([]models.Docu
 	return docs, err
 }
 
+// GetByFolders retrieves the documents of several folders, without their versions
+func (r *documentRepository) GetByFolders(ctx context.Context, folderIDs []uint) ([]models.Document, error) {
+	var docs []models.Document
+	if len(folderIDs) == 0 {
+		return docs, nil
+	}
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("folder_id IN ?", folderIDs).
+		Find(&docs).Error
+	return docs, err
+}
+
+// GetByIDs retrieves several documents by ID with their folders
+func (r *documentRepository) GetByIDs(ctx context.Context, docIDs []uint) ([]models.Document, error) {
+	var docs []models.Document
+	if len(docIDs) == 0 {
+		return docs, nil
+	}
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id IN ?", docIDs).
+		Preload("Folder").
+		Find(&docs).Error
+	return docs, err
+}
+
 // Update updates a document
-func (r *documentRepository) Update(doc *models.Document) error {
-	return r.db.Model(&models.Document{}).
-		Where("tenant_id = ? AND id = ?", doc.TenantID, doc.ID).
+func (r *documentRepository) Update(ctx context.Context, doc *models.Document) error {
+	return database.Conn(ctx, r.db).Model(&models.Document{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", doc.ID).
 		Select("*").
-		Omit("created_at", "Tenant", "Folder", "UploadedBy").
+		Omit("created_at", "Tenant", "Folder", "UploadedBy", "Versions").
 		Updates(doc).Error
 }
 
-// Delete soft deletes a document with tenant isolation
-func (r *documentRepository) Delete(tenantID, docID uint) error {
-	return r.db.Where("tenant_id = ? AND id = ?", tenantID, docID).
-		Delete(&models.Document{}).Error
+// Delete soft deletes a document and its versions with tenant isolation
+func (r *documentRepository) Delete(ctx context.Context, docID uint) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Scopes(database.ScopedTenant).
+			Where("document_id = ?", docID).
+			Delete(&models.DocumentVersion{}).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(database.ScopedTenant).
+			Where("id = ?", docID).
+			Delete(&models.Document{}).Error
+	})
+}
+
+// AddVersion stores a new version and makes it the current one of the document
+// The version number is assigned here, under a row lock on the document
+func (r *documentRepository) AddVersion(ctx context.Context, doc *models.Document, version *models.DocumentVersion) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		var locked models.Document
+		if err := tx.Scopes(database.ScopedTenant).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", doc.ID).
+			First(&locked).Error; err != nil {
+			return err
+		}
+
+		var last int
+		if err := tx.Model(&models.DocumentVersion{}).
+			Scopes(database.ScopedTenant).
+			Where("document_id = ?", doc.ID).
+			Select("COALESCE(MAX(version_number), 0)").
+			Scan(&last).Error; err != nil {
+			return err
+		}
+
+		version.TenantID = doc.TenantID
+		version.DocumentID = doc.ID
+		version.VersionNumber = last + 1
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+
+		doc.ApplyVersion(version)
+		return tx.Model(&models.Document{}).
+			Scopes(database.ScopedTenant).
+			Where("id = ?", doc.ID).
+			Select("original_name", "content_type", "size", "s3_key", "uploaded_by_id", "current_version", "scan_status").
+			Updates(doc).Error
+	})
+}
+
+// GetVersions retrieves all versions of a document, newest first
+func (r *documentRepository) GetVersions(ctx context.Context, docID uint) ([]models.DocumentVersion, error) {
+	var versions []models.DocumentVersion
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("document_id = ?", docID).
+		Omit("content_text").
+		Preload("UploadedBy").
+		Order("version_number DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetVersionsByFolders retrieves the storage key and size of every version of the documents in several folders
+func (r *documentRepository) GetVersionsByFolders(ctx context.Context, folderIDs []uint) ([]models.DocumentVersion, error) {
+	var versions []models.DocumentVersion
+	if len(folderIDs) == 0 {
+		return versions, nil
+	}
+	db := database.Conn(ctx, r.db)
+	docIDs := db.Model(&models.Document{}).
+		Scopes(database.ScopedTenant).
+		Where("folder_id IN ?", folderIDs).
+		Select("id")
+	err := db.Scopes(database.ScopedTenant).
+		Select("id", "document_id", "s3_key", "size").
+		Where("document_id IN (?)", docIDs).
+		Find(&versions).Error
+	return versions, err
+}
+
+// GetVersion retrieves a single version of a document by its number
+func (r *documentRepository) GetVersion(ctx context.Context, docID uint, versionNumber int) (*models.DocumentVersion, error) {
+	var version models.DocumentVersion
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("document_id = ? AND version_number = ?", docID, versionNumber).
+		Omit("content_text").
+		Preload("UploadedBy").
+		First(&version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &version, nil
+}
+
+// IsKeyInUse checks if a storage key belongs to any document version
+func (r *documentRepository) IsKeyInUse(ctx context.Context, key string) (bool, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.DocumentVersion{}).
+		Scopes(database.ScopedTenant).
+		Where("s3_key = ?", key).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetPendingScans retrieves versions waiting for the malware scan, oldest first, across all tenants
+// Used by the background scanner, on the connection that bypasses row-level security
+func (r *documentRepository) GetPendingScans(ctx context.Context, limit int) ([]models.DocumentVersion, error) {
+	var versions []models.DocumentVersion
+	err := database.Conn(ctx, r.db).Where("scan_status = ?", models.ScanPending).
+		Omit("content_text").
+		Order("id ASC").
+		Limit(limit).
+		Find(&versions).Error
+	return versions, err
+}
+
+// SetScanResult records the scan result of a version, and of its document if it is still the current version
+func (r *documentRepository) SetScanResult(ctx context.Context, version *models.DocumentVersion) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.DocumentVersion{}).
+			Scopes(database.ScopedTenant).
+			Where("id = ?", version.ID).
+			UpdateColumns(map[string]interface{}{
+				"scan_status":    version.ScanStatus,
+				"scan_signature": version.ScanSignature,
+				"scanned_at":     version.ScannedAt,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&models.Document{}).
+			Scopes(database.ScopedTenant).
+			Where("id = ? AND current_version = ?", version.DocumentID, version.VersionNumber).
+			UpdateColumn("scan_status", version.ScanStatus).Error
+	})
+}
+
+// UsageByFolder sums the size of every stored version per folder, largest first
+func (r *documentRepository) UsageByFolder(ctx context.Context) ([]models.FolderUsage, error) {
+	var usage []models.FolderUsage
+	err := database.Conn(ctx, r.db).Raw(
+		`SELECT d.folder_id, COALESCE(f.name, '') AS folder_name,
+				COUNT(DISTINCT d.id) AS documents, COALESCE(SUM(v.size), 0) AS bytes
+			FROM document_versions v
+			JOIN documents d ON d.id = v.document_id AND d.deleted_at IS NULL
+			LEFT JOIN folders f ON f.id = d.folder_id
+			WHERE v.tenant_id = ` + database.CurrentTenantSQL + ` AND v.deleted_at IS NULL
+			GROUP BY d.folder_id, f.name
+			ORDER BY bytes DESC`,
+	).Scan(&usage).Error
+	return usage, err
+}
+
+// UsageByUploader sums the size of every stored version per uploader, largest first
+func (r *documentRepository) UsageByUploader(ctx context.Context) ([]models.UploaderUsage, error) {
+	var usage []models.UploaderUsage
+	err := database.Conn(ctx, r.db).Raw(
+		`SELECT v.uploaded_by_id AS user_id, COALESCE(u.name, '') AS name,
+				COUNT(*) AS versions, COALESCE(SUM(v.size), 0) AS bytes
+			FROM document_versions v
+			JOIN documents d ON d.id = v.document_id AND d.deleted_at IS NULL
+			LEFT JOIN users u ON u.id = v.uploaded_by_id
+			WHERE v.tenant_id = ` + database.CurrentTenantSQL + ` AND v.deleted_at IS NULL
+			GROUP BY v.uploaded_by_id, u.name
+			ORDER BY bytes DESC`,
+	).Scan(&usage).Error
+	return usage, err
+}
+
+// Search finds documents whose name, folder or extracted text match a web-style query, best match first
+// Highlights are only computed for the requested page, since ts_headline re-parses the text
+func (r *documentRepository) Search(ctx context.Context, query string, limit, offset int) ([]models.DocumentSearchResult, error) {
+	var hits []struct {
+		ID            uint
+		Rank          float64
+		NameHighlight string
+		Snippet       string
+	}
+	var docs []models.Document
+
+	err := database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(
+			`WITH q AS (SELECT websearch_to_tsquery('portuguese', ?) AS query)
+			SELECT m.id, m.rank,
+				ts_headline('portuguese', m.name, q.query, ?) AS name_highlight,
+				CASE WHEN COALESCE(v.content_text, '') = '' THEN ''
+					ELSE ts_headline('portuguese', v.content_text, q.query, ?) END AS snippet
+			FROM (
+				SELECT d.id, d.name, d.current_version, ts_rank(d.search_vector, q.query) AS rank
+				FROM documents d, q
+				WHERE d.tenant_id = `+database.CurrentTenantSQL+` AND d.deleted_at IS NULL AND d.search_vector @@ q.query
+				ORDER BY rank DESC, d.id DESC
+				LIMIT ? OFFSET ?
+			) m
+			CROSS JOIN q
+			LEFT JOIN document_versions v ON v.document_id = m.id
+				AND v.version_number = m.current_version AND v.deleted_at IS NULL
+			ORDER BY m.rank DESC, m.id DESC`,
+			query, nameHeadlineOptions, snippetHeadlineOptions, limit, offset,
+		).Scan(&hits).Error; err != nil {
+			return err
+		}
+		if len(hits) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(hits))
+		for i, hit := range hits {
+			ids[i] = hit.ID
+		}
+		return tx.Scopes(database.ScopedTenant).
+			Where("id IN ?", ids).
+			Preload("Folder").
+			Preload("UploadedBy").
+			Find(&docs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]models.Document, len(docs))
+	for _, doc := range docs {
+		byID[doc.ID] = doc
+	}
+
+	results := make([]models.DocumentSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		doc, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.DocumentSearchResult{
+			Document:      doc,
+			Rank:          hit.Rank,
+			NameHighlight: hit.NameHighlight,
+			Snippet:       hit.Snippet,
+		})
+	}
+	return results, nil
 }
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// EmailOutboxRepository defines the interface for email outbox operations
+// The outbox is platform-wide, so it has no tenant isolation
+type EmailOutboxRepository interface {
+	Create(ctx context.Context, email *models.OutboxEmail) error
+	GetByID(id uint) (*models.OutboxEmail, error)
+	GetAll(status models.OutboxStatus, limit, offset int) ([]models.OutboxEmail, int64, error)
+	ClaimDue(limit int, lease time.Duration) ([]models.OutboxEmail, error)
+	MarkSent(id uint) error
+	MarkRetry(id uint, lastError string, next time.Time) error
+	MarkFailed(id uint, lastError string) error
+	Requeue(id uint) (bool, error)
+}
+
+// emailOutboxRepository implements EmailOutboxRepository
+type emailOutboxRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailOutboxRepository creates a new email outbox repository
+func NewEmailOutboxRepository(db *gorm.DB) EmailOutboxRepository {
+	return &emailOutboxRepository{db: db}
+}
+
+// Create queues an email in the transaction carried by ctx, or on its own when there is none
+// An email whose idempotency key is already queued is left as it is
+func (r *emailOutboxRepository) Create(ctx context.Context, email *models.OutboxEmail) error {
+	return database.Conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(email).Error
+}
+
+// GetByID retrieves a queued email by ID
+func (r *emailOutboxRepository) GetByID(id uint) (*models.OutboxEmail, error) {
+	var email models.OutboxEmail
+	if err := r.db.First(&email, id).Error; err != nil {
+		return nil, err
+	}
+	return &email, nil
+}
+
+// GetAll retrieves queued emails, newest first, optionally with one status, and the total count
+func (r *emailOutboxRepository) GetAll(status models.OutboxStatus, limit, offset int) ([]models.OutboxEmail, int64, error) {
+	query := r.db.Model(&models.OutboxEmail{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var emails []models.OutboxEmail
+	err := query.Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&emails).Error
+	return emails, total, err
+}
+
+// ClaimDue leases a batch of emails that are due for delivery and counts the attempt
+// SKIP LOCKED and the lease keep concurrent workers from picking the same email;
+// if a worker dies mid-delivery, the email becomes due again once the lease runs out
+func (r *emailOutboxRepository) ClaimDue(limit int, lease time.Duration) ([]models.OutboxEmail, error) {
+	var emails []models.OutboxEmail
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Where("(locked_until IS NULL OR locked_until <= ?)", now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&emails).Error
+		if err != nil || len(emails) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(emails))
+		for i := range emails {
+			ids[i] = emails[i].ID
+			emails[i].Attempts++
+		}
+		return tx.Model(&models.OutboxEmail{}).
+			Where("id IN ?", ids).
+			UpdateColumns(map[string]interface{}{
+				"attempts":     gorm.Expr("attempts + 1"),
+				"locked_until": now.Add(lease),
+				"updated_at":   now,
+			}).Error
+	})
+	return emails, err
+}
+
+// MarkSent records a successful delivery
+func (r *emailOutboxRepository) MarkSent(id uint) error {
+	now := time.Now()
+	return r.db.Model(&models.OutboxEmail{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       models.OutboxSent,
+			"sent_at":      now,
+			"locked_until": nil,
+			"last_error":   "",
+		}).Error
+}
+
+// MarkRetry records a failed attempt and schedules the next one
+func (r *emailOutboxRepository) MarkRetry(id uint, lastError string, next time.Time) error {
+	return r.db.Model(&models.OutboxEmail{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"next_attempt_at": next,
+			"locked_until":    nil,
+			"last_error":      lastError,
+		}).Error
+}
+
+// MarkFailed moves an email to the dead letter state
+func (r *emailOutboxRepository) MarkFailed(id uint, lastError string) error {
+	return r.db.Model(&models.OutboxEmail{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":       models.OutboxFailed,
+			"locked_until": nil,
+			"last_error":   lastError,
+		}).Error
+}
+
+// Requeue gives a failed email a fresh set of attempts; returns false if it was not failed
+func (r *emailOutboxRepository) Requeue(id uint) (bool, error) {
+	result := r.db.Model(&models.OutboxEmail{}).
+		Where("id = ? AND status = ?", id, models.OutboxFailed).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+			"locked_until":    nil,
+		})
+	return result.RowsAffected == 1, result.Error
+}
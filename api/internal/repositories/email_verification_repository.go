@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// EmailVerificationRepository defines the interface for email verification token operations
+type EmailVerificationRepository interface {
+	Create(token *models.EmailVerificationToken) error
+	GetByTokenHash(hash string) (*models.EmailVerificationToken, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateAllByUser(userID uint) error
+}
+
+// emailVerificationRepository implements EmailVerificationRepository
+type emailVerificationRepository struct {
+	db *gorm.DB
+}
+
+// NewEmailVerificationRepository creates a new email verification repository
+func NewEmailVerificationRepository(db *gorm.DB) EmailVerificationRepository {
+	return &emailVerificationRepository{db: db}
+}
+
+// Create creates a new email verification token
+func (r *emailVerificationRepository) Create(token *models.EmailVerificationToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByTokenHash retrieves an email verification token by its hash
+func (r *emailVerificationRepository) GetByTokenHash(hash string) (*models.EmailVerificationToken, error) {
+	var token models.EmailVerificationToken
+	err := r.db.Where("token_hash = ?", hash).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes a token; returns false if it was already used
+func (r *emailVerificationRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.EmailVerificationToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateAllByUser consumes every outstanding token of a user
+func (r *emailVerificationRepository) InvalidateAllByUser(userID uint) error {
+	return r.db.Model(&models.EmailVerificationToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -1,18 +1,30 @@
 package repositories
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // FolderRepository defines the interface for folder operations
 type FolderRepository interface {
-	Create(folder *models.Folder) error
-	GetByID(tenantID, folderID uint) (*models.Folder, error)
-	GetAll(tenantID uint) ([]models.Folder, error)
-	GetByName(tenantID uint, name string) (*models.Folder, error)
-	Update(folder *models.Folder) error
-	Delete(tenantID, folderID uint) error
+	Create(ctx context.Context, folder *models.Folder) error
+	GetByID(ctx context.Context, folderID uint) (*models.Folder, error)
+	GetAll(ctx context.Context) ([]models.Folder, error)
+	GetByIDs(ctx context.Context, folderIDs []uint) ([]models.Folder, error)
+	GetChildren(ctx context.Context, parentID *uint) ([]models.Folder, error)
+	GetSubtree(ctx context.Context, folder *models.Folder) ([]models.Folder, error)
+	GetByName(ctx context.Context, parentID *uint, name string) (*models.Folder, error)
+	Update(ctx context.Context, folder *models.Folder) error
+	Move(ctx context.Context, folder *models.Folder, parent *models.Folder) error
+	Delete(ctx context.Context, folderID uint) error
+	DeleteTree(ctx context.Context, folderIDs []uint) error
+	DeleteAndMoveContents(ctx context.Context, folder *models.Folder) error
 }
 
 // folderRepository implements FolderRepository
@@ -25,15 +37,35 @@ func NewFolderRepository(db *gorm.DB) FolderRepository {
 	return &folderRepository{db: db}
 }
 
-// Create creates a new folder
-func (r *folderRepository) Create(folder *models.Folder) error {
-	return r.db.Create(folder).Error
+// Create creates a new folder under its parent and fills in its path
+func (r *folderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		parentPath := "/"
+		if folder.ParentID != nil {
+			var parent models.Folder
+			if err := tx.Scopes(database.ScopedTenant).
+				Where("id = ?", *folder.ParentID).
+				First(&parent).Error; err != nil {
+				return err
+			}
+			parentPath = parent.Path
+		}
+
+		folder.Path = ""
+		if err := tx.Create(folder).Error; err != nil {
+			return err
+		}
+
+		folder.Path = fmt.Sprintf("%s%d/", parentPath, folder.ID)
+		return tx.Model(folder).Update("path", folder.Path).Error
+	})
 }
 
 // GetByID retrieves a folder by ID with tenant isolation
-func (r *folderRepository) GetByID(tenantID, folderID uint) (*models.Folder, error) {
+func (r *folderRepository) GetByID(ctx context.Context, folderID uint) (*models.Folder, error) {
 	var folder models.Folder
-	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, folderID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", folderID).
 		First(&folder).Error
 	if err != nil {
 		return nil, err
@@ -42,19 +74,59 @@ func (r *folderRepository) GetByID(tenantID, folderID uint) (*models.Folder, err
 }
 
 // GetAll retrieves all folders for a tenant
-func (r *folderRepository) GetAll(tenantID uint) ([]models.Folder, error) {
+func (r *folderRepository) GetAll(ctx context.Context) ([]models.Folder, error) {
 	var folders []models.Folder
-	err := r.db.Where("tenant_id = ?", tenantID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
 		Order("name ASC").
 		Find(&folders).Error
 	return folders, err
 }
 
-// GetByName retrieves a folder by name with tenant isolation
-func (r *folderRepository) GetByName(tenantID uint, name string) (*models.Folder, error) {
+// GetByIDs retrieves several folders by ID with tenant isolation
+func (r *folderRepository) GetByIDs(ctx context.Context, folderIDs []uint) ([]models.Folder, error) {
+	var folders []models.Folder
+	if len(folderIDs) == 0 {
+		return folders, nil
+	}
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id IN ?", folderIDs).
+		Find(&folders).Error
+	return folders, err
+}
+
+// GetChildren retrieves the direct subfolders of a folder, or the root folders when parentID is nil
+func (r *folderRepository) GetChildren(ctx context.Context, parentID *uint) ([]models.Folder, error) {
+	var folders []models.Folder
+	query := database.Conn(ctx, r.db).Scopes(database.ScopedTenant)
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	err := query.Order("name ASC").Find(&folders).Error
+	return folders, err
+}
+
+// GetSubtree retrieves a folder and all of its descendants
+func (r *folderRepository) GetSubtree(ctx context.Context, folder *models.Folder) ([]models.Folder, error) {
+	var folders []models.Folder
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("path LIKE ?", folder.Path+"%").
+		Order("path ASC").
+		Find(&folders).Error
+	return folders, err
+}
+
+// GetByName retrieves a folder by name among the children of a parent
+func (r *folderRepository) GetByName(ctx context.Context, parentID *uint, name string) (*models.Folder, error) {
 	var folder models.Folder
-	err := r.db.Where("tenant_id = ? AND name = ?", tenantID, name).
-		First(&folder).Error
+	query := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).Where("name = ?", name)
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	} else {
+		query = query.Where("parent_id IS NULL")
+	}
+	err := query.First(&folder).Error
 	if err != nil {
 		return nil, err
 	}
@@ -62,16 +134,140 @@ func (r *folderRepository) GetByName(tenantID uint, name string) (*models.Folder
 }
 
 // Update updates a folder
-func (r *folderRepository) Update(folder *models.Folder) error {
-	return r.db.Model(&models.Folder{}).
-		Where("tenant_id = ? AND id = ?", folder.TenantID, folder.ID).
+// The parent and path only change through Move
+func (r *folderRepository) Update(ctx context.Context, folder *models.Folder) error {
+	return database.Conn(ctx, r.db).Model(&models.Folder{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", folder.ID).
 		Select("*").
-		Omit("created_at", "Tenant").
+		Omit("created_at", "parent_id", "path", "Tenant", "Parent").
 		Updates(folder).Error
 }
 
+// Move places a folder under a new parent (nil for the root) and rewrites the paths of its subtree
+// Both folders are locked and reloaded first, so a concurrent move can't turn this one into a cycle
+func (r *folderRepository) Move(ctx context.Context, folder *models.Folder, parent *models.Folder) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		ids := []uint{folder.ID}
+		if parent != nil {
+			ids = append(ids, parent.ID)
+		}
+
+		// Lock in ID order so two moves of the same folders can't deadlock
+		var rows []models.Folder
+		if err := tx.Scopes(database.ScopedTenant).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id IN ?", ids).
+			Order("id ASC").
+			Find(&rows).Error; err != nil {
+			return err
+		}
+
+		var locked, lockedParent *models.Folder
+		for i := range rows {
+			if rows[i].ID == folder.ID {
+				locked = &rows[i]
+			}
+			if parent != nil && rows[i].ID == parent.ID {
+				lockedParent = &rows[i]
+			}
+		}
+		if locked == nil || (parent != nil && lockedParent == nil) {
+			return gorm.ErrRecordNotFound
+		}
+
+		var parentID *uint
+		parentPath := "/"
+		if lockedParent != nil {
+			if locked.Contains(lockedParent) {
+				return models.ErrFolderCycle
+			}
+			parentID = &lockedParent.ID
+			parentPath = lockedParent.Path
+		}
+		newPath := fmt.Sprintf("%s%d/", parentPath, folder.ID)
+
+		if err := tx.Model(&models.Folder{}).
+			Scopes(database.ScopedTenant).
+			Where("id = ?", folder.ID).
+			Update("parent_id", parentID).Error; err != nil {
+			return err
+		}
+		if err := rewritePaths(tx, locked.Path, newPath); err != nil {
+			return err
+		}
+
+		folder.ParentID = parentID
+		folder.Path = newPath
+		return nil
+	})
+}
+
 // Delete soft deletes a folder with tenant isolation
-func (r *folderRepository) Delete(tenantID, folderID uint) error {
-	return r.db.Where("tenant_id = ? AND id = ?", tenantID, folderID).
+func (r *folderRepository) Delete(ctx context.Context, folderID uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", folderID).
 		Delete(&models.Folder{}).Error
 }
+
+// DeleteTree soft deletes folders together with their documents and document versions
+func (r *folderRepository) DeleteTree(ctx context.Context, folderIDs []uint) error {
+	if len(folderIDs) == 0 {
+		return nil
+	}
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		docIDs := tx.Model(&models.Document{}).
+			Scopes(database.ScopedTenant).
+			Where("folder_id IN ?", folderIDs).
+			Select("id")
+		if err := tx.Scopes(database.ScopedTenant).
+			Where("document_id IN (?)", docIDs).
+			Delete(&models.DocumentVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Scopes(database.ScopedTenant).
+			Where("folder_id IN ?", folderIDs).
+			Delete(&models.Document{}).Error; err != nil {
+			return err
+		}
+		return tx.Scopes(database.ScopedTenant).
+			Where("id IN ?", folderIDs).
+			Delete(&models.Folder{}).Error
+	})
+}
+
+// DeleteAndMoveContents soft deletes a folder after handing its documents and subfolders to its parent
+func (r *folderRepository) DeleteAndMoveContents(ctx context.Context, folder *models.Folder) error {
+	return database.Conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Document{}).
+			Scopes(database.ScopedTenant).
+			Where("folder_id = ?", folder.ID).
+			Update("folder_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+		// The folder goes first so a child with the same name can take its place
+		if err := tx.Scopes(database.ScopedTenant).
+			Where("id = ?", folder.ID).
+			Delete(&models.Folder{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Folder{}).
+			Scopes(database.ScopedTenant).
+			Where("parent_id = ?", folder.ID).
+			Update("parent_id", folder.ParentID).Error; err != nil {
+			return err
+		}
+
+		// Drop the folder's own segment from the paths below it
+		parentPath := strings.TrimSuffix(folder.Path, fmt.Sprintf("%d/", folder.ID))
+		return rewritePaths(tx, folder.Path, parentPath)
+	})
+}
+
+// rewritePaths replaces the oldPrefix of every path in a subtree with newPrefix
+func rewritePaths(tx *gorm.DB, oldPrefix, newPrefix string) error {
+	return tx.Model(&models.Folder{}).
+		Scopes(database.ScopedTenant).
+		Where("path LIKE ?", oldPrefix+"%").
+		Update("path", gorm.Expr("CAST(? AS VARCHAR) || SUBSTRING(path FROM CAST(? AS INTEGER))", newPrefix, len(oldPrefix)+1)).Error
+}
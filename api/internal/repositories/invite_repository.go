@@ -1,19 +1,27 @@
 package repositories
 
 import (
+	"context"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
 )
 
 // InviteRepository defines the interface for invite operations
 type InviteRepository interface {
-	Create(invite *models.Invite) error
-	GetByToken(token string) (*models.Invite, error)
-	GetByID(id uint) (*models.Invite, error)
-	GetPendingByEmail(email string) ([]models.Invite, error)
-	GetByTenant(tenantID uint) ([]models.Invite, error)
-	Update(invite *models.Invite) error
-	Delete(id uint) error
+	Create(ctx context.Context, invite *models.Invite) error
+	GetByToken(ctx context.Context, token string) (*models.Invite, error)
+	GetByID(ctx context.Context, id uint) (*models.Invite, error)
+	GetPendingByEmail(ctx context.Context, email string) ([]models.Invite, error)
+	GetByTenant(ctx context.Context) ([]models.Invite, error)
+	Update(ctx context.Context, invite *models.Invite) error
+	MarkAccepted(ctx context.Context, invite *models.Invite, userID uint, at time.Time) (bool, error)
+	Delete(ctx context.Context, id uint) error
+	CountPendingByRole(ctx context.Context, role models.UserRole) (int64, error)
+	MarkExpired(ctx context.Context, now time.Time) (int64, error)
+	GetExpiringWithoutReminder(ctx context.Context, before time.Time, limit int) ([]models.Invite, error)
 }
 
 // inviteRepository implements InviteRepository
@@ -27,14 +35,14 @@ func NewInviteRepository(db *gorm.DB) InviteRepository {
 }
 
 // Create creates a new invite
-func (r *inviteRepository) Create(invite *models.Invite) error {
-	return r.db.Create(invite).Error
+func (r *inviteRepository) Create(ctx context.Context, invite *models.Invite) error {
+	return database.Conn(ctx, r.db).Create(invite).Error
 }
 
-// GetByToken retrieves an invite by token
-func (r *inviteRepository) GetByToken(token string) (*models.Invite, error) {
+// GetByToken retrieves an invite by token (public lookup; needs a Scope with the token)
+func (r *inviteRepository) GetByToken(ctx context.Context, token string) (*models.Invite, error) {
 	var invite models.Invite
-	err := r.db.Where("token = ?", token).
+	err := database.Conn(ctx, r.db).Where("token = ?", token).
 		Preload("Tenant").
 		Preload("InvitedBy").
 		Preload("AcceptedBy").
@@ -45,10 +53,10 @@ func (r *inviteRepository) GetByToken(token string) (*models.Invite, error) {
 	return &invite, nil
 }
 
-// GetByID retrieves an invite by ID
-func (r *inviteRepository) GetByID(id uint) (*models.Invite, error) {
+// GetByID retrieves an invite by ID; callers check that it belongs to their tenant
+func (r *inviteRepository) GetByID(ctx context.Context, id uint) (*models.Invite, error) {
 	var invite models.Invite
-	err := r.db.Where("id = ?", id).
+	err := database.Conn(ctx, r.db).Where("id = ?", id).
 		Preload("Tenant").
 		Preload("InvitedBy").
 		Preload("AcceptedBy").
@@ -59,10 +67,10 @@ func (r *inviteRepository) GetByID(id uint) (*models.Invite, error) {
 	return &invite, nil
 }
 
-// GetPendingByEmail retrieves pending invites for an email
-func (r *inviteRepository) GetPendingByEmail(email string) ([]models.Invite, error) {
+// GetPendingByEmail retrieves pending invites for an email across all tenants (needs a Scope with the email)
+func (r *inviteRepository) GetPendingByEmail(ctx context.Context, email string) ([]models.Invite, error) {
 	var invites []models.Invite
-	err := r.db.Where("email = ? AND status = ?", email, models.InviteStatusPending).
+	err := database.Conn(ctx, r.db).Where("email = ? AND status = ?", email, models.InviteStatusPending).
 		Preload("Tenant").
 		Preload("InvitedBy").
 		Find(&invites).Error
@@ -70,9 +78,9 @@ func (r *inviteRepository) GetPendingByEmail(email string) ([]models.Invite, err
 }
 
 // GetByTenant retrieves all invites for a tenant
-func (r *inviteRepository) GetByTenant(tenantID uint) ([]models.Invite, error) {
+func (r *inviteRepository) GetByTenant(ctx context.Context) ([]models.Invite, error) {
 	var invites []models.Invite
-	err := r.db.Where("tenant_id = ?", tenantID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
 		Preload("InvitedBy").
 		Preload("AcceptedBy").
 		Find(&invites).Error
@@ -80,11 +88,56 @@ func (r *inviteRepository) GetByTenant(tenantID uint) ([]models.Invite, error) {
 }
 
 // Update updates an invite
-func (r *inviteRepository) Update(invite *models.Invite) error {
-	return r.db.Save(invite).Error
+func (r *inviteRepository) Update(ctx context.Context, invite *models.Invite) error {
+	return database.Conn(ctx, r.db).Save(invite).Error
+}
+
+// MarkAccepted atomically marks an invite as accepted by the user, only while it is still pending under the same token
+// Returns false when a concurrent request accepted, revoked or renewed it first
+func (r *inviteRepository) MarkAccepted(ctx context.Context, invite *models.Invite, userID uint, at time.Time) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.Invite{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ? AND token = ? AND status = ?", invite.ID, invite.Token, models.InviteStatusPending).
+		Updates(map[string]interface{}{
+			"status":              models.InviteStatusAccepted,
+			"accepted_by_user_id": userID,
+			"accepted_at":         at,
+		})
+	return result.RowsAffected == 1, result.Error
 }
 
 // Delete soft deletes an invite
-func (r *inviteRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Invite{}, id).Error
+func (r *inviteRepository) Delete(ctx context.Context, id uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).Delete(&models.Invite{}, id).Error
+}
+
+// CountPendingByRole counts the pending invites of a tenant that grant a role
+func (r *inviteRepository) CountPendingByRole(ctx context.Context, role models.UserRole) (int64, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.Invite{}).
+		Scopes(database.ScopedTenant).
+		Where("role = ? AND status = ?", role, models.InviteStatusPending).
+		Count(&count).Error
+	return count, err
+}
+
+// MarkExpired moves pending invites past their expiration to expired, across all tenants
+func (r *inviteRepository) MarkExpired(ctx context.Context, now time.Time) (int64, error) {
+	result := database.Conn(ctx, r.db).Model(&models.Invite{}).
+		Where("status = ? AND expires_at <= ?", models.InviteStatusPending, now).
+		Update("status", models.InviteStatusExpired)
+	return result.RowsAffected, result.Error
+}
+
+// GetExpiringWithoutReminder retrieves pending invites that expire before the given time
+// and have not been reminded yet, across all tenants, soonest first
+func (r *inviteRepository) GetExpiringWithoutReminder(ctx context.Context, before time.Time, limit int) ([]models.Invite, error) {
+	var invites []models.Invite
+	err := database.Conn(ctx, r.db).Where("status = ? AND expires_at > ? AND expires_at <= ? AND reminder_sent_at IS NULL",
+		models.InviteStatusPending, time.Now(), before).
+		Preload("Tenant").
+		Order("expires_at").
+		Limit(limit).
+		Find(&invites).Error
+	return invites, err
 }
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// JobRunRepository defines the interface for the run history of scheduled jobs
+// Jobs are platform-wide, so it has no tenant isolation
+type JobRunRepository interface {
+	Create(run *models.JobRun) error
+	Finish(run *models.JobRun) error
+	HasRunForSlot(jobName string, scheduledAt time.Time) (bool, error)
+	FailRunning(jobName, reason string) error
+	GetAll(jobName string, limit, offset int) ([]models.JobRun, int64, error)
+	GetLast(jobName string) (*models.JobRun, error)
+	DeleteStartedBefore(cutoff time.Time) (int64, error)
+}
+
+// jobRunRepository implements JobRunRepository
+type jobRunRepository struct {
+	db *gorm.DB
+}
+
+// NewJobRunRepository creates a new job run repository
+func NewJobRunRepository(db *gorm.DB) JobRunRepository {
+	return &jobRunRepository{db: db}
+}
+
+// Create records the start of a run
+func (r *jobRunRepository) Create(run *models.JobRun) error {
+	return r.db.Create(run).Error
+}
+
+// Finish records the outcome of a run
+func (r *jobRunRepository) Finish(run *models.JobRun) error {
+	return r.db.Model(&models.JobRun{}).
+		Where("id = ?", run.ID).
+		Updates(map[string]interface{}{
+			"finished_at": run.FinishedAt,
+			"status":      run.Status,
+			"processed":   run.Processed,
+			"error":       run.Error,
+		}).Error
+}
+
+// HasRunForSlot checks whether the job already ran for a schedule slot (or a later one)
+func (r *jobRunRepository) HasRunForSlot(jobName string, scheduledAt time.Time) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.JobRun{}).
+		Where("job_name = ? AND scheduled_at >= ?", jobName, scheduledAt).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FailRunning closes runs left "running" by a process that died mid-run
+func (r *jobRunRepository) FailRunning(jobName, reason string) error {
+	return r.db.Model(&models.JobRun{}).
+		Where("job_name = ? AND status = ?", jobName, models.JobRunRunning).
+		Updates(map[string]interface{}{
+			"finished_at": time.Now(),
+			"status":      models.JobRunFailed,
+			"error":       reason,
+		}).Error
+}
+
+// GetAll retrieves runs, newest first, optionally of one job, and the total count
+func (r *jobRunRepository) GetAll(jobName string, limit, offset int) ([]models.JobRun, int64, error) {
+	query := r.db.Model(&models.JobRun{})
+	if jobName != "" {
+		query = query.Where("job_name = ?", jobName)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []models.JobRun
+	err := query.Order("started_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&runs).Error
+	return runs, total, err
+}
+
+// GetLast retrieves the latest run of a job
+func (r *jobRunRepository) GetLast(jobName string) (*models.JobRun, error) {
+	var run models.JobRun
+	err := r.db.Where("job_name = ?", jobName).
+		Order("started_at DESC, id DESC").
+		First(&run).Error
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// DeleteStartedBefore trims the run history
+func (r *jobRunRepository) DeleteStartedBefore(cutoff time.Time) (int64, error) {
+	result := r.db.Where("started_at < ? AND status <> ?", cutoff, models.JobRunRunning).Delete(&models.JobRun{})
+	return result.RowsAffected, result.Error
+}
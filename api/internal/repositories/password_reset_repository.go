@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// PasswordResetRepository defines the interface for password reset token operations
+type PasswordResetRepository interface {
+	Create(token *models.PasswordResetToken) error
+	GetByTokenHash(hash string) (*models.PasswordResetToken, error)
+	MarkUsed(id uint) (bool, error)
+	InvalidateAllByUser(userID uint) error
+}
+
+// passwordResetRepository implements PasswordResetRepository
+type passwordResetRepository struct {
+	db *gorm.DB
+}
+
+// NewPasswordResetRepository creates a new password reset repository
+func NewPasswordResetRepository(db *gorm.DB) PasswordResetRepository {
+	return &passwordResetRepository{db: db}
+}
+
+// Create creates a new password reset token
+func (r *passwordResetRepository) Create(token *models.PasswordResetToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetByTokenHash retrieves a password reset token by its hash
+func (r *passwordResetRepository) GetByTokenHash(hash string) (*models.PasswordResetToken, error) {
+	var token models.PasswordResetToken
+	err := r.db.Where("token_hash = ?", hash).
+		First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes a token; returns false if it was already used
+func (r *passwordResetRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.PasswordResetToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateAllByUser consumes every outstanding token of a user
+func (r *passwordResetRepository) InvalidateAllByUser(userID uint) error {
+	return r.db.Model(&models.PasswordResetToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository defines the interface for 2FA recovery code operations
+type RecoveryCodeRepository interface {
+	ReplaceAll(userID uint, codes []models.TwoFactorRecoveryCode) error
+	GetUnusedByHash(userID uint, hash string) (*models.TwoFactorRecoveryCode, error)
+	MarkUsed(id uint) (bool, error)
+	DeleteAllByUser(userID uint) error
+}
+
+// recoveryCodeRepository implements RecoveryCodeRepository
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository
+func NewRecoveryCodeRepository(db *gorm.DB) RecoveryCodeRepository {
+	return &recoveryCodeRepository{db: db}
+}
+
+// ReplaceAll deletes a user's existing codes and stores the new set atomically
+func (r *recoveryCodeRepository) ReplaceAll(userID uint, codes []models.TwoFactorRecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userID).
+			Delete(&models.TwoFactorRecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// GetUnusedByHash retrieves an unused recovery code of a user by its hash
+func (r *recoveryCodeRepository) GetUnusedByHash(userID uint, hash string) (*models.TwoFactorRecoveryCode, error) {
+	var code models.TwoFactorRecoveryCode
+	err := r.db.Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		First(&code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkUsed consumes a recovery code; returns false if it was already used
+func (r *recoveryCodeRepository) MarkUsed(id uint) (bool, error) {
+	result := r.db.Model(&models.TwoFactorRecoveryCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteAllByUser permanently removes all recovery codes of a user
+func (r *recoveryCodeRepository) DeleteAllByUser(userID uint) error {
+	return r.db.Unscoped().Where("user_id = ?", userID).
+		Delete(&models.TwoFactorRecoveryCode{}).Error
+}
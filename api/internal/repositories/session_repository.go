@@ -0,0 +1,106 @@
+package repositories
+
+import (
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// SessionRepository defines the interface for session operations
+type SessionRepository interface {
+	Create(session *models.Session) error
+	GetByID(id uint) (*models.Session, error)
+	GetByRefreshTokenHash(hash string) (*models.Session, error)
+	Update(session *models.Session) error
+	RotateRefreshToken(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error)
+	Revoke(id uint) error
+	RevokeAllByUser(userID uint) error
+	RevokeAllByUserAndTenant(userID, tenantID uint) error
+	RevokeAllByTenant(tenantID uint) error
+}
+
+// sessionRepository implements SessionRepository
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository creates a new session repository
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+// Create creates a new session
+func (r *sessionRepository) Create(session *models.Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetByID retrieves a session by ID
+func (r *sessionRepository) GetByID(id uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetByRefreshTokenHash retrieves a session by the hash of its current refresh token
+func (r *sessionRepository) GetByRefreshTokenHash(hash string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("refresh_token_hash = ?", hash).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Update updates a session
+func (r *sessionRepository) Update(session *models.Session) error {
+	return r.db.Save(session).Error
+}
+
+// RotateRefreshToken swaps the refresh token hash only if it still matches oldHash
+// Returns false when another request already rotated the token (concurrent refresh or reuse)
+func (r *sessionRepository) RotateRefreshToken(id uint, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash": newHash,
+			"expires_at":         expiresAt,
+			"last_used_at":       time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Revoke marks a single session as revoked
+func (r *sessionRepository) Revoke(id uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllByUser revokes every active session of a user
+func (r *sessionRepository) RevokeAllByUser(userID uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllByUserAndTenant revokes the active sessions a user holds in a specific tenant
+func (r *sessionRepository) RevokeAllByUserAndTenant(userID, tenantID uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND tenant_id = ? AND revoked_at IS NULL", userID, tenantID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllByTenant revokes every active session bound to a tenant
+func (r *sessionRepository) RevokeAllByTenant(tenantID uint) error {
+	return r.db.Model(&models.Session{}).
+		Where("tenant_id = ? AND revoked_at IS NULL", tenantID).
+		Update("revoked_at", time.Now()).Error
+}
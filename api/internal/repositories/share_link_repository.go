@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// activeShareLinkCondition matches links that can still be opened
+const activeShareLinkCondition = "revoked_at IS NULL AND expires_at > NOW() AND (max_downloads IS NULL OR download_count < max_downloads)"
+
+// ShareLinkRepository defines the interface for document share link operations
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *models.DocumentShareLink) error
+	GetByID(ctx context.Context, linkID uint) (*models.DocumentShareLink, error)
+	GetByTokenHash(ctx context.Context, hash string) (*models.DocumentShareLink, error)
+	GetAll(ctx context.Context, docID *uint, activeOnly bool) ([]models.DocumentShareLink, error)
+	Revoke(ctx context.Context, linkID uint) (bool, error)
+	ConsumeDownload(ctx context.Context, link *models.DocumentShareLink) (bool, error)
+	LogAccess(ctx context.Context, access *models.DocumentShareLinkAccess) error
+	GetAccesses(ctx context.Context, linkID uint) ([]models.DocumentShareLinkAccess, error)
+}
+
+// shareLinkRepository implements ShareLinkRepository
+type shareLinkRepository struct {
+	db *gorm.DB
+}
+
+// NewShareLinkRepository creates a new share link repository
+func NewShareLinkRepository(db *gorm.DB) ShareLinkRepository {
+	return &shareLinkRepository{db: db}
+}
+
+// Create creates a new share link
+func (r *shareLinkRepository) Create(ctx context.Context, link *models.DocumentShareLink) error {
+	return database.Conn(ctx, r.db).Create(link).Error
+}
+
+// GetByID retrieves a share link by ID with tenant isolation
+func (r *shareLinkRepository) GetByID(ctx context.Context, linkID uint) (*models.DocumentShareLink, error) {
+	var link models.DocumentShareLink
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", linkID).
+		First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetByTokenHash retrieves a share link and its document by the token hash
+// Used by the public route, so it needs a Scope with the token hash
+func (r *shareLinkRepository) GetByTokenHash(ctx context.Context, hash string) (*models.DocumentShareLink, error) {
+	var link models.DocumentShareLink
+	err := database.Conn(ctx, r.db).Where("token_hash = ?", hash).
+		Preload("Document").
+		First(&link).Error
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// GetAll retrieves the share links of a tenant, newest first, optionally for one document or only the active ones
+func (r *shareLinkRepository) GetAll(ctx context.Context, docID *uint, activeOnly bool) ([]models.DocumentShareLink, error) {
+	var links []models.DocumentShareLink
+	query := database.Conn(ctx, r.db).Scopes(database.ScopedTenant)
+	if docID != nil {
+		query = query.Where("document_id = ?", *docID)
+	}
+	if activeOnly {
+		query = query.Where(activeShareLinkCondition)
+	}
+	err := query.
+		Preload("Document").
+		Preload("CreatedBy").
+		Order("created_at DESC").
+		Find(&links).Error
+	return links, err
+}
+
+// Revoke disables a share link; returns false if it was already revoked
+func (r *shareLinkRepository) Revoke(ctx context.Context, linkID uint) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.DocumentShareLink{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ? AND revoked_at IS NULL", linkID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// ConsumeDownload counts a download if the link is still active
+// The check and the increment are a single statement, so concurrent downloads can't exceed the maximum
+func (r *shareLinkRepository) ConsumeDownload(ctx context.Context, link *models.DocumentShareLink) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.DocumentShareLink{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ? AND "+activeShareLinkCondition, link.ID).
+		UpdateColumns(map[string]interface{}{
+			"download_count":   gorm.Expr("download_count + 1"),
+			"last_accessed_at": time.Now(),
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+// LogAccess records an attempt to open a share link
+func (r *shareLinkRepository) LogAccess(ctx context.Context, access *models.DocumentShareLinkAccess) error {
+	return database.Conn(ctx, r.db).Create(access).Error
+}
+
+// GetAccesses retrieves the access log of a share link, newest first
+func (r *shareLinkRepository) GetAccesses(ctx context.Context, linkID uint) ([]models.DocumentShareLinkAccess, error) {
+	var accesses []models.DocumentShareLinkAccess
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("share_link_id = ?", linkID).
+		Order("created_at DESC").
+		Find(&accesses).Error
+	return accesses, err
+}
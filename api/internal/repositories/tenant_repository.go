@@ -1,6 +1,9 @@
 package repositories
 
 import (
+	"context"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
 )
@@ -13,6 +16,10 @@ type TenantRepository interface {
 	GetAll() ([]models.Tenant, error)
 	Update(tenant *models.Tenant) error
 	Delete(id uint) error
+	GetStorage(ctx context.Context, id uint) (*models.Tenant, error)
+	ReserveStorage(ctx context.Context, id uint, size, defaultQuota int64) (bool, error)
+	ReleaseStorage(ctx context.Context, id uint, size int64) error
+	SwapStorageWarningLevel(ctx context.Context, id uint, from, to int) (bool, error)
 }
 
 // tenantRepository implements TenantRepository
@@ -58,11 +65,54 @@ func (r *tenantRepository) GetAll() ([]models.Tenant, error) {
 }
 
 // Update updates a tenant
+// Storage usage columns are left alone so a stale copy can't undo concurrent reservations
 func (r *tenantRepository) Update(tenant *models.Tenant) error {
-	return r.db.Save(tenant).Error
+	return r.db.Omit("storage_used_bytes", "storage_warning_level").Save(tenant).Error
 }
 
 // Delete soft deletes a tenant
 func (r *tenantRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Tenant{}, id).Error
 }
+
+// The storage methods run on the transaction carried by ctx, so the usage counter
+// commits or rolls back together with the document rows it accounts for
+
+// GetStorage retrieves a tenant on the transaction carried by ctx, seeing its uncommitted storage changes
+func (r *tenantRepository) GetStorage(ctx context.Context, id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := database.Conn(ctx, r.db).First(&tenant, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// ReserveStorage atomically adds size to the tenant's storage usage unless it would exceed the quota
+// defaultQuota applies when the tenant has no quota of its own; a quota of 0 is unlimited
+func (r *tenantRepository) ReserveStorage(ctx context.Context, id uint, size, defaultQuota int64) (bool, error) {
+	result := database.Conn(ctx, r.db).Exec(
+		`UPDATE tenants SET storage_used_bytes = storage_used_bytes + ?
+		WHERE id = ? AND (COALESCE(storage_quota_bytes, ?) = 0
+			OR storage_used_bytes + ? <= COALESCE(storage_quota_bytes, ?))`,
+		size, id, defaultQuota, size, defaultQuota,
+	)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseStorage atomically subtracts size from the tenant's storage usage
+func (r *tenantRepository) ReleaseStorage(ctx context.Context, id uint, size int64) error {
+	return database.Conn(ctx, r.db).Exec(
+		"UPDATE tenants SET storage_used_bytes = GREATEST(storage_used_bytes - ?, 0) WHERE id = ?",
+		size, id,
+	).Error
+}
+
+// SwapStorageWarningLevel sets the storage warning level only if it is still from
+// Returns false when another request changed it first
+func (r *tenantRepository) SwapStorageWarningLevel(ctx context.Context, id uint, from, to int) (bool, error) {
+	result := database.Conn(ctx, r.db).Model(&models.Tenant{}).
+		Where("id = ? AND storage_warning_level = ?", id, from).
+		UpdateColumn("storage_warning_level", to)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// currentUnitMemberCondition selects memberships in effect today
+const currentUnitMemberCondition = "start_date <= CURRENT_DATE AND (end_date IS NULL OR end_date > CURRENT_DATE)"
+
+// UnitMemberRepository defines the interface for unit membership operations
+type UnitMemberRepository interface {
+	Create(ctx context.Context, member *models.UnitMember) error
+	GetByID(ctx context.Context, memberID uint) (*models.UnitMember, error)
+	GetByUnit(ctx context.Context, unitID uint, includePast bool) ([]models.UnitMember, error)
+	GetCurrentByUser(ctx context.Context, userID uint) ([]models.UnitMember, error)
+	GetCurrentByUsers(ctx context.Context, userIDs []uint) ([]models.UnitMember, error)
+	Update(ctx context.Context, member *models.UnitMember) error
+	Delete(ctx context.Context, memberID uint) error
+}
+
+// unitMemberRepository implements UnitMemberRepository
+type unitMemberRepository struct {
+	db *gorm.DB
+}
+
+// NewUnitMemberRepository creates a new unit membership repository
+func NewUnitMemberRepository(db *gorm.DB) UnitMemberRepository {
+	return &unitMemberRepository{db: db}
+}
+
+// Create creates a new unit membership
+func (r *unitMemberRepository) Create(ctx context.Context, member *models.UnitMember) error {
+	return database.Conn(ctx, r.db).Create(member).Error
+}
+
+// GetByID retrieves a unit membership by ID with tenant isolation
+func (r *unitMemberRepository) GetByID(ctx context.Context, memberID uint) (*models.UnitMember, error) {
+	var member models.UnitMember
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", memberID).
+		Preload("User").
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// GetByUnit retrieves the memberships of a unit, optionally including ended ones
+func (r *unitMemberRepository) GetByUnit(ctx context.Context, unitID uint, includePast bool) ([]models.UnitMember, error) {
+	var members []models.UnitMember
+	query := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("unit_id = ?", unitID)
+	if !includePast {
+		query = query.Where(currentUnitMemberCondition)
+	}
+	err := query.
+		Preload("User").
+		Order("start_date ASC, id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// GetCurrentByUser retrieves the memberships of a user in effect today
+func (r *unitMemberRepository) GetCurrentByUser(ctx context.Context, userID uint) ([]models.UnitMember, error) {
+	return r.GetCurrentByUsers(ctx, []uint{userID})
+}
+
+// GetCurrentByUsers retrieves the memberships in effect today for several users
+func (r *unitMemberRepository) GetCurrentByUsers(ctx context.Context, userIDs []uint) ([]models.UnitMember, error) {
+	var members []models.UnitMember
+	if len(userIDs) == 0 {
+		return members, nil
+	}
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("user_id IN ?", userIDs).
+		Where(currentUnitMemberCondition).
+		Preload("Unit").
+		Order("start_date ASC, id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// Update updates a unit membership
+func (r *unitMemberRepository) Update(ctx context.Context, member *models.UnitMember) error {
+	return database.Conn(ctx, r.db).Model(&models.UnitMember{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", member.ID).
+		Updates(map[string]interface{}{
+			"relationship":          member.Relationship,
+			"start_date":            member.StartDate,
+			"end_date":              member.EndDate,
+			"responsible_for_bills": member.ResponsibleForBills,
+		}).Error
+}
+
+// Delete soft deletes a unit membership
+func (r *unitMemberRepository) Delete(ctx context.Context, memberID uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", memberID).
+		Delete(&models.UnitMember{}).Error
+}
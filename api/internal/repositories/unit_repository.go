@@ -1,19 +1,21 @@
 package repositories
 
 import (
+	"context"
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
 )
 
 // UnitRepository defines the interface for unit operations
 type UnitRepository interface {
-	Create(unit *models.Unit) error
-	GetByID(tenantID, unitID uint) (*models.Unit, error)
-	GetByNumber(tenantID uint, number string) (*models.Unit, error)
-	GetAll(tenantID uint) ([]models.Unit, error)
-	GetByBlock(tenantID uint, block string) ([]models.Unit, error)
-	Update(unit *models.Unit) error
-	Delete(tenantID, unitID uint) error
+	Create(ctx context.Context, unit *models.Unit) error
+	GetByID(ctx context.Context, unitID uint) (*models.Unit, error)
+	GetByNumber(ctx context.Context, number string) (*models.Unit, error)
+	GetAll(ctx context.Context) ([]models.Unit, error)
+	GetByBlock(ctx context.Context, block string) ([]models.Unit, error)
+	Update(ctx context.Context, unit *models.Unit) error
+	Delete(ctx context.Context, unitID uint) error
 }
 
 // unitRepository implements UnitRepository
@@ -27,15 +29,17 @@ func NewUnitRepository(db *gorm.DB) UnitRepository {
 }
 
 // Create creates a new unit
-func (r *unitRepository) Create(unit *models.Unit) error {
-	return r.db.Create(unit).Error
+func (r *unitRepository) Create(ctx context.Context, unit *models.Unit) error {
+	return database.Conn(ctx, r.db).Create(unit).Error
 }
 
 // GetByID retrieves a unit by ID with tenant isolation
-func (r *unitRepository) GetByID(tenantID, unitID uint) (*models.Unit, error) {
+func (r *unitRepository) GetByID(ctx context.Context, unitID uint) (*models.Unit, error) {
 	var unit models.Unit
-	err := r.db.Where("tenant_id = ? AND id = ?", tenantID, unitID).
-		Preload("Users").
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", unitID).
+		Preload("Members", currentUnitMemberCondition).
+		Preload("Members.User").
 		First(&unit).Error
 	if err != nil {
 		return nil, err
@@ -44,9 +48,10 @@ func (r *unitRepository) GetByID(tenantID, unitID uint) (*models.Unit, error) {
 }
 
 // GetByNumber retrieves a unit by number with tenant isolation
-func (r *unitRepository) GetByNumber(tenantID uint, number string) (*models.Unit, error) {
+func (r *unitRepository) GetByNumber(ctx context.Context, number string) (*models.Unit, error) {
 	var unit models.Unit
-	err := r.db.Where("tenant_id = ? AND number = ?", tenantID, number).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("number = ?", number).
 		First(&unit).Error
 	if err != nil {
 		return nil, err
@@ -55,34 +60,37 @@ func (r *unitRepository) GetByNumber(tenantID uint, number string) (*models.Unit
 }
 
 // GetAll retrieves all units for a tenant
-func (r *unitRepository) GetAll(tenantID uint) ([]models.Unit, error) {
+func (r *unitRepository) GetAll(ctx context.Context) ([]models.Unit, error) {
 	var units []models.Unit
-	err := r.db.Where("tenant_id = ?", tenantID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
 		Find(&units).Error
 	return units, err
 }
 
 // GetByBlock retrieves units by block with tenant isolation
-func (r *unitRepository) GetByBlock(tenantID uint, block string) ([]models.Unit, error) {
+func (r *unitRepository) GetByBlock(ctx context.Context, block string) ([]models.Unit, error) {
 	var units []models.Unit
-	err := r.db.Where("tenant_id = ? AND block = ?", tenantID, block).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("block = ?", block).
 		Find(&units).Error
 	return units, err
 }
 
 // Update updates a unit (validates tenant_id to prevent cross-tenant updates)
-func (r *unitRepository) Update(unit *models.Unit) error {
+func (r *unitRepository) Update(ctx context.Context, unit *models.Unit) error {
 	// Use Select("*") to include zero-value fields (e.g. bool false)
 	// in the UPDATE query — otherwise GORM skips them.
-	return r.db.Model(&models.Unit{}).
-		Where("tenant_id = ? AND id = ?", unit.TenantID, unit.ID).
+	return database.Conn(ctx, r.db).Model(&models.Unit{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", unit.ID).
 		Select("*").
 		Omit("created_at", "Tenant", "Users").
 		Updates(unit).Error
 }
 
 // Delete soft deletes a unit with tenant isolation
-func (r *unitRepository) Delete(tenantID, unitID uint) error {
-	return r.db.Where("tenant_id = ? AND id = ?", tenantID, unitID).
+func (r *unitRepository) Delete(ctx context.Context, unitID uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", unitID).
 		Delete(&models.Unit{}).Error
 }
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"gorm.io/gorm"
+)
+
+// UploadSessionRepository defines the interface for upload session operations
+type UploadSessionRepository interface {
+	Create(ctx context.Context, session *models.UploadSession) error
+	GetByID(ctx context.Context, sessionID uint) (*models.UploadSession, error)
+	GetExpiredPending(ctx context.Context, before time.Time, limit int) ([]models.UploadSession, error)
+	Update(ctx context.Context, session *models.UploadSession) error
+}
+
+// uploadSessionRepository implements UploadSessionRepository
+type uploadSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadSessionRepository creates a new upload session repository
+func NewUploadSessionRepository(db *gorm.DB) UploadSessionRepository {
+	return &uploadSessionRepository{db: db}
+}
+
+// Create creates a new upload session
+func (r *uploadSessionRepository) Create(ctx context.Context, session *models.UploadSession) error {
+	return database.Conn(ctx, r.db).Create(session).Error
+}
+
+// GetByID retrieves an upload session by ID with tenant isolation
+func (r *uploadSessionRepository) GetByID(ctx context.Context, sessionID uint) (*models.UploadSession, error) {
+	var session models.UploadSession
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("id = ?", sessionID).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetExpiredPending retrieves pending sessions of every tenant that expired before the given time
+func (r *uploadSessionRepository) GetExpiredPending(ctx context.Context, before time.Time, limit int) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := database.Conn(ctx, r.db).
+		Where("status = ? AND expires_at < ?", models.UploadSessionPending, before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Update updates the status fields of an upload session
+func (r *uploadSessionRepository) Update(ctx context.Context, session *models.UploadSession) error {
+	return database.Conn(ctx, r.db).Model(&models.UploadSession{}).
+		Scopes(database.ScopedTenant).
+		Where("id = ?", session.ID).
+		Updates(map[string]interface{}{
+			"status":       session.Status,
+			"document_id":  session.DocumentID,
+			"completed_at": session.CompletedAt,
+		}).Error
+}
@@ -1,6 +1,8 @@
 package repositories
 
 import (
+	"time"
+
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
 )
@@ -10,8 +12,13 @@ type UserRepository interface {
 	Create(user *models.User) error
 	GetByID(userID uint) (*models.User, error)
 	GetByEmail(email string) (*models.User, error)
-	GetByEmailWithTenants(email string) (*models.User, error)
 	Update(user *models.User) error
+	IncrementFailedLogins(userID uint) (int, error)
+	Lock(userID uint, until time.Time) error
+	ResetFailedLogins(userID uint) error
+	ConsumeTOTPStep(userID uint, step int64) (bool, error)
+	SetTwoFactorChallenge(userID uint, challengeID string) error
+	ConsumeTwoFactorChallenge(userID uint, challengeID string) (bool, error)
 	Delete(userID uint) error
 }
 
@@ -34,7 +41,6 @@ func (r *userRepository) Create(user *models.User) error {
 func (r *userRepository) GetByID(userID uint) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("id = ?", userID).
-		Preload("Unit").
 		First(&user).Error
 	if err != nil {
 		return nil, err
@@ -46,22 +52,6 @@ func (r *userRepository) GetByID(userID uint) (*models.User, error) {
 func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	var user models.User
 	err := r.db.Where("email = ?", email).
-		Preload("Unit").
-		First(&user).Error
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
-}
-
-// GetByEmailWithTenants retrieves a user by email with all their tenants preloaded
-func (r *userRepository) GetByEmailWithTenants(email string) (*models.User, error) {
-	var user models.User
-	err := r.db.Where("email = ?", email).
-		Preload("UserTenants").
-		Preload("UserTenants.Tenant").
-		Preload("Tenants").
-		Preload("Unit").
 		First(&user).Error
 	if err != nil {
 		return nil, err
@@ -70,11 +60,69 @@ func (r *userRepository) GetByEmailWithTenants(email string) (*models.User, erro
 }
 
 // Update updates a user
+// The two-factor replay columns are left alone so a stale copy can't make a used code or challenge valid again
 func (r *userRepository) Update(user *models.User) error {
-	return r.db.Save(user).Error
+	return r.db.Omit("two_factor_last_step", "two_factor_challenge_id").Save(user).Error
+}
+
+// IncrementFailedLogins atomically increments the failed login counter and returns the new value
+func (r *userRepository) IncrementFailedLogins(userID uint) (int, error) {
+	var attempts int
+	err := r.db.Raw(
+		"UPDATE users SET failed_login_attempts = failed_login_attempts + 1 WHERE id = ? RETURNING failed_login_attempts",
+		userID,
+	).Scan(&attempts).Error
+	return attempts, err
+}
+
+// Lock locks the account until the given time, clearing the failed counter and bumping the lockout count
+func (r *userRepository) Lock(userID uint, until time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"lockout_count":         gorm.Expr("lockout_count + 1"),
+			"locked_until":          until,
+		}).Error
+}
+
+// ResetFailedLogins clears the failed login counter, lockout count and any lock
+func (r *userRepository) ResetFailedLogins(userID uint) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"failed_login_attempts": 0,
+			"lockout_count":         0,
+			"locked_until":          nil,
+		}).Error
 }
 
 // Delete soft deletes a user
 func (r *userRepository) Delete(userID uint) error {
 	return r.db.Delete(&models.User{}, userID).Error
 }
+
+// ConsumeTOTPStep atomically records step as the user's last accepted TOTP step
+// Returns false when a code from that step or a later one was already accepted
+func (r *userRepository) ConsumeTOTPStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", userID, step).
+		UpdateColumn("two_factor_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// SetTwoFactorChallenge records the login challenge awaiting a code, replacing any previous one
+func (r *userRepository) SetTwoFactorChallenge(userID uint, challengeID string) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ?", userID).
+		UpdateColumn("two_factor_challenge_id", challengeID).Error
+}
+
+// ConsumeTwoFactorChallenge atomically clears the login challenge if it is still the one awaiting a code
+// Returns false when it was already used or replaced by a newer login
+func (r *userRepository) ConsumeTwoFactorChallenge(userID uint, challengeID string) (bool, error) {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND two_factor_challenge_id = ?", userID, challengeID).
+		UpdateColumn("two_factor_challenge_id", nil)
+	return result.RowsAffected == 1, result.Error
+}
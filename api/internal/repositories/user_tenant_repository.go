@@ -1,21 +1,25 @@
 package repositories
 
 import (
+	"context"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"gorm.io/gorm"
 )
 
 // UserTenantRepository defines the interface for user-tenant relationship operations
 type UserTenantRepository interface {
-	Create(userTenant *models.UserTenant) error
-	GetByUserAndTenant(userID, tenantID uint) (*models.UserTenant, error)
-	GetAllByUser(userID uint) ([]models.UserTenant, error)
-	GetAllByTenant(tenantID uint) ([]models.UserTenant, error)
-	GetAllByTenantPaginated(tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error)
-	Update(userTenant *models.UserTenant) error
-	UpdateIsActive(userID, tenantID uint, isActive bool) error
-	Delete(userID, tenantID uint) error
-	UserBelongsToTenant(userID, tenantID uint) (bool, error)
+	Create(ctx context.Context, userTenant *models.UserTenant) error
+	GetByUserAndTenant(ctx context.Context, userID, tenantID uint) (*models.UserTenant, error)
+	GetAllByUser(ctx context.Context, userID uint) ([]models.UserTenant, error)
+	GetAllByTenant(ctx context.Context) ([]models.UserTenant, error)
+	GetAllByTenantPaginated(ctx context.Context, page, perPage int, search string) ([]models.UserTenant, int64, error)
+	Update(ctx context.Context, userTenant *models.UserTenant) error
+	UpdateIsActive(ctx context.Context, userID uint, isActive bool) error
+	Delete(ctx context.Context, userID uint) error
+	UserBelongsToTenant(ctx context.Context, userID uint) (bool, error)
+	CountByRole(ctx context.Context, role models.UserRole) (int64, error)
 }
 
 // userTenantRepository implements UserTenantRepository
@@ -29,14 +33,16 @@ func NewUserTenantRepository(db *gorm.DB) UserTenantRepository {
 }
 
 // Create creates a new user-tenant relationship
-func (r *userTenantRepository) Create(userTenant *models.UserTenant) error {
-	return r.db.Create(userTenant).Error
+func (r *userTenantRepository) Create(ctx context.Context, userTenant *models.UserTenant) error {
+	return database.Conn(ctx, r.db).Create(userTenant).Error
 }
 
 // GetByUserAndTenant retrieves a user-tenant relationship
-func (r *userTenantRepository) GetByUserAndTenant(userID, tenantID uint) (*models.UserTenant, error) {
+// Also used when switching tenants, so it looks the tenant up instead of using the scoped one
+func (r *userTenantRepository) GetByUserAndTenant(ctx context.Context, userID, tenantID uint) (*models.UserTenant, error) {
 	var userTenant models.UserTenant
-	err := r.db.Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+	err := database.Conn(ctx, r.db).
+		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
 		Preload("User").
 		Preload("Tenant").
 		First(&userTenant).Error
@@ -47,30 +53,31 @@ func (r *userTenantRepository) GetByUserAndTenant(userID, tenantID uint) (*model
 }
 
 // GetAllByUser retrieves all tenants for a user
-func (r *userTenantRepository) GetAllByUser(userID uint) ([]models.UserTenant, error) {
+// It spans tenants, so it needs a Scope with the user
+func (r *userTenantRepository) GetAllByUser(ctx context.Context, userID uint) ([]models.UserTenant, error) {
 	var userTenants []models.UserTenant
-	err := r.db.Where("user_id = ?", userID).
+	err := database.Conn(ctx, r.db).Where("user_id = ?", userID).
 		Preload("Tenant").
 		Find(&userTenants).Error
 	return userTenants, err
 }
 
 // GetAllByTenant retrieves all users for a tenant
-func (r *userTenantRepository) GetAllByTenant(tenantID uint) ([]models.UserTenant, error) {
+func (r *userTenantRepository) GetAllByTenant(ctx context.Context) ([]models.UserTenant, error) {
 	var userTenants []models.UserTenant
-	err := r.db.Where("tenant_id = ?", tenantID).
+	err := database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
 		Preload("User").
 		Find(&userTenants).Error
 	return userTenants, err
 }
 
 // GetAllByTenantPaginated retrieves users for a tenant with pagination and search
-func (r *userTenantRepository) GetAllByTenantPaginated(tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error) {
+func (r *userTenantRepository) GetAllByTenantPaginated(ctx context.Context, page, perPage int, search string) ([]models.UserTenant, int64, error) {
 	var userTenants []models.UserTenant
 	var total int64
 
-	baseQuery := r.db.Model(&models.UserTenant{}).
-		Where("user_tenants.tenant_id = ?", tenantID).
+	baseQuery := database.Conn(ctx, r.db).Model(&models.UserTenant{}).
+		Where("user_tenants.tenant_id = " + database.CurrentTenantSQL).
 		Joins("JOIN users ON users.id = user_tenants.user_id AND users.deleted_at IS NULL")
 
 	if search != "" {
@@ -87,38 +94,55 @@ func (r *userTenantRepository) GetAllByTenantPaginated(tenantID uint, page, perP
 		Offset(offset).
 		Limit(perPage).
 		Find(&userTenants).Error
+	if err != nil {
+		return nil, 0, err
+	}
 
-	return userTenants, total, err
+	return userTenants, total, nil
 }
 
 // Update updates a user-tenant relationship
-func (r *userTenantRepository) Update(userTenant *models.UserTenant) error {
-	return r.db.Model(&models.UserTenant{}).
-		Where("user_id = ? AND tenant_id = ?", userTenant.UserID, userTenant.TenantID).
+func (r *userTenantRepository) Update(ctx context.Context, userTenant *models.UserTenant) error {
+	return database.Conn(ctx, r.db).Model(&models.UserTenant{}).
+		Scopes(database.ScopedTenant).
+		Where("user_id = ?", userTenant.UserID).
 		Updates(userTenant).Error
 }
 
 // UpdateIsActive updates the is_active field for a user-tenant relationship
-func (r *userTenantRepository) UpdateIsActive(userID, tenantID uint, isActive bool) error {
-	return r.db.Model(&models.UserTenant{}).
-		Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+func (r *userTenantRepository) UpdateIsActive(ctx context.Context, userID uint, isActive bool) error {
+	return database.Conn(ctx, r.db).Model(&models.UserTenant{}).
+		Scopes(database.ScopedTenant).
+		Where("user_id = ?", userID).
 		Update("is_active", isActive).Error
 }
 
 // Delete removes a user-tenant relationship
-func (r *userTenantRepository) Delete(userID, tenantID uint) error {
-	return r.db.Where("user_id = ? AND tenant_id = ?", userID, tenantID).
+func (r *userTenantRepository) Delete(ctx context.Context, userID uint) error {
+	return database.Conn(ctx, r.db).Scopes(database.ScopedTenant).
+		Where("user_id = ?", userID).
 		Delete(&models.UserTenant{}).Error
 }
 
-// UserBelongsToTenant checks if a user belongs to a tenant
-func (r *userTenantRepository) UserBelongsToTenant(userID, tenantID uint) (bool, error) {
+// UserBelongsToTenant checks if a user is an active member of the scoped tenant
+func (r *userTenantRepository) UserBelongsToTenant(ctx context.Context, userID uint) (bool, error) {
 	var count int64
-	err := r.db.Model(&models.UserTenant{}).
-		Where("user_id = ? AND tenant_id = ? AND is_active = ?", userID, tenantID, true).
+	err := database.Conn(ctx, r.db).Model(&models.UserTenant{}).
+		Scopes(database.ScopedTenant).
+		Where("user_id = ? AND is_active = ?", userID, true).
 		Count(&count).Error
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
+
+// CountByRole counts the memberships of a tenant that use a role
+func (r *userTenantRepository) CountByRole(ctx context.Context, role models.UserRole) (int64, error) {
+	var count int64
+	err := database.Conn(ctx, r.db).Model(&models.UserTenant{}).
+		Scopes(database.ScopedTenant).
+		Where("role = ?", role).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,223 @@
+// Package scheduler runs background jobs on cron-like schedules.
+// Every replica of the API runs the scheduler; a PostgreSQL advisory lock per job and the run history
+// make sure each schedule slot is run by only one of them.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// jobLockNamespace is the first key of the PostgreSQL advisory locks held while a job runs;
+// the second is the hash of the job name
+const jobLockNamespace int32 = 48_261_103
+
+// JobFunc is the work of a job; it returns how many rows or emails it handled
+// It should stop early when ctx is cancelled (the server is shutting down)
+type JobFunc func(ctx context.Context) (int, error)
+
+// JobInfo describes a registered job
+type JobInfo struct {
+	Name     string         `json:"name"`
+	Schedule string         `json:"schedule"`
+	NextRun  time.Time      `json:"next_run"`
+	LastRun  *models.JobRun `json:"last_run,omitempty"`
+}
+
+// job is a registered job
+type job struct {
+	name     string
+	schedule *Schedule
+	run      JobFunc
+}
+
+// Scheduler runs the registered jobs on their schedules until stopped
+type Scheduler struct {
+	db      *gorm.DB
+	runRepo repositories.JobRunRepository
+	jobs    []*job
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New creates a scheduler; register jobs before calling Start
+func New(db *gorm.DB, runRepo repositories.JobRunRepository) *Scheduler {
+	return &Scheduler{
+		db:      db,
+		runRepo: runRepo,
+	}
+}
+
+// Register adds a job that runs on the cron schedule spec (UTC)
+func (s *Scheduler) Register(name, spec string, run JobFunc) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %s is already registered", name)
+		}
+	}
+	s.jobs = append(s.jobs, &job{name: name, schedule: schedule, run: run})
+	return nil
+}
+
+// Start runs each job in its own goroutine until ctx is cancelled or Stop is called
+// A slot missed while the server was down is not made up; the job runs at its next slot
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go func(j *job) {
+			defer s.wg.Done()
+			s.loop(ctx, j)
+		}(j)
+	}
+	log.Printf("Scheduler started with %d jobs", len(s.jobs))
+}
+
+// Stop cancels the running jobs and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// loop waits for each slot of a job's schedule and runs it
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	for {
+		slot := j.schedule.Next(time.Now())
+		if slot.IsZero() {
+			log.Printf("Job %s: schedule %s never matches", j.name, j.schedule)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(slot))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.runSlot(ctx, j, slot); err != nil {
+			log.Printf("Job %s: %v", j.name, err)
+		}
+	}
+}
+
+// runSlot runs a job for one schedule slot, unless another replica is running it or already did
+// The advisory lock is held on a dedicated connection for the whole run, and released with it if the process dies
+func (s *Scheduler) runSlot(ctx context.Context, j *job, slot time.Time) error {
+	return s.db.Connection(func(conn *gorm.DB) error {
+		var locked bool
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?, hashtext(?))", jobLockNamespace, j.name).Scan(&locked).Error; err != nil {
+			return fmt.Errorf("failed to acquire job lock: %w", err)
+		}
+		if !locked {
+			// Another replica is running it
+			return nil
+		}
+		defer func() {
+			if err := conn.Exec("SELECT pg_advisory_unlock(?, hashtext(?))", jobLockNamespace, j.name).Error; err != nil {
+				log.Printf("WARNING: failed to release lock of job %s: %v", j.name, err)
+			}
+		}()
+
+		done, err := s.runRepo.HasRunForSlot(j.name, slot)
+		if err != nil {
+			return fmt.Errorf("failed to check run history: %w", err)
+		}
+		if done {
+			// Another replica ran it while this one waited for the lock
+			return nil
+		}
+
+		// Holding the lock means nobody else is running the job, so a run still marked running was interrupted
+		if err := s.runRepo.FailRunning(j.name, "interrupted"); err != nil {
+			return fmt.Errorf("failed to close interrupted runs: %w", err)
+		}
+
+		return s.execute(ctx, j, slot)
+	})
+}
+
+// execute runs the job and records the run
+func (s *Scheduler) execute(ctx context.Context, j *job, slot time.Time) error {
+	run := &models.JobRun{
+		JobName:     j.name,
+		ScheduledAt: slot,
+		StartedAt:   time.Now(),
+		Status:      models.JobRunRunning,
+	}
+	if err := s.runRepo.Create(run); err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	processed, err := s.safeRun(ctx, j)
+	finished := time.Now()
+	run.FinishedAt = &finished
+	run.Processed = processed
+	run.Status = models.JobRunSucceeded
+	if err != nil {
+		run.Status = models.JobRunFailed
+		run.Error = err.Error()
+		log.Printf("Job %s failed after %s: %v", j.name, finished.Sub(run.StartedAt).Round(time.Millisecond), err)
+	} else if processed > 0 {
+		log.Printf("Job %s processed %d in %s", j.name, processed, finished.Sub(run.StartedAt).Round(time.Millisecond))
+	}
+
+	if err := s.runRepo.Finish(run); err != nil {
+		return fmt.Errorf("failed to record run outcome: %w", err)
+	}
+	return nil
+}
+
+// safeRun runs the job, turning a panic into a failed run instead of taking the server down
+func (s *Scheduler) safeRun(ctx context.Context, j *job) (processed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.run(ctx)
+}
+
+// Jobs describes the registered jobs with their next and last runs
+func (s *Scheduler) Jobs() ([]JobInfo, error) {
+	now := time.Now()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		info := JobInfo{
+			Name:     j.name,
+			Schedule: j.schedule.String(),
+			NextRun:  j.schedule.Next(now),
+		}
+		last, err := s.runRepo.GetLast(j.name)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get last run: %w", err)
+		}
+		info.LastRun = last
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// Runs retrieves the run history, newest first, optionally of one job
+func (s *Scheduler) Runs(jobName string, page, perPage int) ([]models.JobRun, int64, error) {
+	runs, total, err := s.runRepo.GetAll(jobName, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get job runs: %w", err)
+	}
+	return runs, total, nil
+}
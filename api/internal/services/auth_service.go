@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
 	"github.com/arturbaldoramos/Habitta/pkg/utils"
@@ -19,9 +23,65 @@ type LoginRequest struct {
 
 // LoginResponse represents the login response
 type LoginResponse struct {
-	Token   string                 `json:"token,omitempty"`
-	User    *models.User           `json:"user"`
-	Tenants []TenantSelectionInfo  `json:"tenants,omitempty"`
+	Token        string                `json:"token,omitempty"`
+	RefreshToken string                `json:"refresh_token,omitempty"`
+	ExpiresIn    int                   `json:"expires_in,omitempty"`
+	User         *models.User          `json:"user"`
+	Tenants      []TenantSelectionInfo `json:"tenants,omitempty"`
+
+	// Two-factor flow
+	TwoFactorRequired      bool   `json:"two_factor_required,omitempty"`       // Submit a code with ChallengeToken to finish login
+	ChallengeToken         string `json:"challenge_token,omitempty"`           // Short-lived, only valid for /auth/2fa/verify
+	TwoFactorSetupRequired bool   `json:"two_factor_setup_required,omitempty"` // Tenant requires 2FA; session started without tenant
+}
+
+// VerifyTwoFactorRequest represents the second login step payload
+type VerifyTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required"`
+}
+
+// RefreshRequest represents the refresh token request payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// ForgotPasswordRequest represents the forgot password request payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest represents the reset password request payload
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+// VerifyEmailRequest represents the email verification request payload
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+const (
+	// passwordResetTokenTTL is how long a password reset link stays valid
+	passwordResetTokenTTL = time.Hour
+	// emailVerificationTokenTTL is how long an email verification link stays valid
+	emailVerificationTokenTTL = 48 * time.Hour
+	// twoFactorChallengeTTL is how long the user has to submit a 2FA code after the password step
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// TokenPair represents an access token and its rotating refresh token
+type TokenPair struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// SessionMetadata holds client information recorded on a new session
+type SessionMetadata struct {
+	UserAgent string
+	IPAddress string
 }
 
 // TenantSelectionInfo represents tenant selection information for multi-tenant users
@@ -42,10 +102,18 @@ type RegisterRequest struct {
 
 // AuthService defines the interface for authentication operations
 type AuthService interface {
-	Login(req LoginRequest) (*LoginResponse, error)
-	LoginWithTenant(email, password string, tenantID uint) (*LoginResponse, error)
+	Login(ctx context.Context, req LoginRequest, meta SessionMetadata) (*LoginResponse, error)
+	LoginWithTenant(ctx context.Context, email, password string, tenantID uint, meta SessionMetadata) (*LoginResponse, error)
+	VerifyTwoFactor(ctx context.Context, req VerifyTwoFactorRequest, meta SessionMetadata) (*LoginResponse, error)
 	Register(req RegisterRequest) (*models.User, error)
-	SwitchTenant(userID, tenantID uint) (string, error)
+	SwitchTenant(ctx context.Context, userID, sessionID, tenantID uint) (*TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (*TokenPair, error)
+	Logout(sessionID uint) error
+	ValidateSession(sessionID uint) error
+	ForgotPassword(email string) error
+	ResetPassword(token, newPassword string) error
+	VerifyEmail(token string) error
+	ResendVerification(userID uint) error
 }
 
 // authService implements AuthService
@@ -53,6 +121,12 @@ type authService struct {
 	userRepo       repositories.UserRepository
 	userTenantRepo repositories.UserTenantRepository
 	tenantRepo     repositories.TenantRepository
+	sessionRepo    repositories.SessionRepository
+	resetRepo      repositories.PasswordResetRepository
+	verifyRepo     repositories.EmailVerificationRepository
+	db             *gorm.DB
+	emailService   EmailService
+	twoFactor      TwoFactorService
 	config         *config.Config
 }
 
@@ -61,20 +135,32 @@ func NewAuthService(
 	userRepo repositories.UserRepository,
 	userTenantRepo repositories.UserTenantRepository,
 	tenantRepo repositories.TenantRepository,
+	sessionRepo repositories.SessionRepository,
+	resetRepo repositories.PasswordResetRepository,
+	verifyRepo repositories.EmailVerificationRepository,
+	db *gorm.DB,
+	emailService EmailService,
+	twoFactor TwoFactorService,
 	config *config.Config,
 ) AuthService {
 	return &authService{
 		userRepo:       userRepo,
 		userTenantRepo: userTenantRepo,
 		tenantRepo:     tenantRepo,
+		sessionRepo:    sessionRepo,
+		resetRepo:      resetRepo,
+		verifyRepo:     verifyRepo,
+		db:             db,
+		emailService:   emailService,
+		twoFactor:      twoFactor,
 		config:         config,
 	}
 }
 
 // Login authenticates a user and returns appropriate response based on tenant count
-func (s *authService) Login(req LoginRequest) (*LoginResponse, error) {
-	// Get user by email with all tenants
-	user, err := s.userRepo.GetByEmailWithTenants(req.Email)
+func (s *authService) Login(ctx context.Context, req LoginRequest, meta SessionMetadata) (*LoginResponse, error) {
+	// Get user by email
+	user, err := s.userRepo.GetByEmail(req.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invalid email or password")
@@ -87,63 +173,46 @@ func (s *authService) Login(req LoginRequest) (*LoginResponse, error) {
 		return nil, errors.New("user account is inactive")
 	}
 
+	if user.IsLocked() {
+		return nil, models.ErrAccountLocked
+	}
+
 	// Validate password
 	if err := utils.CheckPassword(req.Password, user.Password); err != nil {
+		s.recordFailedLogin(user)
 		return nil, errors.New("invalid email or password")
 	}
 
 	// Remove password from response
 	user.Password = ""
 
+	user.UserTenants, err = s.getMemberships(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenants: %w", err)
+	}
+	for _, ut := range user.UserTenants {
+		if ut.Tenant != nil {
+			user.Tenants = append(user.Tenants, *ut.Tenant)
+		}
+	}
+
 	// Get active user tenants
 	activeTenants := []models.UserTenant{}
 	for _, ut := range user.UserTenants {
-		if ut.IsActive {
+		if ut.HasAccess() {
 			activeTenants = append(activeTenants, ut)
 		}
 	}
 
 	// Case 1: User has no tenants (orphan user)
 	if len(activeTenants) == 0 {
-		token, err := utils.GenerateJWT(
-			user.ID,
-			user.Email,
-			nil, // no active tenant
-			"",
-			s.config.JWT.Secret,
-			s.config.JWT.ExpirationHours,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate token: %w", err)
-		}
-
-		return &LoginResponse{
-			Token: token,
-			User:  user,
-		}, nil
+		return s.beginSession(user, nil, "", meta) // no active tenant
 	}
 
 	// Case 2: User has exactly one tenant
 	if len(activeTenants) == 1 {
 		tenantID := activeTenants[0].TenantID
-		role := activeTenants[0].Role
-
-		token, err := utils.GenerateJWT(
-			user.ID,
-			user.Email,
-			&tenantID,
-			string(role),
-			s.config.JWT.Secret,
-			s.config.JWT.ExpirationHours,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate token: %w", err)
-		}
-
-		return &LoginResponse{
-			Token: token,
-			User:  user,
-		}, nil
+		return s.beginSession(user, &tenantID, activeTenants[0].Role, meta)
 	}
 
 	// Case 3: User has multiple tenants - return tenant selection list
@@ -165,7 +234,7 @@ func (s *authService) Login(req LoginRequest) (*LoginResponse, error) {
 }
 
 // LoginWithTenant authenticates a user and sets specific tenant as active
-func (s *authService) LoginWithTenant(email, password string, tenantID uint) (*LoginResponse, error) {
+func (s *authService) LoginWithTenant(ctx context.Context, email, password string, tenantID uint, meta SessionMetadata) (*LoginResponse, error) {
 	// Get user by email
 	user, err := s.userRepo.GetByEmail(email)
 	if err != nil {
@@ -180,13 +249,18 @@ func (s *authService) LoginWithTenant(email, password string, tenantID uint) (*L
 		return nil, errors.New("user account is inactive")
 	}
 
+	if user.IsLocked() {
+		return nil, models.ErrAccountLocked
+	}
+
 	// Validate password
 	if err := utils.CheckPassword(password, user.Password); err != nil {
+		s.recordFailedLogin(user)
 		return nil, errors.New("invalid email or password")
 	}
 
 	// Verify user belongs to the requested tenant
-	userTenant, err := s.userTenantRepo.GetByUserAndTenant(user.ID, tenantID)
+	userTenant, err := s.getMembership(ctx, user.ID, tenantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user does not belong to this tenant")
@@ -194,30 +268,76 @@ func (s *authService) LoginWithTenant(email, password string, tenantID uint) (*L
 		return nil, fmt.Errorf("failed to verify tenant access: %w", err)
 	}
 
-	if !userTenant.IsActive {
+	if !userTenant.HasAccess() {
 		return nil, errors.New("user access to this tenant is inactive")
 	}
 
-	// Generate JWT token with active tenant
-	token, err := utils.GenerateJWT(
-		user.ID,
-		user.Email,
-		&tenantID,
-		string(userTenant.Role),
-		s.config.JWT.Secret,
-		s.config.JWT.ExpirationHours,
-	)
+	// Remove password from response
+	user.Password = ""
+
+	// Start a session with the requested tenant as active
+	return s.beginSession(user, &tenantID, userTenant.Role, meta)
+}
+
+// VerifyTwoFactor completes a login that is waiting for a TOTP or recovery code
+func (s *authService) VerifyTwoFactor(ctx context.Context, req VerifyTwoFactorRequest, meta SessionMetadata) (*LoginResponse, error) {
+	claims, err := utils.ValidateChallengeJWT(req.ChallengeToken, utils.ChallengePurposeTwoFactor, s.config.JWT.Secret)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	user, err := s.userRepo.GetByID(claims.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid or expired challenge token")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.Active {
+		return nil, errors.New("user account is inactive")
+	}
+
+	if user.IsLocked() {
+		return nil, models.ErrAccountLocked
+	}
+
+	// Don't spend a code on a challenge that was already used or replaced
+	if user.TwoFactorChallengeID == nil || *user.TwoFactorChallengeID != claims.ID {
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	// Failed codes count towards the lockout just like wrong passwords
+	if err := s.twoFactor.VerifyCode(user, req.Code); err != nil {
+		s.recordFailedLogin(user)
+		return nil, err
+	}
+
+	// Claimed atomically, so two requests racing with the same challenge can't both log in
+	consumed, err := s.userRepo.ConsumeTwoFactorChallenge(user.ID, claims.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume challenge token: %w", err)
+	}
+	if !consumed {
+		return nil, errors.New("invalid or expired challenge token")
+	}
+
+	// Re-resolve the role in case membership changed during the challenge
+	var role models.UserRole
+	if claims.TenantID != nil {
+		userTenant, err := s.getMembership(ctx, user.ID, *claims.TenantID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to verify tenant access: %w", err)
+		}
+		if err != nil || !userTenant.HasAccess() {
+			return nil, errors.New("user access to this tenant is inactive")
+		}
+		role = userTenant.Role
 	}
 
-	// Remove password from response
 	user.Password = ""
 
-	return &LoginResponse{
-		Token: token,
-		User:  user,
-	}, nil
+	return s.completeLogin(user, claims.TenantID, role, meta)
 }
 
 // Register creates a new orphan user account (without tenant)
@@ -256,48 +376,611 @@ func (s *authService) Register(req RegisterRequest) (*models.User, error) {
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// Send verification email (failure does not block registration; user can request a new one)
+	if err := s.sendVerificationEmail(user); err != nil {
+		log.Printf("WARNING: failed to send verification email to %s: %v", user.Email, err)
+	}
+
 	// Remove password from response
 	user.Password = ""
 
 	return user, nil
 }
 
-// SwitchTenant generates a new token with a different active tenant
-func (s *authService) SwitchTenant(userID, tenantID uint) (string, error) {
+// SwitchTenant changes the active tenant of the current session and issues new tokens
+func (s *authService) SwitchTenant(ctx context.Context, userID, sessionID, tenantID uint) (*TokenPair, error) {
 	// Get user
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("user not found")
+			return nil, errors.New("user not found")
 		}
-		return "", fmt.Errorf("failed to get user: %w", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// Verify user belongs to the requested tenant
-	userTenant, err := s.userTenantRepo.GetByUserAndTenant(userID, tenantID)
+	userTenant, err := s.getMembership(ctx, userID, tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user does not belong to this tenant")
+		}
+		return nil, fmt.Errorf("failed to verify tenant access: %w", err)
+	}
+
+	if !userTenant.HasAccess() {
+		return nil, errors.New("user access to this tenant is inactive")
+	}
+
+	if err := s.checkTwoFactorRequirement(user, tenantID, userTenant.Role); err != nil {
+		return nil, err
+	}
+
+	// Get current session
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("session not found")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if session.UserID != userID || !session.IsActive() {
+		return nil, errors.New("session has been revoked or expired")
+	}
+
+	// Point the session at the new tenant and rotate its refresh token
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session.TenantID = &tenantID
+	session.RefreshTokenHash = utils.HashToken(refreshToken)
+	session.ExpiresAt = s.refreshExpiry()
+	session.LastUsedAt = time.Now()
+
+	if err := s.sessionRepo.Update(session); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	return s.signTokens(session, user, userTenant.Role, refreshToken)
+}
+
+// Refresh exchanges a refresh token for a new token pair, rotating the refresh token
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	oldHash := utils.HashToken(refreshToken)
+
+	session, err := s.sessionRepo.GetByRefreshTokenHash(oldHash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invalid refresh token")
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !session.IsActive() {
+		return nil, errors.New("refresh token expired or revoked")
+	}
+
+	// Re-check the user and tenant membership so role changes and removals take effect
+	user, err := s.userRepo.GetByID(session.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = s.sessionRepo.Revoke(session.ID)
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.Active {
+		_ = s.sessionRepo.Revoke(session.ID)
+		return nil, errors.New("user account is inactive")
+	}
+
+	var role models.UserRole
+	if session.TenantID != nil {
+		userTenant, err := s.getMembership(ctx, user.ID, *session.TenantID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to verify tenant access: %w", err)
+		}
+		if err != nil || !userTenant.HasAccess() {
+			_ = s.sessionRepo.Revoke(session.ID)
+			return nil, errors.New("user access to this tenant is inactive")
+		}
+		role = userTenant.Role
+
+		// A tenant may have started requiring 2FA after this session was created
+		if err := s.checkTwoFactorRequirement(user, *session.TenantID, role); err != nil {
+			_ = s.sessionRepo.Revoke(session.ID)
+			return nil, err
+		}
+	}
+
+	newRefreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated, err := s.sessionRepo.RotateRefreshToken(session.ID, oldHash, utils.HashToken(newRefreshToken), s.refreshExpiry())
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !rotated {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	return s.signTokens(session, user, role, newRefreshToken)
+}
+
+// Logout revokes the given session so its access and refresh tokens stop working
+func (s *authService) Logout(sessionID uint) error {
+	if err := s.sessionRepo.Revoke(sessionID); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return nil
+}
+
+// ValidateSession checks that the session referenced by an access token is still active
+func (s *authService) ValidateSession(sessionID uint) error {
+	session, err := s.sessionRepo.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("session not found")
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	if !session.IsActive() {
+		return errors.New("session has been revoked or expired")
+	}
+
+	return nil
+}
+
+// ForgotPassword emails a single-use reset link to the user
+// It never reveals whether the email is registered: unknown or inactive accounts are silently ignored
+func (s *authService) ForgotPassword(email string) error {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.Active {
+		return nil
+	}
+
+	// Only the most recent link should work
+	if err := s.resetRepo.InvalidateAllByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous reset tokens: %w", err)
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	resetToken := &models.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	}
+
+	if err := s.resetRepo.Create(resetToken); err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	// Send reset email (failure is logged only, so the response stays the same)
+	resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.config.Email.AppBaseURL, token)
+	emailMsg, err := renderEmail(EmailPasswordReset, user.Locale, platformBranding, accountLinkEmailData{Name: user.Name, Link: resetLink})
+	if err != nil {
+		log.Printf("WARNING: failed to render password reset email for %s: %v", user.Email, err)
+		return nil
+	}
+	emailMsg.To = user.Email
+	emailMsg.IdempotencyKey = fmt.Sprintf("%s:%s", EmailPasswordReset, resetToken.TokenHash)
+	if err := s.emailService.SendEmail(emailMsg); err != nil {
+		log.Printf("WARNING: failed to send password reset email to %s: %v", user.Email, err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and revokes all of the user's sessions
+func (s *authService) ResetPassword(token, newPassword string) error {
+	resetToken, err := s.resetRepo.GetByTokenHash(utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired reset token")
+		}
+		return fmt.Errorf("failed to get reset token: %w", err)
+	}
+
+	if !resetToken.IsValid() {
+		return errors.New("invalid or expired reset token")
+	}
+
+	// Validate new password before consuming the token
+	if err := utils.IsPasswordValid(newPassword); err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired reset token")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Consume the token atomically so it can only be used once
+	consumed, err := s.resetRepo.MarkUsed(resetToken.ID)
+	if err != nil {
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid or expired reset token")
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	// Proving ownership of the email also lifts any lockout
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		return fmt.Errorf("failed to clear account lockout: %w", err)
+	}
+
+	// Log out every device that may be using the old password
+	if err := s.sessionRepo.RevokeAllByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyEmail marks the user's email as verified using a verification token
+func (s *authService) VerifyEmail(token string) error {
+	verifyToken, err := s.verifyRepo.GetByTokenHash(utils.HashToken(token))
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", errors.New("user does not belong to this tenant")
+			return errors.New("invalid or expired verification token")
+		}
+		return fmt.Errorf("failed to get verification token: %w", err)
+	}
+
+	if !verifyToken.IsValid() {
+		return errors.New("invalid or expired verification token")
+	}
+
+	user, err := s.userRepo.GetByID(verifyToken.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid or expired verification token")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	consumed, err := s.verifyRepo.MarkUsed(verifyToken.ID)
+	if err != nil {
+		return fmt.Errorf("failed to consume verification token: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid or expired verification token")
+	}
+
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return nil
+}
+
+// ResendVerification sends a new verification email to an unverified user
+func (s *authService) ResendVerification(userID uint) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if user.IsEmailVerified() {
+		return errors.New("email address is already verified")
+	}
+
+	return s.sendVerificationEmail(user)
+}
+
+// sendVerificationEmail issues a new verification token and emails the confirmation link
+func (s *authService) sendVerificationEmail(user *models.User) error {
+	// Only the most recent link should work
+	if err := s.verifyRepo.InvalidateAllByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to invalidate previous verification tokens: %w", err)
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	verifyToken := &models.EmailVerificationToken{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(emailVerificationTokenTTL),
+	}
+
+	if err := s.verifyRepo.Create(verifyToken); err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	verifyLink := fmt.Sprintf("%s/verify-email?token=%s", s.config.Email.AppBaseURL, token)
+	emailMsg, err := renderEmail(EmailVerification, user.Locale, platformBranding, accountLinkEmailData{Name: user.Name, Link: verifyLink})
+	if err != nil {
+		return err
+	}
+	emailMsg.To = user.Email
+	emailMsg.IdempotencyKey = fmt.Sprintf("%s:%s", EmailVerification, verifyToken.TokenHash)
+
+	if err := s.emailService.SendEmail(emailMsg); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	return nil
+}
+
+// getMemberships returns all memberships of the user
+// Logins have no request scope yet, so the lookup runs in one that exposes the user's own memberships
+func (s *authService) getMemberships(ctx context.Context, userID uint) ([]models.UserTenant, error) {
+	var userTenants []models.UserTenant
+	err := database.RunScoped(ctx, s.db, database.Scope{UserID: userID}, func(ctx context.Context) error {
+		var err error
+		userTenants, err = s.userTenantRepo.GetAllByUser(ctx, userID)
+		return err
+	})
+	return userTenants, err
+}
+
+// getMembership returns the user's membership in a tenant, looked up like getMemberships
+func (s *authService) getMembership(ctx context.Context, userID, tenantID uint) (*models.UserTenant, error) {
+	var userTenant *models.UserTenant
+	err := database.RunScoped(ctx, s.db, database.Scope{UserID: userID}, func(ctx context.Context) error {
+		var err error
+		userTenant, err = s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
+		return err
+	})
+	return userTenant, err
+}
+
+// beginSession either starts a session or, if the user has 2FA enabled, returns a challenge token
+// The challenge's ID is stored on the user, so only the latest challenge works, and only once
+func (s *authService) beginSession(user *models.User, tenantID *uint, role models.UserRole, meta SessionMetadata) (*LoginResponse, error) {
+	if user.TwoFactorEnabled {
+		challengeID, err := utils.GenerateSecureToken(16)
+		if err != nil {
+			return nil, err
+		}
+
+		challenge, err := utils.GenerateChallengeJWT(user.ID, tenantID, utils.ChallengePurposeTwoFactor, challengeID, s.config.JWT.Secret, twoFactorChallengeTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate challenge token: %w", err)
+		}
+
+		if err := s.userRepo.SetTwoFactorChallenge(user.ID, challengeID); err != nil {
+			return nil, fmt.Errorf("failed to save challenge token: %w", err)
+		}
+
+		return &LoginResponse{
+			User:              user,
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		}, nil
+	}
+
+	return s.completeLogin(user, tenantID, role, meta)
+}
+
+// completeLogin starts a session once all authentication factors have been checked
+// Managers of a tenant that requires 2FA but have not enrolled get a session without tenant,
+// which still lets them reach /account/2fa to enroll
+func (s *authService) completeLogin(user *models.User, tenantID *uint, role models.UserRole, meta SessionMetadata) (*LoginResponse, error) {
+	s.clearFailedLogins(user)
+
+	if tenantID != nil {
+		if err := s.checkTwoFactorRequirement(user, *tenantID, role); err != nil {
+			if !errors.Is(err, models.ErrTwoFactorRequired) {
+				return nil, err
+			}
+
+			tokens, err := s.startSession(user, nil, "", meta)
+			if err != nil {
+				return nil, err
+			}
+
+			response := newLoginResponse(user, tokens)
+			response.TwoFactorSetupRequired = true
+			return response, nil
 		}
-		return "", fmt.Errorf("failed to verify tenant access: %w", err)
 	}
 
-	if !userTenant.IsActive {
-		return "", errors.New("user access to this tenant is inactive")
+	tokens, err := s.startSession(user, tenantID, role, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return newLoginResponse(user, tokens), nil
+}
+
+// checkTwoFactorRequirement returns ErrTwoFactorRequired if the tenant requires 2FA for the role and the user has not enabled it
+func (s *authService) checkTwoFactorRequirement(user *models.User, tenantID uint, role models.UserRole) error {
+	if user.TwoFactorEnabled {
+		return nil
+	}
+
+	required, err := s.twoFactor.IsRequired(tenantID, role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return models.ErrTwoFactorRequired
+	}
+
+	return nil
+}
+
+// recordFailedLogin counts a failed attempt and locks the account once the threshold is reached
+// Errors are only logged so the caller can still return the generic credentials error
+func (s *authService) recordFailedLogin(user *models.User) {
+	attempts, err := s.userRepo.IncrementFailedLogins(user.ID)
+	if err != nil {
+		log.Printf("WARNING: failed to record failed login for user %d: %v", user.ID, err)
+		return
+	}
+
+	threshold := s.config.Security.LockoutThreshold
+	if threshold <= 0 || attempts < threshold {
+		return
+	}
+
+	lockedUntil := time.Now().Add(s.lockoutDuration(user.LockoutCount))
+	if err := s.userRepo.Lock(user.ID, lockedUntil); err != nil {
+		log.Printf("WARNING: failed to lock user %d: %v", user.ID, err)
+		return
 	}
 
-	// Generate new JWT token with new active tenant
+	s.sendLockoutEmail(user, lockedUntil)
+}
+
+// clearFailedLogins resets the lockout state after a successful login
+func (s *authService) clearFailedLogins(user *models.User) {
+	if user.FailedLoginAttempts == 0 && user.LockoutCount == 0 && user.LockedUntil == nil {
+		return
+	}
+
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		log.Printf("WARNING: failed to reset failed logins for user %d: %v", user.ID, err)
+	}
+}
+
+// lockoutDuration doubles the base lock duration for each previous consecutive lockout, up to the configured maximum
+func (s *authService) lockoutDuration(previousLockouts int) time.Duration {
+	base := time.Duration(s.config.Security.LockoutBaseMinutes) * time.Minute
+	max := time.Duration(s.config.Security.LockoutMaxMinutes) * time.Minute
+
+	duration := base
+	for i := 0; i < previousLockouts && duration < max; i++ {
+		duration *= 2
+	}
+	if max > 0 && duration > max {
+		duration = max
+	}
+
+	return duration
+}
+
+// sendLockoutEmail notifies the user that their account was locked
+func (s *authService) sendLockoutEmail(user *models.User, lockedUntil time.Time) {
+	resetLink := fmt.Sprintf("%s/forgot-password", s.config.Email.AppBaseURL)
+	emailMsg, err := renderEmail(EmailAccountLocked, user.Locale, platformBranding, accountLockedEmailData{
+		Name:        user.Name,
+		LockedUntil: lockedUntil,
+		Link:        resetLink,
+	})
+	if err != nil {
+		log.Printf("WARNING: failed to render lockout email for %s: %v", user.Email, err)
+		return
+	}
+	emailMsg.To = user.Email
+	// Keyed by the lockout's number, so requests that hit the limit together send one email
+	emailMsg.IdempotencyKey = fmt.Sprintf("%s:%d:%d", EmailAccountLocked, user.ID, user.LockoutCount+1)
+
+	if err := s.emailService.SendEmail(emailMsg); err != nil {
+		log.Printf("WARNING: failed to send lockout email to %s: %v", user.Email, err)
+	}
+}
+
+// startSession persists a new session and returns its first token pair
+func (s *authService) startSession(user *models.User, tenantID *uint, role models.UserRole, meta SessionMetadata) (*TokenPair, error) {
+	refreshToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &models.Session{
+		UserID:           user.ID,
+		TenantID:         tenantID,
+		RefreshTokenHash: utils.HashToken(refreshToken),
+		ExpiresAt:        s.refreshExpiry(),
+		LastUsedAt:       time.Now(),
+		UserAgent:        truncate(meta.UserAgent, 255),
+		IPAddress:        truncate(meta.IPAddress, 45),
+	}
+
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return s.signTokens(session, user, role, refreshToken)
+}
+
+// signTokens issues a short-lived access token for the session alongside its refresh token
+func (s *authService) signTokens(session *models.Session, user *models.User, role models.UserRole, refreshToken string) (*TokenPair, error) {
+	ttl := time.Duration(s.config.JWT.AccessTokenMinutes) * time.Minute
+
 	token, err := utils.GenerateJWT(
+		session.ID,
 		user.ID,
 		user.Email,
-		&tenantID,
-		string(userTenant.Role),
+		session.TenantID,
+		string(role),
 		s.config.JWT.Secret,
-		s.config.JWT.ExpirationHours,
+		ttl,
 	)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
-	return token, nil
+	return &TokenPair{
+		Token:        token,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(ttl.Seconds()),
+	}, nil
+}
+
+// refreshExpiry returns the expiration time for a newly issued refresh token
+func (s *authService) refreshExpiry() time.Time {
+	return time.Now().Add(time.Duration(s.config.JWT.RefreshTokenDays) * 24 * time.Hour)
+}
+
+// newLoginResponse builds a login response carrying a token pair
+func newLoginResponse(user *models.User, tokens *TokenPair) *LoginResponse {
+	return &LoginResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
+	}
+}
+
+// truncate limits a string to max bytes
+func truncate(value string, max int) string {
+	if len(value) > max {
+		return value[:max]
+	}
+	return value
 }
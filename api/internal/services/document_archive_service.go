@@ -0,0 +1,302 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// maxArchiveDocuments caps how many documents a single ZIP download can hold
+const maxArchiveDocuments = 1000
+
+// skippedListName is the file listing the documents left out of an archive
+const skippedListName = "ARQUIVOS_NAO_INCLUIDOS.txt"
+
+// ArchiveRequest selects the documents of a ZIP download: a folder with its subfolders, or a list of documents
+type ArchiveRequest struct {
+	FolderID    *uint  `json:"folder_id"`
+	DocumentIDs []uint `json:"document_ids"`
+}
+
+// DocumentArchive is a ZIP download whose contents are resolved and ready to be streamed
+type DocumentArchive struct {
+	FileName string
+	entries  []archiveEntry
+	skipped  []string
+}
+
+// archiveEntry is one stored file and its path inside the archive
+type archiveEntry struct {
+	path        string
+	key         string
+	contentType string
+	modified    time.Time
+}
+
+// DocumentArchiveService defines the interface for bulk ZIP downloads
+type DocumentArchiveService interface {
+	Prepare(ctx context.Context, tenantID uint, req ArchiveRequest) (*DocumentArchive, error)
+	Write(ctx context.Context, w io.Writer, archive *DocumentArchive) error
+}
+
+// documentArchiveService implements DocumentArchiveService
+type documentArchiveService struct {
+	docRepo    repositories.DocumentRepository
+	folderRepo repositories.FolderRepository
+	storageSvc StorageService
+	db         *gorm.DB
+}
+
+// NewDocumentArchiveService creates a new document archive service
+func NewDocumentArchiveService(
+	docRepo repositories.DocumentRepository,
+	folderRepo repositories.FolderRepository,
+	storageSvc StorageService,
+	db *gorm.DB,
+) DocumentArchiveService {
+	return &documentArchiveService{
+		docRepo:    docRepo,
+		folderRepo: folderRepo,
+		storageSvc: storageSvc,
+		db:         db,
+	}
+}
+
+// Prepare resolves which files go into the archive and where
+// It runs before anything is streamed, so a bad request can still get a proper error response.
+// Downloads carry no request transaction, so the lookups get a short one that ends before the streaming starts
+func (s *documentArchiveService) Prepare(ctx context.Context, tenantID uint, req ArchiveRequest) (*DocumentArchive, error) {
+	var archive *DocumentArchive
+	err := database.Scoped(ctx, s.db, func(ctx context.Context) error {
+		var err error
+		archive, err = s.prepare(ctx, tenantID, req)
+		return err
+	})
+	return archive, err
+}
+
+// prepare picks the kind of archive requested
+func (s *documentArchiveService) prepare(ctx context.Context, tenantID uint, req ArchiveRequest) (*DocumentArchive, error) {
+	switch {
+	case req.FolderID != nil && len(req.DocumentIDs) > 0:
+		return nil, errors.New("choose either folder_id or document_ids")
+	case req.FolderID != nil:
+		return s.prepareFolder(ctx, tenantID, *req.FolderID)
+	case len(req.DocumentIDs) > 0:
+		return s.prepareDocuments(ctx, tenantID, req.DocumentIDs)
+	default:
+		return nil, errors.New("folder_id or document_ids is required")
+	}
+}
+
+// prepareFolder archives a folder and its subfolders, with the folder as the archive's top directory
+func (s *documentArchiveService) prepareFolder(ctx context.Context, tenantID, folderID uint) (*DocumentArchive, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("folder not found")
+		}
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	subtree, err := s.folderRepo.GetSubtree(ctx, folder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subfolders: %w", err)
+	}
+
+	ids := make([]uint, len(subtree))
+	for i := range subtree {
+		ids[i] = subtree[i].ID
+	}
+	docs, err := s.docRepo.GetByFolders(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+	if len(docs) > maxArchiveDocuments {
+		return nil, fmt.Errorf("folder has %d documents; archives are limited to %d", len(docs), maxArchiveDocuments)
+	}
+
+	// Paths start at the requested folder, whatever its ancestors are
+	depth := len(folder.PathIDs()) - 1
+	archive := newDocumentArchive(archiveSegment(folder.Name) + ".zip")
+	archive.add(docs, subtree, depth)
+	return archive, nil
+}
+
+// prepareDocuments archives a selection of documents, each under its full folder path
+func (s *documentArchiveService) prepareDocuments(ctx context.Context, tenantID uint, docIDs []uint) (*DocumentArchive, error) {
+	if len(docIDs) > maxArchiveDocuments {
+		return nil, fmt.Errorf("archives are limited to %d documents", maxArchiveDocuments)
+	}
+
+	docs, err := s.docRepo.GetByIDs(ctx, docIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	found := make(map[uint]bool, len(docs))
+	for i := range docs {
+		found[docs[i].ID] = true
+	}
+	for _, id := range docIDs {
+		if !found[id] {
+			return nil, fmt.Errorf("document %d not found", id)
+		}
+	}
+
+	var ancestorIDs []uint
+	for i := range docs {
+		if docs[i].Folder != nil {
+			ancestorIDs = append(ancestorIDs, docs[i].Folder.PathIDs()...)
+		}
+	}
+	folders, err := s.folderRepo.GetByIDs(ctx, ancestorIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder paths: %w", err)
+	}
+
+	archive := newDocumentArchive("documentos.zip")
+	archive.add(docs, folders, 0)
+	return archive, nil
+}
+
+// newDocumentArchive creates an empty archive
+func newDocumentArchive(fileName string) *DocumentArchive {
+	return &DocumentArchive{FileName: fileName}
+}
+
+// add places the current version of each document under its folder path, skipping the ones that can't be downloaded
+// folders must hold every folder on the documents' paths; the first depth levels of each path are left out
+func (a *DocumentArchive) add(docs []models.Document, folders []models.Folder, depth int) {
+	byID := make(map[uint]*models.Folder, len(folders))
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+
+	used := make(map[string]bool)
+	for i := range docs {
+		doc := &docs[i]
+
+		var dirs []string
+		if doc.FolderID != nil {
+			if folder, ok := byID[*doc.FolderID]; ok {
+				for _, id := range folder.PathIDs()[depth:] {
+					if ancestor, ok := byID[id]; ok {
+						dirs = append(dirs, archiveSegment(ancestor.Name))
+					}
+				}
+			}
+		}
+		entryPath := uniqueArchivePath(path.Join(append(dirs, archiveSegment(doc.Name))...), used)
+
+		if doc.ScanStatus.DownloadError() != nil {
+			reason := "bloqueado pela verificação de vírus"
+			if doc.ScanStatus == models.ScanPending {
+				reason = "ainda em verificação de vírus"
+			}
+			a.skipped = append(a.skipped, fmt.Sprintf("%s (%s)", entryPath, reason))
+			continue
+		}
+
+		a.entries = append(a.entries, archiveEntry{
+			path:        entryPath,
+			key:         doc.S3Key,
+			contentType: doc.ContentType,
+			modified:    doc.UpdatedAt,
+		})
+	}
+}
+
+// Write streams the archive to w, reading one file at a time from storage
+// Files are copied straight into the ZIP stream, so memory use does not grow with their size
+func (s *documentArchiveService) Write(ctx context.Context, w io.Writer, archive *DocumentArchive) error {
+	zw := zip.NewWriter(w)
+
+	for _, entry := range archive.entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.writeEntry(ctx, zw, entry); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", entry.path, err)
+		}
+	}
+
+	if len(archive.skipped) > 0 {
+		list, err := zw.Create(skippedListName)
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", skippedListName, err)
+		}
+		text := "Arquivos não incluídos neste ZIP:\r\n\r\n" +
+			strings.Join(archive.skipped, "\r\n") + "\r\n"
+		if _, err := io.WriteString(list, text); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", skippedListName, err)
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeEntry copies one stored file into the archive
+func (s *documentArchiveService) writeEntry(ctx context.Context, zw *zip.Writer, entry archiveEntry) error {
+	content, err := s.storageSvc.Read(ctx, entry.key)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	dst, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     entry.path,
+		Method:   archiveMethod(entry.contentType),
+		Modified: entry.modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, content)
+	return err
+}
+
+// archiveMethod deflates formats that compress well and stores the ones that are already compressed
+func archiveMethod(contentType string) uint16 {
+	switch {
+	case strings.HasPrefix(contentType, "text/"),
+		contentType == "application/msword",
+		contentType == "application/vnd.ms-excel",
+		contentType == "application/vnd.ms-powerpoint":
+		return zip.Deflate
+	default:
+		return zip.Store
+	}
+}
+
+// archiveSegment turns a folder or document name into a single path segment of the archive
+func archiveSegment(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_").Replace(name)
+	return utils.DisplayFileName(name)
+}
+
+// uniqueArchivePath numbers a path that is already taken, e.g. "ata (2).pdf"
+func uniqueArchivePath(p string, used map[string]bool) string {
+	candidate := p
+	ext := path.Ext(p)
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", strings.TrimSuffix(p, ext), n, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// scanBatchSize caps how many versions one scan pass picks up
+const scanBatchSize = 20
+
+// DocumentScanService defines the interface for the background malware scan of uploaded files
+type DocumentScanService interface {
+	Notify()
+	ScanPending(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+}
+
+// documentScanService implements DocumentScanService
+type documentScanService struct {
+	docRepo    repositories.DocumentRepository
+	storageSvc StorageService
+	scanner    MalwareScanner
+	db         *gorm.DB
+	wake       chan struct{}
+}
+
+// NewDocumentScanService creates a new document scan service
+func NewDocumentScanService(
+	docRepo repositories.DocumentRepository,
+	storageSvc StorageService,
+	scanner MalwareScanner,
+	db *gorm.DB,
+) DocumentScanService {
+	return &documentScanService{
+		docRepo:    docRepo,
+		storageSvc: storageSvc,
+		scanner:    scanner,
+		db:         db,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Notify wakes the scanner after an upload so files don't wait for the next tick
+// Callers run it once the upload commits, or the scanner would look before the version is visible
+func (s *documentScanService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// A scan pass is already due
+	}
+}
+
+// ScanPending scans a batch of pending versions, across all tenants
+// Versions the scanner can't be reached for stay pending and are retried on the next pass
+func (s *documentScanService) ScanPending(ctx context.Context) (int, error) {
+	versions, err := s.docRepo.GetPendingScans(ctx, scanBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get pending scans: %w", err)
+	}
+
+	scanned := 0
+	for i := range versions {
+		version := &versions[i]
+		if err := s.scan(ctx, version); err != nil {
+			log.Printf("Failed to scan document %d version %d: %v", version.DocumentID, version.VersionNumber, err)
+			continue
+		}
+		// Pending versions come from every tenant; each result is saved in a scope of its tenant
+		err := database.RunScoped(ctx, s.db, database.Scope{TenantID: version.TenantID}, func(ctx context.Context) error {
+			return s.docRepo.SetScanResult(ctx, version)
+		})
+		if err != nil {
+			log.Printf("Failed to save scan result of document %d version %d: %v", version.DocumentID, version.VersionNumber, err)
+			continue
+		}
+		if version.ScanStatus == models.ScanInfected {
+			log.Printf("Malware found in document %d version %d (tenant %d): %s", version.DocumentID, version.VersionNumber, version.TenantID, version.ScanSignature)
+		}
+		scanned++
+	}
+
+	return scanned, nil
+}
+
+// Run scans pending versions every interval, or sooner when notified, until ctx is cancelled
+func (s *documentScanService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		// Keep going while full batches come back so a backlog drains without waiting for the ticker
+		for ctx.Err() == nil {
+			scanned, err := s.ScanPending(ctx)
+			if err != nil {
+				log.Printf("Document scanner: %v", err)
+				break
+			}
+			if scanned < scanBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// scan streams a version's file to the scanner and sets its scan fields
+func (s *documentScanService) scan(ctx context.Context, version *models.DocumentVersion) error {
+	content, err := s.storageSvc.Read(ctx, version.S3Key)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			s.setResult(version, models.ScanFailed, "file not found in storage")
+			return nil
+		}
+		return err
+	}
+	defer content.Close()
+
+	result, err := s.scanner.Scan(ctx, content)
+	switch {
+	case errors.Is(err, ErrScanRejected):
+		s.setResult(version, models.ScanFailed, err.Error())
+	case err != nil:
+		return err
+	case result.Infected:
+		s.setResult(version, models.ScanInfected, result.Signature)
+	default:
+		s.setResult(version, models.ScanClean, "")
+	}
+	return nil
+}
+
+// setResult fills in the scan fields of a version
+func (s *documentScanService) setResult(version *models.DocumentVersion, status models.ScanStatus, signature string) {
+	now := time.Now()
+	if len(signature) > 255 {
+		signature = signature[:255]
+	}
+	version.ScanStatus = status
+	version.ScanSignature = signature
+	version.ScannedAt = &now
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+)
+
+// newTestScanService creates a scan service that reads files from a temporary local storage
+func newTestScanService(t *testing.T, scanner MalwareScanner) (*documentScanService, StorageService) {
+	t.Helper()
+
+	storage, err := newLocalStorageService(config.StorageConfig{
+		LocalRoot:  t.TempDir(),
+		SigningKey: "test-signing-key",
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %v", err)
+	}
+
+	svc := NewDocumentScanService(nil, storage, scanner, nil).(*documentScanService)
+	return svc, storage
+}
+
+func TestDocumentScanServiceScan(t *testing.T) {
+	tests := []struct {
+		name          string
+		reply         string
+		wantStatus    models.ScanStatus
+		wantSignature string
+		wantDownload  error
+	}{
+		{
+			name:         "clean file can be downloaded",
+			reply:        "stream: OK\x00",
+			wantStatus:   models.ScanClean,
+			wantDownload: nil,
+		},
+		{
+			name:          "infected file is quarantined",
+			reply:         "stream: Eicar-Signature FOUND\x00",
+			wantStatus:    models.ScanInfected,
+			wantSignature: "Eicar-Signature",
+			wantDownload:  models.ErrScanBlocked,
+		},
+		{
+			name:          "rejected file is blocked",
+			reply:         "INSTREAM size limit exceeded. ERROR\x00",
+			wantStatus:    models.ScanFailed,
+			wantSignature: "scanner rejected the file: INSTREAM size limit exceeded.",
+			wantDownload:  models.ErrScanBlocked,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clamd := startFakeClamd(t, tt.reply)
+			svc, storage := newTestScanService(t, NewClamdScanner("unix", clamd.path, 5*time.Second))
+
+			if err := storage.Upload(context.Background(), "docs/1/file.pdf", bytes.NewReader([]byte("%PDF-1.4")), "application/pdf", 8); err != nil {
+				t.Fatalf("failed to store file: %v", err)
+			}
+			version := &models.DocumentVersion{S3Key: "docs/1/file.pdf", ScanStatus: models.ScanPending}
+
+			if err := svc.scan(context.Background(), version); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if version.ScanStatus != tt.wantStatus {
+				t.Fatalf("want status %s, got %s", tt.wantStatus, version.ScanStatus)
+			}
+			if version.ScanSignature != tt.wantSignature {
+				t.Fatalf("want signature %q, got %q", tt.wantSignature, version.ScanSignature)
+			}
+			if version.ScannedAt == nil {
+				t.Fatal("want ScannedAt to be set")
+			}
+			if err := version.ScanStatus.DownloadError(); !errors.Is(err, tt.wantDownload) {
+				t.Fatalf("want download error %v, got %v", tt.wantDownload, err)
+			}
+		})
+	}
+}
+
+// When clamd can't give a verdict the version stays pending: it is retried on the next pass
+// and can't be downloaded meanwhile, so an unreachable scanner fails closed
+func TestDocumentScanServiceScanFailsClosed(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanner func(t *testing.T) MalwareScanner
+	}{
+		{
+			name: "connection failure",
+			scanner: func(t *testing.T) MalwareScanner {
+				return NewClamdScanner("unix", t.TempDir()+"/clamd.sock", time.Second)
+			},
+		},
+		{
+			name: "malformed reply",
+			scanner: func(t *testing.T) MalwareScanner {
+				return NewClamdScanner("unix", startFakeClamd(t, "stream: ???\x00").path, 5*time.Second)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, storage := newTestScanService(t, tt.scanner(t))
+
+			if err := storage.Upload(context.Background(), "docs/1/file.pdf", bytes.NewReader([]byte("%PDF-1.4")), "application/pdf", 8); err != nil {
+				t.Fatalf("failed to store file: %v", err)
+			}
+			version := &models.DocumentVersion{S3Key: "docs/1/file.pdf", ScanStatus: models.ScanPending}
+
+			if err := svc.scan(context.Background(), version); err == nil {
+				t.Fatal("want an error so the version is retried")
+			}
+
+			if version.ScanStatus != models.ScanPending || version.ScannedAt != nil {
+				t.Fatalf("want the version left pending, got %s", version.ScanStatus)
+			}
+			if err := version.ScanStatus.DownloadError(); !errors.Is(err, models.ErrScanPending) {
+				t.Fatalf("want ErrScanPending, got %v", err)
+			}
+		})
+	}
+}
+
+// The none driver passes every file, failing open by design
+func TestNoopScannerFailsOpen(t *testing.T) {
+	scanner, err := NewMalwareScanner(config.ScannerConfig{Driver: "none"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	svc, storage := newTestScanService(t, scanner)
+
+	if err := storage.Upload(context.Background(), "docs/1/file.pdf", bytes.NewReader([]byte("%PDF-1.4")), "application/pdf", 8); err != nil {
+		t.Fatalf("failed to store file: %v", err)
+	}
+	version := &models.DocumentVersion{S3Key: "docs/1/file.pdf", ScanStatus: models.ScanPending}
+
+	if err := svc.scan(context.Background(), version); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version.ScanStatus != models.ScanClean {
+		t.Fatalf("want status %s, got %s", models.ScanClean, version.ScanStatus)
+	}
+}
+
+func TestDocumentScanServiceScanMissingFile(t *testing.T) {
+	svc, _ := newTestScanService(t, &noopScanner{})
+	version := &models.DocumentVersion{S3Key: "docs/1/missing.pdf", ScanStatus: models.ScanPending}
+
+	if err := svc.scan(context.Background(), version); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version.ScanStatus != models.ScanFailed {
+		t.Fatalf("want status %s, got %s", models.ScanFailed, version.ScanStatus)
+	}
+}
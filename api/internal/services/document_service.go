@@ -1,61 +1,206 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"html"
+	"io"
+	"log"
 	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
-const maxFileSize = 10 * 1024 * 1024 // 10 MB
+const maxFileSize = 10 * 1024 * 1024 // 10 MB; larger files go through upload sessions
+
+// Residents' searches filter matches in batches and give up after maxSharedSearchScan of them
+const (
+	sharedSearchBatch   = 100
+	maxSharedSearchScan = 1000
+)
+
+const (
+	// maxShareLinkDuration is how far in the future a share link may expire
+	maxShareLinkDuration = 90 * 24 * time.Hour
+	// shareLinkURLDuration is how long the storage URL a share link redirects to stays valid
+	shareLinkURLDuration = 2 * time.Minute
+)
+
+// sniffLength is how many leading bytes content type detection looks at
+const sniffLength = 512
+
+// StoredFile describes a file that is already in storage and needs a document record
+// ContentType is replaced by the one detected from the stored bytes, and Text is extracted from them
+type StoredFile struct {
+	Key         string
+	Name        string
+	ContentType string
+	Size        int64
+	Text        string
+}
 
 // DocumentService defines the interface for document operations
 type DocumentService interface {
-	Upload(tenantID, userID uint, folderID *uint, file multipart.File, header *multipart.FileHeader) (*models.Document, error)
-	GetAll(tenantID uint, folderID *uint) ([]models.Document, error)
-	GetByID(tenantID, docID uint) (*models.Document, error)
-	GetDownloadURL(tenantID, docID uint) (string, error)
-	Delete(tenantID, docID uint) error
-	MoveToFolder(tenantID, docID uint, folderID *uint) error
+	Upload(ctx context.Context, tenantID, userID uint, folderID *uint, file multipart.File, header *multipart.FileHeader) (*models.Document, error)
+	Register(ctx context.Context, tenantID, userID uint, folderID *uint, file StoredFile) (*models.Document, error)
+	RegisterVersion(ctx context.Context, tenantID, userID, docID uint, file StoredFile) (*models.DocumentVersion, error)
+	CheckFileType(tenantID uint, fileName string) (*models.FileType, error)
+	GetAll(ctx context.Context, tenantID uint, folderID *uint) ([]models.Document, error)
+	GetByID(ctx context.Context, tenantID, docID uint) (*models.Document, error)
+	GetDownloadURL(ctx context.Context, tenantID, docID uint) (string, error)
+	Delete(ctx context.Context, tenantID, docID uint) error
+	MoveToFolder(ctx context.Context, tenantID, docID uint, folderID *uint) error
+	UploadVersion(ctx context.Context, tenantID, userID, docID uint, file multipart.File, header *multipart.FileHeader) (*models.DocumentVersion, error)
+	ListVersions(ctx context.Context, tenantID, docID uint) ([]models.DocumentVersion, error)
+	GetVersionDownloadURL(ctx context.Context, tenantID, docID uint, versionNumber int) (string, error)
+	RestoreVersion(ctx context.Context, tenantID, docID uint, versionNumber int) (*models.Document, error)
+	SetVisibility(ctx context.Context, tenantID, docID uint, visibility models.Visibility, audience models.Audience) (*models.Document, error)
+	ListSharedFolders(ctx context.Context, tenantID, userID uint) ([]models.Folder, error)
+	ListShared(ctx context.Context, tenantID, userID uint, folderID *uint) ([]models.Document, error)
+	GetSharedDownloadURL(ctx context.Context, tenantID, userID, docID uint) (string, error)
+	Search(ctx context.Context, tenantID uint, query string, page, perPage int) ([]models.DocumentSearchResult, error)
+	SearchShared(ctx context.Context, tenantID, userID uint, query string, page, perPage int) ([]models.DocumentSearchResult, error)
+	CreateShareLink(ctx context.Context, tenantID, userID, docID uint, req CreateShareLinkRequest) (*models.DocumentShareLink, error)
+	ListShareLinks(ctx context.Context, tenantID uint, docID *uint, includeInactive bool) ([]models.DocumentShareLink, error)
+	ListShareLinkAccesses(ctx context.Context, tenantID, linkID uint) ([]models.DocumentShareLinkAccess, error)
+	RevokeShareLink(ctx context.Context, tenantID, linkID uint) error
+	OpenShareLink(ctx context.Context, token, password string, client ShareLinkClient) (string, error)
+}
+
+// CreateShareLinkRequest represents the request to create a public link to a document
+type CreateShareLinkRequest struct {
+	ExpiresAt    time.Time `json:"expires_at" binding:"required"`
+	Password     string    `json:"password" binding:"omitempty,min=6,max=72"`
+	MaxDownloads *int      `json:"max_downloads" binding:"omitempty,min=1"`
+}
+
+// ShareLinkClient identifies who opened a share link, for the access log
+type ShareLinkClient struct {
+	IPAddress string
+	UserAgent string
 }
 
 // documentService implements DocumentService
 type documentService struct {
-	docRepo    repositories.DocumentRepository
-	folderRepo repositories.FolderRepository
-	storageSvc StorageService
+	docRepo        repositories.DocumentRepository
+	folderRepo     repositories.FolderRepository
+	unitMemberRepo repositories.UnitMemberRepository
+	tenantRepo     repositories.TenantRepository
+	storageSvc     StorageService
+	quotaService   StorageQuotaService
+	scanService    DocumentScanService
+	shareLinkRepo  repositories.ShareLinkRepository
+	db             *gorm.DB
+	apiBaseURL     string
 }
 
 // NewDocumentService creates a new document service
 func NewDocumentService(
 	docRepo repositories.DocumentRepository,
 	folderRepo repositories.FolderRepository,
+	unitMemberRepo repositories.UnitMemberRepository,
+	tenantRepo repositories.TenantRepository,
 	storageSvc StorageService,
+	quotaService StorageQuotaService,
+	scanService DocumentScanService,
+	shareLinkRepo repositories.ShareLinkRepository,
+	db *gorm.DB,
+	apiBaseURL string,
 ) DocumentService {
 	return &documentService{
-		docRepo:    docRepo,
-		folderRepo: folderRepo,
-		storageSvc: storageSvc,
+		docRepo:        docRepo,
+		folderRepo:     folderRepo,
+		unitMemberRepo: unitMemberRepo,
+		tenantRepo:     tenantRepo,
+		storageSvc:     storageSvc,
+		quotaService:   quotaService,
+		scanService:    scanService,
+		shareLinkRepo:  shareLinkRepo,
+		db:             db,
+		apiBaseURL:     apiBaseURL,
 	}
 }
 
 // Upload uploads a file and creates a document record
-func (s *documentService) Upload(tenantID, userID uint, folderID *uint, file multipart.File, header *multipart.FileHeader) (*models.Document, error) {
+// It runs without a request transaction: the checks and the saved record each get a short one,
+// so no connection is held while the file goes to storage
+func (s *documentService) Upload(ctx context.Context, tenantID, userID uint, folderID *uint, file multipart.File, header *multipart.FileHeader) (*models.Document, error) {
 	// Validate file size
 	if header.Size > maxFileSize {
 		return nil, errors.New("file size exceeds maximum of 10MB")
 	}
 
-	// Validate folder exists if specified
+	var fileType *models.FileType
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		// Validate folder exists if specified
+		if folderID != nil {
+			if _, err := s.folderRepo.GetByID(ctx, *folderID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errors.New("folder not found")
+				}
+				return fmt.Errorf("failed to validate folder: %w", err)
+			}
+		}
+
+		var err error
+		if fileType, err = s.inspectUpload(tenantID, file, header); err != nil {
+			return err
+		}
+
+		// Don't store a file that can't fit; the space is reserved along with the record
+		return s.quotaService.Check(tenantID, header.Size)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s3Key, err := s.storeFile(ctx, tenantID, file, header, fileType.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := StoredFile{
+		Key:         s3Key,
+		Name:        utils.DisplayFileName(header.Filename),
+		ContentType: fileType.ContentType,
+		Size:        header.Size,
+		Text:        extractText(fileType.ContentType, file, header.Size),
+	}
+	// The reservation is atomic, so concurrent uploads that passed the check can't overshoot the quota
+	var doc *models.Document
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.quotaService.Reserve(ctx, tenantID, header.Size); err != nil {
+			return err
+		}
+		var err error
+		doc, err = s.create(ctx, tenantID, userID, folderID, stored)
+		return err
+	})
+	if err != nil {
+		// Try to clean up the uploaded file on DB error
+		_ = s.storageSvc.Delete(ctx, s3Key)
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// Register creates a document for a file that was uploaded straight to storage
+func (s *documentService) Register(ctx context.Context, tenantID, userID uint, folderID *uint, file StoredFile) (*models.Document, error) {
 	if folderID != nil {
-		_, err := s.folderRepo.GetByID(tenantID, *folderID)
-		if err != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *folderID); err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return nil, errors.New("folder not found")
 			}
@@ -63,46 +208,183 @@ func (s *documentService) Upload(tenantID, userID uint, folderID *uint, file mul
 		}
 	}
 
-	// Generate S3 key
-	fileUUID := uuid.New().String()
-	s3Key := fmt.Sprintf("tenants/%d/documents/%s/%s", tenantID, fileUUID, header.Filename)
-
-	// Detect content type
-	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/octet-stream"
+	if err := s.inspectStored(tenantID, &file); err != nil {
+		return nil, err
 	}
 
-	// Upload to S3
-	ctx := context.Background()
-	if err := s.storageSvc.Upload(ctx, s3Key, file, contentType, header.Size); err != nil {
-		return nil, fmt.Errorf("failed to upload file: %w", err)
+	// The reservation and the record commit or roll back together
+	var doc *models.Document
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.quotaService.Reserve(ctx, tenantID, file.Size); err != nil {
+			return err
+		}
+		var err error
+		doc, err = s.create(ctx, tenantID, userID, folderID, file)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Save document metadata
+	return doc, nil
+}
+
+// create saves the document metadata along with its first version
+func (s *documentService) create(ctx context.Context, tenantID, userID uint, folderID *uint, file StoredFile) (*models.Document, error) {
 	doc := &models.Document{
-		TenantID:     tenantID,
-		FolderID:     folderID,
-		Name:         header.Filename,
-		OriginalName: header.Filename,
-		ContentType:  contentType,
-		Size:         header.Size,
-		S3Key:        s3Key,
-		UploadedByID: userID,
+		TenantID:       tenantID,
+		FolderID:       folderID,
+		Name:           file.Name,
+		OriginalName:   file.Name,
+		ContentType:    file.ContentType,
+		Size:           file.Size,
+		S3Key:          file.Key,
+		UploadedByID:   userID,
+		CurrentVersion: 1,
+		ScanStatus:     models.ScanPending,
+		Versions: []models.DocumentVersion{
+			{
+				TenantID:      tenantID,
+				VersionNumber: 1,
+				OriginalName:  file.Name,
+				ContentType:   file.ContentType,
+				Size:          file.Size,
+				S3Key:         file.Key,
+				UploadedByID:  userID,
+				ScanStatus:    models.ScanPending,
+				ContentText:   file.Text,
+			},
+		},
 	}
 
-	if err := s.docRepo.Create(doc); err != nil {
-		// Try to clean up the uploaded file on DB error
-		_ = s.storageSvc.Delete(ctx, s3Key)
+	if err := s.docRepo.Create(ctx, doc); err != nil {
 		return nil, fmt.Errorf("failed to save document: %w", err)
 	}
 
+	database.AfterCommit(ctx, s.scanService.Notify)
 	return doc, nil
 }
 
+// newDocumentKey generates a unique storage key for a document file
+// The client's file name is sanitized; the original is only kept in the document metadata
+func newDocumentKey(tenantID uint, filename string) string {
+	return fmt.Sprintf("tenants/%d/documents/%s/%s", tenantID, uuid.New().String(), utils.SanitizeFileName(filename))
+}
+
+// storeFile uploads a file under a fresh storage key
+func (s *documentService) storeFile(ctx context.Context, tenantID uint, file multipart.File, header *multipart.FileHeader, contentType string) (string, error) {
+	s3Key := newDocumentKey(tenantID, header.Filename)
+
+	if err := s.storageSvc.Upload(ctx, s3Key, file, contentType, header.Size); err != nil {
+		return "", fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return s3Key, nil
+}
+
+// CheckFileType checks that the tenant accepts the file's extension, before any bytes are uploaded
+func (s *documentService) CheckFileType(tenantID uint, fileName string) (*models.FileType, error) {
+	tenant, err := s.tenantRepo.GetByID(tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	ext := fileExtension(fileName)
+	if !tenant.AllowedFileTypes.Allows(ext) {
+		if ext == "" {
+			return nil, fmt.Errorf("%w: files need an extension", models.ErrFileTypeNotAllowed)
+		}
+		return nil, fmt.Errorf("%w: .%s", models.ErrFileTypeNotAllowed, ext)
+	}
+
+	fileType := models.FileTypes[ext]
+	return &fileType, nil
+}
+
+// inspect checks a file's extension against the tenant's allow-list and its first bytes against the extension
+// The client's Content-Type is never trusted; the returned type carries the content type to store
+func (s *documentService) inspect(tenantID uint, fileName string, head []byte) (*models.FileType, error) {
+	fileType, err := s.CheckFileType(tenantID, fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	sniffed, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if !fileType.Matches(head, sniffed) {
+		return nil, fmt.Errorf("%w: .%s file looks like %s", models.ErrFileContentMismatch, fileExtension(fileName), sniffed)
+	}
+
+	return fileType, nil
+}
+
+// inspectUpload inspects a file received through the API, rewinding it for the upload
+func (s *documentService) inspectUpload(tenantID uint, file multipart.File, header *multipart.FileHeader) (*models.FileType, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return s.inspect(tenantID, header.Filename, head[:n])
+}
+
+// inspectStored inspects a file uploaded straight to storage and sets its detected content type
+func (s *documentService) inspectStored(tenantID uint, file *StoredFile) error {
+	content, err := s.storageSvc.Read(context.Background(), file.Key)
+	if err != nil {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+	defer content.Close()
+
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(content, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return fmt.Errorf("failed to read uploaded file: %w", err)
+	}
+
+	fileType, err := s.inspect(tenantID, file.Name, head[:n])
+	if err != nil {
+		return err
+	}
+
+	file.ContentType = fileType.ContentType
+	file.Text = s.extractStoredText(file)
+	return nil
+}
+
+// extractStoredText extracts the searchable text of a file uploaded straight to storage
+// The file is read into memory, so only files under maxExtractSize are read at all
+func (s *documentService) extractStoredText(file *StoredFile) string {
+	if file.Size <= 0 || file.Size > maxExtractSize || !extractsText(file.ContentType) {
+		return ""
+	}
+
+	content, err := s.storageSvc.Read(context.Background(), file.Key)
+	if err != nil {
+		log.Printf("Failed to read %s for text extraction: %v", file.Key, err)
+		return ""
+	}
+	defer content.Close()
+
+	data, err := io.ReadAll(io.LimitReader(content, maxExtractSize))
+	if err != nil {
+		log.Printf("Failed to read %s for text extraction: %v", file.Key, err)
+		return ""
+	}
+	return extractText(file.ContentType, bytes.NewReader(data), int64(len(data)))
+}
+
+// fileExtension returns the lower-case extension of a file name, without the dot
+func fileExtension(fileName string) string {
+	return strings.TrimPrefix(strings.ToLower(path.Ext(utils.SanitizeFileName(fileName))), ".")
+}
+
 // GetAll retrieves all documents, optionally filtered by folder
-func (s *documentService) GetAll(tenantID uint, folderID *uint) ([]models.Document, error) {
-	docs, err := s.docRepo.GetAll(tenantID, folderID)
+func (s *documentService) GetAll(ctx context.Context, tenantID uint, folderID *uint) ([]models.Document, error) {
+	docs, err := s.docRepo.GetAll(ctx, folderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get documents: %w", err)
 	}
@@ -110,8 +392,8 @@ func (s *documentService) GetAll(tenantID uint, folderID *uint) ([]models.Docume
 }
 
 // GetByID retrieves a document by ID
-func (s *documentService) GetByID(tenantID, docID uint) (*models.Document, error) {
-	doc, err := s.docRepo.GetByID(tenantID, docID)
+func (s *documentService) GetByID(ctx context.Context, tenantID, docID uint) (*models.Document, error) {
+	doc, err := s.docRepo.GetByID(ctx, docID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("document not found")
@@ -122,8 +404,8 @@ func (s *documentService) GetByID(tenantID, docID uint) (*models.Document, error
 }
 
 // GetDownloadURL generates a presigned URL for downloading a document
-func (s *documentService) GetDownloadURL(tenantID, docID uint) (string, error) {
-	doc, err := s.docRepo.GetByID(tenantID, docID)
+func (s *documentService) GetDownloadURL(ctx context.Context, tenantID, docID uint) (string, error) {
+	doc, err := s.docRepo.GetByID(ctx, docID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return "", errors.New("document not found")
@@ -131,6 +413,10 @@ func (s *documentService) GetDownloadURL(tenantID, docID uint) (string, error) {
 		return "", fmt.Errorf("failed to get document: %w", err)
 	}
 
+	if err := doc.ScanStatus.DownloadError(); err != nil {
+		return "", err
+	}
+
 	url, err := s.storageSvc.GetPresignedURL(context.Background(), doc.S3Key, 15*time.Minute)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate download URL: %w", err)
@@ -139,9 +425,9 @@ func (s *documentService) GetDownloadURL(tenantID, docID uint) (string, error) {
 	return url, nil
 }
 
-// Delete removes a document from S3 and the database
-func (s *documentService) Delete(tenantID, docID uint) error {
-	doc, err := s.docRepo.GetByID(tenantID, docID)
+// Delete removes a document and all of its versions from S3 and the database
+func (s *documentService) Delete(ctx context.Context, tenantID, docID uint) error {
+	doc, err := s.docRepo.GetByID(ctx, docID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("document not found")
@@ -149,22 +435,53 @@ func (s *documentService) Delete(tenantID, docID uint) error {
 		return fmt.Errorf("failed to get document: %w", err)
 	}
 
-	// Delete from S3
-	if err := s.storageSvc.Delete(context.Background(), doc.S3Key); err != nil {
-		return fmt.Errorf("failed to delete file from storage: %w", err)
+	versions, err := s.docRepo.GetVersions(ctx, docID)
+	if err != nil {
+		return fmt.Errorf("failed to get document versions: %w", err)
+	}
+
+	keys := map[string]bool{doc.S3Key: true}
+	var size int64
+	for _, v := range versions {
+		keys[v.S3Key] = true
+		size += v.Size
+	}
+
+	// Delete from database first; the stored versions are only removed once that commits,
+	// so a failed delete never leaves a document pointing at missing files
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.docRepo.Delete(ctx, docID); err != nil {
+			return fmt.Errorf("failed to delete document record: %w", err)
+		}
+		database.AfterCommit(ctx, func() {
+			deleteStoredFiles(s.storageSvc, keys)
+		})
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Delete from database
-	if err := s.docRepo.Delete(tenantID, docID); err != nil {
-		return fmt.Errorf("failed to delete document record: %w", err)
+	if err := s.quotaService.Release(ctx, tenantID, size); err != nil {
+		log.Printf("Failed to release storage of document %d: %v", docID, err)
 	}
 
 	return nil
 }
 
+// deleteStoredFiles removes files from storage after their records were deleted
+// Failures are only logged: the records are gone, so at worst an orphaned file is left behind
+func deleteStoredFiles(storageSvc StorageService, keys map[string]bool) {
+	for key := range keys {
+		if err := storageSvc.Delete(context.Background(), key); err != nil {
+			log.Printf("Failed to delete file %s from storage: %v", key, err)
+		}
+	}
+}
+
 // MoveToFolder moves a document to a different folder
-func (s *documentService) MoveToFolder(tenantID, docID uint, folderID *uint) error {
-	doc, err := s.docRepo.GetByID(tenantID, docID)
+func (s *documentService) MoveToFolder(ctx context.Context, tenantID, docID uint, folderID *uint) error {
+	doc, err := s.docRepo.GetByID(ctx, docID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("document not found")
@@ -174,7 +491,7 @@ func (s *documentService) MoveToFolder(tenantID, docID uint, folderID *uint) err
 
 	// Validate target folder exists if specified
 	if folderID != nil {
-		_, err := s.folderRepo.GetByID(tenantID, *folderID)
+		_, err := s.folderRepo.GetByID(ctx, *folderID)
 		if err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				return errors.New("target folder not found")
@@ -184,9 +501,601 @@ func (s *documentService) MoveToFolder(tenantID, docID uint, folderID *uint) err
 	}
 
 	doc.FolderID = folderID
-	if err := s.docRepo.Update(doc); err != nil {
+	if err := s.docRepo.Update(ctx, doc); err != nil {
 		return fmt.Errorf("failed to move document: %w", err)
 	}
 
 	return nil
 }
+
+// UploadVersion uploads a new file for an existing document and makes it the current version
+// Previous versions stay in storage and can still be downloaded or restored.
+// Like Upload, it uses short transactions around the database work instead of a request transaction
+func (s *documentService) UploadVersion(ctx context.Context, tenantID, userID, docID uint, file multipart.File, header *multipart.FileHeader) (*models.DocumentVersion, error) {
+	if header.Size > maxFileSize {
+		return nil, errors.New("file size exceeds maximum of 10MB")
+	}
+
+	var doc *models.Document
+	var fileType *models.FileType
+	err := database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		var err error
+		doc, err = s.docRepo.GetByID(ctx, docID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("document not found")
+			}
+			return fmt.Errorf("failed to get document: %w", err)
+		}
+
+		if fileType, err = s.inspectUpload(tenantID, file, header); err != nil {
+			return err
+		}
+
+		return s.quotaService.Check(tenantID, header.Size)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s3Key, err := s.storeFile(ctx, tenantID, file, header, fileType.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := StoredFile{
+		Key:         s3Key,
+		Name:        utils.DisplayFileName(header.Filename),
+		ContentType: fileType.ContentType,
+		Size:        header.Size,
+		Text:        extractText(fileType.ContentType, file, header.Size),
+	}
+	var version *models.DocumentVersion
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.quotaService.Reserve(ctx, tenantID, header.Size); err != nil {
+			return err
+		}
+		var err error
+		version, err = s.addVersion(ctx, doc, userID, stored)
+		return err
+	})
+	if err != nil {
+		_ = s.storageSvc.Delete(ctx, s3Key)
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// RegisterVersion adds a file that was uploaded straight to storage as the new current version of a document
+func (s *documentService) RegisterVersion(ctx context.Context, tenantID, userID, docID uint, file StoredFile) (*models.DocumentVersion, error) {
+	doc, err := s.GetByID(ctx, tenantID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.inspectStored(tenantID, &file); err != nil {
+		return nil, err
+	}
+
+	var version *models.DocumentVersion
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.quotaService.Reserve(ctx, tenantID, file.Size); err != nil {
+			return err
+		}
+		var err error
+		version, err = s.addVersion(ctx, doc, userID, file)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+// addVersion saves a stored file as the next version of a document
+func (s *documentService) addVersion(ctx context.Context, doc *models.Document, userID uint, file StoredFile) (*models.DocumentVersion, error) {
+	version := &models.DocumentVersion{
+		OriginalName: file.Name,
+		ContentType:  file.ContentType,
+		Size:         file.Size,
+		S3Key:        file.Key,
+		UploadedByID: userID,
+		ScanStatus:   models.ScanPending,
+		ContentText:  file.Text,
+	}
+
+	if err := s.docRepo.AddVersion(ctx, doc, version); err != nil {
+		return nil, fmt.Errorf("failed to save document version: %w", err)
+	}
+
+	database.AfterCommit(ctx, s.scanService.Notify)
+	return version, nil
+}
+
+// ListVersions retrieves the version history of a document
+func (s *documentService) ListVersions(ctx context.Context, tenantID, docID uint) ([]models.DocumentVersion, error) {
+	if _, err := s.GetByID(ctx, tenantID, docID); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.docRepo.GetVersions(ctx, docID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document versions: %w", err)
+	}
+	return versions, nil
+}
+
+// GetVersionDownloadURL generates a presigned URL for downloading a specific version
+func (s *documentService) GetVersionDownloadURL(ctx context.Context, tenantID, docID uint, versionNumber int) (string, error) {
+	version, err := s.getVersion(ctx, tenantID, docID, versionNumber)
+	if err != nil {
+		return "", err
+	}
+
+	if err := version.ScanStatus.DownloadError(); err != nil {
+		return "", err
+	}
+
+	url, err := s.storageSvc.GetPresignedURL(context.Background(), version.S3Key, 15*time.Minute)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate download URL: %w", err)
+	}
+
+	return url, nil
+}
+
+// RestoreVersion makes an older version the current one of the document
+func (s *documentService) RestoreVersion(ctx context.Context, tenantID, docID uint, versionNumber int) (*models.Document, error) {
+	doc, err := s.GetByID(ctx, tenantID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := s.getVersion(ctx, tenantID, docID, versionNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.CurrentVersion == version.VersionNumber {
+		return nil, errors.New("version is already the current one")
+	}
+
+	// A pending version may be restored (it becomes downloadable once clean), a blocked one may not
+	if version.ScanStatus == models.ScanInfected || version.ScanStatus == models.ScanFailed {
+		return nil, models.ErrScanBlocked
+	}
+
+	doc.ApplyVersion(version)
+	if err := s.docRepo.Update(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to restore document version: %w", err)
+	}
+
+	return doc, nil
+}
+
+// getVersion retrieves a version of a document, mapping not found errors
+func (s *documentService) getVersion(ctx context.Context, tenantID, docID uint, versionNumber int) (*models.DocumentVersion, error) {
+	version, err := s.docRepo.GetVersion(ctx, docID, versionNumber)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document version not found")
+		}
+		return nil, fmt.Errorf("failed to get document version: %w", err)
+	}
+	return version, nil
+}
+
+// SetVisibility changes who can see a document outside of management
+func (s *documentService) SetVisibility(ctx context.Context, tenantID, docID uint, visibility models.Visibility, audience models.Audience) (*models.Document, error) {
+	doc, err := s.GetByID(ctx, tenantID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := normalizeVisibility(&visibility, &audience, true); err != nil {
+		return nil, err
+	}
+
+	doc.Visibility = visibility
+	doc.Audience = audience
+	if err := s.docRepo.Update(ctx, doc); err != nil {
+		return nil, fmt.Errorf("failed to update document visibility: %w", err)
+	}
+
+	return doc, nil
+}
+
+// CreateShareLink creates a public link to a document for someone outside the platform
+// The returned link carries the token and URL; only the token's hash is stored, so they can't be shown again
+func (s *documentService) CreateShareLink(ctx context.Context, tenantID, userID, docID uint, req CreateShareLinkRequest) (*models.DocumentShareLink, error) {
+	doc, err := s.GetByID(ctx, tenantID, docID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.ScanStatus.DownloadError(); err != nil {
+		return nil, err
+	}
+
+	if !req.ExpiresAt.After(time.Now()) {
+		return nil, errors.New("expires_at must be in the future")
+	}
+	if req.ExpiresAt.After(time.Now().Add(maxShareLinkDuration)) {
+		return nil, errors.New("share links can expire at most 90 days from now")
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.DocumentShareLink{
+		TenantID:     tenantID,
+		DocumentID:   doc.ID,
+		TokenHash:    utils.HashToken(token),
+		ExpiresAt:    req.ExpiresAt,
+		MaxDownloads: req.MaxDownloads,
+		CreatedByID:  userID,
+	}
+	if req.Password != "" {
+		hash, err := utils.HashPassword(req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = hash
+		link.HasPassword = true
+	}
+
+	if err := s.shareLinkRepo.Create(ctx, link); err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	link.Token = token
+	link.URL = fmt.Sprintf("%s/api/share/%s", strings.TrimRight(s.apiBaseURL, "/"), token)
+	link.Document = doc
+	return link, nil
+}
+
+// ListShareLinks retrieves the share links of the tenant, optionally for one document
+// Revoked, expired and used-up links are only included when asked for
+func (s *documentService) ListShareLinks(ctx context.Context, tenantID uint, docID *uint, includeInactive bool) ([]models.DocumentShareLink, error) {
+	links, err := s.shareLinkRepo.GetAll(ctx, docID, !includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	return links, nil
+}
+
+// ListShareLinkAccesses retrieves every attempt to open a share link
+func (s *documentService) ListShareLinkAccesses(ctx context.Context, tenantID, linkID uint) ([]models.DocumentShareLinkAccess, error) {
+	if _, err := s.getShareLink(ctx, tenantID, linkID); err != nil {
+		return nil, err
+	}
+
+	accesses, err := s.shareLinkRepo.GetAccesses(ctx, linkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link accesses: %w", err)
+	}
+	return accesses, nil
+}
+
+// RevokeShareLink disables a share link right away
+func (s *documentService) RevokeShareLink(ctx context.Context, tenantID, linkID uint) error {
+	if _, err := s.getShareLink(ctx, tenantID, linkID); err != nil {
+		return err
+	}
+
+	revoked, err := s.shareLinkRepo.Revoke(ctx, linkID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if !revoked {
+		return errors.New("share link is already revoked")
+	}
+	return nil
+}
+
+// getShareLink retrieves a share link of the tenant, mapping not found errors
+func (s *documentService) getShareLink(ctx context.Context, tenantID, linkID uint) (*models.DocumentShareLink, error) {
+	link, err := s.shareLinkRepo.GetByID(ctx, linkID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	return link, nil
+}
+
+// OpenShareLink resolves a public share link to a short-lived download URL of its document
+// Every attempt on an existing link is logged with its outcome, including wrong passwords
+func (s *documentService) OpenShareLink(ctx context.Context, token, password string, client ShareLinkClient) (string, error) {
+	hash := utils.HashToken(token)
+
+	// The link's tenant is unknown until the link is found, so it is looked up in a scope that exposes only this link
+	var tenantID uint
+	err := database.RunScoped(ctx, s.db, database.Scope{ShareTokenHash: hash}, func(ctx context.Context) error {
+		link, err := s.shareLinkRepo.GetByTokenHash(ctx, hash)
+		if err != nil {
+			return err
+		}
+		tenantID = link.TenantID
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", models.ErrShareLinkNotFound
+		}
+		return "", fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	// The attempt is logged even when it is refused, so refusals don't roll the transaction back
+	var url string
+	var openErr error
+	err = database.RunScoped(ctx, s.db, database.Scope{TenantID: tenantID, ShareTokenHash: hash}, func(ctx context.Context) error {
+		url, openErr = s.openShareLink(ctx, hash, password, client)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return url, openErr
+}
+
+// openShareLink checks the share link and counts the download, in a scope of the link's tenant
+func (s *documentService) openShareLink(ctx context.Context, hash, password string, client ShareLinkClient) (string, error) {
+	link, err := s.shareLinkRepo.GetByTokenHash(ctx, hash)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", models.ErrShareLinkNotFound
+		}
+		return "", fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	if reason := link.UnavailableReason(); reason != "" {
+		s.logShareAccess(ctx, link, reason, client)
+		return "", models.ErrShareLinkUnavailable
+	}
+
+	if link.HasPassword {
+		if password == "" {
+			s.logShareAccess(ctx, link, models.ShareAccessPasswordRequired, client)
+			return "", models.ErrSharePasswordRequired
+		}
+		if utils.CheckPassword(password, link.PasswordHash) != nil {
+			s.logShareAccess(ctx, link, models.ShareAccessWrongPassword, client)
+			return "", models.ErrSharePasswordInvalid
+		}
+	}
+
+	// The document may have been deleted, or replaced by a version that isn't cleared yet
+	if link.Document == nil {
+		s.logShareAccess(ctx, link, models.ShareAccessUnavailable, client)
+		return "", models.ErrShareLinkUnavailable
+	}
+	if err := link.Document.ScanStatus.DownloadError(); err != nil {
+		s.logShareAccess(ctx, link, models.ShareAccessUnavailable, client)
+		return "", err
+	}
+
+	consumed, err := s.shareLinkRepo.ConsumeDownload(ctx, link)
+	if err != nil {
+		return "", fmt.Errorf("failed to count download: %w", err)
+	}
+	if !consumed {
+		// Another download used up the link (or it expired) since it was read
+		s.logShareAccess(ctx, link, models.ShareAccessExhausted, client)
+		return "", models.ErrShareLinkUnavailable
+	}
+
+	url, err := s.storageSvc.GetPresignedURL(context.Background(), link.Document.S3Key, shareLinkURLDuration)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate download URL: %w", err)
+	}
+
+	s.logShareAccess(ctx, link, models.ShareAccessGranted, client)
+	return url, nil
+}
+
+// logShareAccess records an attempt to open a share link; failures are logged, never returned
+func (s *documentService) logShareAccess(ctx context.Context, link *models.DocumentShareLink, outcome models.ShareAccessOutcome, client ShareLinkClient) {
+	userAgent := client.UserAgent
+	for len(userAgent) > 255 {
+		_, size := utf8.DecodeLastRuneInString(userAgent)
+		userAgent = userAgent[:len(userAgent)-size]
+	}
+
+	access := &models.DocumentShareLinkAccess{
+		TenantID:    link.TenantID,
+		ShareLinkID: link.ID,
+		Outcome:     outcome,
+		IPAddress:   client.IPAddress,
+		UserAgent:   userAgent,
+	}
+	if err := s.shareLinkRepo.LogAccess(ctx, access); err != nil {
+		log.Printf("Failed to log access to share link %d: %v", link.ID, err)
+	}
+}
+
+// ListSharedFolders retrieves the folders a resident can browse
+// A folder is listed when it is shared with the resident or holds a document that is
+func (s *documentService) ListSharedFolders(ctx context.Context, tenantID, userID uint) ([]models.Folder, error) {
+	scope, err := s.residentScope(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := s.folderRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders: %w", err)
+	}
+
+	docs, err := s.docRepo.GetAll(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	withDocs := make(map[uint]bool)
+	for i := range docs {
+		if docs[i].FolderID != nil && docs[i].ScanStatus == models.ScanClean && docs[i].VisibleTo(scope) {
+			withDocs[*docs[i].FolderID] = true
+		}
+	}
+
+	shared := make([]models.Folder, 0, len(folders))
+	for i := range folders {
+		if folders[i].VisibleTo(scope) || withDocs[folders[i].ID] {
+			shared = append(shared, folders[i])
+		}
+	}
+	return shared, nil
+}
+
+// ListShared retrieves the documents a resident can see, optionally filtered by folder
+func (s *documentService) ListShared(ctx context.Context, tenantID, userID uint, folderID *uint) ([]models.Document, error) {
+	scope, err := s.residentScope(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.docRepo.GetAll(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	// Residents only see files that passed the malware scan
+	shared := make([]models.Document, 0, len(docs))
+	for i := range docs {
+		if docs[i].ScanStatus == models.ScanClean && docs[i].VisibleTo(scope) {
+			shared = append(shared, docs[i])
+		}
+	}
+	return shared, nil
+}
+
+// GetSharedDownloadURL generates a presigned URL for a document shared with the resident
+func (s *documentService) GetSharedDownloadURL(ctx context.Context, tenantID, userID, docID uint) (string, error) {
+	scope, err := s.residentScope(ctx, tenantID, userID)
+	if err != nil {
+		return "", err
+	}
+
+	doc, err := s.GetByID(ctx, tenantID, docID)
+	if err != nil {
+		return "", err
+	}
+
+	// Hidden documents look the same as missing ones
+	if !doc.VisibleTo(scope) {
+		return "", errors.New("document not found")
+	}
+
+	if err := doc.ScanStatus.DownloadError(); err != nil {
+		return "", err
+	}
+
+	url, err := s.storageSvc.GetPresignedURL(context.Background(), doc.S3Key, 15*time.Minute)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate download URL: %w", err)
+	}
+
+	return url, nil
+}
+
+// Search runs a full-text search over every document of the tenant
+func (s *documentService) Search(ctx context.Context, tenantID uint, query string, page, perPage int) ([]models.DocumentSearchResult, error) {
+	query, err := searchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := s.docRepo.Search(ctx, query, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+	return markHighlights(results), nil
+}
+
+// SearchShared runs a full-text search over the documents shared with a resident
+// Visibility is resolved in Go, so matches are fetched in batches until the page is filled
+func (s *documentService) SearchShared(ctx context.Context, tenantID, userID uint, query string, page, perPage int) ([]models.DocumentSearchResult, error) {
+	query, err := searchQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	scope, err := s.residentScope(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	skip := (page - 1) * perPage
+	shared := make([]models.DocumentSearchResult, 0, perPage)
+	for offset := 0; len(shared) < perPage && offset < maxSharedSearchScan; offset += sharedSearchBatch {
+		results, err := s.docRepo.Search(ctx, query, sharedSearchBatch, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search documents: %w", err)
+		}
+
+		for i := range results {
+			doc := &results[i].Document
+			if doc.ScanStatus != models.ScanClean || !doc.VisibleTo(scope) {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(shared) < perPage {
+				shared = append(shared, results[i])
+			}
+		}
+
+		if len(results) < sharedSearchBatch {
+			break
+		}
+	}
+	return markHighlights(shared), nil
+}
+
+// searchQuery validates a search query
+func searchQuery(query string) (string, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < 2 {
+		return "", fmt.Errorf("%w: use at least 2 characters", models.ErrInvalidSearchQuery)
+	}
+	if len(query) > 200 {
+		return "", fmt.Errorf("%w: use at most 200 characters", models.ErrInvalidSearchQuery)
+	}
+	return query, nil
+}
+
+// markHighlights HTML-escapes the highlights and wraps the matched words in <mark> tags
+func markHighlights(results []models.DocumentSearchResult) []models.DocumentSearchResult {
+	marks := strings.NewReplacer(models.HighlightStart, "<mark>", models.HighlightStop, "</mark>")
+	for i := range results {
+		results[i].NameHighlight = marks.Replace(html.EscapeString(results[i].NameHighlight))
+		results[i].Snippet = marks.Replace(html.EscapeString(results[i].Snippet))
+	}
+	return results
+}
+
+// residentScope collects the units and blocks the user currently lives in
+func (s *documentService) residentScope(ctx context.Context, tenantID, userID uint) (models.ResidentScope, error) {
+	var scope models.ResidentScope
+
+	members, err := s.unitMemberRepo.GetCurrentByUser(ctx, userID)
+	if err != nil {
+		return scope, fmt.Errorf("failed to get unit memberships: %w", err)
+	}
+
+	for _, m := range members {
+		scope.UnitIDs = append(scope.UnitIDs, m.UnitID)
+		if m.Unit != nil && m.Unit.Block != "" {
+			scope.Blocks = append(scope.Blocks, m.Unit.Block)
+		}
+	}
+	return scope, nil
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// outboxBatchSize caps how many emails one delivery pass picks up
+	outboxBatchSize = 20
+	// outboxLease is how long a worker holds an email while delivering it
+	outboxLease = 5 * time.Minute
+	// outboxMaxAttempts is how many deliveries are tried before an email is dead-lettered
+	outboxMaxAttempts = 10
+	// outboxBaseBackoff is the wait after the first failure; it doubles on each further one
+	outboxBaseBackoff = time.Minute
+	// outboxMaxBackoff caps the wait between two attempts
+	outboxMaxBackoff = 6 * time.Hour
+)
+
+// EmailOutboxService defines the interface for the transactional email outbox
+// It is itself an EmailService: SendEmail queues the email, and a background worker hands it to the delivery backend
+type EmailOutboxService interface {
+	EmailService
+	Enqueue(ctx context.Context, msg EmailMessage) error
+	Notify()
+	DeliverPending(ctx context.Context) (int, error)
+	Run(ctx context.Context, interval time.Duration)
+	List(status models.OutboxStatus, page, perPage int) ([]models.OutboxEmail, int64, error)
+	GetByID(id uint) (*models.OutboxEmail, error)
+	Retry(id uint) (*models.OutboxEmail, error)
+}
+
+// emailOutboxService implements EmailOutboxService
+type emailOutboxService struct {
+	outboxRepo repositories.EmailOutboxRepository
+	backend    EmailService
+	wake       chan struct{}
+}
+
+// NewEmailOutboxService creates a new email outbox delivering through backend (console, Resend or SMTP)
+func NewEmailOutboxService(outboxRepo repositories.EmailOutboxRepository, backend EmailService) EmailOutboxService {
+	return &emailOutboxService{
+		outboxRepo: outboxRepo,
+		backend:    backend,
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// SendEmail queues an email on its own, for callers without a transaction of their own
+// Callers should set an IdempotencyKey naming what the email is for; without one it is derived from the content
+func (s *emailOutboxService) SendEmail(msg EmailMessage) error {
+	return s.Enqueue(context.Background(), msg)
+}
+
+// Enqueue queues an email in the transaction carried by ctx, so it is only sent if the transaction commits
+// The worker is notified once it has; an email whose idempotency key is already queued is not queued again
+func (s *emailOutboxService) Enqueue(ctx context.Context, msg EmailMessage) error {
+	key := msg.IdempotencyKey
+	if key == "" {
+		key = "email:" + utils.HashToken(msg.To+"\x00"+msg.Subject+"\x00"+msg.HTML+"\x00"+msg.Text)
+	}
+
+	email := &models.OutboxEmail{
+		IdempotencyKey: key,
+		ToAddress:      msg.To,
+		Subject:        msg.Subject,
+		HTML:           msg.HTML,
+		Text:           msg.Text,
+		Status:         models.OutboxPending,
+		NextAttemptAt:  time.Now(),
+	}
+	if err := s.outboxRepo.Create(ctx, email); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+
+	database.AfterCommit(ctx, s.Notify)
+	return nil
+}
+
+// Notify wakes the worker after an email is queued so it doesn't wait for the next tick
+func (s *emailOutboxService) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// A delivery pass is already due
+	}
+}
+
+// DeliverPending delivers a batch of due emails and returns how many were picked up
+func (s *emailOutboxService) DeliverPending(ctx context.Context) (int, error) {
+	emails, err := s.outboxRepo.ClaimDue(outboxBatchSize, outboxLease)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim queued emails: %w", err)
+	}
+
+	for i := range emails {
+		if ctx.Err() != nil {
+			// The lease runs out and another pass picks the rest up
+			break
+		}
+		s.deliver(&emails[i])
+	}
+
+	return len(emails), nil
+}
+
+// deliver hands one email to the backend and records the outcome
+func (s *emailOutboxService) deliver(email *models.OutboxEmail) {
+	err := s.backend.SendEmail(EmailMessage{
+		To:             email.ToAddress,
+		Subject:        email.Subject,
+		HTML:           email.HTML,
+		Text:           email.Text,
+		IdempotencyKey: email.IdempotencyKey,
+	})
+	if err == nil {
+		if err := s.outboxRepo.MarkSent(email.ID); err != nil {
+			log.Printf("Failed to mark email %d as sent: %v", email.ID, err)
+		}
+		return
+	}
+
+	if email.Attempts >= outboxMaxAttempts {
+		log.Printf("Giving up on email %d to %s after %d attempts: %v", email.ID, email.ToAddress, email.Attempts, err)
+		if err := s.outboxRepo.MarkFailed(email.ID, err.Error()); err != nil {
+			log.Printf("Failed to mark email %d as failed: %v", email.ID, err)
+		}
+		return
+	}
+
+	next := time.Now().Add(outboxBackoff(email.Attempts))
+	log.Printf("Failed to deliver email %d to %s (attempt %d, retrying at %s): %v", email.ID, email.ToAddress, email.Attempts, next.Format(time.RFC3339), err)
+	if err := s.outboxRepo.MarkRetry(email.ID, err.Error(), next); err != nil {
+		log.Printf("Failed to schedule retry of email %d: %v", email.ID, err)
+	}
+}
+
+// outboxBackoff is the wait after the given number of failed attempts: 1m, 2m, 4m, ... up to outboxMaxBackoff
+func outboxBackoff(attempts int) time.Duration {
+	wait := outboxBaseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+	return wait
+}
+
+// Run delivers due emails every interval, or sooner when notified, until ctx is cancelled
+func (s *emailOutboxService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+
+		// Keep going while full batches come back so a backlog drains without waiting for the ticker
+		for ctx.Err() == nil {
+			delivered, err := s.DeliverPending(ctx)
+			if err != nil {
+				log.Printf("Email outbox: %v", err)
+				break
+			}
+			if delivered < outboxBatchSize {
+				break
+			}
+		}
+	}
+}
+
+// List retrieves queued emails for inspection, optionally with one status
+func (s *emailOutboxService) List(status models.OutboxStatus, page, perPage int) ([]models.OutboxEmail, int64, error) {
+	switch status {
+	case "", models.OutboxPending, models.OutboxSent, models.OutboxFailed:
+	default:
+		return nil, 0, fmt.Errorf("invalid status: %s", status)
+	}
+
+	emails, total, err := s.outboxRepo.GetAll(status, perPage, (page-1)*perPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get emails: %w", err)
+	}
+	return emails, total, nil
+}
+
+// GetByID retrieves a queued email
+func (s *emailOutboxService) GetByID(id uint) (*models.OutboxEmail, error) {
+	email, err := s.outboxRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrOutboxEmailNotFound
+		}
+		return nil, fmt.Errorf("failed to get email: %w", err)
+	}
+	return email, nil
+}
+
+// Retry puts a dead-lettered email back in the queue with a fresh set of attempts
+// The idempotency key is kept, so a backend that already accepted the email won't send it twice
+func (s *emailOutboxService) Retry(id uint) (*models.OutboxEmail, error) {
+	if _, err := s.GetByID(id); err != nil {
+		return nil, err
+	}
+
+	requeued, err := s.outboxRepo.Requeue(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue email: %w", err)
+	}
+	if !requeued {
+		return nil, models.ErrOutboxEmailNotRetryable
+	}
+	s.Notify()
+
+	return s.GetByID(id)
+}
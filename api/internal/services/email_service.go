@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"html"
 	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"strings"
 
 	"github.com/arturbaldoramos/Habitta/internal/config"
 )
@@ -16,6 +19,11 @@ type EmailMessage struct {
 	To      string
 	Subject string
 	HTML    string
+	Text    string // Plain-text alternative; derived from HTML when empty
+
+	// IdempotencyKey identifies the email across delivery attempts, so a retry after an
+	// ambiguous failure (e.g. a timeout) doesn't reach the recipient twice
+	IdempotencyKey string
 }
 
 // EmailService defines the interface for sending emails
@@ -43,6 +51,7 @@ func (s *resendEmailService) SendEmail(msg EmailMessage) error {
 		"to":      []string{msg.To},
 		"subject": msg.Subject,
 		"html":    msg.HTML,
+		"text":    msg.PlainText(),
 	}
 
 	body, err := json.Marshal(payload)
@@ -57,6 +66,9 @@ func (s *resendEmailService) SendEmail(msg EmailMessage) error {
 
 	req.Header.Set("Authorization", "Bearer "+s.apiKey)
 	req.Header.Set("Content-Type", "application/json")
+	if msg.IdempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", msg.IdempotencyKey)
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -72,16 +84,58 @@ func (s *resendEmailService) SendEmail(msg EmailMessage) error {
 	return nil
 }
 
-// NewEmailService creates the appropriate email service based on environment
+// NewEmailService creates the email service selected by EMAIL_DRIVER
 func NewEmailService(cfg *config.Config) EmailService {
-	if cfg.Server.Env == "development" {
-		log.Println("Using console email service (development mode)")
+	switch cfg.Email.Driver {
+	case "smtp":
+		log.Printf("Using SMTP email service (%s:%d, %s)", cfg.Email.SMTP.Host, cfg.Email.SMTP.Port, cfg.Email.SMTP.Security)
+		return NewSMTPEmailService(cfg.Email.SMTP, cfg.Email.FromAddress)
+	case "resend":
+		log.Println("Using Resend email service")
+		return &resendEmailService{
+			apiKey:      cfg.Email.ResendAPIKey,
+			fromAddress: cfg.Email.FromAddress,
+		}
+	default:
+		log.Println("Using console email service")
 		return &consoleEmailService{}
 	}
+}
+
+// PlainText returns the message's text alternative, deriving it from the HTML body when none was set
+func (m EmailMessage) PlainText() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return htmlToText(m.HTML)
+}
+
+var (
+	htmlDropBlocks = regexp.MustCompile(`(?is)<(head|style|script)[^>]*>.*?</(head|style|script)>`)
+	htmlLinks      = regexp.MustCompile(`(?is)<a\s[^>]*href\s*=\s*["']([^"']+)["'][^>]*>(.*?)</a>`)
+	htmlLineBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|h[1-6]|li|tr|table|blockquote)>`)
+	htmlTags       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLines     = regexp.MustCompile(`\n{3,}`)
+)
 
-	log.Println("Using Resend email service")
-	return &resendEmailService{
-		apiKey:      cfg.Email.ResendAPIKey,
-		fromAddress: cfg.Email.FromAddress,
+// htmlToText renders an email's HTML body as readable plain text, keeping link targets next to their labels
+func htmlToText(body string) string {
+	text := htmlDropBlocks.ReplaceAllString(body, "")
+	text = htmlLinks.ReplaceAllStringFunc(text, func(link string) string {
+		parts := htmlLinks.FindStringSubmatch(link)
+		label := strings.TrimSpace(htmlTags.ReplaceAllString(parts[2], ""))
+		if label == "" || label == parts[1] {
+			return parts[1]
+		}
+		return label + " (" + parts[1] + ")"
+	})
+	text = htmlLineBreaks.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(htmlTags.ReplaceAllString(text, ""))
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.Join(strings.Fields(line), " ")
 	}
+	text = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(text)
 }
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+)
+
+//go:embed templates/emails
+var emailTemplateFS embed.FS
+
+// EmailTemplate names an email with a template in every supported locale
+type EmailTemplate string
+
+const (
+	EmailInvite         EmailTemplate = "invite"
+	EmailInviteReminder EmailTemplate = "invite_reminder"
+	EmailPasswordReset  EmailTemplate = "password_reset"
+	EmailVerification   EmailTemplate = "email_verification"
+	EmailAccountLocked  EmailTemplate = "account_locked"
+	EmailStorageWarning EmailTemplate = "storage_warning"
+)
+
+// emailTemplateNames lists every template, so a missing translation fails at startup instead of at send time
+var emailTemplateNames = []EmailTemplate{
+	EmailInvite,
+	EmailInviteReminder,
+	EmailPasswordReset,
+	EmailVerification,
+	EmailAccountLocked,
+	EmailStorageWarning,
+}
+
+// EmailBranding is the identity shown at the top of an email: the condominium, or Habitta itself
+type EmailBranding struct {
+	Name    string
+	LogoURL string
+}
+
+// platformBranding brands emails that don't belong to a condominium (account emails)
+var platformBranding = EmailBranding{Name: "Habitta"}
+
+// tenantBranding brands emails sent on behalf of a condominium
+func tenantBranding(tenant *models.Tenant) EmailBranding {
+	if tenant == nil {
+		return platformBranding
+	}
+	return EmailBranding{Name: tenant.Name, LogoURL: tenant.LogoURL}
+}
+
+// Data of each template, available as .Data
+type (
+	inviteEmailData struct { // invite and invite_reminder
+		Role      models.UserRole
+		Link      string
+		ExpiresAt time.Time
+	}
+	accountLinkEmailData struct { // password_reset and email_verification
+		Name string
+		Link string
+	}
+	accountLockedEmailData struct {
+		Name        string
+		LockedUntil time.Time
+		Link        string
+	}
+	storageWarningEmailData struct {
+		Level      int
+		UsedBytes  int64
+		QuotaBytes int64
+		Link       string
+	}
+)
+
+// emailView is what every template is executed with
+type emailView struct {
+	Locale string
+	Brand  EmailBranding
+	Data   interface{}
+}
+
+// emailButton is the argument of the "button" block
+type emailButton struct {
+	URL   string
+	Label string
+}
+
+// localizedEmail holds the parsed templates of one email in one locale
+type localizedEmail struct {
+	subject *texttemplate.Template // Subjects are plain text, so they must not be HTML-escaped
+	html    *htmltemplate.Template
+}
+
+// emailTemplates holds every email by locale; parsed once, at startup
+var emailTemplates = mustParseEmailTemplates()
+
+// mustParseEmailTemplates parses the embedded templates; a broken template is a programming error
+func mustParseEmailTemplates() map[string]map[EmailTemplate]localizedEmail {
+	layout := "templates/emails/layout.html"
+	parsed := make(map[string]map[EmailTemplate]localizedEmail, len(models.SupportedLocales))
+
+	for _, locale := range models.SupportedLocales {
+		funcs := emailFuncs(locale)
+		common := "templates/emails/" + locale + "/common.html"
+		parsed[locale] = make(map[EmailTemplate]localizedEmail, len(emailTemplateNames))
+
+		for _, name := range emailTemplateNames {
+			file := "templates/emails/" + locale + "/" + string(name) + ".html"
+			parsed[locale][name] = localizedEmail{
+				subject: texttemplate.Must(texttemplate.New(string(name)).Funcs(texttemplate.FuncMap(funcs)).ParseFS(emailTemplateFS, file)),
+				html:    htmltemplate.Must(htmltemplate.New(string(name)).Funcs(htmltemplate.FuncMap(funcs)).ParseFS(emailTemplateFS, layout, common, file)),
+			}
+		}
+	}
+
+	return parsed
+}
+
+// renderEmail renders a template in the locale, falling back to DefaultLocale, with a plain-text alternative
+// The caller fills in the recipient and, when needed, the idempotency key
+func renderEmail(name EmailTemplate, locale string, brand EmailBranding, data interface{}) (EmailMessage, error) {
+	locale = models.ResolveLocale(locale)
+	tmpl, ok := emailTemplates[locale][name]
+	if !ok {
+		return EmailMessage{}, fmt.Errorf("unknown email template: %s", name)
+	}
+	view := emailView{Locale: locale, Brand: brand, Data: data}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.ExecuteTemplate(&subject, "subject", view); err != nil {
+		return EmailMessage{}, fmt.Errorf("failed to render %s email subject: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&body, "layout", view); err != nil {
+		return EmailMessage{}, fmt.Errorf("failed to render %s email: %w", name, err)
+	}
+
+	return EmailMessage{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    body.String(),
+		Text:    htmlToText(body.String()),
+	}, nil
+}
+
+// emailFuncs are the template helpers, bound to a locale
+func emailFuncs(locale string) map[string]interface{} {
+	return map[string]interface{}{
+		"role": func(role models.UserRole) string {
+			return roleLabel(locale, role)
+		},
+		"datetime": func(t time.Time) string {
+			if locale == models.LocaleEn {
+				return t.Format("Jan 2, 2006 3:04 PM MST")
+			}
+			return t.Format("02/01/2006 15:04 MST")
+		},
+		"bytes": formatBytes,
+		"link": func(url, label string) emailButton {
+			return emailButton{URL: url, Label: label}
+		},
+	}
+}
+
+// roleLabels are the display names of the built-in roles
+var roleLabels = map[string]map[models.UserRole]string{
+	models.LocalePtBR: {
+		models.RoleAdmin:             "Administrador",
+		models.RoleSindico:           "Síndico",
+		models.RoleSubsindico:        "Subsíndico",
+		models.RoleConselheiroFiscal: "Conselheiro fiscal",
+		models.RolePorteiro:          "Porteiro",
+		models.RoleAdministradora:    "Administradora",
+		models.RoleMorador:           "Morador",
+	},
+	models.LocaleEn: {
+		models.RoleAdmin:             "Administrator",
+		models.RoleSindico:           "Building manager",
+		models.RoleSubsindico:        "Deputy building manager",
+		models.RoleConselheiroFiscal: "Fiscal council member",
+		models.RolePorteiro:          "Doorman",
+		models.RoleAdministradora:    "Property management company",
+		models.RoleMorador:           "Resident",
+	},
+}
+
+// roleLabel returns the display name of a role; custom roles are shown by name, e.g. "zelador_noturno" as "Zelador noturno"
+func roleLabel(locale string, role models.UserRole) string {
+	if label, ok := roleLabels[locale][role]; ok {
+		return label
+	}
+	name := strings.ReplaceAll(string(role), "_", " ")
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+)
+
+// Run "go test ./internal/services -run TestRenderEmail -update" after changing a template, and review the diff
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenEmail is a rendering compared against testdata/golden/<locale>/<file>.{html,txt}
+type goldenEmail struct {
+	file     string
+	template EmailTemplate
+	brand    EmailBranding
+	data     interface{}
+}
+
+var (
+	goldenTenant = &models.Tenant{Name: "Condomínio Jardim das Acácias", LogoURL: "https://cdn.habitta.test/logos/1.png"}
+	goldenTime   = time.Date(2026, time.March, 14, 18, 30, 0, 0, time.UTC)
+)
+
+var goldenEmails = []goldenEmail{
+	{
+		file:     "invite",
+		template: EmailInvite,
+		brand:    tenantBranding(goldenTenant),
+		data:     inviteEmailData{Role: models.RoleMorador, Link: "https://app.habitta.test/invites/abc123", ExpiresAt: goldenTime},
+	},
+	{
+		file:     "invite_reminder",
+		template: EmailInviteReminder,
+		brand:    tenantBranding(goldenTenant),
+		data:     inviteEmailData{Role: models.UserRole("zelador_noturno"), Link: "https://app.habitta.test/invites/abc123", ExpiresAt: goldenTime},
+	},
+	{
+		file:     "password_reset",
+		template: EmailPasswordReset,
+		brand:    platformBranding,
+		data:     accountLinkEmailData{Name: "Maria Souza", Link: "https://app.habitta.test/reset-password?token=abc123"},
+	},
+	{
+		file:     "email_verification",
+		template: EmailVerification,
+		brand:    platformBranding,
+		data:     accountLinkEmailData{Name: "Maria Souza", Link: "https://app.habitta.test/verify-email?token=abc123"},
+	},
+	{
+		file:     "account_locked",
+		template: EmailAccountLocked,
+		brand:    platformBranding,
+		data:     accountLockedEmailData{Name: "Maria Souza", LockedUntil: goldenTime, Link: "https://app.habitta.test/forgot-password"},
+	},
+	{
+		file:     "storage_warning",
+		template: EmailStorageWarning,
+		brand:    tenantBranding(goldenTenant),
+		data:     storageWarningEmailData{Level: 90, UsedBytes: 966367641, QuotaBytes: 1073741824, Link: "https://app.habitta.test/documents"},
+	},
+	{
+		file:     "storage_warning_full",
+		template: EmailStorageWarning,
+		brand:    tenantBranding(goldenTenant),
+		data:     storageWarningEmailData{Level: 100, UsedBytes: 1073741824, QuotaBytes: 1073741824, Link: "https://app.habitta.test/documents"},
+	},
+	// Missing branding: a condominium without a logo, and no condominium at all
+	{
+		file:     "invite_without_logo",
+		template: EmailInvite,
+		brand:    tenantBranding(&models.Tenant{Name: "Residencial Aurora"}),
+		data:     inviteEmailData{Role: models.RoleSindico, Link: "https://app.habitta.test/invites/abc123", ExpiresAt: goldenTime},
+	},
+	{
+		file:     "invite_without_tenant",
+		template: EmailInvite,
+		brand:    tenantBranding(nil),
+		data:     inviteEmailData{Role: models.RoleSindico, Link: "https://app.habitta.test/invites/abc123", ExpiresAt: goldenTime},
+	},
+}
+
+func TestRenderEmailGolden(t *testing.T) {
+	for _, locale := range models.SupportedLocales {
+		for _, tt := range goldenEmails {
+			t.Run(locale+"/"+tt.file, func(t *testing.T) {
+				msg, err := renderEmail(tt.template, locale, tt.brand, tt.data)
+				if err != nil {
+					t.Fatalf("failed to render: %v", err)
+				}
+				checkGolden(t, locale, tt.file, msg)
+			})
+		}
+	}
+}
+
+// Every template needs a golden file, so a new one can't ship untested
+func TestRenderEmailGoldenCoversEveryTemplate(t *testing.T) {
+	covered := make(map[EmailTemplate]bool)
+	for _, tt := range goldenEmails {
+		covered[tt.template] = true
+	}
+	for _, name := range emailTemplateNames {
+		if !covered[name] {
+			t.Errorf("template %s has no golden file", name)
+		}
+	}
+}
+
+// Locales without translations fall back to DefaultLocale
+func TestRenderEmailUnknownLocale(t *testing.T) {
+	for _, locale := range []string{"fr", "en-US", ""} {
+		for _, tt := range goldenEmails {
+			t.Run(locale+"/"+tt.file, func(t *testing.T) {
+				msg, err := renderEmail(tt.template, locale, tt.brand, tt.data)
+				if err != nil {
+					t.Fatalf("failed to render: %v", err)
+				}
+
+				html, text := readGolden(t, models.DefaultLocale, tt.file)
+				if msg.HTML != html || goldenText(msg) != text {
+					t.Errorf("locale %q did not fall back to %s", locale, models.DefaultLocale)
+				}
+			})
+		}
+	}
+}
+
+func TestRenderEmailUnknownTemplate(t *testing.T) {
+	if _, err := renderEmail(EmailTemplate("unknown"), models.DefaultLocale, platformBranding, nil); err == nil {
+		t.Fatal("want an error for an unknown template")
+	}
+}
+
+// checkGolden compares an email with its golden files, or rewrites them with -update
+func checkGolden(t *testing.T, locale, file string, msg EmailMessage) {
+	t.Helper()
+
+	htmlPath, textPath := goldenPaths(locale, file)
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(htmlPath), 0o755); err != nil {
+			t.Fatalf("failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(htmlPath, []byte(msg.HTML), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", htmlPath, err)
+		}
+		if err := os.WriteFile(textPath, []byte(goldenText(msg)), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %v", textPath, err)
+		}
+		return
+	}
+
+	html, text := readGolden(t, locale, file)
+	if msg.HTML != html {
+		t.Errorf("HTML differs from %s (run with -update if the change is intended)\n--- got ---\n%s", htmlPath, msg.HTML)
+	}
+	if got := goldenText(msg); got != text {
+		t.Errorf("text differs from %s (run with -update if the change is intended)\n--- got ---\n%s", textPath, got)
+	}
+}
+
+// readGolden reads the HTML and text golden files of an email
+func readGolden(t *testing.T, locale, file string) (string, string) {
+	t.Helper()
+
+	htmlPath, textPath := goldenPaths(locale, file)
+	html, err := os.ReadFile(htmlPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	text, err := os.ReadFile(textPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -update to create it): %v", err)
+	}
+	return string(html), string(text)
+}
+
+// goldenPaths returns the HTML and text golden files of an email
+func goldenPaths(locale, file string) (string, string) {
+	base := filepath.Join("testdata", "golden", locale, file)
+	return base + ".html", base + ".txt"
+}
+
+// goldenText is the subject followed by the plain-text body
+func goldenText(msg EmailMessage) string {
+	return "Subject: " + msg.Subject + "\n\n" + msg.Text + "\n"
+}
@@ -1,78 +1,154 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log"
 
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
 	"gorm.io/gorm"
 )
 
+// FolderDeleteMode selects what happens to the contents of a deleted folder
+type FolderDeleteMode string
+
+const (
+	// FolderDeleteEmptyOnly refuses to delete a folder that still has documents or subfolders
+	FolderDeleteEmptyOnly FolderDeleteMode = ""
+	// FolderDeleteRecursive removes every subfolder and document, including the stored files
+	FolderDeleteRecursive FolderDeleteMode = "recursive"
+	// FolderDeleteMoveToParent hands documents and subfolders to the parent folder
+	FolderDeleteMoveToParent FolderDeleteMode = "move_to_parent"
+)
+
 // FolderService defines the interface for folder operations
 type FolderService interface {
-	Create(folder *models.Folder) error
-	GetByID(tenantID, folderID uint) (*models.Folder, error)
-	GetAll(tenantID uint) ([]models.Folder, error)
-	Update(folder *models.Folder) error
-	Delete(tenantID, folderID uint) error
+	Create(ctx context.Context, folder *models.Folder) error
+	GetByID(ctx context.Context, tenantID, folderID uint) (*models.Folder, error)
+	GetAll(ctx context.Context, tenantID uint) ([]models.Folder, error)
+	GetChildren(ctx context.Context, tenantID uint, parentID *uint) ([]models.Folder, error)
+	Update(ctx context.Context, folder *models.Folder) error
+	Move(ctx context.Context, tenantID, folderID uint, parentID *uint) (*models.Folder, error)
+	Delete(ctx context.Context, tenantID, folderID uint, mode FolderDeleteMode) error
 }
 
 // folderService implements FolderService
 type folderService struct {
-	folderRepo repositories.FolderRepository
+	folderRepo   repositories.FolderRepository
+	docRepo      repositories.DocumentRepository
+	storageSvc   StorageService
+	quotaService StorageQuotaService
 }
 
 // NewFolderService creates a new folder service
-func NewFolderService(folderRepo repositories.FolderRepository) FolderService {
+func NewFolderService(
+	folderRepo repositories.FolderRepository,
+	docRepo repositories.DocumentRepository,
+	storageSvc StorageService,
+	quotaService StorageQuotaService,
+) FolderService {
 	return &folderService{
-		folderRepo: folderRepo,
+		folderRepo:   folderRepo,
+		docRepo:      docRepo,
+		storageSvc:   storageSvc,
+		quotaService: quotaService,
 	}
 }
 
 // Create creates a new folder with validation
-func (s *folderService) Create(folder *models.Folder) error {
+func (s *folderService) Create(ctx context.Context, folder *models.Folder) error {
 	if folder.Name == "" {
 		return errors.New("folder name is required")
 	}
 
-	// Check if folder name already exists for this tenant
-	existing, err := s.folderRepo.GetByName(folder.TenantID, folder.Name)
+	if folder.Visibility == "" {
+		folder.Visibility = models.VisibilityManagement
+	}
+	if err := normalizeVisibility(&folder.Visibility, &folder.Audience, false); err != nil {
+		return err
+	}
+
+	// Validate parent exists if specified
+	if folder.ParentID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *folder.ParentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("parent folder not found")
+			}
+			return fmt.Errorf("failed to validate parent folder: %w", err)
+		}
+	}
+
+	// Check if folder name already exists among its siblings
+	existing, err := s.folderRepo.GetByName(ctx, folder.ParentID, folder.Name)
 	if err == nil && existing != nil {
-		return errors.New("folder name already exists for this tenant")
+		return errors.New("folder name already exists in this folder")
 	}
 
-	if err := s.folderRepo.Create(folder); err != nil {
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
 		return fmt.Errorf("failed to create folder: %w", err)
 	}
 
 	return nil
 }
 
-// GetByID retrieves a folder by ID
-func (s *folderService) GetByID(tenantID, folderID uint) (*models.Folder, error) {
-	folder, err := s.folderRepo.GetByID(tenantID, folderID)
+// GetByID retrieves a folder by ID along with its breadcrumbs
+func (s *folderService) GetByID(ctx context.Context, tenantID, folderID uint) (*models.Folder, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("folder not found")
 		}
 		return nil, fmt.Errorf("failed to get folder: %w", err)
 	}
+
+	ancestors, err := s.folderRepo.GetByIDs(ctx, folder.PathIDs())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folder path: %w", err)
+	}
+	names := make(map[uint]string, len(ancestors))
+	for _, a := range ancestors {
+		names[a.ID] = a.Name
+	}
+	for _, id := range folder.PathIDs() {
+		folder.Breadcrumbs = append(folder.Breadcrumbs, models.FolderCrumb{ID: id, Name: names[id]})
+	}
+
 	return folder, nil
 }
 
 // GetAll retrieves all folders for a tenant
-func (s *folderService) GetAll(tenantID uint) ([]models.Folder, error) {
-	folders, err := s.folderRepo.GetAll(tenantID)
+func (s *folderService) GetAll(ctx context.Context, tenantID uint) ([]models.Folder, error) {
+	folders, err := s.folderRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders: %w", err)
+	}
+	return folders, nil
+}
+
+// GetChildren retrieves the direct subfolders of a folder, or the root folders when parentID is nil
+func (s *folderService) GetChildren(ctx context.Context, tenantID uint, parentID *uint) ([]models.Folder, error) {
+	if parentID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *parentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("folder not found")
+			}
+			return nil, fmt.Errorf("failed to get folder: %w", err)
+		}
+	}
+
+	folders, err := s.folderRepo.GetChildren(ctx, parentID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get folders: %w", err)
 	}
 	return folders, nil
 }
 
-// Update updates a folder
-func (s *folderService) Update(folder *models.Folder) error {
-	existing, err := s.folderRepo.GetByID(folder.TenantID, folder.ID)
+// Update updates a folder's name, description and sharing rules
+func (s *folderService) Update(ctx context.Context, folder *models.Folder) error {
+	existing, err := s.folderRepo.GetByID(ctx, folder.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("folder not found")
@@ -84,24 +160,82 @@ func (s *folderService) Update(folder *models.Folder) error {
 		return errors.New("folder name is required")
 	}
 
-	// Check if name is being changed and if it's already taken
+	// The parent only changes through Move
+	folder.ParentID = existing.ParentID
+	folder.Path = existing.Path
+
+	// Keep the current sharing rules when the request doesn't set them
+	if folder.Visibility == "" {
+		folder.Visibility = existing.Visibility
+		folder.Audience = existing.Audience
+	}
+	if err := normalizeVisibility(&folder.Visibility, &folder.Audience, false); err != nil {
+		return err
+	}
+
+	// Check if name is being changed and if it's already taken among the siblings
 	if folder.Name != existing.Name {
-		existingWithName, err := s.folderRepo.GetByName(folder.TenantID, folder.Name)
+		existingWithName, err := s.folderRepo.GetByName(ctx, folder.ParentID, folder.Name)
 		if err == nil && existingWithName != nil && existingWithName.ID != folder.ID {
-			return errors.New("folder name already exists for this tenant")
+			return errors.New("folder name already exists in this folder")
 		}
 	}
 
-	if err := s.folderRepo.Update(folder); err != nil {
+	if err := s.folderRepo.Update(ctx, folder); err != nil {
 		return fmt.Errorf("failed to update folder: %w", err)
 	}
 
 	return nil
 }
 
-// Delete deletes a folder
-func (s *folderService) Delete(tenantID, folderID uint) error {
-	_, err := s.folderRepo.GetByID(tenantID, folderID)
+// Move places a folder under another parent, or at the root when parentID is nil
+func (s *folderService) Move(ctx context.Context, tenantID, folderID uint, parentID *uint) (*models.Folder, error) {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("folder not found")
+		}
+		return nil, fmt.Errorf("failed to get folder: %w", err)
+	}
+
+	var parent *models.Folder
+	if parentID != nil {
+		parent, err = s.folderRepo.GetByID(ctx, *parentID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("target folder not found")
+			}
+			return nil, fmt.Errorf("failed to get folder: %w", err)
+		}
+
+		// A folder can't be moved into itself or one of its descendants
+		// Checked again by the repository with both folders locked
+		if folder.Contains(parent) {
+			return nil, models.ErrFolderCycle
+		}
+	}
+
+	existingWithName, err := s.folderRepo.GetByName(ctx, parentID, folder.Name)
+	if err == nil && existingWithName != nil && existingWithName.ID != folder.ID {
+		return nil, errors.New("folder name already exists in the target folder")
+	}
+
+	if err := s.folderRepo.Move(ctx, folder, parent); err != nil {
+		if errors.Is(err, models.ErrFolderCycle) {
+			return nil, err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("folder not found")
+		}
+		return nil, fmt.Errorf("failed to move folder: %w", err)
+	}
+
+	return folder, nil
+}
+
+// Delete deletes a folder, handling its contents according to mode
+func (s *folderService) Delete(ctx context.Context, tenantID, folderID uint, mode FolderDeleteMode) error {
+	folder, err := s.folderRepo.GetByID(ctx, folderID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("folder not found")
@@ -109,9 +243,111 @@ func (s *folderService) Delete(tenantID, folderID uint) error {
 		return fmt.Errorf("failed to get folder: %w", err)
 	}
 
-	if err := s.folderRepo.Delete(tenantID, folderID); err != nil {
+	switch mode {
+	case FolderDeleteEmptyOnly:
+		return s.deleteEmpty(ctx, folder)
+	case FolderDeleteRecursive:
+		return s.deleteRecursive(ctx, folder)
+	case FolderDeleteMoveToParent:
+		return s.deleteMovingContents(ctx, folder)
+	default:
+		return fmt.Errorf("invalid delete mode: %s", mode)
+	}
+}
+
+// deleteEmpty deletes a folder that has no documents or subfolders
+func (s *folderService) deleteEmpty(ctx context.Context, folder *models.Folder) error {
+	children, err := s.folderRepo.GetChildren(ctx, &folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get subfolders: %w", err)
+	}
+	docs, err := s.docRepo.GetByFolder(ctx, folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get documents: %w", err)
+	}
+	if len(children) > 0 || len(docs) > 0 {
+		return errors.New("folder is not empty; use mode=recursive or mode=move_to_parent")
+	}
+
+	if err := s.folderRepo.Delete(ctx, folder.ID); err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return nil
+}
+
+// deleteRecursive deletes a folder, its subfolders and their documents, including every stored version
+func (s *folderService) deleteRecursive(ctx context.Context, folder *models.Folder) error {
+	subtree, err := s.folderRepo.GetSubtree(ctx, folder)
+	if err != nil {
+		return fmt.Errorf("failed to get subfolders: %w", err)
+	}
+	folderIDs := make([]uint, 0, len(subtree))
+	for _, f := range subtree {
+		folderIDs = append(folderIDs, f.ID)
+	}
+
+	versions, err := s.docRepo.GetVersionsByFolders(ctx, folderIDs)
+	if err != nil {
+		return fmt.Errorf("failed to get document versions: %w", err)
+	}
+
+	// The current file of a document is always one of its versions
+	keys := make(map[string]bool)
+	var size int64
+	for _, v := range versions {
+		keys[v.S3Key] = true
+		size += v.Size
+	}
+
+	// Delete from database first; the stored versions are only removed once that commits
+	if err := s.folderRepo.DeleteTree(ctx, folderIDs); err != nil {
 		return fmt.Errorf("failed to delete folder: %w", err)
 	}
+	database.AfterCommit(ctx, func() {
+		deleteStoredFiles(s.storageSvc, keys)
+	})
+
+	if err := s.quotaService.Release(ctx, folder.TenantID, size); err != nil {
+		log.Printf("Failed to release storage of folder %d: %v", folder.ID, err)
+	}
+	return nil
+}
 
+// deleteMovingContents deletes a folder after moving its documents and subfolders to its parent
+func (s *folderService) deleteMovingContents(ctx context.Context, folder *models.Folder) error {
+	children, err := s.folderRepo.GetChildren(ctx, &folder.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get subfolders: %w", err)
+	}
+	for _, child := range children {
+		existing, err := s.folderRepo.GetByName(ctx, folder.ParentID, child.Name)
+		if err == nil && existing != nil && existing.ID != folder.ID {
+			return fmt.Errorf("parent folder already has a subfolder named %q", child.Name)
+		}
+	}
+
+	if err := s.folderRepo.DeleteAndMoveContents(ctx, folder); err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	return nil
+}
+
+// normalizeVisibility validates sharing rules and drops the audience when it doesn't apply
+func normalizeVisibility(visibility *models.Visibility, audience *models.Audience, allowInherit bool) error {
+	valid := models.IsValidFolderVisibility(*visibility)
+	if allowInherit {
+		valid = models.IsValidDocumentVisibility(*visibility)
+	}
+	if !valid {
+		return fmt.Errorf("invalid visibility: %s", *visibility)
+	}
+
+	if *visibility != models.VisibilityUnits {
+		*audience = models.Audience{}
+		return nil
+	}
+	if audience.IsEmpty() {
+		return errors.New("audience must list at least one unit or block")
+	}
 	return nil
 }
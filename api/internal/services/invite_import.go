@@ -0,0 +1,278 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxBulkInvites caps how many invites one import can create
+const maxBulkInvites = 500
+
+// BulkInviteRow is one invite of a bulk import
+type BulkInviteRow struct {
+	Email      string          `json:"email"`
+	Role       models.UserRole `json:"role"`        // Defaults to morador
+	UnitNumber string          `json:"unit_number"` // Optional; the invitee is linked to the unit on acceptance
+}
+
+// BulkInviteRequest represents the JSON body of a bulk import
+type BulkInviteRequest struct {
+	Invites []BulkInviteRow `json:"invites" binding:"required"`
+}
+
+// BulkInviteRowError reports why a row was not imported; rows are numbered from 1, not counting the CSV header
+type BulkInviteRowError struct {
+	Row   int    `json:"row"`
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
+
+// BulkInviteResult reports the invites created and the rows that were rejected
+type BulkInviteResult struct {
+	Created []models.Invite      `json:"created"`
+	Errors  []BulkInviteRowError `json:"errors"`
+}
+
+// CreateInvitesBulk validates every row, then creates the valid ones, with their emails queued, in a single transaction
+// A rejected row doesn't stop the others from being imported
+func (s *inviteService) CreateInvitesBulk(ctx context.Context, tenantID, inviterUserID uint, rows []BulkInviteRow) (*BulkInviteResult, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("no invites to import")
+	}
+	if len(rows) > maxBulkInvites {
+		return nil, fmt.Errorf("too many invites: at most %d per import", maxBulkInvites)
+	}
+
+	inviterRole, err := s.inviterRole(ctx, tenantID, inviterUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := s.unitRepo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get units: %w", err)
+	}
+	unitsByNumber := make(map[string]uint, len(units))
+	for _, unit := range units {
+		unitsByNumber[strings.ToLower(unit.Number)] = unit.ID
+	}
+
+	tenantInvites, err := s.inviteRepo.GetByTenant(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending invites: %w", err)
+	}
+	pending := make(map[string]bool, len(tenantInvites))
+	for _, invite := range tenantInvites {
+		if invite.IsValid() {
+			pending[strings.ToLower(invite.Email)] = true
+		}
+	}
+
+	result := &BulkInviteResult{
+		Created: []models.Invite{},
+		Errors:  []BulkInviteRowError{},
+	}
+	roleErrors := make(map[models.UserRole]error)
+	seen := make(map[string]int, len(rows))
+	expiresAt := time.Now().Add(inviteTTL)
+	var invites []*models.Invite
+
+	for i, row := range rows {
+		email := strings.TrimSpace(row.Email)
+		reject := func(err error) {
+			result.Errors = append(result.Errors, BulkInviteRowError{Row: i + 1, Email: email, Error: err.Error()})
+		}
+
+		if email == "" {
+			reject(errors.New("email is required"))
+			continue
+		}
+		if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+			reject(errors.New("invalid email"))
+			continue
+		}
+
+		key := strings.ToLower(email)
+		if first, ok := seen[key]; ok {
+			reject(fmt.Errorf("duplicate of row %d", first))
+			continue
+		}
+		seen[key] = i + 1
+
+		role := models.UserRole(strings.TrimSpace(string(row.Role)))
+		if role == "" {
+			role = models.RoleMorador
+		}
+		roleErr, checked := roleErrors[role]
+		if !checked {
+			roleErr = s.roleService.ValidateAssignableRole(ctx, tenantID, inviterRole, role)
+			roleErrors[role] = roleErr
+		}
+		if roleErr != nil {
+			reject(roleErr)
+			continue
+		}
+
+		var unitID *uint
+		if number := strings.TrimSpace(row.UnitNumber); number != "" {
+			id, ok := unitsByNumber[strings.ToLower(number)]
+			if !ok {
+				reject(fmt.Errorf("unit %s not found", number))
+				continue
+			}
+			unitID = &id
+		}
+
+		if pending[key] {
+			reject(errors.New("pending invite already exists for this email and tenant"))
+			continue
+		}
+		if user, err := s.userRepo.GetByEmail(email); err == nil && user != nil {
+			belongsToTenant, err := s.userTenantRepo.UserBelongsToTenant(ctx, user.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check tenant membership: %w", err)
+			}
+			if belongsToTenant {
+				reject(errors.New("user already belongs to this tenant"))
+				continue
+			}
+		}
+
+		invites = append(invites, &models.Invite{
+			TenantID:        tenantID,
+			Email:           email,
+			Role:            role,
+			UnitID:          unitID,
+			Token:           uuid.New().String(),
+			Status:          models.InviteStatusPending,
+			InvitedByUserID: inviterUserID,
+			ExpiresAt:       expiresAt,
+		})
+	}
+
+	if len(invites) == 0 {
+		return result, nil
+	}
+
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		tenant := &models.Tenant{}
+		if err := database.Conn(ctx, s.db).First(tenant, tenantID).Error; err != nil {
+			return fmt.Errorf("failed to get tenant: %w", err)
+		}
+
+		for _, invite := range invites {
+			if err := s.inviteRepo.Create(ctx, invite); err != nil {
+				return fmt.Errorf("failed to create invite for %s: %w", invite.Email, err)
+			}
+			if err := s.enqueueInviteEmail(ctx, EmailInvite, invite, tenant); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, invite := range invites {
+		result.Created = append(result.Created, *invite)
+	}
+
+	return result, nil
+}
+
+// bulkInviteColumns maps the accepted CSV header names to columns
+var bulkInviteColumns = map[string]string{
+	"email":       "email",
+	"e-mail":      "email",
+	"role":        "role",
+	"papel":       "role",
+	"unit_number": "unit_number",
+	"unit":        "unit_number",
+	"unidade":     "unit_number",
+}
+
+// ParseBulkInviteCSV reads the rows of a bulk import from a CSV
+// The header row is optional: without one, the columns are email, role and unit_number, in this order.
+// Both comma and semicolon (the default of spreadsheets in pt-BR) are accepted as separators
+func ParseBulkInviteCSV(r io.Reader) ([]BulkInviteRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	firstLine, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	if strings.Count(firstLine, ";") > strings.Count(firstLine, ",") {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV is empty")
+	}
+
+	columns := map[string]int{"email": 0, "role": 1, "unit_number": 2}
+	if header := records[0]; isBulkInviteHeader(header) {
+		columns = map[string]int{}
+		for i, name := range header {
+			if column, ok := bulkInviteColumns[strings.ToLower(strings.TrimSpace(name))]; ok {
+				columns[column] = i
+			}
+		}
+		if _, ok := columns["email"]; !ok {
+			return nil, errors.New("CSV header has no email column")
+		}
+		records = records[1:]
+	}
+	if len(records) > maxBulkInvites {
+		return nil, fmt.Errorf("too many invites: at most %d per import", maxBulkInvites)
+	}
+
+	field := func(record []string, column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	rows := make([]BulkInviteRow, 0, len(records))
+	for _, record := range records {
+		rows = append(rows, BulkInviteRow{
+			Email:      field(record, "email"),
+			Role:       models.UserRole(field(record, "role")),
+			UnitNumber: field(record, "unit_number"),
+		})
+	}
+
+	return rows, nil
+}
+
+// isBulkInviteHeader tells a header row from a first data row, which always has an email
+func isBulkInviteHeader(record []string) bool {
+	for _, name := range record {
+		if bulkInviteColumns[strings.ToLower(strings.TrimSpace(name))] == "email" {
+			return true
+		}
+	}
+	return false
+}
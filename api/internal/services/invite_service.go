@@ -1,11 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"time"
 
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
 	"github.com/arturbaldoramos/Habitta/pkg/utils"
@@ -16,7 +17,7 @@ import (
 // CreateInviteRequest represents the request to create a new invite
 type CreateInviteRequest struct {
 	Email string          `json:"email" binding:"required,email"`
-	Role  models.UserRole `json:"role" binding:"required,oneof=admin sindico morador"`
+	Role  models.UserRole `json:"role" binding:"required"`
 }
 
 // AcceptInviteRequest represents the request to accept an invite
@@ -29,21 +30,37 @@ type AcceptInviteRequest struct {
 
 // InviteService defines the interface for invite operations
 type InviteService interface {
-	CreateInvite(tenantID, inviterUserID uint, req CreateInviteRequest) (*models.Invite, error)
-	GetInviteByToken(token string) (*models.Invite, error)
-	AcceptInvite(token string, req AcceptInviteRequest) (*models.User, error)
-	GetPendingInvitesByEmail(email string) ([]models.Invite, error)
-	CancelInvite(inviteID, userID, tenantID uint) error
-	GetTenantInvites(tenantID uint) ([]models.Invite, error)
+	CreateInvite(ctx context.Context, tenantID, inviterUserID uint, req CreateInviteRequest) (*models.Invite, error)
+	GetInviteByToken(ctx context.Context, token string) (*models.Invite, error)
+	AcceptInvite(ctx context.Context, token string, req AcceptInviteRequest) (*models.User, error)
+	GetPendingInvitesByEmail(ctx context.Context, email string) ([]models.Invite, error)
+	CancelInvite(ctx context.Context, inviteID, userID, tenantID uint) error
+	GetTenantInvites(ctx context.Context, tenantID uint) ([]models.Invite, error)
+	ResendInvite(ctx context.Context, inviteID uint) (*models.Invite, error)
+	ResendTenantInvite(ctx context.Context, inviteID, userID, tenantID uint) (*models.Invite, error)
+	CreateInvitesBulk(ctx context.Context, tenantID, inviterUserID uint, rows []BulkInviteRow) (*BulkInviteResult, error)
+	ExpireInvites(ctx context.Context) (int, error)
+	SendExpiryReminders(ctx context.Context) (int, error)
 }
 
+const (
+	// inviteTTL is how long an invite link stays valid
+	inviteTTL = 7 * 24 * time.Hour
+	// inviteReminderWindow is how long before expiring a pending invite gets its reminder
+	inviteReminderWindow = 24 * time.Hour
+	// inviteReminderBatchSize caps how many reminders one run queues
+	inviteReminderBatchSize = 200
+)
+
 // inviteService implements InviteService
 type inviteService struct {
 	inviteRepo     repositories.InviteRepository
 	userRepo       repositories.UserRepository
 	userTenantRepo repositories.UserTenantRepository
+	unitRepo       repositories.UnitRepository
+	roleService    RoleService
 	db             *gorm.DB
-	emailService   EmailService
+	emailOutbox    EmailOutboxService
 	appBaseURL     string
 }
 
@@ -52,46 +69,46 @@ func NewInviteService(
 	inviteRepo repositories.InviteRepository,
 	userRepo repositories.UserRepository,
 	userTenantRepo repositories.UserTenantRepository,
+	unitRepo repositories.UnitRepository,
+	roleService RoleService,
 	db *gorm.DB,
-	emailService EmailService,
+	emailOutbox EmailOutboxService,
 	appBaseURL string,
 ) InviteService {
 	return &inviteService{
 		inviteRepo:     inviteRepo,
 		userRepo:       userRepo,
 		userTenantRepo: userTenantRepo,
+		unitRepo:       unitRepo,
+		roleService:    roleService,
 		db:             db,
-		emailService:   emailService,
+		emailOutbox:    emailOutbox,
 		appBaseURL:     appBaseURL,
 	}
 }
 
-// CreateInvite creates a new invite (only síndico or admin can invite)
-func (s *inviteService) CreateInvite(tenantID, inviterUserID uint, req CreateInviteRequest) (*models.Invite, error) {
-	// Verify inviter has permission (síndico or admin)
-	userTenant, err := s.userTenantRepo.GetByUserAndTenant(inviterUserID, tenantID)
+// CreateInvite creates a new invite (requires invites.create; the role cannot exceed the inviter's)
+func (s *inviteService) CreateInvite(ctx context.Context, tenantID, inviterUserID uint, req CreateInviteRequest) (*models.Invite, error) {
+	inviterRole, err := s.inviterRole(ctx, tenantID, inviterUserID)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("user does not belong to this tenant")
-		}
-		return nil, fmt.Errorf("failed to verify user tenant: %w", err)
+		return nil, err
 	}
 
-	if userTenant.Role != models.RoleSindico && userTenant.Role != models.RoleAdmin {
-		return nil, errors.New("only síndico or admin can create invites")
+	if err := s.roleService.ValidateAssignableRole(ctx, tenantID, inviterRole, req.Role); err != nil {
+		return nil, err
 	}
 
 	// Check if email already belongs to this tenant
 	invitedUser, err := s.userRepo.GetByEmail(req.Email)
 	if err == nil && invitedUser != nil {
-		belongsToTenant, err := s.userTenantRepo.UserBelongsToTenant(invitedUser.ID, tenantID)
+		belongsToTenant, err := s.userTenantRepo.UserBelongsToTenant(ctx, invitedUser.ID)
 		if err == nil && belongsToTenant {
 			return nil, errors.New("user already belongs to this tenant")
 		}
 	}
 
 	// Check if there's already a pending invite for this email and tenant
-	pendingInvites, err := s.inviteRepo.GetPendingByEmail(req.Email)
+	pendingInvites, err := s.inviteRepo.GetPendingByEmail(ctx, req.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, fmt.Errorf("failed to check pending invites: %w", err)
 	}
@@ -110,43 +127,59 @@ func (s *inviteService) CreateInvite(tenantID, inviterUserID uint, req CreateInv
 		Token:           uuid.New().String(),
 		Status:          models.InviteStatusPending,
 		InvitedByUserID: inviterUserID,
-		ExpiresAt:       time.Now().Add(7 * 24 * time.Hour), // Expires in 7 days
+		ExpiresAt:       time.Now().Add(inviteTTL),
 	}
 
-	if err := s.inviteRepo.Create(invite); err != nil {
-		return nil, fmt.Errorf("failed to create invite: %w", err)
+	// The email is queued with the invite, so an email outage delays it instead of losing it
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
+		if err := s.inviteRepo.Create(ctx, invite); err != nil {
+			return fmt.Errorf("failed to create invite: %w", err)
+		}
+		return s.enqueueInviteEmail(ctx, EmailInvite, invite, nil)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Reload invite with relationships
-	invite, err = s.inviteRepo.GetByToken(invite.Token)
+	invite, err = s.inviteRepo.GetByToken(ctx, invite.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to reload invite: %w", err)
 	}
 
-	// Send invite email (failure does not block invite creation)
-	inviteLink := fmt.Sprintf("%s/invites/%s", s.appBaseURL, invite.Token)
-	emailMsg := EmailMessage{
-		To:      invite.Email,
-		Subject: "Você foi convidado para o Habitta",
-		HTML: fmt.Sprintf(
-			`<h2>Você recebeu um convite!</h2>
-			<p>Você foi convidado para participar de um condomínio no Habitta como <strong>%s</strong>.</p>
-			<p>Clique no link abaixo para aceitar o convite:</p>
-			<p><a href="%s">Aceitar Convite</a></p>
-			<p>Este convite expira em 7 dias.</p>`,
-			invite.Role, inviteLink,
-		),
+	return invite, nil
+}
+
+// inviterRole returns the inviter's role in the tenant, checking that it grants invites.create
+func (s *inviteService) inviterRole(ctx context.Context, tenantID, inviterUserID uint) (models.UserRole, error) {
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, inviterUserID, tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("user does not belong to this tenant")
+		}
+		return "", fmt.Errorf("failed to verify user tenant: %w", err)
+	}
+
+	canInvite, err := s.roleService.HasPermission(ctx, tenantID, userTenant.Role, models.PermInvitesCreate)
+	if err != nil {
+		return "", err
 	}
-	if err := s.emailService.SendEmail(emailMsg); err != nil {
-		log.Printf("WARNING: failed to send invite email to %s: %v", invite.Email, err)
+	if !canInvite {
+		return "", errors.New("you do not have permission to create invites")
 	}
 
-	return invite, nil
+	return userTenant.Role, nil
 }
 
 // GetInviteByToken retrieves an invite by token
-func (s *inviteService) GetInviteByToken(token string) (*models.Invite, error) {
-	invite, err := s.inviteRepo.GetByToken(token)
+// Used by the public routes, so the token is what row-level security lets it read
+func (s *inviteService) GetInviteByToken(ctx context.Context, token string) (*models.Invite, error) {
+	var invite *models.Invite
+	err := database.RunScoped(ctx, s.db, database.Scope{InviteToken: token}, func(ctx context.Context) error {
+		var err error
+		invite, err = s.inviteRepo.GetByToken(ctx, token)
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("invite not found")
@@ -158,14 +191,11 @@ func (s *inviteService) GetInviteByToken(token string) (*models.Invite, error) {
 }
 
 // AcceptInvite accepts an invite and creates user-tenant relationship
-func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*models.User, error) {
+func (s *inviteService) AcceptInvite(ctx context.Context, token string, req AcceptInviteRequest) (*models.User, error) {
 	// Get invite
-	invite, err := s.inviteRepo.GetByToken(token)
+	invite, err := s.GetInviteByToken(ctx, token)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invite not found")
-		}
-		return nil, fmt.Errorf("failed to get invite: %w", err)
+		return nil, err
 	}
 
 	// Validate invite
@@ -178,8 +208,10 @@ func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*mo
 
 	var user *models.User
 
-	// Use transaction to ensure atomicity
-	err = s.db.Transaction(func(tx *gorm.DB) error {
+	// Use transaction to ensure atomicity, scoped to the invite's tenant
+	err = database.RunScoped(ctx, s.db, database.Scope{TenantID: invite.TenantID}, func(ctx context.Context) error {
+		tx := database.Conn(ctx, s.db)
+
 		// Check if user exists
 		existingUser, err := s.userRepo.GetByEmail(invite.Email)
 		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -190,8 +222,13 @@ func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*mo
 			// User exists - just create user-tenant relationship
 			user = existingUser
 
+			// Unverified accounts may have been registered by someone else with this address
+			if !user.IsEmailVerified() {
+				return models.ErrEmailNotVerified
+			}
+
 			// Verify user doesn't already belong to this tenant
-			belongsToTenant, err := s.userTenantRepo.UserBelongsToTenant(user.ID, invite.TenantID)
+			belongsToTenant, err := s.userTenantRepo.UserBelongsToTenant(ctx, user.ID)
 			if err != nil {
 				return fmt.Errorf("failed to check tenant membership: %w", err)
 			}
@@ -215,13 +252,16 @@ func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*mo
 				return fmt.Errorf("failed to hash password: %w", err)
 			}
 
+			// The invite link was delivered to this address, which proves ownership
+			now := time.Now()
 			user = &models.User{
-				Email:    invite.Email,
-				Password: hashedPassword,
-				Name:     req.Name,
-				Phone:    req.Phone,
-				CPF:      req.CPF,
-				Active:   true,
+				Email:           invite.Email,
+				Password:        hashedPassword,
+				Name:            req.Name,
+				Phone:           req.Phone,
+				CPF:             req.CPF,
+				Active:          true,
+				EmailVerifiedAt: &now,
 			}
 
 			if err := tx.Create(user).Error; err != nil {
@@ -242,16 +282,39 @@ func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*mo
 			return fmt.Errorf("failed to create user-tenant relationship: %w", err)
 		}
 
-		// Mark invite as accepted
+		// Link the unit named by the invite, unless it was deleted in the meantime
+		if invite.UnitID != nil {
+			var unitCount int64
+			if err := tx.Model(&models.Unit{}).Scopes(database.ScopedTenant).Where("id = ?", *invite.UnitID).Count(&unitCount).Error; err != nil {
+				return fmt.Errorf("failed to check invite unit: %w", err)
+			}
+			if unitCount > 0 {
+				member := &models.UnitMember{
+					TenantID:     invite.TenantID,
+					UnitID:       *invite.UnitID,
+					UserID:       user.ID,
+					Relationship: models.UnitRelationshipTenant,
+					StartDate:    today(),
+				}
+				if err := tx.Create(member).Error; err != nil {
+					return fmt.Errorf("failed to link unit: %w", err)
+				}
+			}
+		}
+
+		// Mark invite as accepted, unless a concurrent request got there first
 		now := time.Now()
+		accepted, err := s.inviteRepo.MarkAccepted(ctx, invite, user.ID, now)
+		if err != nil {
+			return fmt.Errorf("failed to update invite: %w", err)
+		}
+		if !accepted {
+			return errors.New("invite is no longer pending")
+		}
 		invite.Status = models.InviteStatusAccepted
 		invite.AcceptedByUserID = &user.ID
 		invite.AcceptedAt = &now
 
-		if err := tx.Save(invite).Error; err != nil {
-			return fmt.Errorf("failed to update invite: %w", err)
-		}
-
 		return nil
 	})
 
@@ -266,8 +329,8 @@ func (s *inviteService) AcceptInvite(token string, req AcceptInviteRequest) (*mo
 }
 
 // GetPendingInvitesByEmail retrieves pending invites for an email
-func (s *inviteService) GetPendingInvitesByEmail(email string) ([]models.Invite, error) {
-	invites, err := s.inviteRepo.GetPendingByEmail(email)
+func (s *inviteService) GetPendingInvitesByEmail(ctx context.Context, email string) ([]models.Invite, error) {
+	invites, err := s.inviteRepo.GetPendingByEmail(ctx, email)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pending invites: %w", err)
 	}
@@ -284,9 +347,9 @@ func (s *inviteService) GetPendingInvitesByEmail(email string) ([]models.Invite,
 }
 
 // CancelInvite cancels an invite (only inviter, síndico, or admin can cancel)
-func (s *inviteService) CancelInvite(inviteID, userID, tenantID uint) error {
+func (s *inviteService) CancelInvite(ctx context.Context, inviteID, userID, tenantID uint) error {
 	// Get invite
-	invite, err := s.inviteRepo.GetByID(inviteID)
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("invite not found")
@@ -299,8 +362,8 @@ func (s *inviteService) CancelInvite(inviteID, userID, tenantID uint) error {
 		return errors.New("invite does not belong to this tenant")
 	}
 
-	// Verify user has permission (inviter, síndico, or admin)
-	userTenant, err := s.userTenantRepo.GetByUserAndTenant(userID, tenantID)
+	// Verify user has permission (the inviter, or invites.cancel)
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user does not belong to this tenant")
@@ -309,15 +372,18 @@ func (s *inviteService) CancelInvite(inviteID, userID, tenantID uint) error {
 	}
 
 	isInviter := invite.InvitedByUserID == userID
-	isAdminOrSindico := userTenant.Role == models.RoleAdmin || userTenant.Role == models.RoleSindico
+	canCancel, err := s.roleService.HasPermission(ctx, tenantID, userTenant.Role, models.PermInvitesCancel)
+	if err != nil {
+		return err
+	}
 
-	if !isInviter && !isAdminOrSindico {
-		return errors.New("only the inviter, síndico, or admin can cancel invites")
+	if !isInviter && !canCancel {
+		return errors.New("only the inviter or members allowed to cancel invites can cancel it")
 	}
 
 	// Mark invite as cancelled
 	invite.Status = models.InviteStatusCancelled
-	if err := s.inviteRepo.Update(invite); err != nil {
+	if err := s.inviteRepo.Update(ctx, invite); err != nil {
 		return fmt.Errorf("failed to cancel invite: %w", err)
 	}
 
@@ -325,11 +391,145 @@ func (s *inviteService) CancelInvite(inviteID, userID, tenantID uint) error {
 }
 
 // GetTenantInvites retrieves all invites for a tenant
-func (s *inviteService) GetTenantInvites(tenantID uint) ([]models.Invite, error) {
-	invites, err := s.inviteRepo.GetByTenant(tenantID)
+func (s *inviteService) GetTenantInvites(ctx context.Context, tenantID uint) ([]models.Invite, error) {
+	invites, err := s.inviteRepo.GetByTenant(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant invites: %w", err)
 	}
 
 	return invites, nil
 }
+
+// ResendInvite renews a pending or expired invite and queues its email again
+func (s *inviteService) ResendInvite(ctx context.Context, inviteID uint) (*models.Invite, error) {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invite not found")
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return s.renewInvite(ctx, invite)
+}
+
+// ResendTenantInvite renews an invite of the active tenant (only the inviter or members allowed to create invites)
+func (s *inviteService) ResendTenantInvite(ctx context.Context, inviteID, userID, tenantID uint) (*models.Invite, error) {
+	invite, err := s.inviteRepo.GetByID(ctx, inviteID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invite not found")
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	if invite.TenantID != tenantID {
+		return nil, errors.New("invite does not belong to this tenant")
+	}
+
+	if invite.InvitedByUserID != userID {
+		if _, err := s.inviterRole(ctx, tenantID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.renewInvite(ctx, invite)
+}
+
+// renewInvite rotates the invite's token, so links already sent stop working, extends its expiration
+// and queues the email with the new link in the same transaction
+func (s *inviteService) renewInvite(ctx context.Context, invite *models.Invite) (*models.Invite, error) {
+	if invite.Status != models.InviteStatusPending && invite.Status != models.InviteStatusExpired {
+		return nil, fmt.Errorf("invite is %s", invite.Status)
+	}
+
+	invite.Token = uuid.New().String()
+	invite.Status = models.InviteStatusPending
+	invite.ExpiresAt = time.Now().Add(inviteTTL)
+	invite.ReminderSentAt = nil
+	err := database.RunScoped(ctx, s.db, database.Scope{TenantID: invite.TenantID}, func(ctx context.Context) error {
+		if err := s.inviteRepo.Update(ctx, invite); err != nil {
+			return fmt.Errorf("failed to update invite: %w", err)
+		}
+		return s.enqueueInviteEmail(ctx, EmailInvite, invite, invite.Tenant)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invite, nil
+}
+
+// ExpireInvites marks pending invites past their expiration as expired (scheduled job)
+func (s *inviteService) ExpireInvites(ctx context.Context) (int, error) {
+	expired, err := s.inviteRepo.MarkExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire invites: %w", err)
+	}
+	return int(expired), nil
+}
+
+// SendExpiryReminders queues a reminder for each pending invite expiring within inviteReminderWindow (scheduled job)
+// Each invite is reminded once per expiration date; renewing it allows a new reminder
+func (s *inviteService) SendExpiryReminders(ctx context.Context) (int, error) {
+	invites, err := s.inviteRepo.GetExpiringWithoutReminder(ctx, time.Now().Add(inviteReminderWindow), inviteReminderBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expiring invites: %w", err)
+	}
+
+	sent := 0
+	for i := range invites {
+		if ctx.Err() != nil {
+			break
+		}
+		invite := &invites[i]
+
+		err := database.RunScoped(ctx, s.db, database.Scope{TenantID: invite.TenantID}, func(ctx context.Context) error {
+			if err := s.enqueueInviteEmail(ctx, EmailInviteReminder, invite, invite.Tenant); err != nil {
+				return err
+			}
+			return database.Conn(ctx, s.db).Model(&models.Invite{}).
+				Scopes(database.ScopedTenant).
+				Where("id = ?", invite.ID).
+				Update("reminder_sent_at", time.Now()).Error
+		})
+		if err != nil {
+			return sent, fmt.Errorf("failed to remind invite %d: %w", invite.ID, err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// enqueueInviteEmail queues an email with the invite link (the invite itself or its reminder) in the transaction carried by ctx
+// It is branded with the condominium and written in the invitee's language, or the condominium's if they have no account yet
+// Each expiration date gets its own idempotency key, so resending queues a new email
+// The tenant is loaded in the transaction when the caller doesn't have it at hand
+func (s *inviteService) enqueueInviteEmail(ctx context.Context, name EmailTemplate, invite *models.Invite, tenant *models.Tenant) error {
+	tx := database.Conn(ctx, s.db)
+	if tenant == nil {
+		tenant = &models.Tenant{}
+		if err := tx.First(tenant, invite.TenantID).Error; err != nil {
+			return fmt.Errorf("failed to get tenant: %w", err)
+		}
+	}
+
+	locale := tenant.Locale
+	if user, err := s.userRepo.GetByEmail(invite.Email); err == nil && user != nil {
+		locale = models.ResolveLocale(user.Locale, tenant.Locale)
+	}
+
+	msg, err := renderEmail(name, locale, tenantBranding(tenant), inviteEmailData{
+		Role:      invite.Role,
+		Link:      fmt.Sprintf("%s/invites/%s", s.appBaseURL, invite.Token),
+		ExpiresAt: invite.ExpiresAt,
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = invite.Email
+	msg.IdempotencyKey = fmt.Sprintf("%s:%d:%d", name, invite.ID, invite.ExpiresAt.Unix())
+
+	return s.emailOutbox.Enqueue(ctx, msg)
+}
@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/google/uuid"
+)
+
+// LocalFilesRoute is the API path that serves files of the local storage backend
+const LocalFilesRoute = "/api/storage/files"
+
+// ErrInvalidSignature is returned when a signed download URL is tampered with or expired
+var ErrInvalidSignature = errors.New("invalid or expired download link")
+
+// multipartDir is the directory under the storage root where parts of multipart uploads are staged
+const multipartDir = ".multipart"
+
+// LocalFileStore serves files of the local backend through signed, expiring URLs
+// It is implemented by the local StorageService; the S3 backend relies on S3 presigned URLs instead
+type LocalFileStore interface {
+	VerifySignature(key string, expires int64, signature string) error
+	Open(key string) (*os.File, error)
+	VerifyUploadSignature(key, uploadID string, partNumber int32, expires int64, signature string) error
+	WriteUpload(key, uploadID string, partNumber int32, body io.Reader) (string, error)
+}
+
+// localStorageService implements StorageService on the local filesystem
+type localStorageService struct {
+	root       string
+	baseURL    string
+	signingKey []byte
+}
+
+// newLocalStorageService creates a disk-backed storage service rooted at cfg.LocalRoot
+func newLocalStorageService(cfg config.StorageConfig) (StorageService, error) {
+	if cfg.SigningKey == "" {
+		return nil, errors.New("a signing key is required for local storage")
+	}
+
+	root, err := filepath.Abs(cfg.LocalRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage root: %w", err)
+	}
+
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage root: %w", err)
+	}
+
+	return &localStorageService{
+		root:       root,
+		baseURL:    strings.TrimRight(cfg.PublicBaseURL, "/"),
+		signingKey: []byte(cfg.SigningKey),
+	}, nil
+}
+
+// Upload writes a file under the storage root
+// The file is written to a temporary name first so readers never see partial uploads
+func (s *localStorageService) Upload(ctx context.Context, key string, body io.Reader, contentType string, size int64) error {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a file from the storage root (missing files are not an error, like S3)
+func (s *localStorageService) Delete(ctx context.Context, key string) error {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// GetPresignedURL returns an HMAC-signed URL to LocalFilesRoute that expires after duration
+func (s *localStorageService) GetPresignedURL(ctx context.Context, key string, duration time.Duration) (string, error) {
+	if _, err := s.resolve(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(duration).Unix()
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(key, expires))
+
+	return fmt.Sprintf("%s%s/%s?%s", s.baseURL, LocalFilesRoute, escapeKey(key), query.Encode()), nil
+}
+
+// VerifySignature checks a download signature and its expiry
+func (s *localStorageService) VerifySignature(key string, expires int64, signature string) error {
+	if time.Now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+
+	expected := s.sign(key, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Open opens a stored file for reading
+func (s *localStorageService) Open(key string) (*os.File, error) {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(filePath)
+}
+
+// Head returns the size of a stored file; the content type is inferred from its extension
+func (s *localStorageService) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	return &ObjectInfo{
+		Size:        info.Size(),
+		ContentType: mime.TypeByExtension(filepath.Ext(filePath)),
+	}, nil
+}
+
+// Read streams a stored file's content; the caller must close it
+func (s *localStorageService) Read(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := s.Open(key)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+// PresignPut returns a signed URL that accepts a PUT of the whole file on LocalFilesRoute
+func (s *localStorageService) PresignPut(ctx context.Context, key, contentType string, duration time.Duration) (string, error) {
+	return s.uploadURL(key, "", 0, duration)
+}
+
+// CreateMultipartUpload creates a staging directory for the parts of an upload
+func (s *localStorageService) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	if _, err := s.resolve(key); err != nil {
+		return "", err
+	}
+
+	uploadID := uuid.New().String()
+	dir := s.partsDir(uploadID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	// Remember the key so parts can't be attached to another file
+	if err := os.WriteFile(filepath.Join(dir, "key"), []byte(key), 0o640); err != nil {
+		return "", fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return uploadID, nil
+}
+
+// PresignUploadPart returns a signed URL that accepts a PUT of one part
+func (s *localStorageService) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	return s.uploadURL(key, uploadID, partNumber, duration)
+}
+
+// CompleteMultipartUpload concatenates the staged parts, checking their ETags, into the final file
+func (s *localStorageService) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	dir, err := s.openUpload(key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	filePath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	sorted := append([]CompletedPart(nil), parts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PartNumber < sorted[j].PartNumber })
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	for i, part := range sorted {
+		if i > 0 && part.PartNumber == sorted[i-1].PartNumber {
+			tmp.Close()
+			return fmt.Errorf("duplicate part %d", part.PartNumber)
+		}
+		if err := appendPart(tmp, filepath.Join(dir, strconv.Itoa(int(part.PartNumber))), part.ETag); err != nil {
+			tmp.Close()
+			return fmt.Errorf("part %d: %w", part.PartNumber, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+
+	return os.RemoveAll(dir)
+}
+
+// AbortMultipartUpload removes the staged parts of an upload
+func (s *localStorageService) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return fmt.Errorf("invalid upload ID: %q", uploadID)
+	}
+
+	if err := os.RemoveAll(s.partsDir(uploadID)); err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyUploadSignature checks an upload signature and its expiry
+func (s *localStorageService) VerifyUploadSignature(key, uploadID string, partNumber int32, expires int64, signature string) error {
+	if time.Now().Unix() > expires {
+		return ErrInvalidSignature
+	}
+
+	expected := s.signUpload(key, uploadID, partNumber, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// WriteUpload stores the body of a signed PUT, either the whole file or one part of a multipart upload
+// It returns the ETag the client passes back when completing a multipart upload
+func (s *localStorageService) WriteUpload(key, uploadID string, partNumber int32, body io.Reader) (string, error) {
+	hash := sha256.New()
+	body = io.TeeReader(body, hash)
+
+	if uploadID == "" {
+		if err := s.Upload(context.Background(), key, body, "", -1); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	dir, err := s.openUpload(key, uploadID)
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".part-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create part: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write part: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write part: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, strconv.Itoa(int(partNumber)))); err != nil {
+		return "", fmt.Errorf("failed to store part: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// uploadURL builds a signed PUT URL on LocalFilesRoute
+func (s *localStorageService) uploadURL(key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	if _, err := s.resolve(key); err != nil {
+		return "", err
+	}
+
+	expires := time.Now().Add(duration).Unix()
+
+	query := url.Values{}
+	if uploadID != "" {
+		query.Set("upload_id", uploadID)
+		query.Set("part_number", strconv.Itoa(int(partNumber)))
+	}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.signUpload(key, uploadID, partNumber, expires))
+
+	return fmt.Sprintf("%s%s/%s?%s", s.baseURL, LocalFilesRoute, escapeKey(key), query.Encode()), nil
+}
+
+// partsDir returns the staging directory of a multipart upload
+func (s *localStorageService) partsDir(uploadID string) string {
+	return filepath.Join(s.root, multipartDir, uploadID)
+}
+
+// openUpload returns the staging directory of a multipart upload after checking it belongs to key
+func (s *localStorageService) openUpload(key, uploadID string) (string, error) {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return "", fmt.Errorf("invalid upload ID: %q", uploadID)
+	}
+
+	dir := s.partsDir(uploadID)
+	stored, err := os.ReadFile(filepath.Join(dir, "key"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.New("upload not found")
+		}
+		return "", fmt.Errorf("failed to read upload: %w", err)
+	}
+	if string(stored) != key {
+		return "", errors.New("upload does not belong to this key")
+	}
+
+	return dir, nil
+}
+
+// appendPart copies a staged part into dst, checking it against the ETag returned when it was uploaded
+func appendPart(dst io.Writer, partPath, etag string) error {
+	part, err := os.Open(partPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.New("part not uploaded")
+		}
+		return err
+	}
+	defer part.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(dst, hash), part); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != strings.Trim(etag, `"`) {
+		return errors.New("ETag does not match")
+	}
+
+	return nil
+}
+
+// signUpload computes the HMAC-SHA256 that authorizes a PUT of a file or part
+func (s *localStorageService) signUpload(key, uploadID string, partNumber int32, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte("PUT\n"))
+	mac.Write([]byte(key))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(uploadID))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(strconv.Itoa(int(partNumber))))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sign computes the HMAC-SHA256 of a key and expiry
+func (s *localStorageService) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// resolve maps a storage key to a path inside the root, rejecting keys that escape it
+func (s *localStorageService) resolve(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\r\n") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+
+	cleaned := path.Clean(key)
+	if cleaned != key || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid storage key: %q", key)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(cleaned)), nil
+}
+
+// escapeKey escapes each path segment of a key for use in a URL
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+)
+
+// ErrScanRejected is returned when the scanner answers but refuses to scan a file, e.g. over its size limit
+// Retrying won't help; other errors (scanner unreachable, timeouts) are worth retrying
+var ErrScanRejected = errors.New("scanner rejected the file")
+
+// clamdChunkSize is the size of the chunks streamed to clamd
+const clamdChunkSize = 64 * 1024
+
+// ScanResult is the verdict of a malware scan
+type ScanResult struct {
+	Infected  bool
+	Signature string // Name of the detected malware
+}
+
+// MalwareScanner scans file contents for malware
+type MalwareScanner interface {
+	Scan(ctx context.Context, content io.Reader) (*ScanResult, error)
+}
+
+// NewMalwareScanner creates the scanner selected by SCANNER_DRIVER (none or clamav)
+func NewMalwareScanner(cfg config.ScannerConfig) (MalwareScanner, error) {
+	switch cfg.Driver {
+	case "", "none":
+		return &noopScanner{}, nil
+	case "clamav":
+		network, address, err := parseSocketAddress(cfg.ClamAVAddress)
+		if err != nil {
+			return nil, err
+		}
+		return NewClamdScanner(network, address, time.Duration(cfg.TimeoutSeconds)*time.Second), nil
+	default:
+		return nil, fmt.Errorf("unknown scanner driver: %s", cfg.Driver)
+	}
+}
+
+// noopScanner passes every file (development, or when no scanner is deployed)
+type noopScanner struct{}
+
+func (s *noopScanner) Scan(ctx context.Context, content io.Reader) (*ScanResult, error) {
+	return &ScanResult{}, nil
+}
+
+// clamdScanner streams files to a ClamAV daemon with the INSTREAM command
+type clamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamdScanner creates a scanner that talks to clamd over a tcp or unix socket
+func NewClamdScanner(network, address string, timeout time.Duration) MalwareScanner {
+	return &clamdScanner{network: network, address: address, timeout: timeout}
+}
+
+// Scan sends the content to clamd in length-prefixed chunks and parses its verdict
+// Replies look like "stream: OK", "stream: Eicar-Signature FOUND" or "INSTREAM size limit exceeded. ERROR"
+func (s *clamdScanner) Scan(ctx context.Context, content io.Reader) (*ScanResult, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, fmt.Errorf("failed to set clamd deadline: %w", err)
+	}
+
+	// clamd may hang up mid-stream (e.g. size limit); its reply then explains why
+	if err := s.stream(conn, content); err != nil {
+		if result, replyErr := s.readReply(conn); replyErr == nil || errors.Is(replyErr, ErrScanRejected) {
+			return result, replyErr
+		}
+		return nil, err
+	}
+
+	return s.readReply(conn)
+}
+
+// stream writes the INSTREAM command, the content chunks and the terminating zero-length chunk
+func (s *clamdScanner) stream(w io.Writer, content io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return fmt.Errorf("failed to send command to clamd: %w", err)
+	}
+
+	buf := make([]byte, 4+clamdChunkSize)
+	for {
+		n, readErr := io.ReadFull(content, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := w.Write(buf[:4+n]); err != nil {
+				return fmt.Errorf("failed to stream file to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return fmt.Errorf("failed to read file: %w", readErr)
+		}
+	}
+
+	if _, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to stream file to clamd: %w", err)
+	}
+	return nil
+}
+
+// readReply reads clamd's null-terminated reply
+func (s *clamdScanner) readReply(r io.Reader) (*ScanResult, error) {
+	reply, err := bufio.NewReader(r).ReadString(0)
+	if err != nil && (err != io.EOF || reply == "") {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+
+	switch {
+	case strings.HasSuffix(reply, " OK"):
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		signature := strings.TrimSuffix(reply, " FOUND")
+		if i := strings.Index(signature, ": "); i >= 0 {
+			signature = signature[i+2:]
+		}
+		return &ScanResult{Infected: true, Signature: signature}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("%w: %s", ErrScanRejected, strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply: %q", reply)
+	}
+}
+
+// parseSocketAddress splits tcp://host:port or unix:///path into a network and an address
+func parseSocketAddress(addr string) (string, string, error) {
+	network, address, ok := strings.Cut(addr, "://")
+	if !ok || address == "" || (network != "tcp" && network != "unix") {
+		return "", "", fmt.Errorf("invalid CLAMAV_ADDRESS %q: use tcp://host:port or unix:///path", addr)
+	}
+	return network, address, nil
+}
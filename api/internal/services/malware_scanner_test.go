@@ -0,0 +1,222 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClamd is a stand-in for a ClamAV daemon listening on a unix socket
+// It reads one INSTREAM request per connection and answers with reply
+type fakeClamd struct {
+	path  string
+	reply string
+
+	mu       sync.Mutex
+	received [][]byte
+	err      error
+}
+
+// startFakeClamd listens on a socket in a temporary directory until the test ends
+func startFakeClamd(t *testing.T, reply string) *fakeClamd {
+	t.Helper()
+
+	path := t.TempDir() + "/clamd.sock"
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", path, err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	clamd := &fakeClamd{path: path, reply: reply}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go clamd.serve(conn)
+		}
+	}()
+	return clamd
+}
+
+// serve reads the command and the length-prefixed chunks, then sends the reply
+func (c *fakeClamd) serve(conn net.Conn) {
+	defer conn.Close()
+
+	content, err := readInstream(bufio.NewReader(conn))
+	c.mu.Lock()
+	c.received = append(c.received, content)
+	if err != nil && c.err == nil {
+		c.err = err
+	}
+	c.mu.Unlock()
+
+	io.WriteString(conn, c.reply)
+}
+
+// Received returns the contents streamed to the daemon so far, and the first protocol error seen
+func (c *fakeClamd) Received() ([][]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.received, c.err
+}
+
+// readInstream parses a zINSTREAM request up to its zero-length chunk
+func readInstream(r *bufio.Reader) ([]byte, error) {
+	command, err := r.ReadString(0)
+	if err != nil {
+		return nil, err
+	}
+	if command != "zINSTREAM\x00" {
+		return nil, errors.New("unexpected command " + command)
+	}
+
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return content.Bytes(), nil
+		}
+		if size > clamdChunkSize {
+			return nil, errors.New("chunk larger than clamdChunkSize")
+		}
+		if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func TestClamdScannerScan(t *testing.T) {
+	// Larger than one chunk, so the content is split
+	content := bytes.Repeat([]byte("habitta "), clamdChunkSize/4)
+
+	tests := []struct {
+		name          string
+		reply         string
+		wantResult    *ScanResult
+		wantRejected  bool
+		wantErrSubstr string
+	}{
+		{
+			name:       "clean",
+			reply:      "stream: OK\x00",
+			wantResult: &ScanResult{},
+		},
+		{
+			name:       "infected",
+			reply:      "stream: Eicar-Signature FOUND\x00",
+			wantResult: &ScanResult{Infected: true, Signature: "Eicar-Signature"},
+		},
+		{
+			name:         "rejected",
+			reply:        "INSTREAM size limit exceeded. ERROR\x00",
+			wantRejected: true,
+		},
+		{
+			name:          "malformed reply",
+			reply:         "stream: ???\x00",
+			wantErrSubstr: "unexpected clamd reply",
+		},
+		{
+			name:          "empty reply",
+			reply:         "",
+			wantErrSubstr: "failed to read clamd reply",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clamd := startFakeClamd(t, tt.reply)
+			scanner := NewClamdScanner("unix", clamd.path, 5*time.Second)
+
+			result, err := scanner.Scan(context.Background(), bytes.NewReader(content))
+
+			received, protoErr := clamd.Received()
+			if protoErr != nil {
+				t.Fatalf("scanner broke the INSTREAM protocol: %v", protoErr)
+			}
+			if len(received) != 1 || !bytes.Equal(received[0], content) {
+				t.Fatalf("clamd did not receive the file content")
+			}
+
+			switch {
+			case tt.wantRejected:
+				if !errors.Is(err, ErrScanRejected) {
+					t.Fatalf("want ErrScanRejected, got %v", err)
+				}
+			case tt.wantErrSubstr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErrSubstr, err)
+				}
+				if errors.Is(err, ErrScanRejected) {
+					t.Fatalf("a malformed reply must not count as a rejection, so the file is retried: %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if *result != *tt.wantResult {
+					t.Fatalf("want %+v, got %+v", *tt.wantResult, *result)
+				}
+			}
+		})
+	}
+}
+
+func TestClamdScannerConnectionFailure(t *testing.T) {
+	scanner := NewClamdScanner("unix", t.TempDir()+"/clamd.sock", time.Second)
+
+	_, err := scanner.Scan(context.Background(), strings.NewReader("content"))
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to clamd") {
+		t.Fatalf("want a connection error, got %v", err)
+	}
+	if errors.Is(err, ErrScanRejected) {
+		t.Fatalf("an unreachable scanner must not count as a rejection, so the file is retried: %v", err)
+	}
+}
+
+func TestParseSocketAddress(t *testing.T) {
+	tests := []struct {
+		addr        string
+		wantNetwork string
+		wantAddress string
+		wantErr     bool
+	}{
+		{addr: "tcp://localhost:3310", wantNetwork: "tcp", wantAddress: "localhost:3310"},
+		{addr: "unix:///run/clamav/clamd.ctl", wantNetwork: "unix", wantAddress: "/run/clamav/clamd.ctl"},
+		{addr: "localhost:3310", wantErr: true},
+		{addr: "udp://localhost:3310", wantErr: true},
+		{addr: "tcp://", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			network, address, err := parseSocketAddress(tt.addr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, got %s %s", network, address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if network != tt.wantNetwork || address != tt.wantAddress {
+				t.Fatalf("want %s %s, got %s %s", tt.wantNetwork, tt.wantAddress, network, address)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+const (
+	// softDeleteRetention is how long soft-deleted rows are kept before being purged
+	softDeleteRetention = 30 * 24 * time.Hour
+	// jobRunRetention is how long the run history of scheduled jobs is kept
+	jobRunRetention = 90 * 24 * time.Hour
+)
+
+// purgeableModels are the soft-deleted rows purged for good, children before parents
+// Tenants and users are kept: deleting them cascades to data that is still in use.
+// Documents release their files from storage when deleted, so only the rows are left
+var purgeableModels = []interface{}{
+	&models.Session{},
+	&models.PasswordResetToken{},
+	&models.EmailVerificationToken{},
+	&models.TwoFactorRecoveryCode{},
+	&models.Invite{},
+	&models.UnitMember{},
+	&models.UserTenant{},
+	&models.CustomRole{},
+	&models.DocumentShareLink{},
+	&models.UploadSession{},
+	&models.DocumentVersion{},
+	&models.Document{},
+	&models.Folder{},
+	&models.Unit{},
+}
+
+// RetentionService defines the interface for purging data past its retention
+type RetentionService interface {
+	PurgeDeleted(ctx context.Context) (int, error)
+}
+
+// retentionService implements RetentionService
+type retentionService struct {
+	db         *gorm.DB
+	jobRunRepo repositories.JobRunRepository
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(db *gorm.DB, jobRunRepo repositories.JobRunRepository) RetentionService {
+	return &retentionService{
+		db:         db,
+		jobRunRepo: jobRunRepo,
+	}
+}
+
+// PurgeDeleted hard-deletes rows soft-deleted more than softDeleteRetention ago and trims the job run history (scheduled job)
+// It runs without a tenant, so row-level security doesn't restrict it to one condominium
+func (s *retentionService) PurgeDeleted(ctx context.Context) (int, error) {
+	cutoff := time.Now().Add(-softDeleteRetention)
+
+	purged := 0
+	for _, model := range purgeableModels {
+		if ctx.Err() != nil {
+			return purged, ctx.Err()
+		}
+		result := s.db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(model)
+		if result.Error != nil {
+			return purged, fmt.Errorf("failed to purge %T: %w", model, result.Error)
+		}
+		purged += int(result.RowsAffected)
+	}
+
+	runs, err := s.jobRunRepo.DeleteStartedBefore(time.Now().Add(-jobRunRetention))
+	if err != nil {
+		return purged, fmt.Errorf("failed to trim job run history: %w", err)
+	}
+
+	return purged + int(runs), nil
+}
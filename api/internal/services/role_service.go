@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// roleNamePattern restricts custom role names to lowercase slugs (stored in memberships and invites)
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// CreateRoleRequest represents the request to create a custom role
+type CreateRoleRequest struct {
+	Name        string              `json:"name" binding:"required"`
+	DisplayName string              `json:"display_name" binding:"required"`
+	Description string              `json:"description"`
+	Permissions []models.Permission `json:"permissions" binding:"required"`
+}
+
+// UpdateRoleRequest represents the request to update a custom role
+type UpdateRoleRequest struct {
+	DisplayName *string             `json:"display_name"`
+	Description *string             `json:"description"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// RoleInfo describes a role available in a tenant (built-in or custom)
+type RoleInfo struct {
+	ID          *uint              `json:"id,omitempty"`
+	Name        models.UserRole    `json:"name"`
+	DisplayName string             `json:"display_name"`
+	Description string             `json:"description,omitempty"`
+	Builtin     bool               `json:"builtin"`
+	Permissions models.Permissions `json:"permissions"`
+}
+
+// builtinRoleNames holds the display names of the built-in roles, in listing order
+var builtinRoleNames = []struct {
+	Role        models.UserRole
+	DisplayName string
+}{
+	{models.RoleAdmin, "Administrador"},
+	{models.RoleSindico, "Síndico"},
+	{models.RoleSubsindico, "Subsíndico"},
+	{models.RoleAdministradora, "Administradora"},
+	{models.RoleConselheiroFiscal, "Conselheiro Fiscal"},
+	{models.RolePorteiro, "Porteiro"},
+	{models.RoleMorador, "Morador"},
+}
+
+// RoleService defines the interface for role and permission operations
+type RoleService interface {
+	ListPermissions() []models.PermissionDefinition
+	ListRoles(ctx context.Context, tenantID uint) ([]RoleInfo, error)
+	CreateRole(ctx context.Context, tenantID uint, actorPerms models.Permissions, req CreateRoleRequest) (*models.CustomRole, error)
+	UpdateRole(ctx context.Context, tenantID, roleID uint, actorRole models.UserRole, actorPerms models.Permissions, req UpdateRoleRequest) (*models.CustomRole, error)
+	DeleteRole(ctx context.Context, tenantID, roleID uint) error
+	GetPermissions(ctx context.Context, tenantID uint, role string) (models.Permissions, error)
+	HasPermission(ctx context.Context, tenantID uint, role models.UserRole, perm models.Permission) (bool, error)
+	ValidateAssignableRole(ctx context.Context, tenantID uint, assignerRole, role models.UserRole) error
+}
+
+// roleService implements RoleService
+type roleService struct {
+	roleRepo       repositories.CustomRoleRepository
+	userTenantRepo repositories.UserTenantRepository
+	inviteRepo     repositories.InviteRepository
+}
+
+// NewRoleService creates a new role service
+func NewRoleService(
+	roleRepo repositories.CustomRoleRepository,
+	userTenantRepo repositories.UserTenantRepository,
+	inviteRepo repositories.InviteRepository,
+) RoleService {
+	return &roleService{
+		roleRepo:       roleRepo,
+		userTenantRepo: userTenantRepo,
+		inviteRepo:     inviteRepo,
+	}
+}
+
+// ListPermissions returns the permission registry
+func (s *roleService) ListPermissions() []models.PermissionDefinition {
+	return models.PermissionRegistry
+}
+
+// ListRoles returns the built-in roles followed by the tenant's custom roles
+func (s *roleService) ListRoles(ctx context.Context, tenantID uint) ([]RoleInfo, error) {
+	roles := make([]RoleInfo, 0, len(builtinRoleNames))
+	for _, builtin := range builtinRoleNames {
+		roles = append(roles, RoleInfo{
+			Name:        builtin.Role,
+			DisplayName: builtin.DisplayName,
+			Builtin:     true,
+			Permissions: models.BuiltinRolePermissions[builtin.Role],
+		})
+	}
+
+	custom, err := s.roleRepo.GetAllByTenant(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get custom roles: %w", err)
+	}
+
+	for i := range custom {
+		roles = append(roles, RoleInfo{
+			ID:          &custom[i].ID,
+			Name:        custom[i].Name,
+			DisplayName: custom[i].DisplayName,
+			Description: custom[i].Description,
+			Permissions: custom[i].Permissions,
+		})
+	}
+
+	return roles, nil
+}
+
+// CreateRole creates a custom role for a tenant, granting only permissions the actor holds
+func (s *roleService) CreateRole(ctx context.Context, tenantID uint, actorPerms models.Permissions, req CreateRoleRequest) (*models.CustomRole, error) {
+	name := models.UserRole(req.Name)
+	if !roleNamePattern.MatchString(req.Name) {
+		return nil, errors.New("role name must be a lowercase slug (letters, digits and underscores)")
+	}
+	if models.IsBuiltinRole(name) {
+		return nil, errors.New("role name is reserved for a built-in role")
+	}
+
+	permissions, err := validatePermissions(req.Permissions, actorPerms)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.roleRepo.GetByName(ctx, name)
+	if err == nil && existing != nil {
+		return nil, errors.New("role name already exists for this tenant")
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing role: %w", err)
+	}
+
+	role := &models.CustomRole{
+		TenantID:    tenantID,
+		Name:        name,
+		DisplayName: req.DisplayName,
+		Description: req.Description,
+		Permissions: permissions,
+	}
+
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return role, nil
+}
+
+// UpdateRole updates a custom role; changes apply to members on their next request
+// The actor cannot edit their own role, nor a role or grant with permissions they do not have
+func (s *roleService) UpdateRole(ctx context.Context, tenantID, roleID uint, actorRole models.UserRole, actorPerms models.Permissions, req UpdateRoleRequest) (*models.CustomRole, error) {
+	role, err := s.getRole(ctx, tenantID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	if role.Name == actorRole {
+		return nil, errors.New("cannot edit your own role")
+	}
+	if !actorPerms.Covers(role.Permissions) {
+		return nil, errors.New("cannot edit a role with permissions you do not have")
+	}
+
+	if req.DisplayName != nil {
+		if *req.DisplayName == "" {
+			return nil, errors.New("display name cannot be empty")
+		}
+		role.DisplayName = *req.DisplayName
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.Permissions != nil {
+		permissions, err := validatePermissions(req.Permissions, actorPerms)
+		if err != nil {
+			return nil, err
+		}
+		role.Permissions = permissions
+	}
+
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	return role, nil
+}
+
+// DeleteRole deletes a custom role that is no longer assigned to members or pending invites
+func (s *roleService) DeleteRole(ctx context.Context, tenantID, roleID uint) error {
+	role, err := s.getRole(ctx, tenantID, roleID)
+	if err != nil {
+		return err
+	}
+
+	members, err := s.userTenantRepo.CountByRole(ctx, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to count members: %w", err)
+	}
+	if members > 0 {
+		return errors.New("role is assigned to members; reassign them before deleting it")
+	}
+
+	invites, err := s.inviteRepo.CountPendingByRole(ctx, role.Name)
+	if err != nil {
+		return fmt.Errorf("failed to count invites: %w", err)
+	}
+	if invites > 0 {
+		return errors.New("role is used by pending invites; cancel them before deleting it")
+	}
+
+	if err := s.roleRepo.Delete(ctx, roleID); err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	return nil
+}
+
+// GetPermissions resolves the permissions of a role in a tenant
+// Unknown roles resolve to an empty set rather than an error
+func (s *roleService) GetPermissions(ctx context.Context, tenantID uint, role string) (models.Permissions, error) {
+	if perms, ok := models.BuiltinRolePermissions[models.UserRole(role)]; ok {
+		return perms, nil
+	}
+
+	custom, err := s.roleRepo.GetByName(ctx, models.UserRole(role))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Permissions{}, nil
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+
+	return custom.Permissions, nil
+}
+
+// HasPermission checks if a role grants a permission in a tenant
+func (s *roleService) HasPermission(ctx context.Context, tenantID uint, role models.UserRole, perm models.Permission) (bool, error) {
+	perms, err := s.GetPermissions(ctx, tenantID, string(role))
+	if err != nil {
+		return false, err
+	}
+	return perms.Has(perm), nil
+}
+
+// ValidateAssignableRole checks that a role exists in the tenant and grants nothing the assigner lacks
+func (s *roleService) ValidateAssignableRole(ctx context.Context, tenantID uint, assignerRole, role models.UserRole) error {
+	if !models.IsBuiltinRole(role) {
+		if _, err := s.roleRepo.GetByName(ctx, role); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("role %q does not exist in this tenant", role)
+			}
+			return fmt.Errorf("failed to get role: %w", err)
+		}
+	}
+
+	assignerPerms, err := s.GetPermissions(ctx, tenantID, string(assignerRole))
+	if err != nil {
+		return err
+	}
+	rolePerms, err := s.GetPermissions(ctx, tenantID, string(role))
+	if err != nil {
+		return err
+	}
+
+	if !assignerPerms.Covers(rolePerms) {
+		return errors.New("cannot assign a role with permissions you do not have")
+	}
+
+	return nil
+}
+
+// getRole retrieves a custom role by ID
+func (s *roleService) getRole(ctx context.Context, tenantID, roleID uint) (*models.CustomRole, error) {
+	role, err := s.roleRepo.GetByID(ctx, roleID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	return role, nil
+}
+
+// validatePermissions rejects unknown or platform-only permissions and permissions the actor lacks, and removes duplicates
+func validatePermissions(perms []models.Permission, actorPerms models.Permissions) (models.Permissions, error) {
+	result := make(models.Permissions, 0, len(perms))
+	for _, perm := range perms {
+		if !models.IsValidPermission(perm) {
+			return nil, fmt.Errorf("unknown permission: %s", perm)
+		}
+		if perm == models.PermTenantsManage {
+			return nil, fmt.Errorf("permission %s cannot be granted by a tenant", perm)
+		}
+		if !result.Has(perm) {
+			result = append(result, perm)
+		}
+	}
+	if !actorPerms.Covers(result) {
+		return nil, errors.New("cannot grant permissions you do not have")
+	}
+	return result, nil
+}
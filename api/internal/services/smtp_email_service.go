@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+)
+
+// smtpEmailService sends emails through an SMTP server (self-hosted setups and local mail sinks)
+type smtpEmailService struct {
+	host        string
+	port        int
+	username    string
+	password    string
+	security    string
+	fromAddress string
+	timeout     time.Duration
+}
+
+// NewSMTPEmailService creates an email service that delivers through the given SMTP server
+func NewSMTPEmailService(cfg config.SMTPConfig, fromAddress string) EmailService {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &smtpEmailService{
+		host:        cfg.Host,
+		port:        cfg.Port,
+		username:    cfg.Username,
+		password:    cfg.Password,
+		security:    cfg.Security,
+		fromAddress: fromAddress,
+		timeout:     timeout,
+	}
+}
+
+func (s *smtpEmailService) SendEmail(msg EmailMessage) error {
+	from, err := mail.ParseAddress(s.fromAddress)
+	if err != nil {
+		return fmt.Errorf("invalid EMAIL_FROM address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	raw, err := buildMIMEMessage(from, to, msg)
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.username != "" {
+		// PlainAuth refuses to send credentials over an unencrypted connection, except to localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP server rejected recipient: %w", err)
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start SMTP data: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected email: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// dial connects to the SMTP server and secures the connection according to SMTP_SECURITY
+func (s *smtpEmailService) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	tlsConfig := &tls.Config{ServerName: s.host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	var err error
+	if s.security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	// Bounds the whole conversation, so a stalled server can't hang the caller
+	conn.SetDeadline(time.Now().Add(s.timeout))
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+
+	if s.security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to start TLS with SMTP server: %w", err)
+		}
+	}
+
+	return client, nil
+}
+
+// buildMIMEMessage renders the email as multipart/alternative with a plain-text and an HTML part
+func buildMIMEMessage(from, to *mail.Address, msg EmailMessage) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", msg.PlainText()},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(from.Address, msg.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", from.String()},
+		{"To", to.String()},
+		{"Subject", mime.QEncoding.Encode("UTF-8", stripLineBreaks(msg.Subject))},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": parts.Boundary()})},
+	} {
+		fmt.Fprintf(&buf, "%s: %s\r\n", h[0], h[1])
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
+// newMessageID generates a Message-ID in the sender's domain
+// Emails with an idempotency key keep the same ID on every attempt, so receiving servers can drop duplicates
+func newMessageID(fromAddress, idempotencyKey string) (string, error) {
+	var token string
+	if idempotencyKey != "" {
+		token = utils.HashToken(idempotencyKey)[:32]
+	} else {
+		var err error
+		if token, err = utils.GenerateSecureToken(16); err != nil {
+			return "", fmt.Errorf("failed to generate message id: %w", err)
+		}
+	}
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 {
+		domain = fromAddress[at+1:]
+	}
+	return "<" + token + "@" + domain + ">", nil
+}
+
+// stripLineBreaks keeps user-supplied text from injecting extra headers
+func stripLineBreaks(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,271 @@
+package services
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/pkg/mailsink"
+)
+
+const testFromAddress = "Habitta <no-reply@habitta.test>"
+
+// startMailSink starts an SMTP sink that is closed when the test ends
+func startMailSink(t *testing.T) *mailsink.Server {
+	t.Helper()
+
+	sink, err := mailsink.Start("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start mail sink: %v", err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	return sink
+}
+
+// newSinkEmailService creates an smtp email service that delivers to sink
+func newSinkEmailService(t *testing.T, sink *mailsink.Server, username, password string) EmailService {
+	t.Helper()
+
+	host, port, err := net.SplitHostPort(sink.Addr())
+	if err != nil {
+		t.Fatalf("invalid sink address: %v", err)
+	}
+	portNumber, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("invalid sink port: %v", err)
+	}
+
+	return NewSMTPEmailService(config.SMTPConfig{
+		Host:           host,
+		Port:           portNumber,
+		Username:       username,
+		Password:       password,
+		Security:       "none",
+		TimeoutSeconds: 5,
+	}, testFromAddress)
+}
+
+func TestSMTPEmailServiceSendEmail(t *testing.T) {
+	sink := startMailSink(t)
+	svc := newSinkEmailService(t, sink, "", "")
+
+	msg := EmailMessage{
+		To:      "Maria Souza <maria@example.com>",
+		Subject: "Convite para o condomínio Jardim das Acácias",
+		HTML:    `<p>Olá, Maria!</p><p><a href="https://app.habitta.test/invites/abc">Aceitar convite</a></p>`,
+	}
+	if err := svc.SendEmail(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("want 1 message, got %d", len(messages))
+	}
+	got := messages[0]
+
+	if got.From != "no-reply@habitta.test" {
+		t.Errorf("want envelope sender no-reply@habitta.test, got %q", got.From)
+	}
+	if len(got.To) != 1 || got.To[0] != "maria@example.com" {
+		t.Errorf("want envelope recipient maria@example.com, got %v", got.To)
+	}
+	if got.Username != "" {
+		t.Errorf("want no AUTH without credentials, got user %q", got.Username)
+	}
+
+	if got.Subject != msg.Subject {
+		t.Errorf("want subject %q, got %q", msg.Subject, got.Subject)
+	}
+	for _, name := range []string{"Date", "Message-ID"} {
+		if got.Header.Get(name) == "" {
+			t.Errorf("want a %s header", name)
+		}
+	}
+	if !strings.HasSuffix(got.Header.Get("Message-ID"), "@habitta.test>") {
+		t.Errorf("want a Message-ID in the sender's domain, got %q", got.Header.Get("Message-ID"))
+	}
+	if from := got.Header.Get("From"); !strings.Contains(from, "no-reply@habitta.test") {
+		t.Errorf("want From header with the sender, got %q", from)
+	}
+	if to := got.Header.Get("To"); !strings.Contains(to, "maria@example.com") {
+		t.Errorf("want To header with the recipient, got %q", to)
+	}
+	if got.Header.Get("MIME-Version") != "1.0" {
+		t.Errorf("want MIME-Version 1.0, got %q", got.Header.Get("MIME-Version"))
+	}
+	if ct := got.Header.Get("Content-Type"); !strings.HasPrefix(ct, "multipart/alternative;") {
+		t.Errorf("want a multipart/alternative message, got %q", ct)
+	}
+
+	if got.HTML != msg.HTML {
+		t.Errorf("want HTML part %q, got %q", msg.HTML, got.HTML)
+	}
+	if got.Text != msg.PlainText() {
+		t.Errorf("want text part %q, got %q", msg.PlainText(), got.Text)
+	}
+	if !strings.Contains(got.Text, "https://app.habitta.test/invites/abc") {
+		t.Errorf("want the link target in the text part, got %q", got.Text)
+	}
+}
+
+func TestSMTPEmailServiceSendEmailTextPart(t *testing.T) {
+	sink := startMailSink(t)
+	svc := newSinkEmailService(t, sink, "", "")
+
+	msg := EmailMessage{
+		To:      "maria@example.com",
+		Subject: "Lembrete",
+		HTML:    "<p>Reunião amanhã às 19h</p>",
+		Text:    "Reunião amanhã às 19h",
+	}
+	if err := svc.SendEmail(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("want 1 message, got %d", len(messages))
+	}
+	if messages[0].Text != msg.Text {
+		t.Errorf("want text part %q, got %q", msg.Text, messages[0].Text)
+	}
+}
+
+func TestSMTPEmailServiceSendEmailHeaderInjection(t *testing.T) {
+	sink := startMailSink(t)
+	svc := newSinkEmailService(t, sink, "", "")
+
+	err := svc.SendEmail(EmailMessage{
+		To:      "maria@example.com",
+		Subject: "Olá\r\nBcc: intruso@example.com",
+		HTML:    "<p>Olá</p>",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := sink.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("want 1 message, got %d", len(messages))
+	}
+	if bcc := messages[0].Header.Get("Bcc"); bcc != "" {
+		t.Errorf("subject injected a Bcc header: %q", bcc)
+	}
+	if len(messages[0].To) != 1 {
+		t.Errorf("want only the recipient in the envelope, got %v", messages[0].To)
+	}
+}
+
+func TestSMTPEmailServiceIdempotencyKey(t *testing.T) {
+	sink := startMailSink(t)
+	svc := newSinkEmailService(t, sink, "", "")
+
+	msg := EmailMessage{To: "maria@example.com", Subject: "Convite", HTML: "<p>Olá</p>", IdempotencyKey: "invite:42"}
+	for i := 0; i < 2; i++ {
+		if err := svc.SendEmail(msg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := svc.SendEmail(EmailMessage{To: "maria@example.com", Subject: "Outro", HTML: "<p>Olá</p>"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	messages := sink.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("want 3 messages, got %d", len(messages))
+	}
+	first, retry, other := messages[0].Header.Get("Message-ID"), messages[1].Header.Get("Message-ID"), messages[2].Header.Get("Message-ID")
+	if first != retry {
+		t.Errorf("want the same Message-ID on every attempt, got %q and %q", first, retry)
+	}
+	if other == first {
+		t.Errorf("want a new Message-ID without an idempotency key, got %q twice", other)
+	}
+}
+
+func TestSMTPEmailServiceAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		username      string
+		password      string
+		wantErrSubstr string
+	}{
+		{name: "valid credentials", username: "habitta", password: "secret"},
+		{name: "wrong password", username: "habitta", password: "wrong", wantErrSubstr: "failed to authenticate with SMTP server"},
+		{name: "no credentials", wantErrSubstr: "SMTP server rejected sender"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := startMailSink(t)
+			sink.RequireAuth("habitta", "secret")
+			svc := newSinkEmailService(t, sink, tt.username, tt.password)
+
+			err := svc.SendEmail(EmailMessage{To: "maria@example.com", Subject: "Convite", HTML: "<p>Olá</p>"})
+
+			if tt.wantErrSubstr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSubstr) {
+					t.Fatalf("want error containing %q, got %v", tt.wantErrSubstr, err)
+				}
+				if n := len(sink.Messages()); n != 0 {
+					t.Fatalf("want no message delivered, got %d", n)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			messages := sink.Messages()
+			if len(messages) != 1 || messages[0].Username != tt.username {
+				t.Fatalf("want 1 message sent as %q, got %+v", tt.username, messages)
+			}
+		})
+	}
+}
+
+func TestSMTPEmailServiceRejectedRecipient(t *testing.T) {
+	sink := startMailSink(t)
+	sink.RejectRecipient("ninguem@example.com")
+	svc := newSinkEmailService(t, sink, "", "")
+
+	err := svc.SendEmail(EmailMessage{To: "ninguem@example.com", Subject: "Convite", HTML: "<p>Olá</p>"})
+	if err == nil || !strings.Contains(err.Error(), "SMTP server rejected recipient") || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("want the sink's 550 rejection, got %v", err)
+	}
+	if n := len(sink.Messages()); n != 0 {
+		t.Fatalf("want no message delivered, got %d", n)
+	}
+}
+
+func TestSMTPEmailServiceUnreachableServer(t *testing.T) {
+	sink := startMailSink(t)
+	svc := newSinkEmailService(t, sink, "", "")
+	sink.Close()
+
+	err := svc.SendEmail(EmailMessage{To: "maria@example.com", Subject: "Convite", HTML: "<p>Olá</p>"})
+	if err == nil || !strings.Contains(err.Error(), "failed to connect to SMTP server") {
+		t.Fatalf("want a connection error, got %v", err)
+	}
+}
+
+func TestSMTPEmailServiceInvalidAddresses(t *testing.T) {
+	sink := startMailSink(t)
+
+	svc := newSinkEmailService(t, sink, "", "")
+	if err := svc.SendEmail(EmailMessage{To: "not an address", Subject: "Convite", HTML: "<p>Olá</p>"}); err == nil {
+		t.Error("want an error for an invalid recipient")
+	}
+
+	svc = NewSMTPEmailService(config.SMTPConfig{Host: "127.0.0.1", Port: 25, Security: "none"}, "not an address")
+	if err := svc.SendEmail(EmailMessage{To: "maria@example.com", Subject: "Convite", HTML: "<p>Olá</p>"}); err == nil {
+		t.Error("want an error for an invalid sender")
+	}
+
+	if n := len(sink.Messages()); n != 0 {
+		t.Fatalf("want no message delivered, got %d", n)
+	}
+}
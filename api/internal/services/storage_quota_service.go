@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// storageWarningLevels are the usage percentages that email the síndicos, highest first
+var storageWarningLevels = []int{100, 80}
+
+// StorageUsage is the storage report of a tenant
+type StorageUsage struct {
+	UsedBytes  int64                  `json:"used_bytes"`
+	QuotaBytes int64                  `json:"quota_bytes"` // 0 means unlimited
+	Percent    float64                `json:"percent"`
+	ByFolder   []models.FolderUsage   `json:"by_folder"`
+	ByUploader []models.UploaderUsage `json:"by_uploader"`
+}
+
+// StorageQuotaService defines the interface for tenant storage accounting
+type StorageQuotaService interface {
+	Check(tenantID uint, size int64) error
+	Reserve(ctx context.Context, tenantID uint, size int64) error
+	Release(ctx context.Context, tenantID uint, size int64) error
+	GetUsage(ctx context.Context, tenantID uint) (*StorageUsage, error)
+}
+
+// storageQuotaService implements StorageQuotaService
+type storageQuotaService struct {
+	tenantRepo     repositories.TenantRepository
+	userTenantRepo repositories.UserTenantRepository
+	docRepo        repositories.DocumentRepository
+	emailOutbox    EmailOutboxService
+	defaultQuota   int64
+	appBaseURL     string
+}
+
+// NewStorageQuotaService creates a new storage quota service
+func NewStorageQuotaService(
+	tenantRepo repositories.TenantRepository,
+	userTenantRepo repositories.UserTenantRepository,
+	docRepo repositories.DocumentRepository,
+	emailOutbox EmailOutboxService,
+	defaultQuotaMB int,
+	appBaseURL string,
+) StorageQuotaService {
+	return &storageQuotaService{
+		tenantRepo:     tenantRepo,
+		userTenantRepo: userTenantRepo,
+		docRepo:        docRepo,
+		emailOutbox:    emailOutbox,
+		defaultQuota:   int64(defaultQuotaMB) * 1024 * 1024,
+		appBaseURL:     appBaseURL,
+	}
+}
+
+// Check tells whether a file of the given size still fits in the quota, without reserving it
+func (s *storageQuotaService) Check(tenantID uint, size int64) error {
+	tenant, err := s.getTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	quota := tenant.StorageQuota(s.defaultQuota)
+	if quota > 0 && tenant.StorageUsedBytes+size > quota {
+		return models.ErrStorageQuotaExceeded
+	}
+	return nil
+}
+
+// Reserve counts size against the tenant's quota, failing if it doesn't fit
+// The check and the increment are a single statement, so concurrent uploads can't overshoot
+// It runs on the transaction carried by ctx: call it in the one that saves the files it counts
+func (s *storageQuotaService) Reserve(ctx context.Context, tenantID uint, size int64) error {
+	ok, err := s.tenantRepo.ReserveStorage(ctx, tenantID, size, s.defaultQuota)
+	if err != nil {
+		return fmt.Errorf("failed to reserve storage: %w", err)
+	}
+	if !ok {
+		return models.ErrStorageQuotaExceeded
+	}
+
+	s.syncWarningLevel(ctx, tenantID)
+	return nil
+}
+
+// Release gives back storage previously reserved when files are deleted, in the transaction carried by ctx
+func (s *storageQuotaService) Release(ctx context.Context, tenantID uint, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+
+	if err := s.tenantRepo.ReleaseStorage(ctx, tenantID, size); err != nil {
+		return fmt.Errorf("failed to release storage: %w", err)
+	}
+
+	s.syncWarningLevel(ctx, tenantID)
+	return nil
+}
+
+// GetUsage reports the tenant's usage and quota with totals by folder and by uploader
+func (s *storageQuotaService) GetUsage(ctx context.Context, tenantID uint) (*StorageUsage, error) {
+	tenant, err := s.getTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	byFolder, err := s.docRepo.UsageByFolder(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by folder: %w", err)
+	}
+
+	byUploader, err := s.docRepo.UsageByUploader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage by uploader: %w", err)
+	}
+
+	usage := &StorageUsage{
+		UsedBytes:  tenant.StorageUsedBytes,
+		QuotaBytes: tenant.StorageQuota(s.defaultQuota),
+		ByFolder:   byFolder,
+		ByUploader: byUploader,
+	}
+	if usage.QuotaBytes > 0 {
+		usage.Percent = math.Round(float64(usage.UsedBytes)*1000/float64(usage.QuotaBytes)) / 10
+	}
+
+	return usage, nil
+}
+
+// getTenant retrieves a tenant, mapping not found errors
+func (s *storageQuotaService) getTenant(tenantID uint) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetByID(tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// syncWarningLevel moves the tenant's warning level to match its usage and emails the síndicos when it goes up
+// The level is swapped atomically so each threshold is only announced once; failures are logged, never returned
+func (s *storageQuotaService) syncWarningLevel(ctx context.Context, tenantID uint) {
+	tenant, err := s.tenantRepo.GetStorage(ctx, tenantID)
+	if err != nil {
+		log.Printf("Failed to get tenant %d for storage warning: %v", tenantID, err)
+		return
+	}
+
+	quota := tenant.StorageQuota(s.defaultQuota)
+	level := storageWarningLevel(tenant.StorageUsedBytes, quota)
+	if level == tenant.StorageWarningLevel {
+		return
+	}
+
+	swapped, err := s.tenantRepo.SwapStorageWarningLevel(ctx, tenantID, tenant.StorageWarningLevel, level)
+	if err != nil {
+		log.Printf("Failed to update storage warning level of tenant %d: %v", tenantID, err)
+		return
+	}
+	if !swapped || level < tenant.StorageWarningLevel {
+		return
+	}
+
+	s.sendWarningEmails(ctx, tenant, level, quota)
+}
+
+// sendWarningEmails tells every active síndico of the tenant how much of the quota is in use
+// The emails are queued in the transaction that moved the level, so a rolled back upload sends none
+func (s *storageQuotaService) sendWarningEmails(ctx context.Context, tenant *models.Tenant, level int, quota int64) {
+	members, err := s.userTenantRepo.GetAllByTenant(ctx)
+	if err != nil {
+		log.Printf("Failed to get members of tenant %d for storage warning: %v", tenant.ID, err)
+		return
+	}
+
+	data := storageWarningEmailData{
+		Level:      level,
+		UsedBytes:  tenant.StorageUsedBytes,
+		QuotaBytes: quota,
+		Link:       s.appBaseURL + "/documents",
+	}
+
+	for _, m := range members {
+		if m.Role != models.RoleSindico || !m.IsActive || m.User == nil {
+			continue
+		}
+		msg, err := renderEmail(EmailStorageWarning, m.User.Locale, tenantBranding(tenant), data)
+		if err != nil {
+			log.Printf("Failed to render storage warning for %s: %v", m.User.Email, err)
+			continue
+		}
+		msg.To = m.User.Email
+		msg.IdempotencyKey = fmt.Sprintf("%s:%d:%d:%d:%d", EmailStorageWarning, tenant.ID, level, tenant.StorageUsedBytes, m.UserID)
+		if err := s.emailOutbox.Enqueue(ctx, msg); err != nil {
+			log.Printf("Failed to queue storage warning to %s: %v", m.User.Email, err)
+		}
+	}
+}
+
+// storageWarningLevel returns the highest warning level reached by the usage, or 0
+func storageWarningLevel(used, quota int64) int {
+	if quota <= 0 {
+		return 0
+	}
+	for _, level := range storageWarningLevels {
+		if used*100 >= quota*int64(level) {
+			return level
+		}
+	}
+	return 0
+}
+
+// formatBytes renders a size for people, e.g. "1.5 GB"
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
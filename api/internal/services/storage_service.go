@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"time"
@@ -10,13 +11,38 @@ import (
 	awsconfig "github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
+// ErrObjectNotFound is returned by Head and Read when no object exists under the key
+var ErrObjectNotFound = errors.New("object not found")
+
 // StorageService defines the interface for file storage operations
 type StorageService interface {
 	Upload(ctx context.Context, key string, body io.Reader, contentType string, size int64) error
 	Delete(ctx context.Context, key string) error
 	GetPresignedURL(ctx context.Context, key string, duration time.Duration) (string, error)
+	Head(ctx context.Context, key string) (*ObjectInfo, error)
+	Read(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Direct uploads: the client sends the bytes to storage using these URLs
+	PresignPut(ctx context.Context, key, contentType string, duration time.Duration) (string, error)
+	CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error)
+	PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, duration time.Duration) (string, error)
+	CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error
+	AbortMultipartUpload(ctx context.Context, key, uploadID string) error
+}
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Size        int64
+	ContentType string
+}
+
+// CompletedPart identifies an uploaded part of a multipart upload
+type CompletedPart struct {
+	PartNumber int32  `json:"part_number" binding:"required,min=1"`
+	ETag       string `json:"etag" binding:"required"`
 }
 
 // s3StorageService implements StorageService using AWS S3
@@ -26,8 +52,20 @@ type s3StorageService struct {
 	bucket       string
 }
 
-// NewStorageService creates a new S3/MinIO storage service
+// NewStorageService creates the storage backend selected by STORAGE_DRIVER (s3 or local)
 func NewStorageService(cfg config.StorageConfig) (StorageService, error) {
+	switch cfg.Driver {
+	case "", "s3":
+		return newS3StorageService(cfg)
+	case "local":
+		return newLocalStorageService(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+}
+
+// newS3StorageService creates a new S3/MinIO storage service
+func newS3StorageService(cfg config.StorageConfig) (StorageService, error) {
 	opts := []func(*s3.Options){
 		func(o *s3.Options) {
 			o.Region = cfg.Region
@@ -43,7 +81,13 @@ func NewStorageService(cfg config.StorageConfig) (StorageService, error) {
 	}
 
 	client := s3.New(s3.Options{}, opts...)
-	presignClient := s3.NewPresignClient(client)
+
+	// Presigned URLs are used by browsers, which don't send the SDK's default checksum headers
+	presignClient := s3.NewPresignClient(client, func(o *s3.PresignOptions) {
+		o.ClientOptions = append(o.ClientOptions, func(o *s3.Options) {
+			o.RequestChecksumCalculation = awsconfig.RequestChecksumCalculationWhenRequired
+		})
+	})
 
 	svc := &s3StorageService{
 		client:        client,
@@ -121,3 +165,126 @@ func (s *s3StorageService) GetPresignedURL(ctx context.Context, key string, dura
 
 	return result.URL, nil
 }
+
+// Head returns the size and content type of a stored object
+func (s *s3StorageService) Head(ctx context.Context, key string) (*ObjectInfo, error) {
+	result, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &notFound) || errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to head object: %w", err)
+	}
+
+	return &ObjectInfo{
+		Size:        awsconfig.ToInt64(result.ContentLength),
+		ContentType: awsconfig.ToString(result.ContentType),
+	}, nil
+}
+
+// Read streams an object's content; the caller must close it
+func (s *s3StorageService) Read(ctx context.Context, key string) (io.ReadCloser, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    &key,
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+
+	return result.Body, nil
+}
+
+// PresignPut generates a presigned URL for uploading a whole object with a single PUT
+// The client must send the same Content-Type header
+func (s *s3StorageService) PresignPut(ctx context.Context, key, contentType string, duration time.Duration) (string, error) {
+	result, err := s.presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &s.bucket,
+		Key:         &key,
+		ContentType: &contentType,
+	}, s3.WithPresignExpires(duration))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return result.URL, nil
+}
+
+// CreateMultipartUpload starts a multipart upload and returns its upload ID
+func (s *s3StorageService) CreateMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	result, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      &s.bucket,
+		Key:         &key,
+		ContentType: &contentType,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	return awsconfig.ToString(result.UploadId), nil
+}
+
+// PresignUploadPart generates a presigned URL for uploading one part of a multipart upload
+func (s *s3StorageService) PresignUploadPart(ctx context.Context, key, uploadID string, partNumber int32, duration time.Duration) (string, error) {
+	result, err := s.presignClient.PresignUploadPart(ctx, &s3.UploadPartInput{
+		Bucket:     &s.bucket,
+		Key:        &key,
+		UploadId:   &uploadID,
+		PartNumber: &partNumber,
+	}, s3.WithPresignExpires(duration))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+
+	return result.URL, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the final object
+func (s *s3StorageService) CompleteMultipartUpload(ctx context.Context, key, uploadID string, parts []CompletedPart) error {
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, p := range parts {
+		completed = append(completed, types.CompletedPart{
+			ETag:       awsconfig.String(p.ETag),
+			PartNumber: awsconfig.Int32(p.PartNumber),
+		})
+	}
+
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          &s.bucket,
+		Key:             &key,
+		UploadId:        &uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// AbortMultipartUpload discards a multipart upload and its parts (an unknown upload is not an error)
+func (s *s3StorageService) AbortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   &s.bucket,
+		Key:      &key,
+		UploadId: &uploadID,
+	})
+	if err != nil {
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return nil
+		}
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	return nil
+}
@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
+	"github.com/arturbaldoramos/Habitta/internal/database"
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
 	"gorm.io/gorm"
@@ -17,15 +21,26 @@ type CreateTenantRequest struct {
 	Phone string `json:"phone"`
 }
 
+// UpdateTenantSettingsRequest represents the request to change tenant settings
+// Omitted fields are left unchanged; an empty allowed_file_types list accepts every supported type
+type UpdateTenantSettingsRequest struct {
+	RequireTwoFactor *bool     `json:"require_two_factor"`
+	AllowedFileTypes *[]string `json:"allowed_file_types"`
+	LogoURL          *string   `json:"logo_url" binding:"omitempty,max=512"` // Empty removes the logo
+	Locale           *string   `json:"locale"`
+}
+
 // TenantManagementService defines the interface for tenant management operations
 type TenantManagementService interface {
-	CreateTenantByUser(userID uint, req CreateTenantRequest) (*models.Tenant, error)
+	CreateTenantByUser(ctx context.Context, userID uint, req CreateTenantRequest) (*models.Tenant, error)
+	UpdateSettings(userID, tenantID uint, req UpdateTenantSettingsRequest) (*models.Tenant, error)
 }
 
 // tenantManagementService implements TenantManagementService
 type tenantManagementService struct {
 	tenantRepo     repositories.TenantRepository
 	userTenantRepo repositories.UserTenantRepository
+	userRepo       repositories.UserRepository
 	db             *gorm.DB
 }
 
@@ -33,20 +48,35 @@ type tenantManagementService struct {
 func NewTenantManagementService(
 	tenantRepo repositories.TenantRepository,
 	userTenantRepo repositories.UserTenantRepository,
+	userRepo repositories.UserRepository,
 	db *gorm.DB,
 ) TenantManagementService {
 	return &tenantManagementService{
 		tenantRepo:     tenantRepo,
 		userTenantRepo: userTenantRepo,
+		userRepo:       userRepo,
 		db:             db,
 	}
 }
 
 // CreateTenantByUser creates a new tenant and makes the user a síndico automatically
-func (s *tenantManagementService) CreateTenantByUser(userID uint, req CreateTenantRequest) (*models.Tenant, error) {
+func (s *tenantManagementService) CreateTenantByUser(ctx context.Context, userID uint, req CreateTenantRequest) (*models.Tenant, error) {
+	// Only users with a verified email can become síndico of a new tenant
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if !user.IsEmailVerified() {
+		return nil, models.ErrEmailNotVerified
+	}
+
 	// Use transaction to ensure both tenant and user_tenant are created together
 	var tenant *models.Tenant
-	err := s.db.Transaction(func(tx *gorm.DB) error {
+	err = database.Transaction(ctx, s.db, func(ctx context.Context) error {
 		// Create tenant
 		tenant = &models.Tenant{
 			Name:   req.Name,
@@ -56,24 +86,26 @@ func (s *tenantManagementService) CreateTenantByUser(userID uint, req CreateTena
 			Active: true,
 		}
 
-		if err := tx.Create(tenant).Error; err != nil {
+		if err := database.Conn(ctx, s.db).Create(tenant).Error; err != nil {
 			return fmt.Errorf("failed to create tenant: %w", err)
 		}
 
-		// Create user-tenant relationship with síndico role
-		userTenant := &models.UserTenant{
-			UserID:   userID,
-			TenantID: tenant.ID,
-			Role:     models.RoleSindico,
-			IsActive: true,
-			JoinedAt: time.Now(),
-		}
+		// Create user-tenant relationship with síndico role, in a scope of the new tenant
+		return database.RunScoped(ctx, s.db, database.Scope{TenantID: tenant.ID, UserID: userID}, func(ctx context.Context) error {
+			userTenant := &models.UserTenant{
+				UserID:   userID,
+				TenantID: tenant.ID,
+				Role:     models.RoleSindico,
+				IsActive: true,
+				JoinedAt: time.Now(),
+			}
 
-		if err := tx.Create(userTenant).Error; err != nil {
-			return fmt.Errorf("failed to create user-tenant relationship: %w", err)
-		}
+			if err := database.Conn(ctx, s.db).Create(userTenant).Error; err != nil {
+				return fmt.Errorf("failed to create user-tenant relationship: %w", err)
+			}
 
-		return nil
+			return nil
+		})
 	})
 
 	if err != nil {
@@ -82,3 +114,60 @@ func (s *tenantManagementService) CreateTenantByUser(userID uint, req CreateTena
 
 	return tenant, nil
 }
+
+// UpdateSettings changes the security and upload settings of a tenant
+// Requiring 2FA is only allowed once the requesting manager has enabled it, so they do not lock themselves out
+func (s *tenantManagementService) UpdateSettings(userID, tenantID uint, req UpdateTenantSettingsRequest) (*models.Tenant, error) {
+	tenant, err := s.tenantRepo.GetByID(tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("tenant not found")
+		}
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	if req.RequireTwoFactor != nil && *req.RequireTwoFactor && !tenant.RequireTwoFactor {
+		user, err := s.userRepo.GetByID(userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if !user.TwoFactorEnabled {
+			return nil, errors.New("enable two-factor authentication on your account before requiring it")
+		}
+	}
+
+	if req.RequireTwoFactor != nil {
+		tenant.RequireTwoFactor = *req.RequireTwoFactor
+	}
+
+	if req.AllowedFileTypes != nil {
+		allowed, err := models.NormalizeFileTypes(*req.AllowedFileTypes)
+		if err != nil {
+			return nil, err
+		}
+		tenant.AllowedFileTypes = allowed
+	}
+
+	if req.LogoURL != nil {
+		if *req.LogoURL != "" {
+			u, err := url.Parse(*req.LogoURL)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return nil, errors.New("logo_url must be an http(s) URL")
+			}
+		}
+		tenant.LogoURL = *req.LogoURL
+	}
+
+	if req.Locale != nil {
+		if !models.IsSupportedLocale(*req.Locale) {
+			return nil, models.ErrUnsupportedLocale
+		}
+		tenant.Locale = *req.Locale
+	}
+
+	if err := s.tenantRepo.Update(tenant); err != nil {
+		return nil, fmt.Errorf("failed to update tenant settings: %w", err)
+	}
+
+	return tenant, nil
+}
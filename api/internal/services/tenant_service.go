@@ -17,17 +17,20 @@ type TenantService interface {
 	GetAll() ([]models.Tenant, error)
 	Update(tenant *models.Tenant) error
 	Delete(id uint) error
+	Deactivate(id uint) error
 }
 
 // tenantService implements TenantService
 type tenantService struct {
-	tenantRepo repositories.TenantRepository
+	tenantRepo  repositories.TenantRepository
+	sessionRepo repositories.SessionRepository
 }
 
 // NewTenantService creates a new tenant service
-func NewTenantService(tenantRepo repositories.TenantRepository) TenantService {
+func NewTenantService(tenantRepo repositories.TenantRepository, sessionRepo repositories.SessionRepository) TenantService {
 	return &tenantService{
-		tenantRepo: tenantRepo,
+		tenantRepo:  tenantRepo,
+		sessionRepo: sessionRepo,
 	}
 }
 
@@ -119,6 +122,16 @@ func (s *tenantService) Update(tenant *models.Tenant) error {
 		return errors.New("CNPJ is required")
 	}
 
+	if tenant.StorageQuotaBytes != nil && *tenant.StorageQuotaBytes < 0 {
+		return errors.New("storage quota must not be negative")
+	}
+
+	allowed, err := models.NormalizeFileTypes(tenant.AllowedFileTypes)
+	if err != nil {
+		return err
+	}
+	tenant.AllowedFileTypes = allowed
+
 	// Check if CNPJ is being changed and if it's already taken
 	if tenant.CNPJ != existing.CNPJ {
 		existingWithCNPJ, err := s.tenantRepo.GetByCNPJ(tenant.CNPJ)
@@ -127,6 +140,10 @@ func (s *tenantService) Update(tenant *models.Tenant) error {
 		}
 	}
 
+	// Storage usage is tracked by the quota service, never set by hand
+	tenant.StorageUsedBytes = existing.StorageUsedBytes
+	tenant.StorageWarningLevel = existing.StorageWarningLevel
+
 	// Update tenant
 	if err := s.tenantRepo.Update(tenant); err != nil {
 		return fmt.Errorf("failed to update tenant: %w", err)
@@ -153,3 +170,29 @@ func (s *tenantService) Delete(id uint) error {
 
 	return nil
 }
+
+// Deactivate blocks access to a tenant without deleting its data and revokes its sessions
+func (s *tenantService) Deactivate(id uint) error {
+	tenant, err := s.tenantRepo.GetByID(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("tenant not found")
+		}
+		return fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	if !tenant.Active {
+		return errors.New("tenant is already inactive")
+	}
+
+	tenant.Active = false
+	if err := s.tenantRepo.Update(tenant); err != nil {
+		return fmt.Errorf("failed to deactivate tenant: %w", err)
+	}
+
+	if err := s.sessionRepo.RevokeAllByTenant(id); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
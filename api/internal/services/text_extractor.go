@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+const (
+	// maxExtractSize is the largest file whose text is extracted for search
+	maxExtractSize = 20 * 1024 * 1024
+	// maxExtractedText caps the text indexed per version
+	maxExtractedText = 256 * 1024
+	// extractTimeout bounds how long an upload waits for text extraction
+	extractTimeout = 10 * time.Second
+)
+
+// extractText pulls the searchable text out of a PDF or plain-text file
+// Other types, oversized files and files that fail to parse yield an empty string; search then only sees the name
+func extractText(contentType string, content io.ReaderAt, size int64) string {
+	if size <= 0 || size > maxExtractSize || !extractsText(contentType) {
+		return ""
+	}
+
+	var extract func() (string, error)
+	switch contentType {
+	case "text/plain", "text/csv":
+		extract = func() (string, error) {
+			return readText(io.NewSectionReader(content, 0, size))
+		}
+	default:
+		extract = func() (string, error) {
+			return extractPDFText(content, size)
+		}
+	}
+
+	// Malformed PDFs can make the parser spin or panic; neither may take the upload down with it
+	result := make(chan string, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Text extraction panicked: %v", r)
+				result <- ""
+			}
+		}()
+		text, err := extract()
+		if err != nil {
+			log.Printf("Failed to extract text: %v", err)
+		}
+		result <- text
+	}()
+
+	select {
+	case text := <-result:
+		return text
+	case <-time.After(extractTimeout):
+		log.Printf("Text extraction timed out after %s", extractTimeout)
+		return ""
+	}
+}
+
+// extractsText tells whether text is extracted from files of the content type
+func extractsText(contentType string) bool {
+	switch contentType {
+	case "text/plain", "text/csv", "application/pdf":
+		return true
+	}
+	return false
+}
+
+// extractPDFText returns the plain text of every page of a PDF
+func extractPDFText(content io.ReaderAt, size int64) (string, error) {
+	reader, err := pdf.NewReader(content, size)
+	if err != nil {
+		return "", fmt.Errorf("failed to open pdf: %w", err)
+	}
+
+	text, err := reader.GetPlainText()
+	if err != nil {
+		return "", fmt.Errorf("failed to read pdf text: %w", err)
+	}
+	return readText(text)
+}
+
+// readText reads up to maxExtractedText bytes and cleans them for indexing
+// Invalid UTF-8, NULs and other control characters (which Postgres text columns reject or tsvector ignores) are dropped
+func readText(r io.Reader) (string, error) {
+	raw, err := io.ReadAll(io.LimitReader(r, maxExtractedText))
+	if err != nil {
+		return "", err
+	}
+
+	text := strings.ToValidUTF8(string(raw), "")
+	text = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	return strings.TrimSpace(text), nil
+}
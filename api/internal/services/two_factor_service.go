@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	// totpIssuer is the issuer name shown in authenticator apps
+	totpIssuer = "Habitta"
+	// recoveryCodeCount is how many recovery codes are issued at once
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet avoids ambiguous characters (0/o, 1/l)
+	recoveryCodeAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+// TwoFactorSetupResponse represents the data needed to enroll an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // Render as QR code
+}
+
+// TwoFactorCodeRequest represents a request carrying a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// DisableTwoFactorRequest represents the request to turn off two-factor authentication
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// TwoFactorService defines the interface for two-factor authentication operations
+type TwoFactorService interface {
+	Setup(userID uint) (*TwoFactorSetupResponse, error)
+	Confirm(userID uint, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	VerifyCode(user *models.User, code string) error
+	IsRequired(tenantID uint, role models.UserRole) (bool, error)
+}
+
+// twoFactorService implements TwoFactorService
+type twoFactorService struct {
+	userRepo       repositories.UserRepository
+	userTenantRepo repositories.UserTenantRepository
+	tenantRepo     repositories.TenantRepository
+	recoveryRepo   repositories.RecoveryCodeRepository
+}
+
+// NewTwoFactorService creates a new two-factor service
+func NewTwoFactorService(
+	userRepo repositories.UserRepository,
+	userTenantRepo repositories.UserTenantRepository,
+	tenantRepo repositories.TenantRepository,
+	recoveryRepo repositories.RecoveryCodeRepository,
+) TwoFactorService {
+	return &twoFactorService{
+		userRepo:       userRepo,
+		userTenantRepo: userTenantRepo,
+		tenantRepo:     tenantRepo,
+		recoveryRepo:   recoveryRepo,
+	}
+}
+
+// Setup generates a new pending TOTP secret for the user (not active until confirmed)
+func (s *twoFactorService) Setup(userID uint) (*TwoFactorSetupResponse, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactorSecret = secret
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to save two-factor secret: %w", err)
+	}
+
+	return &TwoFactorSetupResponse{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(totpIssuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables two-factor authentication once the user proves the authenticator works
+// Returns the plaintext recovery codes, which are shown only once
+func (s *twoFactorService) Confirm(userID uint, code string) ([]string, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if user.TwoFactorSecret == "" {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+
+	step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return nil, errors.New("invalid two-factor code")
+	}
+	if err := s.consumeTOTPStep(user, step); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TwoFactorEnabled = true
+	if err := s.userRepo.Update(user); err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after re-checking password and code
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, password, code string) error {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.TwoFactorEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := utils.CheckPassword(password, user.Password); err != nil {
+		return errors.New("invalid password")
+	}
+
+	if err := s.VerifyCode(user, code); err != nil {
+		return err
+	}
+
+	// Managers of a tenant that requires 2FA cannot turn it off
+	userTenants, err := s.userTenantRepo.GetAllByUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user tenants: %w", err)
+	}
+	for _, ut := range userTenants {
+		if ut.IsActive && ut.Tenant != nil && ut.Tenant.RequireTwoFactor && models.IsManagerRole(ut.Role) {
+			return fmt.Errorf("two-factor authentication is required by %s", ut.Tenant.Name)
+		}
+	}
+
+	if err := s.recoveryRepo.DeleteAllByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	user.TwoFactorEnabled = false
+	user.TwoFactorSecret = ""
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes after verifying a current code
+func (s *twoFactorService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.getUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := s.VerifyCode(user, code); err != nil {
+		return nil, err
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// VerifyCode accepts either a current TOTP code or an unused recovery code; both can only be used once
+func (s *twoFactorService) VerifyCode(user *models.User, code string) error {
+	if !user.TwoFactorEnabled || user.TwoFactorSecret == "" {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if step, ok := utils.ValidateTOTP(user.TwoFactorSecret, code, time.Now()); ok {
+		return s.consumeTOTPStep(user, step)
+	}
+
+	recoveryCode, err := s.recoveryRepo.GetUnusedByHash(user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid two-factor code")
+		}
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+
+	consumed, err := s.recoveryRepo.MarkUsed(recoveryCode.ID)
+	if err != nil {
+		return fmt.Errorf("failed to consume recovery code: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid two-factor code")
+	}
+
+	return nil
+}
+
+// consumeTOTPStep records the time step of a valid TOTP code, refusing it if that step or a later one was already used
+// The step only moves forward, so a code seen by someone else can't be replayed within the skew window
+func (s *twoFactorService) consumeTOTPStep(user *models.User, step int64) error {
+	consumed, err := s.userRepo.ConsumeTOTPStep(user.ID, step)
+	if err != nil {
+		return fmt.Errorf("failed to record two-factor code: %w", err)
+	}
+	if !consumed {
+		return errors.New("invalid two-factor code")
+	}
+
+	user.TwoFactorLastStep = step
+	return nil
+}
+
+// IsRequired checks whether a tenant requires 2FA for the given role
+func (s *twoFactorService) IsRequired(tenantID uint, role models.UserRole) (bool, error) {
+	if !models.IsManagerRole(role) {
+		return false, nil
+	}
+
+	tenant, err := s.tenantRepo.GetByID(tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, errors.New("tenant not found")
+		}
+		return false, fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	return tenant.RequireTwoFactor, nil
+}
+
+// getUser loads a user by ID
+func (s *twoFactorService) getUser(userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// issueRecoveryCodes generates a new set of recovery codes, storing only their hashes
+func (s *twoFactorService) issueRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.TwoFactorRecoveryCode, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, models.TwoFactorRecoveryCode{
+			UserID:   userID,
+			CodeHash: utils.HashToken(normalizeRecoveryCode(code)),
+		})
+	}
+
+	if err := s.recoveryRepo.ReplaceAll(userID, records); err != nil {
+		return nil, fmt.Errorf("failed to save recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted as xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+
+	var sb strings.Builder
+	for i, v := range b {
+		if i == 5 {
+			sb.WriteByte('-')
+		}
+		sb.WriteByte(recoveryCodeAlphabet[int(v)%len(recoveryCodeAlphabet)])
+	}
+	return sb.String(), nil
+}
+
+// normalizeRecoveryCode strips formatting so codes can be typed with or without the dash
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
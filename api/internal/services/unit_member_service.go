@@ -0,0 +1,224 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// AddUnitMemberRequest represents the request to link a user to a unit
+type AddUnitMemberRequest struct {
+	UserID              uint                    `json:"user_id" binding:"required"`
+	Relationship        models.UnitRelationship `json:"relationship" binding:"required"`
+	StartDate           *time.Time              `json:"start_date"`
+	EndDate             *time.Time              `json:"end_date"`
+	ResponsibleForBills bool                    `json:"responsible_for_bills"`
+}
+
+// UpdateUnitMemberRequest represents the request to update a unit membership
+type UpdateUnitMemberRequest struct {
+	Relationship        *models.UnitRelationship `json:"relationship"`
+	StartDate           *time.Time               `json:"start_date"`
+	EndDate             *time.Time               `json:"end_date"`
+	ClearEndDate        bool                     `json:"clear_end_date"`
+	ResponsibleForBills *bool                    `json:"responsible_for_bills"`
+}
+
+// UnitMemberService defines the interface for unit membership operations
+type UnitMemberService interface {
+	ListMembers(ctx context.Context, tenantID, unitID uint, includePast bool) ([]models.UnitMember, error)
+	AddMember(ctx context.Context, tenantID, unitID uint, req AddUnitMemberRequest) (*models.UnitMember, error)
+	UpdateMember(ctx context.Context, tenantID, unitID, memberID uint, req UpdateUnitMemberRequest) (*models.UnitMember, error)
+	RemoveMember(ctx context.Context, tenantID, unitID, memberID uint) error
+}
+
+// unitMemberService implements UnitMemberService
+type unitMemberService struct {
+	unitRepo       repositories.UnitRepository
+	unitMemberRepo repositories.UnitMemberRepository
+	userTenantRepo repositories.UserTenantRepository
+}
+
+// NewUnitMemberService creates a new unit membership service
+func NewUnitMemberService(
+	unitRepo repositories.UnitRepository,
+	unitMemberRepo repositories.UnitMemberRepository,
+	userTenantRepo repositories.UserTenantRepository,
+) UnitMemberService {
+	return &unitMemberService{
+		unitRepo:       unitRepo,
+		unitMemberRepo: unitMemberRepo,
+		userTenantRepo: userTenantRepo,
+	}
+}
+
+// ListMembers retrieves the members of a unit (current only unless includePast)
+func (s *unitMemberService) ListMembers(ctx context.Context, tenantID, unitID uint, includePast bool) ([]models.UnitMember, error) {
+	if err := s.ensureUnit(ctx, tenantID, unitID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.unitMemberRepo.GetByUnit(ctx, unitID, includePast)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit members: %w", err)
+	}
+
+	for i := range members {
+		if members[i].User != nil {
+			members[i].User.Password = ""
+		}
+	}
+
+	return members, nil
+}
+
+// AddMember links a tenant member to a unit
+func (s *unitMemberService) AddMember(ctx context.Context, tenantID, unitID uint, req AddUnitMemberRequest) (*models.UnitMember, error) {
+	if err := s.ensureUnit(ctx, tenantID, unitID); err != nil {
+		return nil, err
+	}
+
+	if !models.IsValidUnitRelationship(req.Relationship) {
+		return nil, errors.New("relationship must be proprietario, inquilino or dependente")
+	}
+
+	if _, err := s.userTenantRepo.GetByUserAndTenant(ctx, req.UserID, tenantID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user does not belong to this tenant")
+		}
+		return nil, fmt.Errorf("failed to get user-tenant: %w", err)
+	}
+
+	member := &models.UnitMember{
+		TenantID:            tenantID,
+		UnitID:              unitID,
+		UserID:              req.UserID,
+		Relationship:        req.Relationship,
+		StartDate:           today(),
+		EndDate:             req.EndDate,
+		ResponsibleForBills: req.ResponsibleForBills,
+	}
+	if req.StartDate != nil {
+		member.StartDate = *req.StartDate
+	}
+
+	if err := validateUnitMemberDates(member); err != nil {
+		return nil, err
+	}
+
+	current, err := s.unitMemberRepo.GetCurrentByUser(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check unit members: %w", err)
+	}
+	for _, existing := range current {
+		if existing.UnitID == unitID {
+			return nil, errors.New("user is already a current member of this unit")
+		}
+	}
+
+	if err := s.unitMemberRepo.Create(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to add unit member: %w", err)
+	}
+
+	return s.getMember(ctx, tenantID, unitID, member.ID)
+}
+
+// UpdateMember changes the relationship, dates or bill responsibility of a membership
+func (s *unitMemberService) UpdateMember(ctx context.Context, tenantID, unitID, memberID uint, req UpdateUnitMemberRequest) (*models.UnitMember, error) {
+	member, err := s.getMember(ctx, tenantID, unitID, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Relationship != nil {
+		if !models.IsValidUnitRelationship(*req.Relationship) {
+			return nil, errors.New("relationship must be proprietario, inquilino or dependente")
+		}
+		member.Relationship = *req.Relationship
+	}
+	if req.StartDate != nil {
+		member.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		member.EndDate = req.EndDate
+	}
+	if req.ClearEndDate {
+		member.EndDate = nil
+	}
+	if req.ResponsibleForBills != nil {
+		member.ResponsibleForBills = *req.ResponsibleForBills
+	}
+
+	if err := validateUnitMemberDates(member); err != nil {
+		return nil, err
+	}
+
+	if err := s.unitMemberRepo.Update(ctx, member); err != nil {
+		return nil, fmt.Errorf("failed to update unit member: %w", err)
+	}
+
+	return member, nil
+}
+
+// RemoveMember deletes a membership record; to keep history, set end_date instead
+func (s *unitMemberService) RemoveMember(ctx context.Context, tenantID, unitID, memberID uint) error {
+	if _, err := s.getMember(ctx, tenantID, unitID, memberID); err != nil {
+		return err
+	}
+
+	if err := s.unitMemberRepo.Delete(ctx, memberID); err != nil {
+		return fmt.Errorf("failed to remove unit member: %w", err)
+	}
+
+	return nil
+}
+
+// ensureUnit checks that a unit exists in the tenant
+func (s *unitMemberService) ensureUnit(ctx context.Context, tenantID, unitID uint) error {
+	if _, err := s.unitRepo.GetByID(ctx, unitID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("unit not found")
+		}
+		return fmt.Errorf("failed to get unit: %w", err)
+	}
+	return nil
+}
+
+// getMember retrieves a membership and checks it belongs to the unit
+func (s *unitMemberService) getMember(ctx context.Context, tenantID, unitID, memberID uint) (*models.UnitMember, error) {
+	member, err := s.unitMemberRepo.GetByID(ctx, memberID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("unit member not found")
+		}
+		return nil, fmt.Errorf("failed to get unit member: %w", err)
+	}
+
+	if member.UnitID != unitID {
+		return nil, errors.New("unit member not found")
+	}
+
+	if member.User != nil {
+		member.User.Password = ""
+	}
+
+	return member, nil
+}
+
+// validateUnitMemberDates checks that a membership does not end before it starts
+func validateUnitMemberDates(member *models.UnitMember) error {
+	if member.EndDate != nil && !member.EndDate.After(member.StartDate) {
+		return errors.New("end_date must be after start_date")
+	}
+	return nil
+}
+
+// today returns the current date at midnight UTC
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
@@ -11,13 +12,13 @@ import (
 
 // UnitService defines the interface for unit operations
 type UnitService interface {
-	Create(unit *models.Unit) error
-	GetByID(tenantID, unitID uint) (*models.Unit, error)
-	GetByNumber(tenantID uint, number string) (*models.Unit, error)
-	GetAll(tenantID uint) ([]models.Unit, error)
-	GetByBlock(tenantID uint, block string) ([]models.Unit, error)
-	Update(unit *models.Unit) error
-	Delete(tenantID, unitID uint) error
+	Create(ctx context.Context, unit *models.Unit) error
+	GetByID(ctx context.Context, tenantID, unitID uint) (*models.Unit, error)
+	GetByNumber(ctx context.Context, tenantID uint, number string) (*models.Unit, error)
+	GetAll(ctx context.Context, tenantID uint) ([]models.Unit, error)
+	GetByBlock(ctx context.Context, tenantID uint, block string) ([]models.Unit, error)
+	Update(ctx context.Context, unit *models.Unit) error
+	Delete(ctx context.Context, tenantID, unitID uint) error
 }
 
 // unitService implements UnitService
@@ -38,7 +39,7 @@ func NewUnitService(
 }
 
 // Create creates a new unit with validation
-func (s *unitService) Create(unit *models.Unit) error {
+func (s *unitService) Create(ctx context.Context, unit *models.Unit) error {
 	// Validate tenant exists and is active
 	tenant, err := s.tenantRepo.GetByID(unit.TenantID)
 	if err != nil {
@@ -58,7 +59,7 @@ func (s *unitService) Create(unit *models.Unit) error {
 	}
 
 	// Check if unit number already exists for this tenant
-	existingUnit, err := s.unitRepo.GetByNumber(unit.TenantID, unit.Number)
+	existingUnit, err := s.unitRepo.GetByNumber(ctx, unit.Number)
 	if err == nil && existingUnit != nil {
 		return errors.New("unit number already registered for this tenant")
 	}
@@ -70,7 +71,7 @@ func (s *unitService) Create(unit *models.Unit) error {
 	}
 
 	// Create unit
-	if err := s.unitRepo.Create(unit); err != nil {
+	if err := s.unitRepo.Create(ctx, unit); err != nil {
 		return fmt.Errorf("failed to create unit: %w", err)
 	}
 
@@ -78,8 +79,8 @@ func (s *unitService) Create(unit *models.Unit) error {
 }
 
 // GetByID retrieves a unit by ID with tenant isolation
-func (s *unitService) GetByID(tenantID, unitID uint) (*models.Unit, error) {
-	unit, err := s.unitRepo.GetByID(tenantID, unitID)
+func (s *unitService) GetByID(ctx context.Context, tenantID, unitID uint) (*models.Unit, error) {
+	unit, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("unit not found")
@@ -91,12 +92,12 @@ func (s *unitService) GetByID(tenantID, unitID uint) (*models.Unit, error) {
 }
 
 // GetByNumber retrieves a unit by number with tenant isolation
-func (s *unitService) GetByNumber(tenantID uint, number string) (*models.Unit, error) {
+func (s *unitService) GetByNumber(ctx context.Context, tenantID uint, number string) (*models.Unit, error) {
 	if number == "" {
 		return nil, errors.New("unit number is required")
 	}
 
-	unit, err := s.unitRepo.GetByNumber(tenantID, number)
+	unit, err := s.unitRepo.GetByNumber(ctx, number)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("unit not found")
@@ -108,8 +109,8 @@ func (s *unitService) GetByNumber(tenantID uint, number string) (*models.Unit, e
 }
 
 // GetAll retrieves all units for a tenant
-func (s *unitService) GetAll(tenantID uint) ([]models.Unit, error) {
-	units, err := s.unitRepo.GetAll(tenantID)
+func (s *unitService) GetAll(ctx context.Context, tenantID uint) ([]models.Unit, error) {
+	units, err := s.unitRepo.GetAll(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get units: %w", err)
 	}
@@ -118,12 +119,12 @@ func (s *unitService) GetAll(tenantID uint) ([]models.Unit, error) {
 }
 
 // GetByBlock retrieves units by block with tenant isolation
-func (s *unitService) GetByBlock(tenantID uint, block string) ([]models.Unit, error) {
+func (s *unitService) GetByBlock(ctx context.Context, tenantID uint, block string) ([]models.Unit, error) {
 	if block == "" {
 		return nil, errors.New("block is required")
 	}
 
-	units, err := s.unitRepo.GetByBlock(tenantID, block)
+	units, err := s.unitRepo.GetByBlock(ctx, block)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get units: %w", err)
 	}
@@ -132,9 +133,9 @@ func (s *unitService) GetByBlock(tenantID uint, block string) ([]models.Unit, er
 }
 
 // Update updates a unit
-func (s *unitService) Update(unit *models.Unit) error {
+func (s *unitService) Update(ctx context.Context, unit *models.Unit) error {
 	// Validate unit exists
-	existing, err := s.unitRepo.GetByID(unit.TenantID, unit.ID)
+	existing, err := s.unitRepo.GetByID(ctx, unit.ID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("unit not found")
@@ -149,14 +150,14 @@ func (s *unitService) Update(unit *models.Unit) error {
 
 	// Check if number is being changed and if it's already taken
 	if unit.Number != existing.Number {
-		existingWithNumber, err := s.unitRepo.GetByNumber(unit.TenantID, unit.Number)
+		existingWithNumber, err := s.unitRepo.GetByNumber(ctx, unit.Number)
 		if err == nil && existingWithNumber != nil && existingWithNumber.ID != unit.ID {
 			return errors.New("unit number already registered for this tenant")
 		}
 	}
 
 	// Update unit
-	if err := s.unitRepo.Update(unit); err != nil {
+	if err := s.unitRepo.Update(ctx, unit); err != nil {
 		return fmt.Errorf("failed to update unit: %w", err)
 	}
 
@@ -164,9 +165,9 @@ func (s *unitService) Update(unit *models.Unit) error {
 }
 
 // Delete soft deletes a unit with tenant isolation
-func (s *unitService) Delete(tenantID, unitID uint) error {
+func (s *unitService) Delete(ctx context.Context, tenantID, unitID uint) error {
 	// Validate unit exists
-	_, err := s.unitRepo.GetByID(tenantID, unitID)
+	_, err := s.unitRepo.GetByID(ctx, unitID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("unit not found")
@@ -175,7 +176,7 @@ func (s *unitService) Delete(tenantID, unitID uint) error {
 	}
 
 	// Delete unit
-	if err := s.unitRepo.Delete(tenantID, unitID); err != nil {
+	if err := s.unitRepo.Delete(ctx, unitID); err != nil {
 		return fmt.Errorf("failed to delete unit: %w", err)
 	}
 
@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/arturbaldoramos/Habitta/internal/config"
+	"github.com/arturbaldoramos/Habitta/internal/database"
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"github.com/arturbaldoramos/Habitta/pkg/utils"
+	"gorm.io/gorm"
+)
+
+// sweepBatchSize caps how many expired sessions one sweep aborts
+const sweepBatchSize = 100
+
+// StartUploadRequest represents the request to start a direct upload
+// DocumentID uploads a new version of an existing document; otherwise a new document is created in FolderID
+// The content type comes from the file's extension; the upload must send the session's content_type
+type StartUploadRequest struct {
+	FileName   string `json:"file_name" binding:"required,max=255"`
+	Size       int64  `json:"size" binding:"required,min=1"`
+	FolderID   *uint  `json:"folder_id"`
+	DocumentID *uint  `json:"document_id"`
+}
+
+// UploadPartURL is the presigned URL for one part of a multipart upload
+type UploadPartURL struct {
+	PartNumber int32  `json:"part_number"`
+	URL        string `json:"url"`
+}
+
+// UploadSessionResult is returned when an upload session starts
+// Small files get a single UploadURL; large files get one URL per part
+type UploadSessionResult struct {
+	Session   *models.UploadSession `json:"session"`
+	UploadURL string                `json:"upload_url,omitempty"`
+	Parts     []UploadPartURL       `json:"parts,omitempty"`
+}
+
+// UploadCompletion is the record created by completing an upload session
+type UploadCompletion struct {
+	Document *models.Document        `json:"document,omitempty"`
+	Version  *models.DocumentVersion `json:"version,omitempty"`
+}
+
+// UploadSessionService defines the interface for direct-to-storage uploads
+type UploadSessionService interface {
+	Start(ctx context.Context, tenantID, userID uint, req *StartUploadRequest) (*UploadSessionResult, error)
+	Complete(ctx context.Context, tenantID, userID, sessionID uint, parts []CompletedPart) (*UploadCompletion, error)
+	Abort(ctx context.Context, tenantID, userID, sessionID uint) error
+	SweepExpired(ctx context.Context) (int, error)
+	RunSweeper(ctx context.Context, interval time.Duration)
+}
+
+// uploadSessionService implements UploadSessionService
+type uploadSessionService struct {
+	sessionRepo     repositories.UploadSessionRepository
+	docRepo         repositories.DocumentRepository
+	folderRepo      repositories.FolderRepository
+	documentService DocumentService
+	storageSvc      StorageService
+	quotaService    StorageQuotaService
+	db              *gorm.DB
+	maxSize         int64
+	threshold       int64
+	partSize        int64
+	ttl             time.Duration
+}
+
+// NewUploadSessionService creates a new upload session service
+func NewUploadSessionService(
+	sessionRepo repositories.UploadSessionRepository,
+	docRepo repositories.DocumentRepository,
+	folderRepo repositories.FolderRepository,
+	documentService DocumentService,
+	storageSvc StorageService,
+	quotaService StorageQuotaService,
+	db *gorm.DB,
+	cfg config.StorageConfig,
+) UploadSessionService {
+	const mb = 1024 * 1024
+	return &uploadSessionService{
+		sessionRepo:     sessionRepo,
+		docRepo:         docRepo,
+		folderRepo:      folderRepo,
+		documentService: documentService,
+		storageSvc:      storageSvc,
+		quotaService:    quotaService,
+		db:              db,
+		maxSize:         int64(cfg.MaxUploadMB) * mb,
+		threshold:       int64(cfg.MultipartThresholdMB) * mb,
+		partSize:        int64(cfg.MultipartPartMB) * mb,
+		ttl:             time.Duration(cfg.UploadSessionMinutes) * time.Minute,
+	}
+}
+
+// Start creates an upload session and presigns the URLs the client uploads to
+func (s *uploadSessionService) Start(ctx context.Context, tenantID, userID uint, req *StartUploadRequest) (*UploadSessionResult, error) {
+	if req.Size > s.maxSize {
+		return nil, fmt.Errorf("file size exceeds maximum of %dMB", s.maxSize/(1024*1024))
+	}
+
+	// Validate the file type, the target and the quota up front so the client doesn't upload for nothing
+	// The content is checked and the space reserved on completion, once the file is in storage
+	fileType, err := s.documentService.CheckFileType(tenantID, req.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.quotaService.Check(tenantID, req.Size); err != nil {
+		return nil, err
+	}
+	if req.DocumentID != nil {
+		if _, err := s.docRepo.GetByID(ctx, *req.DocumentID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("document not found")
+			}
+			return nil, fmt.Errorf("failed to get document: %w", err)
+		}
+		req.FolderID = nil
+	} else if req.FolderID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *req.FolderID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("folder not found")
+			}
+			return nil, fmt.Errorf("failed to validate folder: %w", err)
+		}
+	}
+
+	contentType := fileType.ContentType
+
+	session := &models.UploadSession{
+		TenantID:    tenantID,
+		UserID:      userID,
+		FolderID:    req.FolderID,
+		DocumentID:  req.DocumentID,
+		FileName:    utils.DisplayFileName(req.FileName),
+		ContentType: contentType,
+		Size:        req.Size,
+		S3Key:       newDocumentKey(tenantID, req.FileName),
+		Status:      models.UploadSessionPending,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	}
+
+	result := &UploadSessionResult{Session: session}
+
+	if req.Size > s.threshold {
+		uploadID, err := s.storageSvc.CreateMultipartUpload(ctx, session.S3Key, contentType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start upload: %w", err)
+		}
+		session.MultipartUploadID = uploadID
+		session.PartSize = s.partSize
+		session.PartCount = int((req.Size + s.partSize - 1) / s.partSize)
+
+		for n := 1; n <= session.PartCount; n++ {
+			url, err := s.storageSvc.PresignUploadPart(ctx, session.S3Key, uploadID, int32(n), s.ttl)
+			if err != nil {
+				_ = s.storageSvc.AbortMultipartUpload(ctx, session.S3Key, uploadID)
+				return nil, fmt.Errorf("failed to generate upload URL: %w", err)
+			}
+			result.Parts = append(result.Parts, UploadPartURL{PartNumber: int32(n), URL: url})
+		}
+	} else {
+		url, err := s.storageSvc.PresignPut(ctx, session.S3Key, contentType, s.ttl)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate upload URL: %w", err)
+		}
+		result.UploadURL = url
+	}
+
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		if session.IsMultipart() {
+			_ = s.storageSvc.AbortMultipartUpload(ctx, session.S3Key, session.MultipartUploadID)
+		}
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	return result, nil
+}
+
+// Complete verifies the uploaded object and creates the document or version
+func (s *uploadSessionService) Complete(ctx context.Context, tenantID, userID, sessionID uint, parts []CompletedPart) (*UploadCompletion, error) {
+	session, err := s.getPending(ctx, tenantID, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.IsMultipart() {
+		if len(parts) != session.PartCount {
+			return nil, fmt.Errorf("expected %d parts, got %d", session.PartCount, len(parts))
+		}
+		if err := s.storageSvc.CompleteMultipartUpload(ctx, session.S3Key, session.MultipartUploadID, parts); err != nil {
+			return nil, fmt.Errorf("failed to complete upload: %w", err)
+		}
+	}
+
+	// The object must exist with exactly the declared size
+	info, err := s.storageSvc.Head(ctx, session.S3Key)
+	if err != nil {
+		if errors.Is(err, ErrObjectNotFound) {
+			return nil, errors.New("uploaded file not found; upload the file before completing")
+		}
+		return nil, fmt.Errorf("failed to verify upload: %w", err)
+	}
+	if info.Size != session.Size {
+		_ = s.storageSvc.Delete(ctx, session.S3Key)
+		_ = s.abort(ctx, session)
+		return nil, fmt.Errorf("uploaded size %d does not match declared size %d", info.Size, session.Size)
+	}
+
+	file := StoredFile{
+		Key:         session.S3Key,
+		Name:        session.FileName,
+		ContentType: session.ContentType,
+		Size:        info.Size,
+	}
+
+	completion := &UploadCompletion{}
+	if session.DocumentID != nil {
+		completion.Version, err = s.documentService.RegisterVersion(ctx, tenantID, userID, *session.DocumentID, file)
+	} else {
+		completion.Document, err = s.documentService.Register(ctx, tenantID, userID, session.FolderID, file)
+		if err == nil {
+			session.DocumentID = &completion.Document.ID
+		}
+	}
+	if err != nil {
+		// The file was refused; don't keep it around until the sweeper runs
+		if isRejectedFile(err) {
+			if discardErr := s.discard(ctx, session); discardErr == nil {
+				_ = s.abort(ctx, session)
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.finish(ctx, session, models.UploadSessionCompleted); err != nil {
+		return nil, err
+	}
+
+	return completion, nil
+}
+
+// Abort cancels a pending upload and discards whatever was uploaded
+func (s *uploadSessionService) Abort(ctx context.Context, tenantID, userID, sessionID uint) error {
+	session, err := s.getPending(ctx, tenantID, userID, sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.discard(ctx, session); err != nil {
+		return err
+	}
+
+	return s.finish(ctx, session, models.UploadSessionAborted)
+}
+
+// SweepExpired aborts pending sessions whose URLs have expired, across all tenants
+func (s *uploadSessionService) SweepExpired(ctx context.Context) (int, error) {
+	sessions, err := s.sessionRepo.GetExpiredPending(ctx, time.Now(), sweepBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get expired upload sessions: %w", err)
+	}
+
+	swept := 0
+	for i := range sessions {
+		session := &sessions[i]
+		// Expired sessions come from every tenant; each one is aborted in a scope of its tenant
+		err := database.RunScoped(ctx, s.db, database.Scope{TenantID: session.TenantID}, func(ctx context.Context) error {
+			if err := s.discard(ctx, session); err != nil {
+				return fmt.Errorf("failed to discard upload: %w", err)
+			}
+			return s.finish(ctx, session, models.UploadSessionAborted)
+		})
+		if err != nil {
+			log.Printf("Failed to abort upload session %d: %v", session.ID, err)
+			continue
+		}
+		swept++
+	}
+
+	return swept, nil
+}
+
+// RunSweeper calls SweepExpired every interval until ctx is cancelled
+func (s *uploadSessionService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			swept, err := s.SweepExpired(ctx)
+			if err != nil {
+				log.Printf("Upload sweeper: %v", err)
+				continue
+			}
+			if swept > 0 {
+				log.Printf("Upload sweeper: aborted %d expired upload sessions", swept)
+			}
+		}
+	}
+}
+
+// isRejectedFile reports whether registration refused the file itself, so retrying can't succeed
+func isRejectedFile(err error) bool {
+	return errors.Is(err, models.ErrStorageQuotaExceeded) ||
+		errors.Is(err, models.ErrFileTypeNotAllowed) ||
+		errors.Is(err, models.ErrFileContentMismatch)
+}
+
+// getPending retrieves a pending session started by the user
+func (s *uploadSessionService) getPending(ctx context.Context, tenantID, userID, sessionID uint) (*models.UploadSession, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("upload session not found")
+		}
+		return nil, fmt.Errorf("failed to get upload session: %w", err)
+	}
+
+	if session.UserID != userID {
+		return nil, errors.New("upload session not found")
+	}
+	if session.Status != models.UploadSessionPending {
+		return nil, fmt.Errorf("upload session is already %s", session.Status)
+	}
+	if session.IsExpired() {
+		return nil, errors.New("upload session has expired")
+	}
+
+	return session, nil
+}
+
+// discard removes the parts or the object uploaded for a session
+func (s *uploadSessionService) discard(ctx context.Context, session *models.UploadSession) error {
+	if session.IsMultipart() {
+		if err := s.storageSvc.AbortMultipartUpload(ctx, session.S3Key, session.MultipartUploadID); err != nil {
+			return err
+		}
+	}
+
+	// Never remove a file that already made it into a document
+	inUse, err := s.docRepo.IsKeyInUse(ctx, session.S3Key)
+	if err != nil {
+		return fmt.Errorf("failed to check document versions: %w", err)
+	}
+	if inUse {
+		return nil
+	}
+
+	// A single PUT (or a completed multipart upload) may have already created the object
+	return s.storageSvc.Delete(ctx, session.S3Key)
+}
+
+// abort records a session Complete refused as aborted, in a transaction of its own
+// Complete returns an error right after, which rolls back the request's transaction but not this one
+func (s *uploadSessionService) abort(ctx context.Context, session *models.UploadSession) error {
+	err := database.RunScoped(database.WithDB(ctx, s.db), s.db, database.Scope{TenantID: session.TenantID}, func(ctx context.Context) error {
+		return s.finish(ctx, session, models.UploadSessionAborted)
+	})
+	if err != nil {
+		log.Printf("Failed to abort upload session %d: %v", session.ID, err)
+	}
+	return err
+}
+
+// finish records the final status of a session
+func (s *uploadSessionService) finish(ctx context.Context, session *models.UploadSession, status models.UploadSessionStatus) error {
+	now := time.Now()
+	session.Status = status
+	session.CompletedAt = &now
+
+	if err := s.sessionRepo.Update(ctx, session); err != nil {
+		return fmt.Errorf("failed to update upload session: %w", err)
+	}
+	return nil
+}
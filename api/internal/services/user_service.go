@@ -1,8 +1,10 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/arturbaldoramos/Habitta/internal/models"
 	"github.com/arturbaldoramos/Habitta/internal/repositories"
@@ -21,15 +23,31 @@ type UserInTenant struct {
 	UnitID   *uint  `json:"unit_id"`
 }
 
+// CreateUserRequest represents a user created by an operator (CLI, seed)
+type CreateUserRequest struct {
+	Email         string
+	Password      string
+	Name          string
+	Phone         string
+	CPF           string
+	EmailVerified bool
+}
+
 // UserService defines the interface for user operations
 type UserService interface {
+	CreateUser(req CreateUserRequest) (*models.User, error)
 	GetByID(userID uint) (*models.User, error)
-	GetByIDInTenant(tenantID, userID uint) (*UserInTenant, error)
-	ListByTenant(tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error)
-	UpdateMembership(tenantID, userID uint, isActive bool, unitID *uint) error
+	GetByEmail(email string) (*models.User, error)
+	GetByIDInTenant(ctx context.Context, tenantID, userID uint) (*UserInTenant, error)
+	ListByTenant(ctx context.Context, tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error)
+	UpdateMembership(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint, isActive bool, unitID *uint) error
+	GetUnitIDs(ctx context.Context, tenantID uint, userIDs []uint) (map[uint]uint, error)
 	Update(user *models.User) error
 	UpdatePassword(userID uint, oldPassword, newPassword string) error
-	RemoveFromTenant(tenantID, userID uint) error
+	RemoveFromTenant(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint) error
+	AddToTenant(ctx context.Context, tenantID, userID uint, role models.UserRole) error
+	ResetPassword(userID uint, newPassword string) error
+	ChangeRole(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint, role models.UserRole) error
 }
 
 // userService implements UserService
@@ -37,6 +55,10 @@ type userService struct {
 	userRepo       repositories.UserRepository
 	tenantRepo     repositories.TenantRepository
 	userTenantRepo repositories.UserTenantRepository
+	unitRepo       repositories.UnitRepository
+	unitMemberRepo repositories.UnitMemberRepository
+	sessionRepo    repositories.SessionRepository
+	roleService    RoleService
 }
 
 // NewUserService creates a new user service
@@ -44,17 +66,25 @@ func NewUserService(
 	userRepo repositories.UserRepository,
 	tenantRepo repositories.TenantRepository,
 	userTenantRepo repositories.UserTenantRepository,
+	unitRepo repositories.UnitRepository,
+	unitMemberRepo repositories.UnitMemberRepository,
+	sessionRepo repositories.SessionRepository,
+	roleService RoleService,
 ) UserService {
 	return &userService{
 		userRepo:       userRepo,
 		tenantRepo:     tenantRepo,
 		userTenantRepo: userTenantRepo,
+		unitRepo:       unitRepo,
+		unitMemberRepo: unitMemberRepo,
+		sessionRepo:    sessionRepo,
+		roleService:    roleService,
 	}
 }
 
 // ListByTenant retrieves paginated users for a tenant
-func (s *userService) ListByTenant(tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error) {
-	userTenants, total, err := s.userTenantRepo.GetAllByTenantPaginated(tenantID, page, perPage, search)
+func (s *userService) ListByTenant(ctx context.Context, tenantID uint, page, perPage int, search string) ([]models.UserTenant, int64, error) {
+	userTenants, total, err := s.userTenantRepo.GetAllByTenantPaginated(ctx, page, perPage, search)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -86,7 +116,7 @@ func (s *userService) GetByID(userID uint) (*models.User, error) {
 }
 
 // GetByIDInTenant retrieves a user with tenant-specific data (role, is_active)
-func (s *userService) GetByIDInTenant(tenantID, userID uint) (*UserInTenant, error) {
+func (s *userService) GetByIDInTenant(ctx context.Context, tenantID, userID uint) (*UserInTenant, error) {
 	user, err := s.userRepo.GetByID(userID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -95,7 +125,7 @@ func (s *userService) GetByIDInTenant(tenantID, userID uint) (*UserInTenant, err
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	userTenant, err := s.userTenantRepo.GetByUserAndTenant(userID, tenantID)
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user does not belong to this tenant")
@@ -103,21 +133,50 @@ func (s *userService) GetByIDInTenant(tenantID, userID uint) (*UserInTenant, err
 		return nil, fmt.Errorf("failed to get user-tenant: %w", err)
 	}
 
-	return &UserInTenant{
+	primary, err := s.primaryUnitMember(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserInTenant{
 		ID:       user.ID,
 		Name:     user.Name,
 		Email:    user.Email,
 		Phone:    user.Phone,
 		Role:     string(userTenant.Role),
 		IsActive: userTenant.IsActive,
-		UnitID:   user.UnitID,
-	}, nil
+	}
+	if primary != nil {
+		result.UnitID = &primary.UnitID
+	}
+
+	return result, nil
+}
+
+// GetUnitIDs returns the primary unit of each user in a tenant (users without a unit are omitted)
+// This backs the legacy unit_id field; see UnitMemberService for the full memberships
+func (s *userService) GetUnitIDs(ctx context.Context, tenantID uint, userIDs []uint) (map[uint]uint, error) {
+	members, err := s.unitMemberRepo.GetCurrentByUsers(ctx, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit members: %w", err)
+	}
+
+	// Memberships are ordered by start date, so the first one per user is the primary unit
+	unitIDs := make(map[uint]uint, len(members))
+	for _, m := range members {
+		if _, ok := unitIDs[m.UserID]; !ok {
+			unitIDs[m.UserID] = m.UnitID
+		}
+	}
+
+	return unitIDs, nil
 }
 
 // UpdateMembership updates tenant-specific fields: is_active and unit_id
-func (s *userService) UpdateMembership(tenantID, userID uint, isActive bool, unitID *uint) error {
-	// Validate user belongs to tenant
-	_, err := s.userTenantRepo.GetByUserAndTenant(userID, tenantID)
+// unit_id is the legacy single-unit view: changing it replaces the user's primary unit membership in this tenant.
+// The actor can only change members whose role grants nothing the actor's role lacks
+func (s *userService) UpdateMembership(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint, isActive bool, unitID *uint) error {
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user does not belong to this tenant")
@@ -125,24 +184,101 @@ func (s *userService) UpdateMembership(tenantID, userID uint, isActive bool, uni
 		return fmt.Errorf("failed to get user-tenant: %w", err)
 	}
 
+	if err := s.roleService.ValidateAssignableRole(ctx, tenantID, actorRole, userTenant.Role); err != nil {
+		return errors.New("cannot change a member with permissions you do not have")
+	}
+
 	// Update is_active on user_tenants
-	if err := s.userTenantRepo.UpdateIsActive(userID, tenantID, isActive); err != nil {
+	if err := s.userTenantRepo.UpdateIsActive(ctx, userID, isActive); err != nil {
 		return fmt.Errorf("failed to update membership: %w", err)
 	}
 
-	// Update unit_id on users
-	user, err := s.userRepo.GetByID(userID)
+	// Deactivated members lose access to this tenant immediately
+	if !isActive {
+		if err := s.sessionRepo.RevokeAllByUserAndTenant(userID, tenantID); err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+	}
+
+	return s.setPrimaryUnit(ctx, tenantID, userID, unitID)
+}
+
+// setPrimaryUnit ends the user's primary unit membership and links the new unit, unless it is already current
+func (s *userService) setPrimaryUnit(ctx context.Context, tenantID, userID uint, unitID *uint) error {
+	current, err := s.unitMemberRepo.GetCurrentByUser(ctx, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to get unit members: %w", err)
 	}
-	user.UnitID = unitID
-	if err := s.userRepo.Update(user); err != nil {
-		return fmt.Errorf("failed to update unit: %w", err)
+
+	if unitID != nil {
+		for _, m := range current {
+			if m.UnitID == *unitID {
+				return nil
+			}
+		}
+	}
+
+	if len(current) > 0 {
+		if err := s.endUnitMember(ctx, &current[0]); err != nil {
+			return err
+		}
+	}
+
+	if unitID == nil {
+		return nil
+	}
+
+	unit, err := s.unitRepo.GetByID(ctx, *unitID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("unit not found")
+		}
+		return fmt.Errorf("failed to get unit: %w", err)
 	}
 
+	member := &models.UnitMember{
+		TenantID:     tenantID,
+		UnitID:       unit.ID,
+		UserID:       userID,
+		Relationship: models.UnitRelationshipTenant,
+		StartDate:    today(),
+	}
+	if err := s.unitMemberRepo.Create(ctx, member); err != nil {
+		return fmt.Errorf("failed to add unit member: %w", err)
+	}
+
+	return nil
+}
+
+// endUnitMember ends a membership today, or deletes it if it started today (nothing to keep in the history)
+func (s *userService) endUnitMember(ctx context.Context, member *models.UnitMember) error {
+	end := today()
+	if !end.After(member.StartDate) {
+		if err := s.unitMemberRepo.Delete(ctx, member.ID); err != nil {
+			return fmt.Errorf("failed to remove unit member: %w", err)
+		}
+		return nil
+	}
+
+	member.EndDate = &end
+	if err := s.unitMemberRepo.Update(ctx, member); err != nil {
+		return fmt.Errorf("failed to end unit member: %w", err)
+	}
 	return nil
 }
 
+// primaryUnitMember returns the user's oldest current unit membership in a tenant, or nil
+func (s *userService) primaryUnitMember(ctx context.Context, tenantID, userID uint) (*models.UnitMember, error) {
+	current, err := s.unitMemberRepo.GetCurrentByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unit members: %w", err)
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	return &current[0], nil
+}
+
 // Update updates a user (excluding password)
 func (s *userService) Update(user *models.User) error {
 	// Validate user exists
@@ -219,9 +355,9 @@ func (s *userService) UpdatePassword(userID uint, oldPassword, newPassword strin
 }
 
 // RemoveFromTenant removes a user's membership from a tenant (does NOT delete the user account)
-func (s *userService) RemoveFromTenant(tenantID, userID uint) error {
-	// Validate user belongs to tenant
-	_, err := s.userTenantRepo.GetByUserAndTenant(userID, tenantID)
+// The actor can only remove members whose role grants nothing the actor's role lacks
+func (s *userService) RemoveFromTenant(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint) error {
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user does not belong to this tenant")
@@ -229,10 +365,197 @@ func (s *userService) RemoveFromTenant(tenantID, userID uint) error {
 		return fmt.Errorf("failed to get user-tenant: %w", err)
 	}
 
+	if err := s.roleService.ValidateAssignableRole(ctx, tenantID, actorRole, userTenant.Role); err != nil {
+		return errors.New("cannot remove a member with permissions you do not have")
+	}
+
 	// Remove user-tenant relationship only
-	if err := s.userTenantRepo.Delete(userID, tenantID); err != nil {
+	if err := s.userTenantRepo.Delete(ctx, userID); err != nil {
 		return fmt.Errorf("failed to remove user from tenant: %w", err)
 	}
 
+	// Former members no longer live in or own units of this tenant
+	current, err := s.unitMemberRepo.GetCurrentByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get unit members: %w", err)
+	}
+	for i := range current {
+		if err := s.endUnitMember(ctx, &current[i]); err != nil {
+			return err
+		}
+	}
+
+	// Revoke sessions bound to this tenant so existing tokens stop working right away
+	if err := s.sessionRepo.RevokeAllByUserAndTenant(userID, tenantID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// ChangeRole assigns a new role to a member of a tenant
+// The actor can neither grant nor take away permissions they do not hold themselves
+func (s *userService) ChangeRole(ctx context.Context, tenantID uint, actorRole models.UserRole, userID uint, role models.UserRole) error {
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user does not belong to this tenant")
+		}
+		return fmt.Errorf("failed to get user-tenant: %w", err)
+	}
+
+	if userTenant.Role == role {
+		return nil
+	}
+
+	if err := s.roleService.ValidateAssignableRole(ctx, tenantID, actorRole, userTenant.Role); err != nil {
+		return errors.New("cannot change the role of a member with permissions you do not have")
+	}
+	if err := s.roleService.ValidateAssignableRole(ctx, tenantID, actorRole, role); err != nil {
+		return err
+	}
+
+	userTenant.Role = role
+	if err := s.userTenantRepo.Update(ctx, userTenant); err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+
+	// Tokens carry the active role, so the member signs in again with the new one
+	if err := s.sessionRepo.RevokeAllByUserAndTenant(userID, tenantID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// CreateUser creates a user directly, without the registration email flow
+func (s *userService) CreateUser(req CreateUserRequest) (*models.User, error) {
+	if req.Email == "" || req.Name == "" {
+		return nil, errors.New("email and name are required")
+	}
+
+	if err := utils.IsPasswordValid(req.Password); err != nil {
+		return nil, err
+	}
+
+	existingUser, err := s.userRepo.GetByEmail(req.Email)
+	if err == nil && existingUser != nil {
+		return nil, errors.New("email already registered")
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing email: %w", err)
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Email:    req.Email,
+		Password: hashedPassword,
+		Name:     req.Name,
+		Phone:    req.Phone,
+		CPF:      req.CPF,
+		Active:   true,
+	}
+	if req.EmailVerified {
+		now := time.Now()
+		user.EmailVerifiedAt = &now
+	}
+
+	if err := s.userRepo.Create(user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	user.Password = ""
+
+	return user, nil
+}
+
+// GetByEmail retrieves a user by email
+func (s *userService) GetByEmail(email string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// AddToTenant grants a user a role in a tenant, reactivating or updating an existing membership
+func (s *userService) AddToTenant(ctx context.Context, tenantID, userID uint, role models.UserRole) error {
+	if _, err := s.tenantRepo.GetByID(tenantID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("tenant not found")
+		}
+		return fmt.Errorf("failed to get tenant: %w", err)
+	}
+
+	userTenant, err := s.userTenantRepo.GetByUserAndTenant(ctx, userID, tenantID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to get user-tenant: %w", err)
+	}
+
+	if err == nil {
+		userTenant.Role = role
+		userTenant.IsActive = true
+		if err := s.userTenantRepo.Update(ctx, userTenant); err != nil {
+			return fmt.Errorf("failed to update membership: %w", err)
+		}
+		return nil
+	}
+
+	userTenant = &models.UserTenant{
+		UserID:   userID,
+		TenantID: tenantID,
+		Role:     role,
+		IsActive: true,
+		JoinedAt: time.Now(),
+	}
+
+	if err := s.userTenantRepo.Create(ctx, userTenant); err != nil {
+		return fmt.Errorf("failed to add user to tenant: %w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password without the old one (operator action)
+// All sessions are revoked and any login lockout is lifted
+func (s *userService) ResetPassword(userID uint, newPassword string) error {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := utils.IsPasswordValid(newPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user.Password = hashedPassword
+	if err := s.userRepo.Update(user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := s.userRepo.ResetFailedLogins(user.ID); err != nil {
+		return fmt.Errorf("failed to clear account lockout: %w", err)
+	}
+
+	if err := s.sessionRepo.RevokeAllByUser(user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
 	return nil
 }
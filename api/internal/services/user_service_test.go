@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/arturbaldoramos/Habitta/internal/models"
+	"github.com/arturbaldoramos/Habitta/internal/repositories"
+	"gorm.io/gorm"
+)
+
+// fakeUserTenantRepo keeps the memberships of one tenant in memory
+// Methods the tests don't use panic through the nil embedded interface
+type fakeUserTenantRepo struct {
+	repositories.UserTenantRepository
+	members map[uint]*models.UserTenant
+}
+
+func (r *fakeUserTenantRepo) GetByUserAndTenant(ctx context.Context, userID, tenantID uint) (*models.UserTenant, error) {
+	member, ok := r.members[userID]
+	if !ok || member.TenantID != tenantID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return member, nil
+}
+
+func (r *fakeUserTenantRepo) UpdateIsActive(ctx context.Context, userID uint, isActive bool) error {
+	r.members[userID].IsActive = isActive
+	return nil
+}
+
+func (r *fakeUserTenantRepo) Delete(ctx context.Context, userID uint) error {
+	delete(r.members, userID)
+	return nil
+}
+
+// fakeUnitMemberRepo has no unit memberships
+type fakeUnitMemberRepo struct {
+	repositories.UnitMemberRepository
+}
+
+func (r *fakeUnitMemberRepo) GetCurrentByUser(ctx context.Context, userID uint) ([]models.UnitMember, error) {
+	return nil, nil
+}
+
+// fakeSessionRepo records which users had their sessions revoked
+type fakeSessionRepo struct {
+	repositories.SessionRepository
+	revoked []uint
+}
+
+func (r *fakeSessionRepo) RevokeAllByUserAndTenant(userID, tenantID uint) error {
+	r.revoked = append(r.revoked, userID)
+	return nil
+}
+
+const (
+	testTenantID   = 1
+	testSindicoID  = 10
+	testSubsindico = 11
+	testMoradorID  = 12
+)
+
+// newTestUserService creates a user service over a tenant with a síndico, a subsíndico and a morador
+func newTestUserService() (UserService, *fakeUserTenantRepo, *fakeSessionRepo) {
+	members := &fakeUserTenantRepo{members: map[uint]*models.UserTenant{
+		testSindicoID:  {UserID: testSindicoID, TenantID: testTenantID, Role: models.RoleSindico, IsActive: true},
+		testSubsindico: {UserID: testSubsindico, TenantID: testTenantID, Role: models.RoleSubsindico, IsActive: true},
+		testMoradorID:  {UserID: testMoradorID, TenantID: testTenantID, Role: models.RoleMorador, IsActive: true},
+	}}
+	sessions := &fakeSessionRepo{}
+	// Built-in roles resolve without the role repository
+	roles := NewRoleService(nil, members, nil)
+	return NewUserService(nil, nil, members, nil, &fakeUnitMemberRepo{}, sessions, roles), members, sessions
+}
+
+func TestRemoveFromTenantRequiresCoveringRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		actor     models.UserRole
+		target    uint
+		wantError bool
+	}{
+		{name: "subsíndico can't remove the síndico", actor: models.RoleSubsindico, target: testSindicoID, wantError: true},
+		{name: "subsíndico can remove a morador", actor: models.RoleSubsindico, target: testMoradorID},
+		{name: "síndico can remove the subsíndico", actor: models.RoleSindico, target: testSubsindico},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, members, sessions := newTestUserService()
+
+			err := svc.RemoveFromTenant(context.Background(), testTenantID, tt.actor, tt.target)
+
+			_, stillMember := members.members[tt.target]
+			if tt.wantError {
+				if err == nil {
+					t.Fatal("want an error")
+				}
+				if !stillMember || len(sessions.revoked) != 0 {
+					t.Fatal("a rejected removal must not touch the membership or sessions")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if stillMember {
+				t.Fatal("want the membership removed")
+			}
+		})
+	}
+}
+
+func TestUpdateMembershipRequiresCoveringRole(t *testing.T) {
+	svc, members, sessions := newTestUserService()
+
+	err := svc.UpdateMembership(context.Background(), testTenantID, models.RoleSubsindico, testSindicoID, false, nil)
+	if err == nil {
+		t.Fatal("want an error when a subsíndico deactivates the síndico")
+	}
+	if !members.members[testSindicoID].IsActive || len(sessions.revoked) != 0 {
+		t.Fatal("a rejected update must not deactivate the síndico or revoke sessions")
+	}
+
+	if err := svc.UpdateMembership(context.Background(), testTenantID, models.RoleSubsindico, testMoradorID, false, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if members.members[testMoradorID].IsActive {
+		t.Fatal("want the morador deactivated")
+	}
+}
@@ -0,0 +1,284 @@
+// Package mailsink is a minimal in-process SMTP server that keeps every message it receives.
+// Integration tests point the smtp email driver at it (SMTP_SECURITY=none) and assert on Messages().
+package mailsink
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is an email received by the sink
+type Message struct {
+	From     string   // Envelope sender
+	To       []string // Envelope recipients
+	Username string   // AUTH PLAIN user, if the client authenticated
+	Header   mail.Header
+	Subject  string // Decoded Subject header
+	Text     string // Decoded text/plain part
+	HTML     string // Decoded text/html part
+	Raw      []byte
+}
+
+// Server is a running SMTP sink
+type Server struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	username string          // Set by RequireAuth
+	password string          // Set by RequireAuth
+	rejected map[string]bool // Recipients refused with 550
+}
+
+// Start listens on addr (e.g. "127.0.0.1:0" for a random port) and serves SMTP until Close
+func Start(addr string) (*Server, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: ln}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns the address the sink listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Messages returns the messages received so far, oldest first
+// A message is stored before the sink acknowledges it, so it is visible as soon as the sender returns
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reset forgets the messages received so far
+func (s *Server) Reset() {
+	s.mu.Lock()
+	s.messages = nil
+	s.mu.Unlock()
+}
+
+// RequireAuth makes the sink accept only AUTH PLAIN with these credentials and refuse mail from sessions that didn't authenticate
+func (s *Server) RequireAuth(username, password string) {
+	s.mu.Lock()
+	s.username, s.password = username, password
+	s.mu.Unlock()
+}
+
+// RejectRecipient makes the sink refuse RCPT TO for address, like a server with no such mailbox
+func (s *Server) RejectRecipient(address string) {
+	s.mu.Lock()
+	if s.rejected == nil {
+		s.rejected = make(map[string]bool)
+	}
+	s.rejected[strings.ToLower(address)] = true
+	s.mu.Unlock()
+}
+
+// credentials returns the credentials set by RequireAuth; an empty username accepts any client
+func (s *Server) credentials() (string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.username, s.password
+}
+
+// rejects checks if RejectRecipient was called for address
+func (s *Server) rejects(address string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rejected[strings.ToLower(address)]
+}
+
+// Close stops the sink and waits for open sessions to end
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+// handle runs one SMTP session
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Minute))
+
+	tp := textproto.NewConn(conn)
+	reply := func(line string) bool {
+		return tp.PrintfLine("%s", line) == nil
+	}
+
+	var msg Message
+	if !reply("220 mailsink ESMTP ready") {
+		return
+	}
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			reply("250-mailsink")
+			reply("250-8BITMIME")
+			reply("250 AUTH PLAIN")
+		case "HELO":
+			reply("250 mailsink")
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			if !strings.EqualFold(mech, "PLAIN") {
+				reply("504 unsupported authentication mechanism")
+				continue
+			}
+			username, password := plainAuthCredentials(initial)
+			if wantUser, wantPass := s.credentials(); wantUser != "" && (username != wantUser || password != wantPass) {
+				reply("535 authentication credentials invalid")
+				continue
+			}
+			msg.Username = username
+			reply("235 authenticated")
+		case "MAIL":
+			if wantUser, _ := s.credentials(); wantUser != "" && msg.Username == "" {
+				reply("530 authentication required")
+				continue
+			}
+			msg.From = envelopeAddress(arg)
+			msg.To = nil
+			reply("250 OK")
+		case "RCPT":
+			addr := envelopeAddress(arg)
+			if s.rejects(addr) {
+				reply("550 mailbox unavailable")
+				continue
+			}
+			msg.To = append(msg.To, addr)
+			reply("250 OK")
+		case "DATA":
+			if msg.From == "" || len(msg.To) == 0 {
+				reply("503 need MAIL and RCPT first")
+				continue
+			}
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			raw, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Raw = raw
+			parseContent(&msg)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{Username: msg.Username}
+			reply("250 OK queued")
+		case "RSET":
+			msg = Message{Username: msg.Username}
+			reply("250 OK")
+		case "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// envelopeAddress extracts the address from "FROM:<a@b>" or "TO:<a@b>"
+func envelopeAddress(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(strings.TrimSpace(addr), " ")
+	return strings.Trim(addr, "<>")
+}
+
+// plainAuthCredentials extracts the user and password from an AUTH PLAIN initial response
+func plainAuthCredentials(initial string) (string, string) {
+	decoded, err := base64.StdEncoding.DecodeString(initial)
+	if err != nil {
+		return "", ""
+	}
+	fields := bytes.Split(decoded, []byte{0})
+	if len(fields) != 3 {
+		return "", ""
+	}
+	return string(fields[1]), string(fields[2])
+}
+
+// parseContent decodes the headers and the text and HTML bodies of a received message
+func parseContent(msg *Message) {
+	parsed, err := mail.ReadMessage(bytes.NewReader(msg.Raw))
+	if err != nil {
+		return
+	}
+	msg.Header = parsed.Header
+
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject")); err == nil {
+		msg.Subject = subject
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	collectParts(msg, mediaType, params, parsed.Body)
+}
+
+// collectParts walks a (possibly nested) multipart body, keeping the first text and HTML parts
+func collectParts(msg *Message, mediaType string, params map[string]string, body io.Reader) {
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			// NextPart decodes quoted-printable parts
+			part, err := reader.NextPart()
+			if err != nil {
+				return
+			}
+			partType, partParams, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if err != nil {
+				partType = "text/plain"
+			}
+			collectParts(msg, partType, partParams, part)
+		}
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return
+	}
+	switch {
+	case mediaType == "text/plain" && msg.Text == "":
+		msg.Text = string(content)
+	case mediaType == "text/html" && msg.HTML == "":
+		msg.HTML = string(content)
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxFileNameLength caps file names; storage keys add a tenant prefix and a UUID on top
+const maxFileNameLength = 120
+
+// DisplayFileName cleans a client-supplied file name for showing to users
+// Directory components and control characters are dropped; accents and spaces are kept
+func DisplayFileName(name string) string {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Base(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimSpace(name)
+
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "arquivo"
+	}
+	return truncateFileName(name)
+}
+
+// SanitizeFileName turns a client-supplied file name into a safe storage key segment
+// Accents are stripped and anything other than ASCII letters, digits, dots and dashes becomes an underscore
+func SanitizeFileName(name string) string {
+	name = DisplayFileName(name)
+	ext := path.Ext(name)
+
+	base := sanitizeSegment(strings.TrimSuffix(name, ext))
+	if base == "" {
+		base = "arquivo"
+	}
+	if ext = sanitizeSegment(ext); ext != "" {
+		base += "." + ext
+	}
+	return truncateFileName(base)
+}
+
+// sanitizeSegment keeps the key-safe characters of part of a file name
+func sanitizeSegment(s string) string {
+	var b strings.Builder
+	lastUnderscore := false
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent left over from the decomposition
+			continue
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-'):
+			b.WriteRune(r)
+			lastUnderscore = false
+		default:
+			if !lastUnderscore {
+				b.WriteByte('_')
+				lastUnderscore = true
+			}
+		}
+	}
+
+	// Leading dots would make hidden files; trailing ones confuse extension checks
+	return strings.Trim(b.String(), "._")
+}
+
+// truncateFileName shortens a name to maxFileNameLength bytes, keeping its extension
+func truncateFileName(name string) string {
+	if len(name) <= maxFileNameLength {
+		return name
+	}
+
+	ext := path.Ext(name)
+	if len(ext) > maxFileNameLength/2 {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+
+	// Cut on a rune boundary so multi-byte characters are not split
+	limit := maxFileNameLength - len(ext)
+	for limit > 0 && !utf8.RuneStart(base[limit]) {
+		limit--
+	}
+	return base[:limit] + ext
+}
@@ -10,6 +10,7 @@ import (
 
 // JWTClaims represents the JWT claims structure
 type JWTClaims struct {
+	SessionID      uint   `json:"session_id"`
 	UserID         uint   `json:"user_id"`
 	Email          string `json:"email"`
 	ActiveTenantID *uint  `json:"active_tenant_id,omitempty"` // Nullable - user may have no active tenant
@@ -17,16 +18,29 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT generates a new JWT token for a user
+// ChallengePurposeTwoFactor identifies challenge tokens issued while a login awaits a 2FA code
+const ChallengePurposeTwoFactor = "two_factor"
+
+// ChallengeClaims represents the claims of a short-lived login challenge token
+// Challenge tokens are not bound to a session and cannot be used as access tokens
+type ChallengeClaims struct {
+	UserID   uint   `json:"user_id"`
+	TenantID *uint  `json:"tenant_id,omitempty"` // Tenant requested at login, if any
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// GenerateJWT generates a new short-lived access token bound to a session
 // activeTenantID can be nil for users without an active tenant (orphan users)
-func GenerateJWT(userID uint, email string, activeTenantID *uint, activeRole, secret string, expirationHours int) (string, error) {
+func GenerateJWT(sessionID, userID uint, email string, activeTenantID *uint, activeRole, secret string, ttl time.Duration) (string, error) {
 	claims := JWTClaims{
+		SessionID:      sessionID,
 		UserID:         userID,
 		Email:          email,
 		ActiveTenantID: activeTenantID,
 		ActiveRole:     activeRole,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expirationHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -56,12 +70,61 @@ func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		// Access tokens are always bound to a session
+		if claims.SessionID == 0 {
+			return nil, errors.New("token is not bound to a session")
+		}
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
+// GenerateChallengeJWT generates a short-lived challenge token for a pending login step
+// challengeID becomes the token's jti, which the caller stores to make the token single-use
+func GenerateChallengeJWT(userID uint, tenantID *uint, purpose, challengeID, secret string, ttl time.Duration) (string, error) {
+	claims := ChallengeClaims{
+		UserID:   userID,
+		TenantID: tenantID,
+		Purpose:  purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        challengeID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(secret))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign challenge token: %w", err)
+	}
+
+	return tokenString, nil
+}
+
+// ValidateChallengeJWT validates a challenge token and checks its purpose
+func ValidateChallengeJWT(tokenString, purpose, secret string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse challenge token: %w", err)
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.ID == "" {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	return claims, nil
+}
+
 // ExtractTokenFromHeader extracts the JWT token from the Authorization header
 func ExtractTokenFromHeader(authHeader string) (string, error) {
 	if authHeader == "" {
@@ -0,0 +1,12 @@
+#!/bin/sh
+# Creates the API's login roles when the database volume is first initialized (see migration 000017):
+# habitta_app is subject to row-level security; habitta_worker bypasses it for background work across tenants.
+# Migrations keep running as POSTGRES_USER, the owner of the schema.
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v app_password="$HABITTA_APP_PASSWORD" \
+	-v worker_password="$HABITTA_WORKER_PASSWORD" <<'EOSQL'
+CREATE ROLE habitta_app LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOBYPASSRLS PASSWORD :'app_password';
+CREATE ROLE habitta_worker LOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE BYPASSRLS PASSWORD :'worker_password';
+EOSQL
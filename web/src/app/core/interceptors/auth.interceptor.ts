@@ -1,10 +1,11 @@
-import { HttpInterceptorFn, HttpErrorResponse } from '@angular/common/http';
+import { HttpInterceptorFn, HttpErrorResponse, HttpRequest } from '@angular/common/http';
 import { inject } from '@angular/core';
-import { catchError, throwError } from 'rxjs';
+import { catchError, switchMap, throwError } from 'rxjs';
 import { AuthService } from '../services';
 
 /**
  * Auth Interceptor - Adds JWT token to requests and handles 401 errors
+ * On 401 it tries to refresh the access token once before logging out
  * Automatically registered in app.config.ts with provideHttpClient(withInterceptors([authInterceptor]))
  */
 export const authInterceptor: HttpInterceptorFn = (req, next) => {
@@ -16,27 +17,36 @@ export const authInterceptor: HttpInterceptorFn = (req, next) => {
   const isPublicEndpoint =
     req.url.includes('/auth/login') ||
     req.url.includes('/auth/register') ||
+    req.url.includes('/auth/refresh') ||
     (req.url.includes('/invites/') && req.url.includes('/accept')) ||
     (req.url.match(/\/invites\/[a-f0-9-]+$/) && req.method === 'GET'); // GET /invites/:token
 
-  let authReq = req;
-  if (token && !isPublicEndpoint) {
-    authReq = req.clone({
-      setHeaders: {
-        Authorization: `Bearer ${token}`
-      }
-    });
-  }
+  const withToken = (request: HttpRequest<unknown>, accessToken: string | null) =>
+    accessToken && !isPublicEndpoint
+      ? request.clone({ setHeaders: { Authorization: `Bearer ${accessToken}` } })
+      : request;
 
   // Handle the request and catch errors
-  return next(authReq).pipe(
+  return next(withToken(req, token)).pipe(
     catchError((error: HttpErrorResponse) => {
-      // If we get a 401 Unauthorized error, logout the user
-      if (error.status === 401 && !isPublicEndpoint) {
-        console.error('Unauthorized request - logging out');
-        authService.logout();
+      if (error.status !== 401 || isPublicEndpoint || req.url.includes('/auth/logout')) {
+        return throwError(() => error);
+      }
+
+      // Access token expired or session revoked - try refreshing once
+      if (authService.hasRefreshToken()) {
+        return authService.refreshToken().pipe(
+          switchMap(tokens => next(withToken(req, tokens.token))),
+          catchError(refreshError => {
+            console.error('Session refresh failed - logging out');
+            authService.logout();
+            return throwError(() => refreshError);
+          })
+        );
       }
 
+      console.error('Unauthorized request - logging out');
+      authService.logout();
       return throwError(() => error);
     })
   );
@@ -10,8 +10,26 @@ export interface LoginRequest {
 // Login Response (can return token OR tenant list for multi-tenant users)
 export interface LoginResponse {
   token?: string;
+  refresh_token?: string;
+  expires_in?: number; // Access token lifetime in seconds
   user: User;
   tenants?: TenantSelectionInfo[]; // If user has multiple tenants
+  two_factor_required?: boolean; // Submit a code with challenge_token to finish login
+  challenge_token?: string;
+  two_factor_setup_required?: boolean; // Tenant requires 2FA; session started without tenant
+}
+
+// Second login step when 2FA is enabled
+export interface VerifyTwoFactorRequest {
+  challenge_token: string;
+  code: string;
+}
+
+// Token Pair (returned by refresh and switch-tenant)
+export interface TokenPair {
+  token: string;
+  refresh_token: string;
+  expires_in: number;
 }
 
 // Register Request (orphan user - no tenant_id)
@@ -25,6 +43,7 @@ export interface RegisterRequest {
 
 // JWT Token Payload (decoded)
 export interface JwtPayload {
+  session_id: number;
   user_id: number;
   email: string;
   active_tenant_id?: number; // Nullable - user may have no active tenant
@@ -1,6 +1,8 @@
 export interface Folder {
   id: number;
   tenant_id: number;
+  parent_id: number | null;
+  path: string;
   name: string;
   description: string;
   created_at: string;
@@ -17,6 +19,7 @@ export interface Document {
   size: number;
   s3_key: string;
   uploaded_by_id: number;
+  scan_status: 'pending' | 'clean' | 'infected' | 'failed';
   uploaded_by?: { id: number; name: string; email: string };
   folder?: Folder;
   created_at: string;
@@ -2,6 +2,10 @@
 export enum UserRole {
   ADMIN = 'admin',
   SINDICO = 'sindico',
+  SUBSINDICO = 'subsindico',
+  ADMINISTRADORA = 'administradora',
+  CONSELHEIRO_FISCAL = 'conselheiro_fiscal',
+  PORTEIRO = 'porteiro',
   MORADOR = 'morador',
 }
 
@@ -1,7 +1,7 @@
 import { Injectable, inject, signal, computed, effect } from '@angular/core';
 import { HttpClient } from '@angular/common/http';
 import { Router } from '@angular/router';
-import { Observable, tap, catchError, throwError, map } from 'rxjs';
+import { Observable, tap, catchError, throwError, map, finalize, shareReplay } from 'rxjs';
 import {
   User,
   UserRole,
@@ -10,6 +10,8 @@ import {
   RegisterRequest,
   JwtPayload,
   AuthState,
+  TokenPair,
+  VerifyTwoFactorRequest,
   TenantSelectionInfo
 } from '../models';
 
@@ -22,12 +24,14 @@ export class AuthService {
 
   private readonly API_URL = 'http://localhost:8080/api';
   private readonly TOKEN_KEY = 'habitta_token';
+  private readonly REFRESH_TOKEN_KEY = 'habitta_refresh_token';
   private readonly USER_KEY = 'habitta_user';
   private readonly ACTIVE_TENANT_KEY = 'habitta_active_tenant';
   private readonly ACTIVE_ROLE_KEY = 'habitta_active_role';
 
   // Signals for reactive state
   private readonly tokenSignal = signal<string | null>(null);
+  private readonly refreshTokenSignal = signal<string | null>(null);
   private readonly userSignal = signal<User | null>(null);
   private readonly activeTenantIdSignal = signal<number | null>(null);
   private readonly activeRoleSignal = signal<UserRole | null>(null);
@@ -40,6 +44,9 @@ export class AuthService {
   readonly isAuthenticated = computed(() => !!this.tokenSignal() && !!this.userSignal());
   readonly hasActiveTenant = computed(() => !!this.activeTenantIdSignal());
 
+  // In-flight refresh request shared by concurrent 401 retries
+  private refreshInFlight: Observable<TokenPair> | null = null;
+
   constructor() {
     // Initialize from localStorage on service creation
     this.initializeFromStorage();
@@ -54,6 +61,16 @@ export class AuthService {
       }
     });
 
+    // Effect to sync refresh token changes to localStorage
+    effect(() => {
+      const refreshToken = this.refreshTokenSignal();
+      if (refreshToken) {
+        localStorage.setItem(this.REFRESH_TOKEN_KEY, refreshToken);
+      } else {
+        localStorage.removeItem(this.REFRESH_TOKEN_KEY);
+      }
+    });
+
     // Effect to sync user changes to localStorage
     effect(() => {
       const user = this.userSignal();
@@ -90,6 +107,7 @@ export class AuthService {
    */
   private initializeFromStorage(): void {
     const storedToken = localStorage.getItem(this.TOKEN_KEY);
+    const storedRefreshToken = localStorage.getItem(this.REFRESH_TOKEN_KEY);
     const storedUser = localStorage.getItem(this.USER_KEY);
     const storedTenantId = localStorage.getItem(this.ACTIVE_TENANT_KEY);
     const storedRole = localStorage.getItem(this.ACTIVE_ROLE_KEY);
@@ -98,9 +116,10 @@ export class AuthService {
       try {
         const user = JSON.parse(storedUser) as User;
 
-        // Verify token is not expired
-        if (this.isTokenValid(storedToken)) {
+        // Keep the session if the access token is still valid or can be refreshed
+        if (this.isTokenValid(storedToken) || storedRefreshToken) {
           this.tokenSignal.set(storedToken);
+          this.refreshTokenSignal.set(storedRefreshToken);
           this.userSignal.set(user);
 
           if (storedTenantId) {
@@ -135,6 +154,7 @@ export class AuthService {
           if (data.token) {
             // User has single tenant or no tenant - set token
             this.setTokenAndExtractTenantInfo(data.token);
+            this.refreshTokenSignal.set(data.refresh_token ?? null);
           } else if (data.tenants && data.tenants.length > 0) {
             // User has multiple tenants - don't set token yet
             // Frontend should show tenant selector
@@ -159,6 +179,7 @@ export class AuthService {
 
           if (data.token) {
             this.setTokenAndExtractTenantInfo(data.token);
+            this.refreshTokenSignal.set(data.refresh_token ?? null);
           }
         }),
         catchError(error => {
@@ -168,6 +189,28 @@ export class AuthService {
       );
   }
 
+  /**
+   * Complete a login that requires a two-factor code
+   */
+  verifyTwoFactor(request: VerifyTwoFactorRequest): Observable<LoginResponse> {
+    return this.http.post<{data: LoginResponse}>(`${this.API_URL}/auth/2fa/verify`, request)
+      .pipe(
+        map(response => response.data),
+        tap(data => {
+          this.userSignal.set(data.user);
+
+          if (data.token) {
+            this.setTokenAndExtractTenantInfo(data.token);
+            this.refreshTokenSignal.set(data.refresh_token ?? null);
+          }
+        }),
+        catchError(error => {
+          console.error('Two-factor verification error:', error);
+          return throwError(() => error);
+        })
+      );
+  }
+
   /**
    * Register new orphan user (without tenant)
    */
@@ -190,12 +233,13 @@ export class AuthService {
   /**
    * Switch active tenant
    */
-  switchTenant(tenantId: number): Observable<{token: string}> {
-    return this.http.post<{data: {token: string}}>(`${this.API_URL}/auth/switch-tenant/${tenantId}`, {})
+  switchTenant(tenantId: number): Observable<TokenPair> {
+    return this.http.post<{data: TokenPair}>(`${this.API_URL}/auth/switch-tenant/${tenantId}`, {})
       .pipe(
         map(response => response.data),
         tap(data => {
           this.setTokenAndExtractTenantInfo(data.token);
+          this.refreshTokenSignal.set(data.refresh_token);
         }),
         catchError(error => {
           console.error('Switch tenant error:', error);
@@ -204,6 +248,41 @@ export class AuthService {
       );
   }
 
+  /**
+   * Exchange the refresh token for a new token pair
+   * Concurrent callers share the same in-flight request
+   */
+  refreshToken(): Observable<TokenPair> {
+    const refreshToken = this.refreshTokenSignal();
+    if (!refreshToken) {
+      return throwError(() => new Error('No refresh token available'));
+    }
+
+    if (!this.refreshInFlight) {
+      this.refreshInFlight = this.http.post<{data: TokenPair}>(`${this.API_URL}/auth/refresh`, { refresh_token: refreshToken })
+        .pipe(
+          map(response => response.data),
+          tap(data => {
+            this.setTokenAndExtractTenantInfo(data.token);
+            this.refreshTokenSignal.set(data.refresh_token);
+          }),
+          finalize(() => {
+            this.refreshInFlight = null;
+          }),
+          shareReplay(1)
+        );
+    }
+
+    return this.refreshInFlight;
+  }
+
+  /**
+   * Check if a refresh token is available
+   */
+  hasRefreshToken(): boolean {
+    return !!this.refreshTokenSignal();
+  }
+
   /**
    * Set token and extract tenant info from JWT
    */
@@ -221,7 +300,13 @@ export class AuthService {
    * Logout user and clear state
    */
   logout(): void {
+    // Revoke the session server-side (best effort)
+    if (this.tokenSignal()) {
+      this.http.post(`${this.API_URL}/auth/logout`, {}).subscribe({ error: () => {} });
+    }
+
     this.tokenSignal.set(null);
+    this.refreshTokenSignal.set(null);
     this.userSignal.set(null);
     this.activeTenantIdSignal.set(null);
     this.activeRoleSignal.set(null);
@@ -296,6 +381,7 @@ export class AuthService {
    */
   private clearStorage(): void {
     localStorage.removeItem(this.TOKEN_KEY);
+    localStorage.removeItem(this.REFRESH_TOKEN_KEY);
     localStorage.removeItem(this.USER_KEY);
     localStorage.removeItem(this.ACTIVE_TENANT_KEY);
     localStorage.removeItem(this.ACTIVE_ROLE_KEY);
@@ -37,8 +37,9 @@ export class DocumentService {
     );
   }
 
-  deleteFolder(id: number): Observable<SuccessResponse> {
-    return this.http.delete<SuccessResponse>(`${this.API_URL}/folders/${id}`);
+  deleteFolder(id: number, mode: 'recursive' | 'move_to_parent' = 'move_to_parent'): Observable<SuccessResponse> {
+    const params = new HttpParams().set('mode', mode);
+    return this.http.delete<SuccessResponse>(`${this.API_URL}/folders/${id}`, { params });
   }
 
   // Document operations
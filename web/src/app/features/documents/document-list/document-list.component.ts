@@ -287,11 +287,13 @@ export class DocumentListComponent implements OnInit {
       next: (url) => {
         window.open(url, '_blank');
       },
-      error: () => {
+      error: (err) => {
         this.messageService.add({
           severity: 'error',
           summary: 'Erro',
-          detail: 'Erro ao gerar link de download'
+          detail: err.status === 409
+            ? 'O arquivo ainda não passou pela verificação de vírus'
+            : 'Erro ao gerar link de download'
         });
       }
     });
@@ -167,6 +167,10 @@ export class AcceptInviteComponent implements OnInit {
     const labels: Record<string, string> = {
       'admin': 'Administrador',
       'sindico': 'Síndico',
+      'subsindico': 'Subsíndico',
+      'administradora': 'Administradora',
+      'conselheiro_fiscal': 'Conselheiro Fiscal',
+      'porteiro': 'Porteiro',
       'morador': 'Morador'
     };
     return labels[role] || role;
@@ -48,6 +48,10 @@ export class TenantSelectorComponent {
     const labels: Record<string, string> = {
       'admin': 'Administrador',
       'sindico': 'Síndico',
+      'subsindico': 'Subsíndico',
+      'administradora': 'Administradora',
+      'conselheiro_fiscal': 'Conselheiro Fiscal',
+      'porteiro': 'Porteiro',
       'morador': 'Morador'
     };
     return labels[role] || role;